@@ -0,0 +1,222 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/bernardmuller/go-idle/api"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Runtime role and permission management. Global roles (the ones with
+// no organization) and the permission catalog get CRUD under
+// /admin/roles and /admin/permissions, and admins assign roles to
+// users via POST /admin/users/:id/roles. The built-in admin and user
+// roles are seeded at startup so a fresh database is usable
+// immediately.
+
+// SeedDefaultRoles makes sure the built-in global roles exist.
+func SeedDefaultRoles() {
+	for _, name := range []string{"admin", "user"} {
+		var role models.Role
+		if err := DB.Where("name = ? AND organization_id IS NULL", name).First(&role).Error; err != nil {
+			DB.Create(&models.Role{Name: name})
+		}
+	}
+}
+
+type AdminRoleInput struct {
+	Name        string   `json:"name" binding:"required,min=2,max=64"`
+	Permissions []string `json:"permissions"`
+}
+
+// resolvePermissions maps names to catalog rows, rejecting unknowns.
+func resolvePermissions(c *gin.Context, names []string) ([]models.Permission, bool) {
+	perms := make([]models.Permission, 0, len(names))
+	for _, name := range names {
+		var perm models.Permission
+		if err := DB.Where("name = ?", name).First(&perm).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown permission", "permission": name})
+			return nil, false
+		}
+		perms = append(perms, perm)
+	}
+	return perms, true
+}
+
+func AdminListRoles(c *gin.Context) {
+	var roles []models.Role
+	err := RequestDB(c).Preload("Permissions").
+		Where("organization_id IS NULL").Order("name").Find(&roles).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, roles)
+}
+
+func AdminCreateRole(c *gin.Context) {
+	var input AdminRoleInput
+	if !api.BindJSON(c, &input) {
+		return
+	}
+	perms, ok := resolvePermissions(c, input.Permissions)
+	if !ok {
+		return
+	}
+
+	role := models.Role{Name: input.Name, Permissions: perms}
+	if err := RequestDB(c).Create(&role).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "role already exists"})
+		return
+	}
+	RenderJSON(c, http.StatusCreated, role)
+}
+
+func AdminUpdateRole(c *gin.Context) {
+	var role models.Role
+	if err := RequestDB(c).Where("organization_id IS NULL").First(&role, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "role not found"})
+		return
+	}
+	if role.Name == "admin" || role.Name == "user" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "built-in roles cannot be edited"})
+		return
+	}
+
+	var input AdminRoleInput
+	if !api.BindJSON(c, &input) {
+		return
+	}
+	perms, ok := resolvePermissions(c, input.Permissions)
+	if !ok {
+		return
+	}
+
+	role.Name = input.Name
+	if err := RequestDB(c).Save(&role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := RequestDB(c).Model(&role).Association("Permissions").Replace(perms); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	invalidateRoleMembers(role.ID)
+	RenderJSON(c, http.StatusOK, role)
+}
+
+func AdminDeleteRole(c *gin.Context) {
+	var role models.Role
+	if err := RequestDB(c).Where("organization_id IS NULL").First(&role, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "role not found"})
+		return
+	}
+	if role.Name == "admin" || role.Name == "user" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "built-in roles cannot be deleted"})
+		return
+	}
+
+	var holders int64
+	RequestDB(c).Model(&models.User{}).Where("role_id = ?", role.ID).Count(&holders)
+	if holders > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "role is still assigned to users", "users": holders})
+		return
+	}
+	if err := RequestDB(c).Delete(&role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+type AdminPermissionInput struct {
+	Name string `json:"name" binding:"required,min=2,max=64"`
+}
+
+func AdminListPermissions(c *gin.Context) {
+	var perms []models.Permission
+	if err := RequestDB(c).Order("name").Find(&perms).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, perms)
+}
+
+func AdminCreatePermission(c *gin.Context) {
+	var input AdminPermissionInput
+	if !api.BindJSON(c, &input) {
+		return
+	}
+	perm := models.Permission{Name: input.Name}
+	if err := RequestDB(c).Create(&perm).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "permission already exists"})
+		return
+	}
+	RenderJSON(c, http.StatusCreated, perm)
+}
+
+func AdminDeletePermission(c *gin.Context) {
+	var perm models.Permission
+	if err := RequestDB(c).First(&perm, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "permission not found"})
+		return
+	}
+	// Detach from any roles holding it, then remove from the catalog.
+	RequestDB(c).Exec("DELETE FROM role_permissions WHERE permission_id = ?", perm.ID)
+	if err := RequestDB(c).Delete(&perm).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+type AssignRoleInput struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// AdminAssignRole points a user at a global role by name.
+func AdminAssignRole(c *gin.Context) {
+	var user models.User
+	if err := RequestDB(c).First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	var input AssignRoleInput
+	if !api.BindJSON(c, &input) {
+		return
+	}
+	var role models.Role
+	if err := RequestDB(c).Preload("Permissions").
+		Where("name = ? AND organization_id IS NULL", input.Role).First(&role).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "role not found"})
+		return
+	}
+
+	// Sensitive grants wait for a second admin; see approvals.go.
+	if sensitiveRole(&role) {
+		requestRoleApproval(c, &user, &role)
+		return
+	}
+
+	if err := RequestDB(c).Model(&user).Update("role_id", role.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	userCache.Invalidate(user.ID)
+	recordAudit(AuditRoleAssigned, CurrentUserID(c), user.ID, c.ClientIP(), role.Name)
+	RenderJSON(c, http.StatusOK, gin.H{"user_id": user.ID, "role": role.Name})
+}
+
+// invalidateRoleMembers drops cached users whose permissions just
+// changed.
+func invalidateRoleMembers(roleID uint) {
+	var ids []uint
+	if err := DB.Model(&models.User{}).Where("role_id = ?", roleID).Pluck("id", &ids).Error; err != nil {
+		return
+	}
+	for _, id := range ids {
+		userCache.Invalidate(id)
+	}
+}
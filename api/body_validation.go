@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Structured validation for JSON bodies, the body-side counterpart of
+// QueryValidator. Inputs declare their rules with binding struct tags;
+// a failing request gets a 400 listing every bad field by its JSON
+// name, and unknown fields are rejected instead of silently dropped.
+
+// BindJSON decodes the request body into dst and validates it,
+// writing the field-level problem response itself. Handlers should
+// return immediately when it reports false.
+func BindJSON(c *gin.Context, dst interface{}) bool {
+	decoder := json.NewDecoder(c.Request.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dst); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "validation failed",
+			"fields": []FieldError{decodeFieldError(err)},
+		})
+		return false
+	}
+
+	if err := binding.Validator.ValidateStruct(dst); err != nil {
+		var verrs validator.ValidationErrors
+		if !errors.As(err, &verrs) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return false
+		}
+		fields := make([]FieldError, 0, len(verrs))
+		for _, fe := range verrs {
+			fields = append(fields, FieldError{
+				Field:   jsonFieldName(dst, fe.StructField()),
+				Message: validationMessage(fe),
+			})
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "validation failed",
+			"fields": fields,
+		})
+		return false
+	}
+	return true
+}
+
+// decodeFieldError turns a JSON decode failure into a field-level
+// problem where the error names one.
+func decodeFieldError(err error) FieldError {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return FieldError{Field: typeErr.Field, Message: "must be of type " + typeErr.Type.String()}
+	}
+	if msg := err.Error(); strings.HasPrefix(msg, "json: unknown field ") {
+		field := strings.Trim(strings.TrimPrefix(msg, "json: unknown field "), `"`)
+		return FieldError{Field: field, Message: "unknown field"}
+	}
+	return FieldError{Field: "body", Message: "invalid JSON"}
+}
+
+// validationMessage renders one rule violation in plain words.
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "min":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters", fe.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters", fe.Param())
+		}
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "url":
+		return "must be a valid URL"
+	default:
+		return "is invalid (" + fe.Tag() + ")"
+	}
+}
+
+// jsonFieldName resolves a struct field to the name clients know it
+// by.
+func jsonFieldName(dst interface{}, structField string) string {
+	t := reflect.TypeOf(dst)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return structField
+	}
+	if f, ok := t.FieldByName(structField); ok {
+		if tag, _, _ := strings.Cut(f.Tag.Get("json"), ","); tag != "" && tag != "-" {
+			return tag
+		}
+	}
+	return structField
+}
@@ -0,0 +1,25 @@
+package api
+
+import "github.com/gin-gonic/gin"
+
+// Keys the authentication middleware stores on the request context.
+const (
+	ContextUserID  = "userID"
+	ContextTokenID = "tokenID"
+	ContextOrgIDs  = "orgIDs"
+)
+
+// CurrentUserID returns the authenticated user's id from the context.
+func CurrentUserID(c *gin.Context) uint {
+	id, _ := c.Get(ContextUserID)
+	userID, _ := id.(uint)
+	return userID
+}
+
+// TokenOrgIDs returns the organization ids the caller's tenancy is
+// scoped to; empty means the account belongs to no organization.
+func TokenOrgIDs(c *gin.Context) []uint {
+	v, _ := c.Get(ContextOrgIDs)
+	ids, _ := v.([]uint)
+	return ids
+}
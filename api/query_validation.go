@@ -0,0 +1,95 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Structured validation for query strings. Handlers declare the
+// parameters they accept; anything malformed produces a 400 with
+// field-level problems instead of being silently ignored.
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type QueryValidator struct {
+	c    *gin.Context
+	errs []FieldError
+}
+
+func ValidateQuery(c *gin.Context) *QueryValidator {
+	return &QueryValidator{c: c}
+}
+
+func (q *QueryValidator) addError(field, format string, args ...interface{}) {
+	q.errs = append(q.errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Int parses an integer parameter and enforces inclusive bounds,
+// returning def when the parameter is absent.
+func (q *QueryValidator) Int(name string, def, min, max int) int {
+	raw := q.c.Query(name)
+	if raw == "" {
+		return def
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		q.addError(name, "must be an integer")
+		return def
+	}
+	if v < min || v > max {
+		q.addError(name, "must be between %d and %d", min, max)
+		return def
+	}
+	return v
+}
+
+// Enum restricts a string parameter to a fixed set of values.
+func (q *QueryValidator) Enum(name, def string, allowed ...string) string {
+	raw := q.c.Query(name)
+	if raw == "" {
+		return def
+	}
+	for _, a := range allowed {
+		if raw == a {
+			return raw
+		}
+	}
+	q.addError(name, "must be one of: %s", strings.Join(allowed, ", "))
+	return def
+}
+
+// Date parses an RFC 3339 timestamp or a plain 2006-01-02 date.
+func (q *QueryValidator) Date(name string) *time.Time {
+	raw := q.c.Query(name)
+	if raw == "" {
+		return nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return &t
+		}
+	}
+	q.addError(name, "must be an RFC 3339 timestamp or YYYY-MM-DD date")
+	return nil
+}
+
+// Valid writes the field-level problem response when anything failed;
+// handlers should return immediately when it reports false.
+func (q *QueryValidator) Valid() bool {
+	if len(q.errs) == 0 {
+		return true
+	}
+	q.c.JSON(http.StatusBadRequest, gin.H{
+		"error":  "query validation failed",
+		"fields": q.errs,
+	})
+	return false
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"encoding/json"
+	"mime"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JSON field naming compatibility. Struct tags in this codebase are
+// snake_case, but some existing clients were built against camelCase
+// output. Responses rendered through RenderJSON can be re-cased per
+// request (?naming= query param or a "naming" parameter on the Accept
+// header) or globally via the JSON_NAMING environment variable.
+
+const (
+	NamingSnake = "snake"
+	NamingCamel = "camel"
+)
+
+var defaultNaming = func() string {
+	if v := os.Getenv("JSON_NAMING"); v == NamingCamel {
+		return NamingCamel
+	}
+	return NamingSnake
+}()
+
+// RenderJSON writes v as JSON, re-casing field names to match the
+// naming style requested by the client.
+func RenderJSON(c *gin.Context, status int, v interface{}) {
+	if namingStyle(c) == NamingCamel {
+		c.JSON(status, recase(v, snakeToCamel))
+		return
+	}
+	c.JSON(status, v)
+}
+
+func namingStyle(c *gin.Context) string {
+	if v := c.Query("naming"); v == NamingSnake || v == NamingCamel {
+		return v
+	}
+	if accept := c.GetHeader("Accept"); accept != "" {
+		if _, params, err := mime.ParseMediaType(accept); err == nil {
+			if v := params["naming"]; v == NamingSnake || v == NamingCamel {
+				return v
+			}
+		}
+	}
+	return defaultNaming
+}
+
+// recase round-trips v through encoding/json and rewrites every object
+// key with conv. Tags stay snake_case in source; this is purely an
+// output transform.
+func recase(v interface{}, conv func(string) string) interface{} {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return v
+	}
+	return recaseValue(decoded, conv)
+}
+
+func recaseValue(v interface{}, conv func(string) string) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			out[conv(k)] = recaseValue(val, conv)
+		}
+		return out
+	case []interface{}:
+		for i, val := range t {
+			t[i] = recaseValue(val, conv)
+		}
+		return t
+	default:
+		return v
+	}
+}
+
+func snakeToCamel(s string) string {
+	parts := strings.Split(s, "_")
+	var b strings.Builder
+	for i, p := range parts {
+		if p == "" {
+			continue
+		}
+		if i == 0 {
+			b.WriteString(p)
+			continue
+		}
+		r := []rune(p)
+		r[0] = unicode.ToUpper(r[0])
+		b.WriteString(string(r))
+	}
+	return b.String()
+}
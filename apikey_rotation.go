@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/bernardmuller/go-idle/api"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// API key rotation. Keys already expire individually; this adds the
+// policy layer: an organization can require its members' keys to be
+// rotated every N days (PUT /orgs/:id/api-key-policy, org admins
+// only), POST /me/api-keys/:id/rotate mints a successor with the same
+// name and scopes while the old key keeps working through a grace
+// overlap so deploys can swap credentials without a hard cutover, and
+// a daily sweep stamps policy deadlines onto unexpired keys and
+// notifies owners a week before their keys stop working.
+
+const apiKeyExpiryWarning = 7 * 24 * time.Hour
+
+// apiKeyRotationGrace is the overlap during which a rotated-out key
+// still authenticates.
+func apiKeyRotationGrace() time.Duration {
+	if v, err := strconv.Atoi(os.Getenv("API_KEY_ROTATION_GRACE_DAYS")); err == nil && v > 0 {
+		return time.Duration(v) * 24 * time.Hour
+	}
+	return 7 * 24 * time.Hour
+}
+
+// RotateMyAPIKey replaces a key with a fresh one carrying the same
+// name and scopes. The old key stays valid for the grace period (or
+// until its own expiry, whichever comes first), so both work during
+// the switch.
+func RotateMyAPIKey(c *gin.Context) {
+	userID := CurrentUserID(c)
+
+	var key models.APIKey
+	err := RequestDB(c).Where("user_id = ? AND revoked_at IS NULL", userID).First(&key, c.Param("id")).Error
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "api key not found"})
+		return
+	}
+
+	successor, plaintext, err := mintAPIKey(c, userID, key.Name, key.Scopes, orgRotationDeadlineDays(userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	graceEnd := time.Now().Add(apiKeyRotationGrace())
+	if key.ExpiresAt == nil || key.ExpiresAt.After(graceEnd) {
+		if err := RequestDB(c).Model(&key).Update("expires_at", graceEnd).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		key.ExpiresAt = &graceEnd
+	}
+	recordAudit(AuditAPIKeyRotated, userID, 0, c.ClientIP(), key.Name)
+
+	RenderJSON(c, http.StatusCreated, gin.H{
+		"api_key":         successor,
+		"key":             plaintext,
+		"old_key_expires": key.ExpiresAt,
+	})
+}
+
+// orgRotationDeadlineDays returns the strictest rotation policy across
+// the user's organizations, 0 when none applies.
+func orgRotationDeadlineDays(userID uint) int {
+	var days []int
+	DB.Model(&models.Organization{}).
+		Joins("JOIN memberships ON memberships.organization_id = organizations.id").
+		Where("memberships.user_id = ? AND organizations.api_key_rotation_days > 0", userID).
+		Pluck("organizations.api_key_rotation_days", &days)
+	strictest := 0
+	for _, d := range days {
+		if strictest == 0 || d < strictest {
+			strictest = d
+		}
+	}
+	return strictest
+}
+
+type apiKeyPolicyInput struct {
+	RotationDays int `json:"rotation_days" binding:"min=0,max=3650"`
+}
+
+// SetOrgAPIKeyPolicy sets (or clears, with 0) the org's rotation
+// policy; existing keys pick the deadline up on the next sweep.
+func SetOrgAPIKeyPolicy(c *gin.Context) {
+	org, ok := requireOrgAdmin(c)
+	if !ok {
+		return
+	}
+	var input apiKeyPolicyInput
+	if !api.BindJSON(c, &input) {
+		return
+	}
+	if err := RequestDB(c).Model(org).Update("api_key_rotation_days", input.RotationDays).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, gin.H{"organization_id": org.ID, "rotation_days": input.RotationDays})
+}
+
+// enforceRotationPolicies stamps each policy-bound key with the expiry
+// its organization's policy implies: created_at + N days + grace,
+// clamped to never pull an already-set earlier expiry later.
+func enforceRotationPolicies() {
+	grace := apiKeyRotationGrace()
+	var orgs []models.Organization
+	if err := DB.Where("api_key_rotation_days > 0").Find(&orgs).Error; err != nil {
+		return
+	}
+	for _, org := range orgs {
+		deadline := time.Duration(org.APIKeyRotationDays)*24*time.Hour + grace
+		DB.Exec(`UPDATE api_keys SET expires_at = api_keys.created_at + ?::interval
+			FROM memberships
+			WHERE memberships.user_id = api_keys.user_id
+			  AND memberships.organization_id = ?
+			  AND api_keys.revoked_at IS NULL
+			  AND (api_keys.expires_at IS NULL OR api_keys.expires_at > api_keys.created_at + ?::interval)`,
+			fmt.Sprintf("%d hours", int(deadline.Hours())), org.ID,
+			fmt.Sprintf("%d hours", int(deadline.Hours())))
+	}
+}
+
+// notifyExpiringKeys warns each owner once per key as its expiry
+// enters the warning window.
+func notifyExpiringKeys() {
+	now := time.Now()
+	var keys []models.APIKey
+	err := DB.Where("revoked_at IS NULL AND expiry_notified_at IS NULL AND expires_at IS NOT NULL AND expires_at BETWEEN ? AND ?",
+		now, now.Add(apiKeyExpiryWarning)).Find(&keys).Error
+	if err != nil {
+		return
+	}
+	for _, key := range keys {
+		notificationRouter.Send(key.UserID, Notification{
+			Type:  "security",
+			Title: "API key expiring soon",
+			Body:  fmt.Sprintf("Your API key %q (%s…) expires %s. Rotate it to avoid an outage.", key.Name, key.Prefix, key.ExpiresAt.Format("2006-01-02")),
+		})
+		DB.Model(&key).Update("expiry_notified_at", now)
+	}
+}
+
+// StartAPIKeyRotation runs the policy sweep and expiry warnings daily.
+func StartAPIKeyRotation() {
+	go func() {
+		for {
+			enforceRotationPolicies()
+			notifyExpiringKeys()
+			time.Sleep(24 * time.Hour)
+		}
+	}()
+}
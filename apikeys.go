@@ -0,0 +1,172 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bernardmuller/go-idle/api"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// API keys for machine-to-machine clients. Cron jobs and backend
+// services can't do the interactive login flow, so a user may mint
+// long-lived keys that the Authenticate middleware accepts via
+// X-API-Key as an alternative to a bearer token. The plaintext is
+// shown exactly once at creation and only its hash is stored, the same
+// treatment password-reset tokens get. Keys are scoped by route tag —
+// the grouping the route table already maintains for metrics and
+// OpenAPI — so a key made for automation can reach /game without also
+// being able to change the account's password.
+
+const (
+	apiKeyPrefix  = "gi_"
+	apiKeyMaxKeys = 20
+)
+
+type apiKeyInput struct {
+	Name          string   `json:"name" binding:"required,min=2,max=64"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=1,max=3650"`
+}
+
+// CreateMyAPIKey mints a key. The response carries the plaintext; it
+// is not recoverable afterwards.
+func CreateMyAPIKey(c *gin.Context) {
+	var input apiKeyInput
+	if !api.BindJSON(c, &input) {
+		return
+	}
+	userID := CurrentUserID(c)
+
+	var count int64
+	RequestDB(c).Model(&models.APIKey{}).Where("user_id = ? AND revoked_at IS NULL", userID).Count(&count)
+	if count >= apiKeyMaxKeys {
+		c.JSON(http.StatusConflict, gin.H{"error": "api key limit reached"})
+		return
+	}
+
+	// Scopes must name real route tags, or a typo silently locks the
+	// key out of everything.
+	for _, scope := range input.Scopes {
+		if !knownRouteTag(scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown scope " + scope})
+			return
+		}
+	}
+
+	key, plaintext, err := mintAPIKey(c, userID, input.Name, strings.Join(input.Scopes, ","), input.ExpiresInDays)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	RenderJSON(c, http.StatusCreated, gin.H{"api_key": key, "key": plaintext})
+}
+
+// mintAPIKey creates one key and returns it with its plaintext;
+// shared by creation and rotation. expiresInDays of 0 means no
+// individual expiry (a policy sweep may still stamp one on later).
+func mintAPIKey(c *gin.Context, userID uint, name, scopes string, expiresInDays int) (models.APIKey, string, error) {
+	raw := make([]byte, 20)
+	rand.Read(raw)
+	plaintext := apiKeyPrefix + hex.EncodeToString(raw)
+
+	key := models.APIKey{
+		UserID:  userID,
+		Name:    name,
+		KeyHash: hashResetToken(plaintext),
+		Prefix:  plaintext[:len(apiKeyPrefix)+6],
+		Scopes:  scopes,
+	}
+	if expiresInDays > 0 {
+		expires := time.Now().AddDate(0, 0, expiresInDays)
+		key.ExpiresAt = &expires
+	}
+	if err := RequestDB(c).Create(&key).Error; err != nil {
+		return models.APIKey{}, "", err
+	}
+	recordAudit(AuditAPIKeyCreated, userID, 0, c.ClientIP(), key.Name)
+	return key, plaintext, nil
+}
+
+// GetMyAPIKeys lists the caller's keys; prefixes only, never material.
+func GetMyAPIKeys(c *gin.Context) {
+	var keys []models.APIKey
+	if err := RequestDB(c).Where("user_id = ?", CurrentUserID(c)).Order("id").Find(&keys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, keys)
+}
+
+// DeleteMyAPIKey revokes a key. The row is kept so the audit trail can
+// still name what a revoked key was called.
+func DeleteMyAPIKey(c *gin.Context) {
+	var key models.APIKey
+	if err := RequestDB(c).Where("user_id = ?", CurrentUserID(c)).First(&key, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "api key not found"})
+		return
+	}
+	if key.RevokedAt == nil {
+		now := time.Now()
+		if err := RequestDB(c).Model(&key).Update("revoked_at", now).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	recordAudit(AuditAPIKeyRevoked, CurrentUserID(c), 0, c.ClientIP(), key.Name)
+	c.Status(http.StatusNoContent)
+}
+
+// knownRouteTag reports whether any route in the table carries tag.
+func knownRouteTag(tag string) bool {
+	for _, t := range routeTags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticateAPIKey is the X-API-Key branch of Authenticate. It
+// resolves the key, enforces scope against the current route's tag,
+// and stamps the context the same way the JWT path does.
+func authenticateAPIKey(c *gin.Context, plaintext string) {
+	var key models.APIKey
+	err := RequestDB(c).Where("key_hash = ? AND revoked_at IS NULL", hashResetToken(plaintext)).First(&key).Error
+	if err != nil || (key.ExpiresAt != nil && time.Now().After(*key.ExpiresAt)) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid api key"})
+		return
+	}
+
+	if key.Scopes != "" {
+		tag := routeTags[c.Request.Method+" "+c.FullPath()]
+		allowed := false
+		for _, scope := range strings.Split(key.Scopes, ",") {
+			if scope == tag {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "api key lacks scope for this route"})
+			return
+		}
+	}
+
+	// Last-used is advisory, so it's written at most once a minute per
+	// key rather than per request.
+	now := time.Now()
+	DB.Model(&models.APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", key.ID, now.Add(-time.Minute)).
+		Update("last_used_at", now)
+
+	c.Set("userID", key.UserID)
+	c.Set("apiKeyID", key.ID)
+	withActor(c, key.UserID)
+	c.Next()
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Sign in with Apple. The client obtains an identity token from Apple
+// and posts it here; we verify it against Apple's published JWKS and
+// create or link a local account.
+
+const (
+	appleIssuer  = "https://appleid.apple.com"
+	appleJWKSURL = "https://appleid.apple.com/auth/keys"
+
+	// Apple hides the real address behind its private email relay for
+	// users who choose "Hide My Email".
+	applePrivateRelayDomain = "privaterelay.appleid.com"
+)
+
+type AppleLoginInput struct {
+	IdentityToken string `json:"identity_token"`
+	Name          string `json:"name"`
+}
+
+type appleJWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type appleKeySet struct {
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+var appleKeys appleKeySet
+
+func (s *appleKeySet) get(kid string) (*rsa.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key, ok := s.keys[kid]; ok && time.Since(s.fetchedAt) < time.Hour {
+		return key, nil
+	}
+
+	resp, err := http.Get(appleJWKSURL)
+	if err != nil {
+		return nil, fmt.Errorf("fetching apple jwks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Keys []appleJWK `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decoding apple jwks: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(body.Keys))
+	for _, k := range body.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+	s.keys = keys
+	s.fetchedAt = time.Now()
+
+	key, ok := keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("apple jwks: unknown kid %q", kid)
+	}
+	return key, nil
+}
+
+func (k appleJWK) publicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
+
+func AppleLogin(c *gin.Context) {
+	var input AppleLoginInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// With no client id there is no audience to pin the token to, and
+	// jwt.WithAudience("") skips the check entirely — a token minted for
+	// any other app would verify. Fail closed instead.
+	clientID := os.Getenv("APPLE_CLIENT_ID")
+	if clientID == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "apple login is not configured"})
+		return
+	}
+
+	token, err := jwt.Parse(input.IdentityToken, func(t *jwt.Token) (interface{}, error) {
+		if t.Method.Alg() != "RS256" {
+			return nil, fmt.Errorf("unexpected signing method %q", t.Method.Alg())
+		}
+		kid, _ := t.Header["kid"].(string)
+		return appleKeys.get(kid)
+	}, jwt.WithIssuer(appleIssuer), jwt.WithAudience(clientID))
+	if err != nil || !token.Valid {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid identity token"})
+		return
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	sub, _ := claims["sub"].(string)
+	email, _ := claims["email"].(string)
+	if sub == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "identity token missing subject"})
+		return
+	}
+
+	user, err := findOrCreateSocialUser("apple", sub, email, input.Name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	jwtToken, tokenID, err := CreateTokenWithID(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create token"})
+		return
+	}
+	if err := createSession(c, user.ID, tokenID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create session"})
+		return
+	}
+
+	RenderJSON(c, http.StatusOK, gin.H{"token": jwtToken})
+}
+
+// findOrCreateSocialUser resolves a social identity to a local user,
+// linking by verified email where possible and creating a fresh
+// account otherwise.
+func findOrCreateSocialUser(provider, providerID, email, name string) (*models.User, error) {
+	var account models.SocialAccount
+	err := DB.Where("provider = ? AND provider_id = ?", provider, providerID).First(&account).Error
+	if err == nil {
+		var user models.User
+		if err := DB.First(&user, account.UserID).Error; err != nil {
+			return nil, err
+		}
+		return &user, nil
+	}
+
+	// Private relay addresses are minted per app, so they can never
+	// belong to a pre-existing password account; only link on real,
+	// Apple-verified addresses.
+	linkable := email != "" && !strings.HasSuffix(email, "@"+applePrivateRelayDomain)
+
+	var user models.User
+	if linkable {
+		err = DB.Where("email = ?", email).First(&user).Error
+	}
+	if !linkable || err != nil {
+		// No linkable account: create one with an unusable password.
+		random := make([]byte, 32)
+		rand.Read(random)
+		hash, hashErr := bcrypt.GenerateFromPassword(random, bcrypt.DefaultCost)
+		if hashErr != nil {
+			return nil, hashErr
+		}
+		user = models.User{Name: name, Email: email, Password: string(hash)}
+		if err := DB.Create(&user).Error; err != nil {
+			return nil, err
+		}
+		DB.Create(&models.Player{UserID: user.ID, GoldRate: 1, LastTickAt: time.Now()})
+		indexUser(&user)
+	}
+
+	account = models.SocialAccount{UserID: user.ID, Provider: provider, ProviderID: providerID, Email: email}
+	if err := DB.Create(&account).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
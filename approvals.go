@@ -0,0 +1,166 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/bernardmuller/go-idle/api"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Four-eyes approval for sensitive grants. Assigning the admin role —
+// or any role carrying the admin or support permission — is too big a
+// lever for one compromised account, so AdminAssignRole parks such
+// changes as a pending approval instead of applying them; a second
+// admin reviews the queue at GET /admin/approvals and decides via
+// POST /admin/approvals/:id. The requester cannot approve their own
+// request, undecided requests expire, and every transition lands in
+// the audit log.
+
+// approvalTTL is how long a pending request stays actionable.
+const approvalTTL = 72 * time.Hour
+
+const (
+	ApprovalPending  = "pending"
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
+	ApprovalExpired  = "expired"
+)
+
+// sensitiveRole reports whether granting the role needs a second pair
+// of eyes.
+func sensitiveRole(role *models.Role) bool {
+	if role.Name == "admin" {
+		return true
+	}
+	for _, p := range role.Permissions {
+		if p.Name == "admin" || p.Name == "support" {
+			return true
+		}
+	}
+	return false
+}
+
+// requestRoleApproval parks a sensitive grant for a second admin.
+func requestRoleApproval(c *gin.Context, user *models.User, role *models.Role) {
+	actorID := CurrentUserID(c)
+
+	// One pending request per user+role; re-requesting just returns it.
+	var existing models.RoleApproval
+	err := RequestDB(c).Where("user_id = ? AND role_id = ? AND status = ? AND expires_at > ?",
+		user.ID, role.ID, ApprovalPending, time.Now()).First(&existing).Error
+	if err == nil {
+		RenderJSON(c, http.StatusAccepted, existing)
+		return
+	}
+
+	approval := models.RoleApproval{
+		UserID:      user.ID,
+		RoleID:      role.ID,
+		RoleName:    role.Name,
+		RequestedBy: actorID,
+		Status:      ApprovalPending,
+		ExpiresAt:   time.Now().Add(approvalTTL),
+		CreatedAt:   time.Now(),
+	}
+	if err := RequestDB(c).Create(&approval).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(AuditApprovalRequested, actorID, user.ID, c.ClientIP(), role.Name)
+	RenderJSON(c, http.StatusAccepted, approval)
+}
+
+// expireStaleApprovals flips pending rows past their deadline; run
+// lazily from the list and decide paths so no scheduler is needed.
+func expireStaleApprovals(c *gin.Context) {
+	RequestDB(c).Model(&models.RoleApproval{}).
+		Where("status = ? AND expires_at <= ?", ApprovalPending, time.Now()).
+		Update("status", ApprovalExpired)
+}
+
+// AdminListApprovals returns the approval queue, pending first.
+func AdminListApprovals(c *gin.Context) {
+	q := api.ValidateQuery(c)
+	status := q.Enum("status", ApprovalPending, ApprovalPending, ApprovalApproved, ApprovalRejected, ApprovalExpired)
+	limit := q.Int("limit", 50, 1, 200)
+	if !q.Valid() {
+		return
+	}
+	expireStaleApprovals(c)
+
+	var approvals []models.RoleApproval
+	err := ReadRequestDB(c).Where("status = ?", status).
+		Order("created_at DESC").Limit(limit).Find(&approvals).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, approvals)
+}
+
+type approvalDecisionInput struct {
+	Decision string `json:"decision" binding:"required,oneof=approve reject"`
+}
+
+// AdminDecideApproval records a second admin's decision and, on
+// approval, applies the parked role change.
+func AdminDecideApproval(c *gin.Context) {
+	var input approvalDecisionInput
+	if !api.BindJSON(c, &input) {
+		return
+	}
+	expireStaleApprovals(c)
+
+	var approval models.RoleApproval
+	if err := RequestDB(c).First(&approval, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "approval not found"})
+		return
+	}
+	if approval.Status != ApprovalPending {
+		c.JSON(http.StatusConflict, gin.H{"error": "approval is no longer pending", "status": approval.Status})
+		return
+	}
+	actorID := CurrentUserID(c)
+	if actorID == approval.RequestedBy {
+		c.JSON(http.StatusForbidden, gin.H{"error": "a different admin must decide this request"})
+		return
+	}
+
+	status := ApprovalRejected
+	if input.Decision == "approve" {
+		status = ApprovalApproved
+	}
+	now := time.Now()
+
+	// Guard on pending so two admins deciding at once resolve to one
+	// decision.
+	res := RequestDB(c).Model(&models.RoleApproval{}).
+		Where("id = ? AND status = ?", approval.ID, ApprovalPending).
+		Updates(map[string]interface{}{"status": status, "decided_by": actorID, "decided_at": now})
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": res.Error.Error()})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "approval was decided concurrently"})
+		return
+	}
+
+	if status == ApprovalApproved {
+		if err := RequestDB(c).Model(&models.User{}).Where("id = ?", approval.UserID).
+			Update("role_id", approval.RoleID).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		userCache.Invalidate(approval.UserID)
+		recordAudit(AuditRoleAssigned, actorID, approval.UserID, c.ClientIP(), approval.RoleName)
+	}
+	recordAudit(AuditApprovalDecided, actorID, approval.UserID, c.ClientIP(), approval.RoleName+": "+status)
+
+	approval.Status = status
+	approval.DecidedBy = &actorID
+	approval.DecidedAt = &now
+	RenderJSON(c, http.StatusOK, approval)
+}
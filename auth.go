@@ -0,0 +1,302 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bernardmuller/go-idle/api"
+	"github.com/bernardmuller/go-idle/config"
+	"github.com/bernardmuller/go-idle/hooks"
+	"github.com/bernardmuller/go-idle/internal/handlers"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/bernardmuller/go-idle/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwtSecret is assigned from the loaded configuration at startup.
+var jwtSecret []byte
+
+// authService holds the credential logic; main wires it so the same
+// rules back HTTP and any future transport.
+var authService *services.AuthService
+
+type RegisterInput struct {
+	Name     string `json:"name" binding:"required,min=2,max=64"`
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,min=8,max=128"`
+}
+
+type LoginInput struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+
+	// OTP answers a medium-risk login challenge.
+	OTP string `json:"otp"`
+}
+
+func Register(c *gin.Context) {
+	var input RegisterInput
+	if !api.BindJSON(c, &input) {
+		return
+	}
+
+	if ok, reason := registrationEmailAllowed(input.Email); !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": reason})
+		return
+	}
+	if rejectBreachedPassword(c, input.Password) {
+		return
+	}
+
+	user, err := authService.Register(c.Request.Context(), input.Name, input.Email, input.Password)
+	if err != nil {
+		handlers.RenderError(c, err)
+		return
+	}
+
+	indexUser(&user)
+	recordAudit(AuditUserRegistered, user.ID, 0, c.ClientIP(), user.Email)
+	PublishEvent("user.registered", map[string]interface{}{"user_id": user.ID, "email": user.Email})
+	hooks.RunUserRegistered(&user)
+
+	c.Header("Location", fmt.Sprintf("/users/%d", user.ID))
+	RenderJSON(c, http.StatusCreated, handlers.NewUserResponse(user))
+}
+
+func Login(c *gin.Context) {
+	var input LoginInput
+	if !api.BindJSON(c, &input) {
+		return
+	}
+
+	if cooldown, locked := loginLocked(input.Email); locked {
+		c.Header("Retry-After", strconv.FormatInt(int64(cooldown.Seconds())+1, 10))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "account temporarily locked, try again later"})
+		return
+	}
+
+	user, err := authService.Authenticate(c.Request.Context(), input.Email, input.Password)
+	if err != nil {
+		recordLoginFailure(input.Email)
+		recordLoginOutcome(false)
+		// user.ID is the matched account on a wrong password, zero when
+		// the address was unknown; either way the attempt is recorded.
+		recordAudit(AuditLoginFailure, 0, user.ID, c.ClientIP(), input.Email)
+		PublishEvent("login.failed", map[string]interface{}{"user_id": user.ID, "email": input.Email})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		return
+	}
+	maybeUpgradeHash(&user, input.Password)
+
+	// The password was right; how suspicious is the attempt?
+	risk := loginRiskScore(&user, c.ClientIP())
+	if risk >= riskHighThreshold() {
+		notifyBlockedLogin(&user, c.ClientIP())
+		c.JSON(http.StatusForbidden, gin.H{"error": "login blocked; check your email"})
+		return
+	}
+	if risk >= riskMediumThreshold() {
+		if input.OTP == "" || !redeemLoginOTP(input.Email, input.OTP) {
+			if input.OTP == "" {
+				issueLoginOTP(&user)
+			}
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":     "additional verification required",
+				"challenge": "otp",
+			})
+			return
+		}
+	}
+	// Accounts with TOTP enabled get a short-lived challenge instead of
+	// a session; the real token is issued by POST /login/2fa.
+	if user.TOTPEnabledAt != nil {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":           "two-factor code required",
+			"challenge":       "totp",
+			"challenge_token": newTwoFactorChallenge(user.ID),
+		})
+		return
+	}
+
+	resetLoginFailures(input.Email)
+	recordLoginOutcome(true)
+	recordAudit(AuditLoginSuccess, user.ID, 0, c.ClientIP(), "")
+	PublishEvent("login.succeeded", map[string]interface{}{"user_id": user.ID})
+
+	issueLoginSession(c, &user)
+}
+
+// issueLoginSession mints the access token, records the session, and
+// writes the login response; it is the shared tail of Login and the
+// two-factor confirmation step.
+func issueLoginSession(c *gin.Context, user *models.User) {
+	token, tokenID, err := CreateTokenWithID(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create token"})
+		return
+	}
+	if err := createSession(c, user.ID, tokenID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create session"})
+		return
+	}
+
+	if cookieAuthEnabled() {
+		setAuthCookies(c, token)
+	}
+	hooks.RunLogin(user)
+
+	resp := loginResponse{Token: token}
+	if user.DeletionRequestedAt != nil {
+		// Logging in during the recovery window surfaces the pending
+		// deletion so the client can offer reactivation.
+		resp.DeletionScheduled = true
+		until := user.DeletionRequestedAt.Add(deletionRecoveryWindow)
+		resp.RecoverableUntil = &until
+	}
+	renderHot(c, http.StatusOK, &resp)
+}
+
+func CreateToken(userID uint) (string, error) {
+	token, _, err := CreateTokenWithID(userID)
+	return token, err
+}
+
+// CreateTokenWithID mints a token carrying a unique jti so the session
+// it belongs to can be tracked and revoked.
+func CreateTokenWithID(userID uint) (string, string, error) {
+	tokenID := newTokenID()
+	claims := jwt.MapClaims{
+		"sub": userID,
+		"jti": tokenID,
+		"exp": time.Now().Add(config.Get().TokenTTL).Unix(),
+		"iat": time.Now().Unix(),
+	}
+	applyClaimsHooks(userID, claims)
+	// Plugin hooks may enrich claims or veto issuance; reserved claims
+	// are snapshotted and reasserted so a plugin can't forge them.
+	reserved := make(map[string]interface{}, len(reservedClaims))
+	for k := range reservedClaims {
+		if v, ok := claims[k]; ok {
+			reserved[k] = v
+		}
+	}
+	if err := hooks.RunBeforeTokenIssue(userID, claims); err != nil {
+		return "", "", err
+	}
+	for k, v := range reserved {
+		claims[k] = v
+	}
+	signed, err := signToken(claims)
+	return signed, tokenID, err
+}
+
+// Authenticate extracts the bearer token and stores the user id on the context.
+func Authenticate(c *gin.Context) {
+	// Machine clients send an API key instead of a JWT; see apikeys.go.
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		authenticateAPIKey(c, key)
+		return
+	}
+
+	header := c.GetHeader("Authorization")
+	tokenString, hadPrefix := strings.CutPrefix(header, "Bearer ")
+	fromCookie := false
+	if (!hadPrefix || tokenString == "") && cookieAuthEnabled() {
+		if v, err := c.Cookie(authCookieName); err == nil && v != "" {
+			tokenString = v
+			fromCookie = true
+		}
+	}
+	if tokenString == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing bearer token"})
+		return
+	}
+
+	// A cookie is attached by the browser on cross-site requests, so
+	// cookie-authenticated writes must pass the double-submit check.
+	if fromCookie && !csrfSafeMethod(c.Request.Method) && !csrfTokenValid(c) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "missing or invalid CSRF token"})
+		return
+	}
+
+	userID, tokenID, orgIDs, err := parseAccessToken(tokenString)
+	if err == errSessionRevoked {
+		sessionUnauthorized(c)
+		return
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+	if tokenID != "" {
+		c.Set("tokenID", tokenID)
+	}
+	c.Set("userID", userID)
+	withActor(c, userID)
+	if len(orgIDs) > 0 {
+		c.Set(api.ContextOrgIDs, orgIDs)
+	}
+	c.Next()
+}
+
+var (
+	errTokenInvalid   = errors.New("invalid token")
+	errSessionRevoked = errors.New("session revoked")
+)
+
+// parseAccessToken verifies a bearer token string and returns its
+// subject, session id, and org claims. The HTTP middleware and the
+// gRPC interceptor share it so the two transports can never disagree
+// about what a valid token is.
+func parseAccessToken(tokenString string) (uint, string, []uint, error) {
+	// Pin the algorithms and require an expiry: a token that names a
+	// signing method we don't use (or none) must never verify, and the
+	// keyfunc resolves HMAC and asymmetric keys separately so one can
+	// never stand in for the other.
+	token, err := jwt.Parse(tokenString, jwtKeyfunc,
+		jwt.WithValidMethods(jwtValidMethods()),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil || !token.Valid {
+		return 0, "", nil, errTokenInvalid
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return 0, "", nil, errTokenInvalid
+	}
+	sub, ok := claims["sub"].(float64)
+	if !ok {
+		return 0, "", nil, errTokenInvalid
+	}
+	tokenID := ""
+	if jti, ok := claims["jti"].(string); ok {
+		if !sessionActive(jti) {
+			return 0, "", nil, errSessionRevoked
+		}
+		tokenID = jti
+	}
+	if !tokenVersionCurrent(uint(sub), claims) {
+		return 0, "", nil, errSessionRevoked
+	}
+	var orgIDs []uint
+	if rawOrgs, ok := claims["orgs"].([]interface{}); ok {
+		for _, raw := range rawOrgs {
+			if id, ok := raw.(float64); ok {
+				orgIDs = append(orgIDs, uint(id))
+			}
+		}
+	}
+	return uint(sub), tokenID, orgIDs, nil
+}
+
+// CurrentUserID returns the authenticated user's id from the context.
+func CurrentUserID(c *gin.Context) uint {
+	return api.CurrentUserID(c)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/bernardmuller/go-idle/config"
+)
+
+// Unit tests for the credential primitives; these need no database.
+
+func TestHashPassword(t *testing.T) {
+	cases := []struct {
+		name     string
+		password string
+	}{
+		{"short", "hunter22"},
+		{"typical", "correct horse battery staple"},
+		{"unicode", "pässwörd-ünïcode"},
+		// 71 bytes: the longest input bcrypt hashes without truncating.
+		{"long", strings.Repeat("x", 71)},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			hash, err := hashPassword(tc.password)
+			if err != nil {
+				t.Fatalf("hashPassword: %v", err)
+			}
+			if hash == tc.password {
+				t.Fatalf("hash equals the plaintext")
+			}
+			if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(tc.password)); err != nil {
+				t.Errorf("hash does not verify against its own password: %v", err)
+			}
+			if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(tc.password+"!")); err == nil {
+				t.Errorf("hash verifies against a different password")
+			}
+			cost, err := bcrypt.Cost([]byte(hash))
+			if err != nil || cost != bcryptTargetCost() {
+				t.Errorf("cost = %d (err %v), want %d", cost, err, bcryptTargetCost())
+			}
+		})
+	}
+}
+
+func TestCreateTokenWithID(t *testing.T) {
+	cases := []struct {
+		name   string
+		userID uint
+	}{
+		{"first user", 1},
+		{"large id", 4200000},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			signed, tokenID, err := CreateTokenWithID(tc.userID)
+			if err != nil {
+				t.Fatalf("CreateTokenWithID: %v", err)
+			}
+			if tokenID == "" {
+				t.Fatalf("empty token id")
+			}
+
+			token, err := jwt.Parse(signed, jwtKeyfunc,
+				jwt.WithValidMethods(jwtValidMethods()),
+				jwt.WithExpirationRequired(),
+			)
+			if err != nil || !token.Valid {
+				t.Fatalf("minted token does not verify: %v", err)
+			}
+			claims := token.Claims.(jwt.MapClaims)
+			if sub, _ := claims["sub"].(float64); uint(sub) != tc.userID {
+				t.Errorf("sub = %v, want %d", claims["sub"], tc.userID)
+			}
+			if jti, _ := claims["jti"].(string); jti != tokenID {
+				t.Errorf("jti = %v, want %s", claims["jti"], tokenID)
+			}
+
+			exp, _ := claims["exp"].(float64)
+			iat, _ := claims["iat"].(float64)
+			if ttl := time.Duration(exp-iat) * time.Second; ttl != config.Get().TokenTTL {
+				t.Errorf("token ttl = %v, want %v", ttl, config.Get().TokenTTL)
+			}
+		})
+	}
+}
+
+// TestTamperedTokenRejected flips one payload byte and expects
+// verification to fail.
+func TestTamperedTokenRejected(t *testing.T) {
+	signed, _, err := CreateTokenWithID(7)
+	if err != nil {
+		t.Fatalf("CreateTokenWithID: %v", err)
+	}
+
+	parts := strings.Split(signed, ".")
+	if len(parts) != 3 {
+		t.Fatalf("token has %d segments, want 3", len(parts))
+	}
+	payload := []byte(parts[1])
+	if payload[0] == 'A' {
+		payload[0] = 'B'
+	} else {
+		payload[0] = 'A'
+	}
+	tampered := parts[0] + "." + string(payload) + "." + parts[2]
+
+	if _, err := jwt.Parse(tampered, jwtKeyfunc, jwt.WithValidMethods(jwtValidMethods())); err == nil {
+		t.Errorf("tampered token verified")
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Role-based authorization. Users carry a Role, roles carry
+// Permissions from the global catalog; RequirePermission gates a route
+// on one of them. The built-in "admin" role implicitly holds every
+// permission.
+
+// userHasPermission checks the user's role for a permission.
+func userHasPermission(userID uint, permission string) bool {
+	user, err := loadUserCached(userID)
+	if err != nil || user.Role == nil {
+		return false
+	}
+	if user.Role.Name == "admin" {
+		return true
+	}
+	for _, p := range user.Role.Permissions {
+		if p.Name == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// RequirePermission aborts with 403 unless the authenticated user's
+// role grants the permission.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := CurrentUserID(c)
+		if userID == 0 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			return
+		}
+		if !userHasPermission(userID, permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":      "permission denied",
+				"permission": permission,
+			})
+			return
+		}
+		c.Next()
+	}
+}
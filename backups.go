@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/bernardmuller/go-idle/config"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Scheduled database backups. With BACKUP_DIR set (typically an object
+// store mount), the scheduler runs pg_dump on an interval, records
+// metadata in the backups table, prunes dumps past the retention
+// count, and queues a verification job that test-restores the dump
+// into a scratch database. GET /admin/backups shows the history;
+// POST /admin/backups/run triggers one out of schedule.
+
+const backupVerifyJobType = "backup_verify"
+
+func backupDir() string {
+	return os.Getenv("BACKUP_DIR")
+}
+
+func backupInterval() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("BACKUP_INTERVAL")); err == nil && d > 0 {
+		return d
+	}
+	return 24 * time.Hour
+}
+
+func backupRetention() int {
+	if v, err := strconv.Atoi(os.Getenv("BACKUP_RETENTION")); err == nil && v > 0 {
+		return v
+	}
+	return 7
+}
+
+// runBackup produces one custom-format dump and records the outcome.
+func runBackup() (*models.Backup, error) {
+	backup := models.Backup{Status: "running", StartedAt: time.Now()}
+	if err := DB.Create(&backup).Error; err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(backupDir(), fmt.Sprintf("go-idle-%s-%d.dump", time.Now().UTC().Format("20060102-150405"), backup.ID))
+	out, err := exec.Command("pg_dump", "--format=custom", "--file="+path, config.Get().DatabaseURL).CombinedOutput()
+
+	now := time.Now()
+	backup.FinishedAt = &now
+	backup.Location = path
+	if err != nil {
+		backup.Status = "failed"
+		backup.Error = fmt.Sprintf("%v: %s", err, out)
+		DB.Save(&backup)
+		return &backup, fmt.Errorf("pg_dump: %w", err)
+	}
+	if info, statErr := os.Stat(path); statErr == nil {
+		backup.SizeBytes = info.Size()
+	}
+	backup.Status = "complete"
+	DB.Save(&backup)
+
+	pruneBackups()
+	EnqueueJob(backupVerifyJobType, strconv.FormatUint(uint64(backup.ID), 10), time.Now())
+	return &backup, nil
+}
+
+// pruneBackups deletes dumps (and marks their rows) beyond the
+// retention count, oldest first.
+func pruneBackups() {
+	var complete []models.Backup
+	if err := DB.Where("status IN ?", []string{"complete", "verified"}).Find(&complete).Error; err != nil {
+		return
+	}
+	if len(complete) <= backupRetention() {
+		return
+	}
+	sort.Slice(complete, func(i, j int) bool { return complete[i].StartedAt.Before(complete[j].StartedAt) })
+	for _, old := range complete[:len(complete)-backupRetention()] {
+		os.Remove(old.Location)
+		DB.Model(&models.Backup{}).Where("id = ?", old.ID).Update("status", "pruned")
+	}
+}
+
+// verifyBackup test-restores a dump into a scratch database, then
+// drops it. A dump that restores cleanly is the only one worth
+// keeping.
+func verifyBackup(payload string) error {
+	id, err := strconv.Atoi(payload)
+	if err != nil {
+		return fmt.Errorf("bad backup id %q", payload)
+	}
+	var backup models.Backup
+	if err := DB.First(&backup, id).Error; err != nil {
+		return err
+	}
+
+	scratch := fmt.Sprintf("go_idle_verify_%d", backup.ID)
+	if err := DB.Exec("CREATE DATABASE " + scratch).Error; err != nil {
+		return fmt.Errorf("creating scratch database: %w", err)
+	}
+	defer DB.Exec("DROP DATABASE IF EXISTS " + scratch)
+
+	out, err := exec.Command("pg_restore", "--dbname="+scratchDSN(scratch), "--no-owner", backup.Location).CombinedOutput()
+	if err != nil {
+		DB.Model(&backup).Updates(map[string]interface{}{
+			"status": "verify_failed",
+			"error":  fmt.Sprintf("%v: %s", err, out),
+		})
+		return fmt.Errorf("pg_restore: %w", err)
+	}
+
+	now := time.Now()
+	return DB.Model(&backup).Updates(map[string]interface{}{
+		"status":      "verified",
+		"verified_at": now,
+	}).Error
+}
+
+// scratchDSN points the restore at the scratch database on the same
+// server, coping with both URL and key-value DSNs.
+func scratchDSN(dbname string) string {
+	dsn := config.Get().DatabaseURL
+	if u, err := url.Parse(dsn); err == nil && u.Host != "" {
+		u.Path = "/" + dbname
+		return u.String()
+	}
+	if dbnameKV.MatchString(dsn) {
+		return dbnameKV.ReplaceAllString(dsn, "dbname="+dbname)
+	}
+	return dsn + " dbname=" + dbname
+}
+
+var dbnameKV = regexp.MustCompile(`dbname=\S+`)
+
+func registerBackupJob() {
+	RegisterJobHandler(backupVerifyJobType, verifyBackup)
+}
+
+// StartBackupScheduler runs pg_dump on the configured interval. It
+// stays off unless BACKUP_DIR is set and the store is Postgres.
+func StartBackupScheduler() {
+	if backupDir() == "" || !usingPostgres() {
+		return
+	}
+	go func() {
+		for range time.Tick(backupInterval()) {
+			if _, err := runBackup(); err != nil {
+				requestLogger.Error("backup failed", "error", err)
+			}
+		}
+	}()
+}
+
+func AdminListBackups(c *gin.Context) {
+	var backups []models.Backup
+	if err := RequestDB(c).Order("started_at DESC").Limit(100).Find(&backups).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, backups)
+}
+
+// AdminRunBackup triggers a backup outside the schedule.
+func AdminRunBackup(c *gin.Context) {
+	if backupDir() == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "backups are not configured; set BACKUP_DIR"})
+		return
+	}
+	backup, err := runBackup()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "backup": backup})
+		return
+	}
+	RenderJSON(c, http.StatusCreated, backup)
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bernardmuller/go-idle/api"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Usage-based billing. Billable usage is aggregated per organization
+// into monthly line items — API calls are metered in memory and
+// flushed like the heartbeat batches, while active players and stored
+// telemetry bytes are gauges recomputed from the database each pass.
+// The line items back GET /admin/billing/usage and, when
+// STRIPE_API_KEY is set, are pushed to Stripe's metered-billing API
+// for organizations that carry a Stripe customer id. Stripe is spoken
+// to directly over HTTP: the sync is one form-encoded POST per line
+// item, which doesn't justify a client library.
+
+const usageMeterInterval = 5 * time.Minute
+
+// usagePriceCents is the price per unit of each metric, in fractional
+// cents; the invoice endpoint quotes amounts from it.
+var usagePriceCents = map[string]float64{
+	"api_calls":      0.01,        // per call
+	"active_players": 50,          // per player per month
+	"storage_bytes":  100.0 / 1e9, // per GB per month
+}
+
+func stripeAPIKey() string { return os.Getenv("STRIPE_API_KEY") }
+
+// billingPeriod is the month bucket a moment falls into.
+func billingPeriod(t time.Time) string {
+	return t.UTC().Format("2006-01")
+}
+
+// --- API call metering ----------------------------------------------
+
+// apiCallMeter counts authenticated requests per user between flushes;
+// the flusher resolves users to organizations in one pass.
+var apiCallMeter = struct {
+	sync.Mutex
+	counts map[uint]int64
+}{counts: map[uint]int64{}}
+
+// meterAPICall records one billable request for the user.
+func meterAPICall(userID uint) {
+	apiCallMeter.Lock()
+	apiCallMeter.counts[userID]++
+	apiCallMeter.Unlock()
+}
+
+// flushAPICallMeter folds the in-memory counts into the current
+// month's api_calls line items.
+func flushAPICallMeter() {
+	apiCallMeter.Lock()
+	counts := apiCallMeter.counts
+	apiCallMeter.counts = map[uint]int64{}
+	apiCallMeter.Unlock()
+	if len(counts) == 0 {
+		return
+	}
+
+	userIDs := make([]uint, 0, len(counts))
+	for id := range counts {
+		userIDs = append(userIDs, id)
+	}
+	var memberships []models.Membership
+	if err := DB.Where("user_id IN ?", userIDs).Find(&memberships).Error; err != nil {
+		log.Printf("billing: resolving memberships failed: %v", err)
+		return
+	}
+
+	perOrg := map[uint]int64{}
+	for _, m := range memberships {
+		perOrg[m.OrganizationID] += counts[m.UserID]
+	}
+	period := billingPeriod(time.Now())
+	for orgID, n := range perOrg {
+		if err := addUsage(orgID, period, "api_calls", n); err != nil {
+			log.Printf("billing: recording api calls for org %d failed: %v", orgID, err)
+		}
+	}
+}
+
+// addUsage increments one (org, period, metric) line item.
+func addUsage(orgID uint, period, metric string, delta int64) error {
+	res := DB.Model(&models.UsageLineItem{}).
+		Where("organization_id = ? AND period = ? AND metric = ?", orgID, period, metric).
+		Updates(map[string]interface{}{"quantity": gorm.Expr("quantity + ?", delta), "updated_at": time.Now()})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return DB.Create(&models.UsageLineItem{OrganizationID: orgID, Period: period, Metric: metric, Quantity: delta}).Error
+	}
+	return nil
+}
+
+// setUsage overwrites a gauge-style line item with its recomputed
+// value.
+func setUsage(orgID uint, period, metric string, value int64) error {
+	res := DB.Model(&models.UsageLineItem{}).
+		Where("organization_id = ? AND period = ? AND metric = ?", orgID, period, metric).
+		Updates(map[string]interface{}{"quantity": value, "updated_at": time.Now()})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return DB.Create(&models.UsageLineItem{OrganizationID: orgID, Period: period, Metric: metric, Quantity: value}).Error
+	}
+	return nil
+}
+
+// --- gauge metrics ---------------------------------------------------
+
+type orgQuantity struct {
+	OrganizationID uint
+	Quantity       int64
+}
+
+// recomputeUsageGauges refreshes the active-player and storage gauges
+// for the current month.
+func recomputeUsageGauges() {
+	period := billingPeriod(time.Now())
+	monthStart, _ := time.Parse("2006-01", period)
+
+	var active []orgQuantity
+	err := DB.Raw(`SELECT m.organization_id, COUNT(DISTINCT u.id) AS quantity
+		FROM users u JOIN memberships m ON m.user_id = u.id
+		WHERE u.last_active_at >= ? AND u.deleted_at IS NULL AND m.deleted_at IS NULL
+		GROUP BY m.organization_id`, monthStart).Scan(&active).Error
+	if err != nil {
+		log.Printf("billing: active player gauge failed: %v", err)
+	}
+	for _, row := range active {
+		if err := setUsage(row.OrganizationID, period, "active_players", row.Quantity); err != nil {
+			log.Printf("billing: recording active players for org %d failed: %v", row.OrganizationID, err)
+		}
+	}
+
+	var storage []orgQuantity
+	err = DB.Raw(`SELECT m.organization_id, COALESCE(SUM(octet_length(t.payload)), 0) AS quantity
+		FROM telemetry_events t JOIN memberships m ON m.user_id = t.user_id
+		WHERE m.deleted_at IS NULL
+		GROUP BY m.organization_id`).Scan(&storage).Error
+	if err != nil {
+		log.Printf("billing: storage gauge failed: %v", err)
+	}
+	for _, row := range storage {
+		if err := setUsage(row.OrganizationID, period, "storage_bytes", row.Quantity); err != nil {
+			log.Printf("billing: recording storage for org %d failed: %v", row.OrganizationID, err)
+		}
+	}
+}
+
+// --- Stripe sync -----------------------------------------------------
+
+// pushUsageToStripe reports line items that changed since their last
+// sync as Stripe meter events, one per item, for organizations linked
+// to a Stripe customer.
+func pushUsageToStripe() {
+	key := stripeAPIKey()
+	if key == "" {
+		return
+	}
+
+	var items []models.UsageLineItem
+	err := DB.Where("stripe_synced_at IS NULL OR stripe_synced_at < updated_at").
+		Limit(500).Find(&items).Error
+	if err != nil || len(items) == 0 {
+		return
+	}
+
+	customers := map[uint]string{}
+	for _, item := range items {
+		if _, ok := customers[item.OrganizationID]; ok {
+			continue
+		}
+		var org models.Organization
+		if err := DB.First(&org, item.OrganizationID).Error; err == nil {
+			customers[item.OrganizationID] = org.StripeCustomerID
+		}
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	for _, item := range items {
+		customer := customers[item.OrganizationID]
+		if customer == "" {
+			continue
+		}
+		form := url.Values{
+			"event_name":                  {item.Metric},
+			"payload[stripe_customer_id]": {customer},
+			"payload[value]":              {fmt.Sprintf("%d", item.Quantity)},
+			"identifier":                  {fmt.Sprintf("%d-%s-%s", item.OrganizationID, item.Period, item.Metric)},
+		}
+		req, err := http.NewRequest(http.MethodPost, "https://api.stripe.com/v1/billing/meter_events", strings.NewReader(form.Encode()))
+		if err != nil {
+			continue
+		}
+		req.SetBasicAuth(key, "")
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp, err := client.Do(req)
+		if err != nil {
+			log.Printf("billing: stripe sync failed: %v", err)
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			log.Printf("billing: stripe rejected usage for org %d: %s", item.OrganizationID, resp.Status)
+			continue
+		}
+		now := time.Now()
+		DB.Model(&models.UsageLineItem{}).Where("id = ?", item.ID).Update("stripe_synced_at", now)
+	}
+}
+
+// StartUsageMetering runs the billing loop: frequent meter flushes,
+// with the gauge recompute and Stripe sync folded in once an hour.
+func StartUsageMetering() {
+	go func() {
+		passes := 0
+		for {
+			time.Sleep(usageMeterInterval)
+			flushAPICallMeter()
+			passes++
+			if passes%12 == 0 {
+				recomputeUsageGauges()
+				pushUsageToStripe()
+			}
+		}
+	}()
+}
+
+// --- admin endpoint --------------------------------------------------
+
+// AdminBillingUsage lists invoice line items for a month, priced per
+// unit, optionally narrowed to one organization.
+func AdminBillingUsage(c *gin.Context) {
+	q := api.ValidateQuery(c)
+	orgID := q.Int("org_id", 0, 1, 1<<31)
+	if !q.Valid() {
+		return
+	}
+	period := c.Query("period")
+	if period == "" {
+		period = billingPeriod(time.Now())
+	}
+	if _, err := time.Parse("2006-01", period); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "period must be YYYY-MM"})
+		return
+	}
+
+	query := ReadRequestDB(c).Where("period = ?", period).Order("organization_id, metric")
+	if orgID > 0 {
+		query = query.Where("organization_id = ?", orgID)
+	}
+	var items []models.UsageLineItem
+	if err := query.Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	out := make([]gin.H, 0, len(items))
+	var totalCents float64
+	for _, item := range items {
+		cents := float64(item.Quantity) * usagePriceCents[item.Metric]
+		totalCents += cents
+		out = append(out, gin.H{
+			"organization_id": item.OrganizationID,
+			"period":          item.Period,
+			"metric":          item.Metric,
+			"quantity":        item.Quantity,
+			"amount_cents":    int64(cents),
+			"stripe_synced":   item.StripeSyncedAt != nil,
+			"updated_at":      item.UpdatedAt,
+		})
+	}
+	RenderJSON(c, http.StatusOK, gin.H{
+		"period":             period,
+		"line_items":         out,
+		"total_amount_cents": int64(totalCents),
+	})
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Brute-force protection for the credential endpoints. /login and
+// /register sit behind a token bucket per client IP, and each account
+// additionally locks out after repeated failed logins with
+// exponentially growing cooldowns. State lives in memory behind
+// RateLimitStore so a Redis-backed implementation can replace it when
+// the API runs on more than one box.
+
+const (
+	authRateDefaultLimit  = 10
+	authRateDefaultWindow = time.Minute
+	lockoutDefaultAfter   = 5
+	lockoutBaseCooldown   = 30 * time.Second
+	lockoutMaxCooldown    = time.Hour
+)
+
+func authRateLimit() int {
+	if v, err := strconv.Atoi(os.Getenv("LOGIN_RATE_LIMIT")); err == nil && v > 0 {
+		return v
+	}
+	return authRateDefaultLimit
+}
+
+func authRateWindow() time.Duration {
+	if v, err := time.ParseDuration(os.Getenv("LOGIN_RATE_WINDOW")); err == nil && v > 0 {
+		return v
+	}
+	return authRateDefaultWindow
+}
+
+func lockoutThreshold() int {
+	if v, err := strconv.Atoi(os.Getenv("LOGIN_LOCKOUT_AFTER")); err == nil && v >= 0 {
+		return v
+	}
+	return lockoutDefaultAfter
+}
+
+// RateLimitStore is the state the protection needs; implementations
+// must be safe for concurrent use.
+type RateLimitStore interface {
+	// TakeToken draws from the bucket identified by key; when the
+	// bucket is empty it reports how long until the next token.
+	TakeToken(key string, capacity int, window time.Duration) (ok bool, retryAfter time.Duration)
+	// RecordFailure increments key's failure count and returns it.
+	RecordFailure(key string) int
+	// Failures reads key's current failure count.
+	Failures(key string) int
+	// ResetFailures clears key's failure count and any lockout.
+	ResetFailures(key string)
+	// LockedUntil reports the end of key's current lockout, if any.
+	LockedUntil(key string) (time.Time, bool)
+	// Lock locks key until the given time.
+	Lock(key string, until time.Time)
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type memoryRateLimitStore struct {
+	mu       sync.Mutex
+	buckets  map[string]*tokenBucket
+	failures map[string]int
+	locks    map[string]time.Time
+}
+
+func newMemoryRateLimitStore() *memoryRateLimitStore {
+	return &memoryRateLimitStore{
+		buckets:  make(map[string]*tokenBucket),
+		failures: make(map[string]int),
+		locks:    make(map[string]time.Time),
+	}
+}
+
+// authLimiter is the store the credential endpoints use.
+var authLimiter RateLimitStore = newMemoryRateLimitStore()
+
+func (s *memoryRateLimitStore) TakeToken(key string, capacity int, window time.Duration) (bool, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(capacity), last: now}
+		s.buckets[key] = b
+	}
+
+	refillPerSecond := float64(capacity) / window.Seconds()
+	b.tokens = math.Min(float64(capacity), b.tokens+now.Sub(b.last).Seconds()*refillPerSecond)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / refillPerSecond * float64(time.Second))
+	return false, wait
+}
+
+func (s *memoryRateLimitStore) RecordFailure(key string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[key]++
+	return s.failures[key]
+}
+
+func (s *memoryRateLimitStore) Failures(key string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.failures[key]
+}
+
+func (s *memoryRateLimitStore) ResetFailures(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.failures, key)
+	delete(s.locks, key)
+}
+
+func (s *memoryRateLimitStore) LockedUntil(key string) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	until, ok := s.locks[key]
+	if !ok || time.Now().After(until) {
+		return time.Time{}, false
+	}
+	return until, true
+}
+
+func (s *memoryRateLimitStore) Lock(key string, until time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.locks[key] = until
+}
+
+// AuthRateLimit is the per-IP token bucket in front of /login and
+// /register.
+func AuthRateLimit(c *gin.Context) {
+	ok, retryAfter := authLimiter.TakeToken("ip:"+c.ClientIP(), authRateLimit(), authRateWindow())
+	if !ok {
+		c.Header("Retry-After", strconv.FormatInt(int64(retryAfter.Seconds())+1, 10))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "too many attempts, slow down"})
+		return
+	}
+	c.Next()
+}
+
+// loginLocked answers whether the account is in a lockout cooldown.
+func loginLocked(email string) (time.Duration, bool) {
+	until, locked := authLimiter.LockedUntil("email:" + email)
+	if !locked {
+		return 0, false
+	}
+	return time.Until(until), true
+}
+
+// recordLoginFailure counts a failed attempt and starts (or extends)
+// the lockout once the threshold is crossed; each subsequent failure
+// doubles the cooldown.
+func recordLoginFailure(email string) {
+	threshold := lockoutThreshold()
+	if threshold == 0 {
+		// Lockout disabled.
+		return
+	}
+	failures := authLimiter.RecordFailure("email:" + email)
+	if failures < threshold {
+		return
+	}
+	cooldown := lockoutBaseCooldown * time.Duration(math.Pow(2, float64(failures-threshold)))
+	if cooldown > lockoutMaxCooldown {
+		cooldown = lockoutMaxCooldown
+	}
+	authLimiter.Lock("email:"+email, time.Now().Add(cooldown))
+}
+
+func resetLoginFailures(email string) {
+	authLimiter.ResetFailures("email:" + email)
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bernardmuller/go-idle/gameproto"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Bulk write path for high-volume rows. Telemetry and game events are
+// staged in an in-memory buffer and flushed as multi-row batch inserts
+// (one round trip per batch instead of one per row), either when the
+// buffer fills or on a short timer.
+
+const (
+	bulkFlushSize     = 500
+	bulkFlushInterval = 2 * time.Second
+	bulkInsertBatch   = 200
+)
+
+// telemetryBufferCap bounds how many telemetry rows may sit in memory;
+// past it, ingestion sheds load with 429s instead of queueing without
+// limit while the database is behind.
+var telemetryBufferCap = func() int {
+	if v, err := strconv.Atoi(os.Getenv("TELEMETRY_BUFFER_CAP")); err == nil && v > 0 {
+		return v
+	}
+	return 10000
+}()
+
+type bulkWriter struct {
+	mu        sync.Mutex
+	telemetry []models.TelemetryEvent
+	game      []models.GameEvent
+	rejected  int64
+}
+
+var bulk bulkWriter
+
+// StartBulkWriter flushes the buffers on a timer so rows never sit in
+// memory for more than the flush interval.
+func StartBulkWriter() {
+	go func() {
+		for {
+			time.Sleep(bulkFlushInterval)
+			bulk.Flush()
+		}
+	}()
+}
+
+// AddTelemetry stages events unless the buffer is at capacity; it
+// reports whether the batch was accepted.
+func (b *bulkWriter) AddTelemetry(events ...models.TelemetryEvent) bool {
+	b.mu.Lock()
+	if len(b.telemetry)+len(events) > telemetryBufferCap {
+		b.rejected += int64(len(events))
+		b.mu.Unlock()
+		return false
+	}
+	b.telemetry = append(b.telemetry, events...)
+	full := len(b.telemetry) >= bulkFlushSize
+	b.mu.Unlock()
+	if full {
+		b.Flush()
+	}
+	return true
+}
+
+// TelemetrySaturation reports the buffer fill fraction and how many
+// rows have been shed since startup.
+func (b *bulkWriter) TelemetrySaturation() (fill float64, depth int, rejected int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return float64(len(b.telemetry)) / float64(telemetryBufferCap), len(b.telemetry), b.rejected
+}
+
+func (b *bulkWriter) AddGameEvent(events ...models.GameEvent) {
+	b.mu.Lock()
+	b.game = append(b.game, events...)
+	full := len(b.game) >= bulkFlushSize
+	b.mu.Unlock()
+	if full {
+		b.Flush()
+	}
+}
+
+// Flush writes out everything currently buffered.
+func (b *bulkWriter) Flush() {
+	b.mu.Lock()
+	telemetry := b.telemetry
+	game := b.game
+	b.telemetry = nil
+	b.game = nil
+	b.mu.Unlock()
+
+	if len(telemetry) > 0 {
+		if err := DB.Session(&gorm.Session{PrepareStmt: true}).
+			CreateInBatches(telemetry, bulkInsertBatch).Error; err != nil {
+			log.Printf("bulk: telemetry flush failed (%d rows): %v", len(telemetry), err)
+		}
+	}
+	if len(game) > 0 {
+		if err := DB.Session(&gorm.Session{PrepareStmt: true}).
+			CreateInBatches(game, bulkInsertBatch).Error; err != nil {
+			log.Printf("bulk: game event flush failed (%d rows): %v", len(game), err)
+		}
+	}
+}
+
+type TelemetryInput struct {
+	Name    string `json:"name"`
+	Payload string `json:"payload"`
+}
+
+// suggestedBatchInterval tells clients how often to send batches given
+// the current buffer fill; the fuller the buffer, the slower they
+// should come back.
+func suggestedBatchInterval(fill float64) time.Duration {
+	switch {
+	case fill >= 0.9:
+		return 60 * time.Second
+	case fill >= 0.75:
+		return 30 * time.Second
+	case fill >= 0.5:
+		return 10 * time.Second
+	default:
+		return 5 * time.Second
+	}
+}
+
+// IngestTelemetry accepts a batch of client events and stages them for
+// the bulk writer; the client gets a 202 before any row hits the
+// database. When the buffer is saturated the batch is shed with a 429
+// and hints telling the client when and how often to retry.
+func IngestTelemetry(c *gin.Context) {
+	// Mobile clients may upload the batch as protobuf (see
+	// gameproto/game.proto) to cut payload size on cellular links.
+	var inputs []TelemetryInput
+	if strings.Contains(c.ContentType(), "protobuf") {
+		raw, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		events, err := gameproto.UnmarshalTelemetry(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "malformed protobuf telemetry batch"})
+			return
+		}
+		for _, event := range events {
+			inputs = append(inputs, TelemetryInput{Name: event.Name, Payload: event.Payload})
+		}
+	} else if err := c.ShouldBindJSON(&inputs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := CurrentUserID(c)
+	if !userConsents(userID, ConsentAnalytics) {
+		// No analytics consent: acknowledge and drop, so clients don't
+		// need a separate code path.
+		c.JSON(http.StatusAccepted, gin.H{"accepted": 0})
+		return
+	}
+	now := time.Now()
+	events := make([]models.TelemetryEvent, 0, len(inputs))
+	for _, in := range inputs {
+		if in.Name == "" {
+			continue
+		}
+		events = append(events, models.TelemetryEvent{UserID: userID, Name: in.Name, Payload: in.Payload, CreatedAt: now})
+	}
+
+	fill, _, _ := bulk.TelemetrySaturation()
+	c.Header("X-Telemetry-Batch-Interval", strconv.Itoa(int(suggestedBatchInterval(fill).Seconds())))
+
+	if !bulk.AddTelemetry(events...) {
+		c.Header("Retry-After", strconv.Itoa(int(suggestedBatchInterval(1).Seconds())))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "telemetry buffer saturated, retry later"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"accepted": len(events)})
+}
+
+// AdminTelemetrySaturation exposes the ingestion buffer's health.
+func AdminTelemetrySaturation(c *gin.Context) {
+	fill, depth, rejected := bulk.TelemetrySaturation()
+	RenderJSON(c, http.StatusOK, gin.H{
+		"buffer_depth":    depth,
+		"buffer_cap":      telemetryBufferCap,
+		"fill":            fill,
+		"rejected_events": rejected,
+	})
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Shared cache layer. Cache is the one abstraction the hot read paths
+// sit on — user lookups today, rate limits and token blacklists as they
+// arrive — with an in-memory implementation for single-node deploys and
+// a Redis implementation selected by REDIS_URL for fleets that need the
+// cache shared. The Redis client speaks RESP over TCP directly — like
+// the search and SIEM integrations, no client library. A Redis error is
+// treated as a miss: the cache must never take the request down.
+
+// Cache is a byte-oriented key/value store with per-entry TTLs.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte, ttl time.Duration)
+	Delete(key string)
+}
+
+var (
+	sharedCacheOnce sync.Once
+	sharedCacheImpl Cache
+)
+
+// sharedCache returns the process-wide cache, picking Redis when
+// REDIS_URL is set and falling back to in-memory otherwise.
+func sharedCache() Cache {
+	sharedCacheOnce.Do(func() {
+		if raw := os.Getenv("REDIS_URL"); raw != "" {
+			if rc, err := newRedisCache(raw); err == nil {
+				log.Printf("cache: using redis at %s", rc.addr)
+				sharedCacheImpl = rc
+				return
+			} else {
+				log.Printf("cache: invalid REDIS_URL, using in-memory cache: %v", err)
+			}
+		}
+		sharedCacheImpl = newMemoryCache()
+	})
+	return sharedCacheImpl
+}
+
+// memoryCache is the in-process implementation: a mutex-guarded map
+// with lazy expiry, swept periodically so abandoned keys don't pile up.
+type memoryCache struct {
+	mu      sync.Mutex
+	entries map[string]memoryCacheEntry
+}
+
+type memoryCacheEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+func newMemoryCache() *memoryCache {
+	c := &memoryCache{entries: make(map[string]memoryCacheEntry)}
+	go func() {
+		for {
+			time.Sleep(time.Minute)
+			c.sweep()
+		}
+	}()
+	return c
+}
+
+func (c *memoryCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.value, true
+}
+
+func (c *memoryCache) Set(key string, value []byte, ttl time.Duration) {
+	c.mu.Lock()
+	c.entries[key] = memoryCacheEntry{value: value, expires: time.Now().Add(ttl)}
+	c.mu.Unlock()
+}
+
+func (c *memoryCache) Delete(key string) {
+	c.mu.Lock()
+	delete(c.entries, key)
+	c.mu.Unlock()
+}
+
+func (c *memoryCache) sweep() {
+	now := time.Now()
+	c.mu.Lock()
+	for key, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, key)
+		}
+	}
+	c.mu.Unlock()
+}
+
+const redisTimeout = 2 * time.Second
+
+// redisCache holds one connection guarded by a mutex; on any I/O error
+// the connection is dropped and the next call redials. The traffic here
+// is small GET/SET commands, so a pool isn't worth the machinery yet.
+type redisCache struct {
+	mu       sync.Mutex
+	addr     string
+	password string
+	db       int
+	conn     net.Conn
+	reader   *bufio.Reader
+}
+
+func newRedisCache(raw string) (*redisCache, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "redis" {
+		return nil, fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	rc := &redisCache{addr: u.Host}
+	if !strings.Contains(rc.addr, ":") {
+		rc.addr += ":6379"
+	}
+	if u.User != nil {
+		rc.password, _ = u.User.Password()
+	}
+	if path := strings.TrimPrefix(u.Path, "/"); path != "" {
+		if db, err := strconv.Atoi(path); err == nil {
+			rc.db = db
+		}
+	}
+	return rc, nil
+}
+
+// ensureConn dials and authenticates if the connection is down. Caller
+// holds the mutex.
+func (c *redisCache) ensureConn() error {
+	if c.conn != nil {
+		return nil
+	}
+	conn, err := net.DialTimeout("tcp", c.addr, redisTimeout)
+	if err != nil {
+		return err
+	}
+	c.conn = conn
+	c.reader = bufio.NewReader(conn)
+	if c.password != "" {
+		if _, err := c.roundTrip("AUTH", c.password); err != nil {
+			c.dropConn()
+			return err
+		}
+	}
+	if c.db != 0 {
+		if _, err := c.roundTrip("SELECT", strconv.Itoa(c.db)); err != nil {
+			c.dropConn()
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *redisCache) dropConn() {
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+		c.reader = nil
+	}
+}
+
+// roundTrip writes one RESP command and reads its reply. Caller holds
+// the mutex and has ensured the connection.
+func (c *redisCache) roundTrip(args ...string) ([]byte, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	c.conn.SetDeadline(time.Now().Add(redisTimeout))
+	if _, err := c.conn.Write([]byte(b.String())); err != nil {
+		return nil, err
+	}
+	return c.readReply()
+}
+
+// readReply parses one RESP reply; nil bulk replies come back as a nil
+// slice with no error.
+func (c *redisCache) readReply() ([]byte, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("empty reply")
+	}
+	switch line[0] {
+	case '+', ':':
+		return []byte(line[1:]), nil
+	case '-':
+		return nil, fmt.Errorf("redis: %s", line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.reader, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	default:
+		return nil, fmt.Errorf("unexpected reply type %q", line[0])
+	}
+}
+
+// command runs one command end to end, dropping the connection on
+// transport errors so the next call starts fresh.
+func (c *redisCache) command(args ...string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.ensureConn(); err != nil {
+		return nil, err
+	}
+	reply, err := c.roundTrip(args...)
+	if err != nil && !strings.HasPrefix(err.Error(), "redis:") {
+		c.dropConn()
+	}
+	return reply, err
+}
+
+func (c *redisCache) Get(key string) ([]byte, bool) {
+	reply, err := c.command("GET", key)
+	if err != nil || reply == nil {
+		return nil, false
+	}
+	return reply, true
+}
+
+func (c *redisCache) Set(key string, value []byte, ttl time.Duration) {
+	if _, err := c.command("SET", key, string(value), "PX", strconv.FormatInt(ttl.Milliseconds(), 10)); err != nil {
+		log.Printf("cache: redis set %s failed: %v", key, err)
+	}
+}
+
+func (c *redisCache) Delete(key string) {
+	if _, err := c.command("DEL", key); err != nil {
+		log.Printf("cache: redis del %s failed: %v", key, err)
+	}
+}
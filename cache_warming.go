@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+)
+
+// Profile-guided cache warming. The user read cache records which ids
+// are actually hot; that profile is persisted periodically, and on
+// startup the previous profile is replayed so the cache is warm before
+// the first request instead of after the morning spike. The entries
+// themselves live in the shared cache (cache.go), so with Redis
+// configured a warm user is warm for the whole fleet; only the hit
+// profile stays node-local. Users are gob-encoded rather than JSON so
+// fields hidden from API responses survive the round trip.
+
+const (
+	userCacheTTL         = time.Minute
+	cacheProfileSize     = 100
+	cacheProfileInterval = 5 * time.Minute
+)
+
+type userReadCache struct {
+	mu   sync.Mutex
+	hits map[uint]int64
+}
+
+var userCache = userReadCache{
+	hits: make(map[uint]int64),
+}
+
+func userCacheKey(id uint) string {
+	return fmt.Sprintf("user:%d", id)
+}
+
+// Get returns a cached user and records the access in the profile.
+func (c *userReadCache) Get(id uint) (models.User, bool) {
+	c.mu.Lock()
+	c.hits[id]++
+	c.mu.Unlock()
+
+	raw, ok := sharedCache().Get(userCacheKey(id))
+	if !ok {
+		return models.User{}, false
+	}
+	var user models.User
+	if err := gob.NewDecoder(bytes.NewReader(raw)).Decode(&user); err != nil {
+		return models.User{}, false
+	}
+	return user, true
+}
+
+func (c *userReadCache) Put(user models.User) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(user); err != nil {
+		return
+	}
+	sharedCache().Set(userCacheKey(user.ID), buf.Bytes(), userCacheTTL)
+}
+
+func (c *userReadCache) Invalidate(id uint) {
+	sharedCache().Delete(userCacheKey(id))
+}
+
+// hotKeys returns the top-n most accessed ids since startup.
+func (c *userReadCache) hotKeys(n int) []models.CacheProfile {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	out := make([]models.CacheProfile, 0, len(c.hits))
+	for id, hits := range c.hits {
+		out = append(out, models.CacheProfile{Entity: "user", Key: id, Hits: hits})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Hits > out[j].Hits })
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+// loadUserCached is the read-through path for user-by-id lookups.
+func loadUserCached(id uint) (models.User, error) {
+	return loadUserCachedCtx(context.Background(), id)
+}
+
+// loadUserCachedCtx is the same read-through with the caller's context,
+// so cache time is attributed to the request's Server-Timing sink;
+// callers without a context at hand use loadUserCached.
+func loadUserCachedCtx(ctx context.Context, id uint) (models.User, error) {
+	start := time.Now()
+	user, ok := userCache.Get(id)
+	observeCacheTiming(ctx, time.Since(start))
+	if ok {
+		return user, nil
+	}
+	if err := DB.WithContext(ctx).Preload("Role.Permissions").First(&user, id).Error; err != nil {
+		return models.User{}, err
+	}
+	start = time.Now()
+	userCache.Put(user)
+	observeCacheTiming(ctx, time.Since(start))
+	return user, nil
+}
+
+// persistCacheProfile snapshots the hot set for the next boot.
+func persistCacheProfile() {
+	hot := userCache.hotKeys(cacheProfileSize)
+	if len(hot) == 0 {
+		return
+	}
+	if err := DB.Where("entity = ?", "user").Delete(&models.CacheProfile{}).Error; err != nil {
+		log.Printf("cache profile: clear failed: %v", err)
+		return
+	}
+	if err := DB.CreateInBatches(hot, bulkInsertBatch).Error; err != nil {
+		log.Printf("cache profile: persist failed: %v", err)
+	}
+}
+
+// WarmCachesFromProfile replays the persisted profile into the cache.
+func WarmCachesFromProfile() {
+	var profile []models.CacheProfile
+	if err := DB.Where("entity = ?", "user").Order("hits DESC").Find(&profile).Error; err != nil {
+		return
+	}
+	if len(profile) == 0 {
+		return
+	}
+
+	ids := make([]uint, 0, len(profile))
+	for _, p := range profile {
+		ids = append(ids, p.Key)
+	}
+	var users []models.User
+	if err := DB.Preload("Role.Permissions").Where("id IN ?", ids).Find(&users).Error; err != nil {
+		log.Printf("cache warming: load failed: %v", err)
+		return
+	}
+	for _, u := range users {
+		userCache.Put(u)
+	}
+	log.Printf("cache warming: preloaded %d users from profile", len(users))
+}
+
+func StartCacheProfilePersist() {
+	go func() {
+		for {
+			time.Sleep(cacheProfileInterval)
+			persistCacheProfile()
+		}
+	}()
+}
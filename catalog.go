@@ -0,0 +1,141 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/bernardmuller/go-idle/api"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Generic admin CRUD for catalog entities. mountCatalog wires the full
+// list/get/create/update/delete surface for one model under
+// /admin/catalog/<name>: validation comes from the model's binding
+// tags, every write lands in the catalog_changes audit trail, and the
+// admin group's permission check covers access. Adding a catalog type
+// is one struct in models plus one mountCatalog line in main.
+
+type catalogResource[T any] struct {
+	name string
+}
+
+// mountCatalog registers CRUD routes for one catalog model.
+func mountCatalog[T any](admin *gin.RouterGroup, name string) {
+	r := catalogResource[T]{name: name}
+	g := admin.Group("/catalog/" + name)
+	g.GET("", r.list)
+	g.GET("/:id", r.get)
+	g.POST("", r.create)
+	g.PUT("/:id", r.update)
+	g.DELETE("/:id", r.remove)
+}
+
+// auditCatalog records who did what to which row.
+func (r catalogResource[T]) audit(c *gin.Context, action string, entityID uint) {
+	DB.Create(&models.CatalogChange{
+		Entity:    r.name,
+		EntityID:  entityID,
+		Action:    action,
+		ActorID:   CurrentUserID(c),
+		CreatedAt: time.Now(),
+	})
+}
+
+func (r catalogResource[T]) list(c *gin.Context) {
+	q := ValidateQuery(c)
+	limit := q.Int("limit", 100, 1, 1000)
+	if !q.Valid() {
+		return
+	}
+
+	var rows []T
+	if err := RequestDB(c).Order("id").Limit(limit).Find(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, rows)
+}
+
+func (r catalogResource[T]) get(c *gin.Context) {
+	var row T
+	if err := RequestDB(c).First(&row, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": r.name + " entry not found"})
+		return
+	}
+	RenderJSON(c, http.StatusOK, row)
+}
+
+func (r catalogResource[T]) create(c *gin.Context) {
+	var row T
+	if !api.BindJSON(c, &row) {
+		return
+	}
+	if err := DB.Create(&row).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	r.audit(c, "create", catalogRowID(&row))
+	RenderJSON(c, http.StatusCreated, row)
+}
+
+func (r catalogResource[T]) update(c *gin.Context) {
+	var existing T
+	if err := DB.First(&existing, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": r.name + " entry not found"})
+		return
+	}
+
+	var row T
+	if !api.BindJSON(c, &row) {
+		return
+	}
+	id := catalogRowID(&existing)
+	// Select("*") makes this a full replace, so zeroed fields stick.
+	err := DB.Model(&existing).
+		Select("*").Omit("id", "created_at", "updated_at", "deleted_at").
+		Updates(&row).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	r.audit(c, "update", id)
+	RenderJSON(c, http.StatusOK, existing)
+}
+
+func (r catalogResource[T]) remove(c *gin.Context) {
+	var row T
+	if err := DB.First(&row, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": r.name + " entry not found"})
+		return
+	}
+	id := catalogRowID(&row)
+	if err := DB.Delete(&row).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	r.audit(c, "delete", id)
+	c.Status(http.StatusNoContent)
+}
+
+// catalogRowID pulls the primary key out of any catalog model; every
+// catalog struct embeds gorm.Model, which reflection sees as a
+// promoted ID field.
+func catalogRowID(row interface{}) uint {
+	field := reflect.ValueOf(row).Elem().FieldByName("ID")
+	if field.IsValid() && field.Kind() == reflect.Uint {
+		return uint(field.Uint())
+	}
+	return 0
+}
+
+// AdminCatalogHistory lists recent catalog edits across all entities.
+func AdminCatalogHistory(c *gin.Context) {
+	var changes []models.CatalogChange
+	if err := RequestDB(c).Order("created_at DESC").Limit(200).Find(&changes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, changes)
+}
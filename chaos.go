@@ -0,0 +1,134 @@
+package main
+
+import (
+	"math/rand"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Fault injection for resilience testing. Disabled outright in
+// production; in dev/staging an admin can inject latency, error
+// responses, and simulated database connection drops per route to
+// exercise client retry logic before a real incident does.
+
+type RouteFault struct {
+	// LatencyMs is added to every matching request, with up to
+	// JitterMs of extra random delay.
+	LatencyMs int `json:"latency_ms"`
+	JitterMs  int `json:"jitter_ms"`
+
+	// ErrorRate is the probability [0,1] of failing the request with
+	// ErrorStatus before the handler runs.
+	ErrorRate   float64 `json:"error_rate"`
+	ErrorStatus int     `json:"error_status"`
+
+	// DBDropRate is the probability [0,1] of simulating a lost
+	// database connection (503) for the request.
+	DBDropRate float64 `json:"db_drop_rate"`
+}
+
+type ChaosConfig struct {
+	Enabled bool `json:"enabled"`
+	// Routes maps a gin route pattern ("/users/:id") or "*" to its
+	// fault profile.
+	Routes map[string]RouteFault `json:"routes"`
+}
+
+var (
+	chaosMu     sync.RWMutex
+	chaosConfig = ChaosConfig{Routes: map[string]RouteFault{}}
+)
+
+func chaosAllowed() bool {
+	env := os.Getenv("APP_ENV")
+	return env != "production" && env != "prod"
+}
+
+// ChaosMiddleware applies the configured fault profile for the route.
+func ChaosMiddleware(c *gin.Context) {
+	if !chaosAllowed() {
+		c.Next()
+		return
+	}
+
+	chaosMu.RLock()
+	cfg := chaosConfig
+	fault, ok := cfg.Routes[c.FullPath()]
+	if !ok {
+		fault, ok = cfg.Routes["*"]
+	}
+	chaosMu.RUnlock()
+
+	if !cfg.Enabled || !ok {
+		c.Next()
+		return
+	}
+
+	if fault.LatencyMs > 0 || fault.JitterMs > 0 {
+		delay := time.Duration(fault.LatencyMs) * time.Millisecond
+		if fault.JitterMs > 0 {
+			delay += time.Duration(rand.Intn(fault.JitterMs)) * time.Millisecond
+		}
+		time.Sleep(delay)
+	}
+
+	if fault.DBDropRate > 0 && rand.Float64() < fault.DBDropRate {
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+			"error": "database connection lost (injected)",
+		})
+		return
+	}
+
+	if fault.ErrorRate > 0 && rand.Float64() < fault.ErrorRate {
+		status := fault.ErrorStatus
+		if status == 0 {
+			status = http.StatusInternalServerError
+		}
+		c.AbortWithStatusJSON(status, gin.H{"error": "injected fault"})
+		return
+	}
+
+	c.Next()
+}
+
+func AdminGetChaos(c *gin.Context) {
+	if !chaosAllowed() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "fault injection is disabled in this environment"})
+		return
+	}
+	chaosMu.RLock()
+	defer chaosMu.RUnlock()
+	RenderJSON(c, http.StatusOK, chaosConfig)
+}
+
+func AdminSetChaos(c *gin.Context) {
+	if !chaosAllowed() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "fault injection is disabled in this environment"})
+		return
+	}
+
+	var cfg ChaosConfig
+	if err := c.ShouldBindJSON(&cfg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if cfg.Routes == nil {
+		cfg.Routes = map[string]RouteFault{}
+	}
+	for route, fault := range cfg.Routes {
+		if fault.ErrorRate < 0 || fault.ErrorRate > 1 || fault.DBDropRate < 0 || fault.DBDropRate > 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "rates must be between 0 and 1", "route": route})
+			return
+		}
+	}
+
+	chaosMu.Lock()
+	chaosConfig = cfg
+	chaosMu.Unlock()
+
+	RenderJSON(c, http.StatusOK, cfg)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Derived JWT claims. Deployments can register hooks that enrich
+// freshly minted tokens with claims derived from the user (plan, org
+// memberships, feature flags) without touching the token plumbing.
+// Reserved claims can never be overridden by a hook.
+
+type ClaimsHook func(userID uint, claims jwt.MapClaims)
+
+var claimsHooks []ClaimsHook
+
+var reservedClaims = map[string]bool{
+	"sub": true, "exp": true, "iat": true, "jti": true, "iss": true,
+}
+
+// RegisterClaimsHook adds a hook applied to every token minted after
+// the call.
+func RegisterClaimsHook(hook ClaimsHook) {
+	claimsHooks = append(claimsHooks, hook)
+}
+
+// applyClaimsHooks runs all hooks and drops any attempt to shadow a
+// reserved claim.
+func applyClaimsHooks(userID uint, claims jwt.MapClaims) {
+	for _, hook := range claimsHooks {
+		derived := jwt.MapClaims{}
+		hook(userID, derived)
+		for k, v := range derived {
+			if reservedClaims[k] {
+				continue
+			}
+			claims[k] = v
+		}
+	}
+}
+
+// registerDefaultClaimsHooks wires the claims every client already
+// depends on: the billing plan and the user's organization ids.
+func registerDefaultClaimsHooks() {
+	RegisterClaimsHook(func(userID uint, claims jwt.MapClaims) {
+		claims["plan"] = userPlan(userID)
+	})
+	RegisterClaimsHook(func(userID uint, claims jwt.MapClaims) {
+		var orgIDs []uint
+		DB.Model(&models.Membership{}).Where("user_id = ?", userID).Pluck("organization_id", &orgIDs)
+		if len(orgIDs) > 0 {
+			claims["orgs"] = orgIDs
+		}
+	})
+	RegisterClaimsHook(func(userID uint, claims jwt.MapClaims) {
+		// The token-version claim ties the token to the credentials it
+		// was minted under; see session_revocation.go.
+		if user, err := loadUserCached(userID); err == nil {
+			claims["ver"] = user.TokenVersion
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Client version gating. Game clients send X-Client-Version (semver,
+// e.g. "1.4.2"); the middleware parses it once into the context, warns
+// clients running below CLIENT_WARN_VERSION with a Deprecation header,
+// and refuses clients below CLIENT_MIN_VERSION outright with 426 —
+// the lever for pulling a client release whose game logic is broken
+// badly enough that letting it keep ticking corrupts state. Handlers
+// that need to branch on version (serving an older payload shape, say)
+// use ClientVersionAtLeast. Requests without the header pass: not
+// every caller is a game client.
+
+const clientVersionKey = "clientVersion"
+
+// clientVersion is a parsed semver triple. Pre-release and build
+// suffixes ("1.4.2-rc1") are accepted and ignored for ordering — the
+// gate is about shipped game logic, not release candidates.
+type clientVersion struct {
+	major, minor, patch int
+}
+
+func (v clientVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.major, v.minor, v.patch)
+}
+
+// less orders versions numerically per component.
+func (v clientVersion) less(other clientVersion) bool {
+	if v.major != other.major {
+		return v.major < other.major
+	}
+	if v.minor != other.minor {
+		return v.minor < other.minor
+	}
+	return v.patch < other.patch
+}
+
+// parseClientVersion accepts "1", "1.4", "1.4.2", an optional leading
+// "v", and an optional -pre/+build suffix; missing components are zero.
+func parseClientVersion(raw string) (clientVersion, bool) {
+	raw = strings.TrimPrefix(strings.TrimSpace(raw), "v")
+	if i := strings.IndexAny(raw, "-+"); i >= 0 {
+		raw = raw[:i]
+	}
+	if raw == "" {
+		return clientVersion{}, false
+	}
+	parts := strings.SplitN(raw, ".", 3)
+	var v clientVersion
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return clientVersion{}, false
+		}
+		switch i {
+		case 0:
+			v.major = n
+		case 1:
+			v.minor = n
+		case 2:
+			v.patch = n
+		}
+	}
+	return v, true
+}
+
+func clientMinVersion() (clientVersion, bool) {
+	return parseClientVersion(os.Getenv("CLIENT_MIN_VERSION"))
+}
+func clientWarnVersion() (clientVersion, bool) {
+	return parseClientVersion(os.Getenv("CLIENT_WARN_VERSION"))
+}
+
+// ClientVersionGate parses the header and applies the block/warn
+// thresholds. A header that doesn't parse counts as too old when a
+// minimum is configured — a client mangling its own version string is
+// not one we want writing game state.
+func ClientVersionGate(c *gin.Context) {
+	raw := c.GetHeader("X-Client-Version")
+	if raw == "" {
+		c.Next()
+		return
+	}
+
+	v, ok := parseClientVersion(raw)
+	if min, set := clientMinVersion(); set {
+		if !ok || v.less(min) {
+			c.Header("X-Client-Min-Version", min.String())
+			c.AbortWithStatusJSON(http.StatusUpgradeRequired, gin.H{
+				"error":       "client version no longer supported; please update",
+				"min_version": min.String(),
+			})
+			return
+		}
+	}
+	if !ok {
+		c.Next()
+		return
+	}
+	c.Set(clientVersionKey, v)
+
+	if warn, set := clientWarnVersion(); set && v.less(warn) {
+		// Same header pair the deprecated-route telemetry uses, so
+		// clients have one signal to watch.
+		c.Header("Deprecation", "true")
+		c.Header("X-Client-Min-Version", warn.String())
+	}
+	c.Next()
+}
+
+// ClientVersionAtLeast reports whether the request came from a client
+// at or above the given version; requests without a (valid) version
+// header report false, so callers treat unknown clients as old.
+func ClientVersionAtLeast(c *gin.Context, version string) bool {
+	want, ok := parseClientVersion(version)
+	if !ok {
+		return false
+	}
+	got, ok := c.Get(clientVersionKey)
+	if !ok {
+		return false
+	}
+	have := got.(clientVersion)
+	return !have.less(want)
+}
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Central configuration. Everything the server reads from the
+// environment that used to be a hardcoded value lives here, loaded and
+// validated once at startup. A .env file in the working directory is
+// applied first when present, but is never required.
+
+// devJWTSecret keeps local development working without any setup; in
+// production an explicit JWT_SECRET is mandatory.
+const devJWTSecret = "go-idle-secret"
+
+type Config struct {
+	Env         string
+	Port        int
+	DatabaseURL string
+	JWTSecret   string
+	TokenTTL    time.Duration
+	BcryptCost  int
+	CORSOrigins []string
+
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	IdleTimeout     time.Duration
+	ShutdownTimeout time.Duration
+}
+
+var loaded *Config
+
+// Load reads the environment (after the optional .env overlay),
+// validates every value, and memoizes the result.
+func Load() (*Config, error) {
+	loadDotEnv(".env")
+
+	cfg := &Config{
+		Env:         getDefault("APP_ENV", "development"),
+		DatabaseURL: getDefault("DATABASE_URL", "host=localhost user=postgres password=postgres dbname=goidle port=5432 sslmode=disable"),
+		JWTSecret:   getDefault("JWT_SECRET", devJWTSecret),
+	}
+
+	port, err := intInRange("PORT", 8080, 1, 65535)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Port = port
+
+	cost, err := intInRange("BCRYPT_COST", 12, bcrypt.MinCost, bcrypt.MaxCost)
+	if err != nil {
+		return nil, err
+	}
+	cfg.BcryptCost = cost
+
+	durations := []struct {
+		key string
+		dst *time.Duration
+		def time.Duration
+	}{
+		{"TOKEN_TTL", &cfg.TokenTTL, 24 * time.Hour},
+		{"HTTP_READ_TIMEOUT", &cfg.ReadTimeout, 15 * time.Second},
+		{"HTTP_WRITE_TIMEOUT", &cfg.WriteTimeout, 30 * time.Second},
+		{"HTTP_IDLE_TIMEOUT", &cfg.IdleTimeout, 60 * time.Second},
+		{"SHUTDOWN_TIMEOUT", &cfg.ShutdownTimeout, 20 * time.Second},
+	}
+	for _, d := range durations {
+		*d.dst = d.def
+		if raw := os.Getenv(d.key); raw != "" {
+			v, err := time.ParseDuration(raw)
+			if err != nil || v <= 0 {
+				return nil, fmt.Errorf("%s: %q is not a positive duration", d.key, raw)
+			}
+			*d.dst = v
+		}
+	}
+
+	if raw := os.Getenv("CORS_ALLOWED_ORIGINS"); raw != "" {
+		for _, origin := range strings.Split(raw, ",") {
+			if origin = strings.TrimSpace(origin); origin != "" {
+				cfg.CORSOrigins = append(cfg.CORSOrigins, origin)
+			}
+		}
+	}
+
+	if cfg.Env == "production" && cfg.JWTSecret == devJWTSecret {
+		return nil, fmt.Errorf("JWT_SECRET must be set explicitly in production")
+	}
+
+	loaded = cfg
+	return cfg, nil
+}
+
+// Get returns the loaded configuration; it panics when called before
+// Load so misordered startup fails loudly instead of running on
+// defaults.
+func Get() *Config {
+	if loaded == nil {
+		panic("config.Get called before config.Load")
+	}
+	return loaded
+}
+
+func getDefault(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func intInRange(key string, def, min, max int) (int, error) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %q is not an integer", key, raw)
+	}
+	if v < min || v > max {
+		return 0, fmt.Errorf("%s: %d is outside %d..%d", key, v, min, max)
+	}
+	return v, nil
+}
+
+// loadDotEnv applies KEY=VALUE lines from path without overriding
+// variables already present in the environment. A missing file is not
+// an error.
+func loadDotEnv(path string) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(raw), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+		if _, exists := os.LookupEnv(key); !exists {
+			os.Setenv(key, value)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Configuration export. GET /admin/config shows the effective value of
+// every knob the server reads so support doesn't have to shell into a
+// box to see how it's configured. Secrets are masked but still show
+// whether they are set at all.
+
+type configEntry struct {
+	Key         string
+	Secret      bool
+	Description string
+}
+
+// configRegistry lists every environment variable the server consults.
+// New knobs should be added here when they're introduced.
+var configRegistry = []configEntry{
+	{Key: "DATABASE_URL", Secret: true, Description: "Postgres DSN"},
+	{Key: "APP_ENV", Description: "environment name; gates dev-only tooling"},
+	{Key: "PORT", Description: "HTTP listen port"},
+	{Key: "JWT_SECRET", Secret: true, Description: "HS256 token signing secret"},
+	{Key: "TOKEN_TTL", Description: "access token lifetime (Go duration)"},
+	{Key: "CORS_ALLOWED_ORIGINS", Description: "origins allowed to call the API from a browser"},
+	{Key: "CORS_ALLOWED_METHODS", Description: "methods advertised in preflight responses"},
+	{Key: "CORS_ALLOWED_HEADERS", Description: "request headers advertised in preflight responses"},
+	{Key: "CORS_ALLOW_CREDENTIALS", Description: "allow cookies on cross-origin requests (disables wildcard origins)"},
+	{Key: "JSON_NAMING", Description: "default JSON field casing (snake|camel)"},
+	{Key: "APPLE_CLIENT_ID", Description: "audience for Sign in with Apple tokens"},
+	{Key: "GOOGLE_CLIENT_ID", Description: "OAuth client id for Google login"},
+	{Key: "GOOGLE_CLIENT_SECRET", Secret: true, Description: "OAuth client secret for Google login"},
+	{Key: "GITHUB_CLIENT_ID", Description: "OAuth client id for GitHub login"},
+	{Key: "GITHUB_CLIENT_SECRET", Secret: true, Description: "OAuth client secret for GitHub login"},
+	{Key: "OAUTH_REDIRECT_BASE_URL", Description: "public base URL OAuth callbacks are registered under"},
+	{Key: "JWT_SIGNING_ALG", Description: "token signing algorithm (HS256|RS256|EdDSA)"},
+	{Key: "JWT_PRIVATE_KEY_FILE", Secret: true, Description: "PKCS#8 PEM private key for asymmetric signing"},
+	{Key: "JWT_PUBLIC_KEY_FILES", Description: "PEM files served from the JWKS endpoint"},
+	{Key: "INTROSPECTION_CLIENTS", Secret: true, Description: "id:secret pairs allowed to introspect tokens"},
+	{Key: "BCRYPT_COST", Description: "target bcrypt cost for new password hashes"},
+	{Key: "MAX_SESSIONS_PER_USER", Description: "session concurrency limit"},
+	{Key: "DB_STATEMENT_BUDGET", Description: "per-request statement budget"},
+	{Key: "DB_STATEMENT_BUDGET_ENFORCE", Description: "fail requests over the statement budget"},
+	{Key: "STARTUP_WAIT_SECONDS", Description: "dependency wait deadline at startup"},
+	{Key: "READ_ONLY", Description: "serve as a read-only replica"},
+	{Key: "REGISTRATION_DOMAIN_ALLOWLIST", Description: "only these email domains may register"},
+	{Key: "REGISTRATION_DOMAIN_BLOCKLIST", Description: "email domains rejected at registration"},
+	{Key: "REQUEST_SIGNING_KEYS", Secret: true, Description: "keyid:secret pairs for HMAC request signing"},
+	{Key: "ELASTICSEARCH_URL", Description: "search backend; in-memory index when unset"},
+	{Key: "TELEMETRY_BUFFER_CAP", Description: "telemetry rows buffered before ingestion sheds load"},
+	{Key: "SCHEMA_DRIFT_STRICT", Description: "refuse to start when the live schema has drifted"},
+	{Key: "AUTO_MIGRATE", Description: "allow AutoMigrate outside development"},
+	{Key: "GAME_EVENT_SOURCING", Description: "write game ticks through the event log as source of truth"},
+	{Key: "MOCK_LATENCY_MS", Description: "mock mode: fixed latency added to every response"},
+	{Key: "MOCK_ERROR_EVERY", Description: "mock mode: inject a 500 on every Nth request"},
+	{Key: "HTTP_READ_TIMEOUT", Description: "http.Server read timeout"},
+	{Key: "HTTP_WRITE_TIMEOUT", Description: "http.Server write timeout"},
+	{Key: "HTTP_IDLE_TIMEOUT", Description: "http.Server idle timeout"},
+	{Key: "SHUTDOWN_TIMEOUT", Description: "drain deadline for graceful shutdown"},
+	{Key: "LOGIN_RATE_LIMIT", Description: "credential endpoint requests allowed per IP per window"},
+	{Key: "LOGIN_RATE_WINDOW", Description: "token bucket refill window for credential endpoints"},
+	{Key: "LOGIN_LOCKOUT_AFTER", Description: "failed logins before an account locks out (0 disables)"},
+	{Key: "EVENT_PARTITION_RETENTION_MONTHS", Description: "months of event partitions kept attached"},
+	{Key: "LOGIN_RISK_MEDIUM", Description: "risk score requiring an OTP challenge at login"},
+	{Key: "LOGIN_RISK_HIGH", Description: "risk score at which logins are blocked"},
+	{Key: "PRIMARY_URL", Description: "primary instance writes are redirected to"},
+	{Key: "BACKUP_DIR", Description: "where pg_dump backups land; unset disables backups"},
+	{Key: "BACKUP_INTERVAL", Description: "time between scheduled backups"},
+	{Key: "BACKUP_RETENTION", Description: "how many backups to keep before pruning"},
+	{Key: "PUSH_GATEWAY_URL", Description: "push notification gateway; unset disables the push channel"},
+	{Key: "DATABASE_URL_EU", Secret: true, Description: "Postgres DSN for EU-resident tenants"},
+	{Key: "SANDBOX_ENABLED", Description: "serve sandbox-keyed requests from the sandbox schema"},
+	{Key: "REDIS_URL", Secret: true, Description: "shared cache backend; in-memory cache when unset"},
+	{Key: "RESET_TOKEN_BINDING", Description: "bind emailed reset links to the requesting user agent and IP family"},
+	{Key: "STANDBY", Description: "start as a failover standby awaiting promotion"},
+	{Key: "FAILOVER_TOKEN", Secret: true, Description: "shared secret standbys present to replicate configuration"},
+	{Key: "METRICS_LABELS", Description: "metric labels to keep (method,path,status); unset keeps all"},
+	{Key: "METRICS_MAX_SERIES", Description: "route series cap; overflow folds into path=\"other\""},
+	{Key: "COOKIE_AUTH_ENABLED", Description: "also issue the token as an HttpOnly cookie with CSRF protection"},
+	{Key: "SIEM_URL", Description: "audit event collector (https or syslog URL); unset disables export"},
+	{Key: "TELEMETRY_ARCHIVE_DIR", Description: "where cold telemetry days land; unset disables tiering"},
+	{Key: "TELEMETRY_HOT_DAYS", Description: "days of telemetry kept in Postgres before archival"},
+	{Key: "GRPC_PORT", Description: "port for the internal gRPC interface; unset disables it"},
+	{Key: "SESSION_REVOKE_ON_PASSWORD_CHANGE", Description: "kill old-password tokens via the version claim (default on)"},
+	{Key: "SESSION_REVOKE_GRACE", Description: "how long stale-version tokens keep working after a password change"},
+	{Key: "DB_MAX_OPEN_CONNS", Description: "connection pool ceiling per handle"},
+	{Key: "DB_MAX_IDLE_CONNS", Description: "idle connections kept per handle"},
+	{Key: "DB_CONN_MAX_LIFETIME", Description: "how long a pooled connection may live"},
+	{Key: "READ_REPLICA_URL", Secret: true, Description: "Postgres DSN read paths are routed to; primary when unset"},
+	{Key: "OTEL_EXPORTER_OTLP_ENDPOINT", Description: "OTLP collector base URL for trace export; unset disables tracing"},
+	{Key: "RESPONSE_MAX_BYTES", Description: "response size cap; route table rows can override"},
+	{Key: "WS_FANOUT", Description: "fan bumps out over Redis pub/sub when REDIS_URL is set"},
+	{Key: "WS_FANOUT_SHARDS", Description: "pub/sub channel shard count for the bump fan-out"},
+	{Key: "RESPONSE_SCHEMA_CHECK", Description: "validate documented responses outside production (log|fail|off)"},
+	{Key: "ADMIN_MTLS_PORT", Description: "mTLS admin listener port; unset disables it"},
+	{Key: "ADMIN_MTLS_CERT_FILE", Description: "server certificate for the mTLS listener"},
+	{Key: "ADMIN_MTLS_KEY_FILE", Secret: true, Description: "server key for the mTLS listener"},
+	{Key: "ADMIN_MTLS_CA_FILE", Description: "CA bundle client certificates must chain to"},
+	{Key: "ADMIN_MTLS_ONLY", Description: "refuse /admin on the plain listener"},
+	{Key: "STRIPE_API_KEY", Secret: true, Description: "Stripe secret key; unset disables usage sync"},
+	{Key: "WEBAUTHN_RP_ID", Description: "relying party id passkeys are scoped to"},
+	{Key: "WEBAUTHN_ORIGIN", Description: "exact origin accepted in passkey ceremonies"},
+	{Key: "DATABASE_URLS", Secret: true, Description: "priority-ordered DSN list with automatic failover; overrides DATABASE_URL"},
+	{Key: "CLIENT_MIN_VERSION", Description: "oldest X-Client-Version allowed through; below gets 426"},
+	{Key: "CLIENT_WARN_VERSION", Description: "X-Client-Version below this gets a Deprecation header"},
+	{Key: "SERVER_TIMING", Description: "emit Server-Timing breakdowns on every response"},
+	{Key: "API_KEY_ROTATION_GRACE_DAYS", Description: "overlap during which a rotated-out API key still works"},
+	{Key: "BREACHED_PASSWORD_CHECK", Description: "breached-password consequence (block|warn|off)"},
+	{Key: "BREACHED_PASSWORDS_FILE", Description: "breach corpus: GIBF filter or SHA-1 hex lines"},
+	{Key: "BREACHED_PASSWORD_HIBP", Description: "check the HIBP range API (k-anonymity) before the local filter"},
+	{Key: "DATA_ENCRYPTION_KEY", Secret: true, Description: "hex master key wrapping per-user PII keys (crypto-shredding)"},
+	{Key: "DATA_ENCRYPTION_KEY_PREVIOUS", Secret: true, Description: "outgoing master key accepted during rotation"},
+	{Key: "LOGIN_MAX_CONCURRENT", Description: "in-flight login cap before the waiting room queues attempts; 0 disables"},
+	{Key: "SIEM_FORMAT", Description: "audit export format (json|cef)"},
+	{Key: "LB_MAX_IN_FLIGHT", Description: "request capacity advertised via X-Capacity-Remaining"},
+	{Key: "INACTIVE_DELETE_AFTER_DAYS", Description: "days idle before anonymization; 0 disables cleanup"},
+	{Key: "REQUEST_TIMEOUT_CRITICAL", Description: "deadline override for critical-class routes"},
+	{Key: "REQUEST_TIMEOUT_NORMAL", Description: "deadline override for normal-class routes"},
+	{Key: "REQUEST_TIMEOUT_BACKGROUND", Description: "deadline override for background-class routes"},
+}
+
+var dsnPassword = regexp.MustCompile(`(password=)\S+`)
+
+// maskedValue hides secret material while keeping enough shape to
+// debug with.
+func maskedValue(entry configEntry, value string) string {
+	if value == "" {
+		return ""
+	}
+	if !entry.Secret {
+		return value
+	}
+
+	switch entry.Key {
+	case "DATABASE_URL":
+		// Keep host/database visible; only credentials are sensitive.
+		if u, err := url.Parse(value); err == nil && u.Host != "" {
+			if u.User != nil {
+				u.User = url.User(u.User.Username())
+			}
+			return u.Redacted()
+		}
+		return dsnPassword.ReplaceAllString(value, "${1}*****")
+	case "INTROSPECTION_CLIENTS":
+		pairs := strings.Split(value, ",")
+		for i, pair := range pairs {
+			if id, _, found := strings.Cut(strings.TrimSpace(pair), ":"); found {
+				pairs[i] = id + ":*****"
+			}
+		}
+		return strings.Join(pairs, ",")
+	default:
+		return "*****"
+	}
+}
+
+func AdminExportConfig(c *gin.Context) {
+	out := make([]gin.H, 0, len(configRegistry))
+	for _, entry := range configRegistry {
+		value := os.Getenv(entry.Key)
+		out = append(out, gin.H{
+			"key":         entry.Key,
+			"value":       maskedValue(entry, value),
+			"set":         value != "",
+			"secret":      entry.Secret,
+			"description": entry.Description,
+		})
+	}
+	RenderJSON(c, http.StatusOK, out)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/bernardmuller/go-idle/api"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Consent-based data sharing. Users control three flags — analytics,
+// marketing, third-party sharing — and every pipeline that touches the
+// corresponding data checks them: telemetry drops events without
+// analytics consent, bulk marketing messages skip unconsented users,
+// and org exports mask emails of members who haven't agreed to
+// third-party sharing. Flips are recorded in an append-only audit
+// trail.
+
+const (
+	ConsentAnalytics  = "analytics"
+	ConsentMarketing  = "marketing"
+	ConsentThirdParty = "third_party"
+)
+
+// userConsents answers one flag for one user through the read cache.
+func userConsents(userID uint, flag string) bool {
+	user, err := loadUserCached(userID)
+	if err != nil {
+		// Unknown user: share nothing.
+		return false
+	}
+	switch flag {
+	case ConsentAnalytics:
+		return user.ConsentAnalytics
+	case ConsentMarketing:
+		return user.ConsentMarketing
+	case ConsentThirdParty:
+		return user.ConsentThirdParty
+	}
+	return false
+}
+
+func GetMyConsent(c *gin.Context) {
+	user, err := loadUserCachedCtx(c.Request.Context(), CurrentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	RenderJSON(c, http.StatusOK, gin.H{
+		"analytics":   user.ConsentAnalytics,
+		"marketing":   user.ConsentMarketing,
+		"third_party": user.ConsentThirdParty,
+	})
+}
+
+type ConsentInput struct {
+	Analytics  *bool `json:"analytics"`
+	Marketing  *bool `json:"marketing"`
+	ThirdParty *bool `json:"third_party"`
+}
+
+// UpdateMyConsent applies the provided flags (absent ones are left
+// alone) and audits every actual change.
+func UpdateMyConsent(c *gin.Context) {
+	var input ConsentInput
+	if !api.BindJSON(c, &input) {
+		return
+	}
+
+	userID := CurrentUserID(c)
+	var user models.User
+	if err := RequestDB(c).First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{}
+	var audit []models.ConsentChange
+	apply := func(flag string, current bool, requested *bool, column string) {
+		if requested == nil || *requested == current {
+			return
+		}
+		updates[column] = *requested
+		audit = append(audit, models.ConsentChange{
+			UserID: userID, Flag: flag, OldValue: current, NewValue: *requested, CreatedAt: now,
+		})
+	}
+	apply(ConsentAnalytics, user.ConsentAnalytics, input.Analytics, "consent_analytics")
+	apply(ConsentMarketing, user.ConsentMarketing, input.Marketing, "consent_marketing")
+	apply(ConsentThirdParty, user.ConsentThirdParty, input.ThirdParty, "consent_third_party")
+
+	if len(updates) > 0 {
+		if err := RequestDB(c).Model(&user).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := RequestDB(c).Create(&audit).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		userCache.Invalidate(userID)
+	}
+
+	GetMyConsent(c)
+}
+
+// AdminConsentHistory lists one user's consent audit trail.
+func AdminConsentHistory(c *gin.Context) {
+	var changes []models.ConsentChange
+	err := RequestDB(c).Where("user_id = ?", c.Param("id")).Order("created_at DESC").Find(&changes).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, changes)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/bernardmuller/go-idle/config"
+	"github.com/gin-gonic/gin"
+)
+
+// CORS. Browsers on other origins can only call the API once it
+// answers preflights; the allowlist comes from CORS_ALLOWED_ORIGINS
+// (no middleware is installed when it's empty). Methods and headers
+// are overridable, credentials are opt-in, and a wildcard origin is
+// refused whenever credentials are enabled -- echoing "*" with cookies
+// attached would defeat the allowlist entirely.
+
+func corsAllowedMethods() string {
+	if v := os.Getenv("CORS_ALLOWED_METHODS"); v != "" {
+		return v
+	}
+	return "GET, POST, PUT, PATCH, DELETE, OPTIONS"
+}
+
+func corsAllowedHeaders() string {
+	if v := os.Getenv("CORS_ALLOWED_HEADERS"); v != "" {
+		return v
+	}
+	return "Authorization, Content-Type, X-Request-ID, X-Sandbox-Key"
+}
+
+func corsAllowCredentials() bool {
+	return os.Getenv("CORS_ALLOW_CREDENTIALS") == "true"
+}
+
+// corsAllowedOrigin resolves the Allow-Origin value for a request
+// origin, or "" when the origin isn't allowed.
+func corsAllowedOrigin(origin string) string {
+	credentials := corsAllowCredentials()
+	for _, allowed := range config.Get().CORSOrigins {
+		if allowed == "*" {
+			if credentials {
+				// Never pair a wildcard with credentials; the origin
+				// must be listed explicitly.
+				continue
+			}
+			return "*"
+		}
+		if strings.EqualFold(allowed, origin) {
+			return origin
+		}
+	}
+	return ""
+}
+
+// CORSMiddleware answers preflights and stamps response headers for
+// allowed cross-origin callers.
+func CORSMiddleware(c *gin.Context) {
+	origin := c.GetHeader("Origin")
+	if origin == "" {
+		c.Next()
+		return
+	}
+	c.Header("Vary", "Origin")
+
+	allowed := corsAllowedOrigin(origin)
+	if allowed == "" {
+		// Not an allowed origin: no CORS headers, and preflights stop
+		// here rather than reaching route handlers.
+		if c.Request.Method == http.MethodOptions {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		c.Next()
+		return
+	}
+
+	c.Header("Access-Control-Allow-Origin", allowed)
+	if corsAllowCredentials() {
+		c.Header("Access-Control-Allow-Credentials", "true")
+	}
+
+	if c.Request.Method == http.MethodOptions {
+		c.Header("Access-Control-Allow-Methods", corsAllowedMethods())
+		c.Header("Access-Control-Allow-Headers", corsAllowedHeaders())
+		c.Header("Access-Control-Max-Age", "600")
+		c.AbortWithStatus(http.StatusNoContent)
+		return
+	}
+	c.Next()
+}
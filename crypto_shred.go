@@ -0,0 +1,237 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Crypto-shredding for per-user PII. Sensitive per-user values (today
+// the TOTP secret) are encrypted with a random per-user data key,
+// itself wrapped with the master key from DATA_ENCRYPTION_KEY. When an
+// account is purged the wrapped key is destroyed, which renders every
+// ciphertext for that user — including copies sitting in old database
+// backups — permanently unreadable, without touching the backups
+// themselves. Master key rotation is lazy: ciphertexts wrapped under
+// DATA_ENCRYPTION_KEY_PREVIOUS are re-wrapped under the current key
+// the next time they're used. Without a master key configured the
+// helpers pass values through in plaintext, so the feature is opt-in
+// per deployment.
+
+// piiPrefix marks an encrypted value; bare values read back as-is so
+// rows written before the key existed keep working.
+const piiPrefix = "enc1:"
+
+var errKeyShredded = errors.New("data key has been shredded")
+
+// dataMasterKeys returns the current wrapping key and, when rotating,
+// the previous one. Keys are 32 bytes of hex.
+func dataMasterKeys() [][]byte {
+	var keys [][]byte
+	for _, env := range []string{"DATA_ENCRYPTION_KEY", "DATA_ENCRYPTION_KEY_PREVIOUS"} {
+		raw, err := hex.DecodeString(os.Getenv(env))
+		if err == nil && len(raw) == 32 {
+			keys = append(keys, raw)
+		}
+	}
+	return keys
+}
+
+// sealWithKey is AES-GCM with a random nonce, nonce prepended.
+func sealWithKey(key, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func openWithKey(key, sealed []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+}
+
+// userDataKey returns the user's unwrapped data key, creating one on
+// first use. A key wrapped under the previous master key is re-wrapped
+// under the current one in passing.
+func userDataKey(db *gorm.DB, userID uint) ([]byte, error) {
+	masters := dataMasterKeys()
+	if len(masters) == 0 {
+		return nil, errors.New("DATA_ENCRYPTION_KEY is not configured")
+	}
+
+	var row models.UserDataKey
+	err := db.Where("user_id = ?", userID).First(&row).Error
+	if err == nil {
+		if row.ShreddedAt != nil {
+			return nil, errKeyShredded
+		}
+		wrapped, err := base64.StdEncoding.DecodeString(row.WrappedKey)
+		if err != nil {
+			return nil, err
+		}
+		for i, master := range masters {
+			key, err := openWithKey(master, wrapped)
+			if err != nil {
+				continue
+			}
+			if i > 0 {
+				rewrapped, err := sealWithKey(masters[0], key)
+				if err == nil {
+					db.Model(&models.UserDataKey{}).
+						Where("id = ? AND wrapped_key = ?", row.ID, row.WrappedKey).
+						Update("wrapped_key", base64.StdEncoding.EncodeToString(rewrapped))
+				}
+			}
+			return key, nil
+		}
+		return nil, errors.New("data key unwraps under no configured master key")
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	wrapped, err := sealWithKey(masters[0], key)
+	if err != nil {
+		return nil, err
+	}
+	row = models.UserDataKey{UserID: userID, WrappedKey: base64.StdEncoding.EncodeToString(wrapped)}
+	if err := db.Create(&row).Error; err != nil {
+		// Lost a creation race; read the winner's key.
+		if db.Where("user_id = ?", userID).First(&row).Error == nil {
+			return userDataKey(db, userID)
+		}
+		return nil, err
+	}
+	return key, nil
+}
+
+// encryptPII seals one value under the user's data key; with no master
+// key configured the value is stored in plaintext.
+func encryptPII(db *gorm.DB, userID uint, plaintext string) (string, error) {
+	if len(dataMasterKeys()) == 0 {
+		return plaintext, nil
+	}
+	key, err := userDataKey(db, userID)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := sealWithKey(key, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return piiPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptPII reverses encryptPII; values without the prefix predate
+// the key and come back unchanged.
+func decryptPII(db *gorm.DB, userID uint, stored string) (string, error) {
+	raw, ok := strings.CutPrefix(stored, piiPrefix)
+	if !ok {
+		return stored, nil
+	}
+	key, err := userDataKey(db, userID)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := openWithKey(key, sealed)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// shredUserDataKey destroys the wrapped key; every ciphertext sealed
+// under it, wherever it survives, is unreadable from here on. The row
+// stays as the shredding record.
+func shredUserDataKey(userID uint) {
+	res := DB.Model(&models.UserDataKey{}).
+		Where("user_id = ? AND shredded_at IS NULL", userID).
+		Updates(map[string]interface{}{"wrapped_key": "", "shredded_at": time.Now()})
+	if res.RowsAffected > 0 {
+		recordAudit(AuditKeyShredded, 0, userID, "", "")
+	}
+}
+
+// AdminShreddingReport verifies deletions against the key table: every
+// anonymized account should either have a shredded key or never have
+// had one. Accounts listed with state "live" still have a working key
+// and need attention.
+func AdminShreddingReport(c *gin.Context) {
+	q := ValidateQuery(c)
+	since := q.Date("since")
+	if !q.Valid() {
+		return
+	}
+
+	query := RequestDB(c).Model(&models.User{}).Where("anonymized_at IS NOT NULL")
+	if since != nil {
+		query = query.Where("anonymized_at >= ?", since)
+	}
+	var users []models.User
+	if err := query.Order("anonymized_at").Limit(500).Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	verified := 0
+	entries := make([]gin.H, 0, len(users))
+	for _, u := range users {
+		entry := gin.H{"user_id": u.ID, "anonymized_at": u.AnonymizedAt}
+		var key models.UserDataKey
+		err := RequestDB(c).Where("user_id = ?", u.ID).First(&key).Error
+		switch {
+		case err != nil:
+			entry["key_state"] = "never_created"
+			verified++
+		case key.ShreddedAt != nil && key.WrappedKey == "":
+			entry["key_state"] = "shredded"
+			entry["shredded_at"] = key.ShreddedAt
+			verified++
+		default:
+			entry["key_state"] = "live"
+		}
+		entries = append(entries, entry)
+	}
+
+	RenderJSON(c, http.StatusOK, gin.H{
+		"enabled":  len(dataMasterKeys()) > 0,
+		"total":    len(entries),
+		"verified": verified,
+		"users":    entries,
+	})
+}
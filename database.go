@@ -0,0 +1,153 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/bernardmuller/go-idle/config"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// DB is the shared database handle used by all handlers.
+//
+// The layered packages under internal/ take their handle via
+// constructor injection instead (see buildRouter in main.go); this
+// package-level variable stays because the legacy handlers in package
+// main read it directly, and threading it through every one of them is
+// a separate migration.
+var DB *gorm.DB
+
+// migratedModels is the full model set the schema is kept in sync
+// with; drift detection compares the live schema against it.
+var migratedModels = []interface{}{
+	&models.User{}, &models.Role{}, &models.Permission{}, &models.Player{}, &models.SocialAccount{},
+	&models.Organization{}, &models.Membership{}, &models.TelemetryEvent{}, &models.GameEvent{},
+	&models.AdminMessage{}, &models.UserMessage{}, &models.Session{}, &models.EmailTemplate{},
+	&models.Job{}, &models.CacheProfile{}, &models.GameConfig{}, &models.PasswordResetToken{},
+	&models.WebhookSubscription{}, &models.ConsentChange{}, &models.MaintenanceWindow{},
+	&models.Incident{},
+	&models.Backup{},
+	&models.Item{}, &models.Upgrade{}, &models.Achievement{}, &models.Announcement{}, &models.CatalogChange{},
+	&models.Notification{}, &models.NotificationDelivery{},
+	&models.SandboxKey{},
+	&models.ProfileShare{},
+	&models.InactivityNotice{},
+	&models.AuditLog{},
+	&models.SaveCheckpoint{},
+	&models.TwoFactorBackupCode{},
+	&models.WebauthnCredential{},
+	&models.APIKey{},
+	&models.UsageLineItem{},
+	&models.Saga{},
+	&models.EconomyCounter{},
+	&models.RoleApproval{},
+	&models.MailboxMessage{},
+	&models.FunnelStep{},
+	&models.FunnelEvent{},
+	&models.EconomyFreeze{},
+	&models.UserDataKey{},
+}
+
+// Pool sizing. The database/sql defaults (unbounded open connections)
+// exhaust Postgres under load, so every handle this process opens gets
+// the configured limits.
+func dbMaxOpenConns() int {
+	if v, err := strconv.Atoi(os.Getenv("DB_MAX_OPEN_CONNS")); err == nil && v > 0 {
+		return v
+	}
+	return 25
+}
+
+func dbMaxIdleConns() int {
+	if v, err := strconv.Atoi(os.Getenv("DB_MAX_IDLE_CONNS")); err == nil && v > 0 {
+		return v
+	}
+	return 5
+}
+
+func dbConnMaxLifetime() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("DB_CONN_MAX_LIFETIME")); err == nil && d > 0 {
+		return d
+	}
+	return 30 * time.Minute
+}
+
+// configureConnectionPool applies the pool limits to one handle.
+func configureConnectionPool(sqlDB *sql.DB) {
+	sqlDB.SetMaxOpenConns(dbMaxOpenConns())
+	sqlDB.SetMaxIdleConns(dbMaxIdleConns())
+	sqlDB.SetConnMaxLifetime(dbConnMaxLifetime())
+}
+
+// openDatabase connects and pings without touching the schema. With a
+// DSN list configured the connection goes through the failover pool
+// (see dsn_failover.go).
+func openDatabase() (*gorm.DB, error) {
+	if dsns := databaseDSNs(); len(dsns) > 0 {
+		return openFailoverDatabase(dsns)
+	}
+	return openDatabaseDSN("database", config.Get().DatabaseURL)
+}
+
+// openDatabaseDSN connects one DSN with startup retry and pool limits
+// applied; shared by the primary, the read replica, and the residency
+// stores.
+func openDatabaseDSN(name, dsn string) (*gorm.DB, error) {
+	var db *gorm.DB
+	err := waitForDependency(name, func() error {
+		var openErr error
+		db, openErr = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+		if openErr != nil {
+			return openErr
+		}
+		sqlDB, pingErr := db.DB()
+		if pingErr != nil {
+			return pingErr
+		}
+		return sqlDB.Ping()
+	})
+	if err == nil {
+		if sqlDB, poolErr := db.DB(); poolErr == nil {
+			configureConnectionPool(sqlDB)
+		}
+	}
+	return db, err
+}
+
+func ConnectDatabase() {
+	db, err := openDatabase()
+	if err != nil {
+		log.Fatalf("failed to connect to database: %v", err)
+	}
+
+	if err := db.Use(statementCounter{}); err != nil {
+		log.Fatalf("failed to install statement counter: %v", err)
+	}
+	if err := db.Use(tracingPlugin{}); err != nil {
+		log.Fatalf("failed to install tracing: %v", err)
+	}
+	if err := db.Use(serverTimingPlugin{}); err != nil {
+		log.Fatalf("failed to install server timing: %v", err)
+	}
+	if err := registerStampingCallbacks(db); err != nil {
+		log.Fatalf("failed to install audit stamping: %v", err)
+	}
+
+	// Versioned migrations run first everywhere; AutoMigrate then tops
+	// up the dev schema with models the history doesn't cover yet.
+	if _, err := runMigrations(db); err != nil {
+		log.Fatalf("failed to run migrations: %v", err)
+	}
+	if devAutoMigrate() {
+		if err := db.AutoMigrate(migratedModels...); err != nil {
+			log.Fatalf("failed to migrate database: %v", err)
+		}
+	}
+
+	DB = db
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/bernardmuller/go-idle/internal/handlers"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// Staged account deletion. Deleting an account keeps it recoverable
+// for a window; logging in during the window lets the user reactivate,
+// after which a background purge anonymizes the PII for good.
+
+const deletionRecoveryWindow = 30 * 24 * time.Hour
+
+// DeleteAccount schedules the authenticated user's account for
+// deletion instead of removing it outright.
+func DeleteAccount(c *gin.Context) {
+	var user models.User
+	if err := RequestDB(c).First(&user, CurrentUserID(c)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	now := time.Now()
+	user.DeletionRequestedAt = &now
+	if err := RequestDB(c).Save(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(AuditDeletionRequested, user.ID, 0, c.ClientIP(), "")
+	PublishEvent("user.deletion_requested", map[string]interface{}{"user_id": user.ID})
+
+	RenderJSON(c, http.StatusOK, gin.H{
+		"deletion_requested_at": now,
+		"recoverable_until":     now.Add(deletionRecoveryWindow),
+	})
+}
+
+// ReactivateAccount cancels a pending deletion while the recovery
+// window is still open.
+func ReactivateAccount(c *gin.Context) {
+	var user models.User
+	if err := RequestDB(c).First(&user, CurrentUserID(c)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	if user.DeletionRequestedAt == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no deletion pending"})
+		return
+	}
+	if time.Since(*user.DeletionRequestedAt) > deletionRecoveryWindow {
+		c.JSON(http.StatusGone, gin.H{"error": "recovery window has expired"})
+		return
+	}
+
+	user.DeletionRequestedAt = nil
+	if err := RequestDB(c).Save(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	RenderJSON(c, http.StatusOK, gin.H{"reactivated": true})
+}
+
+// AdminListDeletions exposes accounts in or past their recovery window
+// so support staff can see the state before users ask.
+func AdminListDeletions(c *gin.Context) {
+	q := ValidateQuery(c)
+	state := q.Enum("state", "", "pending", "purged")
+	since := q.Date("since")
+	if !q.Valid() {
+		return
+	}
+
+	query := RequestDB(c).Where("deletion_requested_at IS NOT NULL OR anonymized_at IS NOT NULL")
+	switch state {
+	case "pending":
+		query = RequestDB(c).Where("deletion_requested_at IS NOT NULL AND anonymized_at IS NULL")
+	case "purged":
+		query = RequestDB(c).Where("anonymized_at IS NOT NULL")
+	}
+	if since != nil {
+		query = query.Where("deletion_requested_at >= ?", since)
+	}
+
+	var users []models.User
+	err := query.Find(&users).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	out := make([]gin.H, 0, len(users))
+	for _, u := range users {
+		entry := gin.H{
+			"user_id":       u.ID,
+			"email":         u.Email,
+			"anonymized_at": u.AnonymizedAt,
+		}
+		if u.DeletionRequestedAt != nil {
+			entry["deletion_requested_at"] = u.DeletionRequestedAt
+			entry["recoverable_until"] = u.DeletionRequestedAt.Add(deletionRecoveryWindow)
+			entry["recoverable"] = u.AnonymizedAt == nil && time.Since(*u.DeletionRequestedAt) <= deletionRecoveryWindow
+		}
+		out = append(out, entry)
+	}
+	RenderJSON(c, http.StatusOK, out)
+}
+
+// StartDeletionPurge runs the anonymization pass periodically in the
+// background.
+func StartDeletionPurge() {
+	go func() {
+		for {
+			if err := PurgeExpiredDeletions(); err != nil {
+				log.Printf("deletion purge: %v", err)
+			}
+			time.Sleep(time.Hour)
+		}
+	}()
+}
+
+// PurgeExpiredDeletions anonymizes the PII of every account whose
+// recovery window has lapsed. The row is kept so game history and
+// foreign keys stay intact.
+func PurgeExpiredDeletions() error {
+	cutoff := time.Now().Add(-deletionRecoveryWindow)
+
+	var users []models.User
+	err := DB.Where("deletion_requested_at IS NOT NULL AND deletion_requested_at < ? AND anonymized_at IS NULL", cutoff).
+		Find(&users).Error
+	if err != nil {
+		return err
+	}
+
+	for i := range users {
+		if err := anonymizeUser(&users[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func anonymizeUser(user *models.User) error {
+	random := make([]byte, 32)
+	rand.Read(random)
+	hash, err := bcrypt.GenerateFromPassword(random, bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	user.Name = "Deleted User"
+	user.Email = fmt.Sprintf("deleted-%d@anonymized.invalid", user.ID)
+	user.Password = string(hash)
+	user.AnonymizedAt = &now
+	if err := DB.Save(user).Error; err != nil {
+		return err
+	}
+
+	indexUser(user)
+	userCache.Invalidate(user.ID)
+	if err := DB.Where("user_id = ?", user.ID).Delete(&models.SocialAccount{}).Error; err != nil {
+		return err
+	}
+	// Destroying the data key makes the user's encrypted PII unreadable
+	// in residual backups too; see crypto_shred.go.
+	shredUserDataKey(user.ID)
+	PublishEvent("user.deleted", map[string]interface{}{"user_id": user.ID})
+	return nil
+}
+
+// AdminRestoreUser lifts a soft delete; the account and its email
+// become live again immediately.
+func AdminRestoreUser(c *gin.Context) {
+	var user models.User
+	if err := RequestDB(c).Unscoped().First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	if !user.DeletedAt.Valid {
+		c.JSON(http.StatusConflict, gin.H{"error": "user is not deleted"})
+		return
+	}
+
+	if err := RequestDB(c).Unscoped().Model(&user).Update("deleted_at", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	user.DeletedAt = gorm.DeletedAt{}
+	userCache.Invalidate(user.ID)
+	indexUser(&user)
+	recordAudit(AuditUserRestored, CurrentUserID(c), user.ID, c.ClientIP(), "")
+	RenderJSON(c, http.StatusOK, handlers.NewUserResponse(user))
+}
+
+// AdminPurgeUser removes a soft-deleted user for good. Live accounts
+// must be deleted first; this is the one place a user row actually
+// disappears.
+func AdminPurgeUser(c *gin.Context) {
+	var user models.User
+	if err := RequestDB(c).Unscoped().First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	if !user.DeletedAt.Valid {
+		c.JSON(http.StatusConflict, gin.H{"error": "only deleted users can be purged"})
+		return
+	}
+
+	err := RequestDB(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.Session{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.SocialAccount{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&models.User{}, user.ID).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	userCache.Invalidate(user.ID)
+	shredUserDataKey(user.ID)
+	recordAudit(AuditUserPurged, CurrentUserID(c), user.ID, c.ClientIP(), user.Email)
+	PublishEvent("user.deleted", map[string]interface{}{"user_id": user.ID})
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deprecation telemetry. Routes (and request fields) marked deprecated
+// in their RouteMeta announce it with Deprecation/Sunset headers, and
+// every remaining caller is tallied by user agent and user id so the
+// removal date can be argued with data instead of hope. The aggregate
+// is in-memory and served from GET /admin/deprecations.
+
+// deprecatedBodyPeekLimit caps how much of a request body is buffered
+// to look for deprecated fields.
+const deprecatedBodyPeekLimit = 64 << 10
+
+type deprecationUsage struct {
+	Route     string    `json:"route"`
+	Field     string    `json:"field,omitempty"`
+	UserAgent string    `json:"user_agent"`
+	UserID    uint      `json:"user_id,omitempty"`
+	Count     int64     `json:"count"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+var deprecationStats = struct {
+	mu    sync.Mutex
+	usage map[string]*deprecationUsage
+}{usage: make(map[string]*deprecationUsage)}
+
+func recordDeprecatedUse(route, field, agent string, userID uint) {
+	key := route + "|" + field + "|" + agent
+	deprecationStats.mu.Lock()
+	defer deprecationStats.mu.Unlock()
+
+	entry, ok := deprecationStats.usage[key]
+	if !ok {
+		entry = &deprecationUsage{Route: route, Field: field, UserAgent: agent}
+		deprecationStats.usage[key] = entry
+	}
+	entry.Count++
+	entry.LastSeen = time.Now()
+	entry.UserID = userID
+}
+
+// deprecatedFieldsUsed peeks at a JSON body for top-level deprecated
+// fields, restoring the body for the real handler.
+func deprecatedFieldsUsed(c *gin.Context, fields []string) []string {
+	if c.Request.Body == nil || !strings.Contains(c.GetHeader("Content-Type"), "json") {
+		return nil
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, deprecatedBodyPeekLimit))
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+	if err != nil {
+		return nil
+	}
+
+	var payload map[string]json.RawMessage
+	if json.Unmarshal(body, &payload) != nil {
+		return nil
+	}
+	var used []string
+	for _, field := range fields {
+		if _, ok := payload[field]; ok {
+			used = append(used, field)
+		}
+	}
+	return used
+}
+
+// DeprecationTelemetry stamps headers on deprecated routes and tallies
+// who is still calling them.
+func DeprecationTelemetry(c *gin.Context) {
+	meta, ok := routeMeta[c.Request.Method+" "+c.FullPath()]
+	if !ok || (!meta.Deprecated && len(meta.DeprecatedFields) == 0) {
+		c.Next()
+		return
+	}
+
+	route := c.Request.Method + " " + c.FullPath()
+	var fieldsUsed []string
+	if len(meta.DeprecatedFields) > 0 {
+		fieldsUsed = deprecatedFieldsUsed(c, meta.DeprecatedFields)
+	}
+	if meta.Deprecated || len(fieldsUsed) > 0 {
+		c.Header("Deprecation", "true")
+		if meta.Sunset != "" {
+			if t, err := time.Parse("2006-01-02", meta.Sunset); err == nil {
+				c.Header("Sunset", t.UTC().Format(http.TimeFormat))
+			}
+		}
+	}
+
+	// Tally after the handler so the user id set by Authenticate is
+	// available.
+	c.Next()
+
+	agent := c.GetHeader("User-Agent")
+	if meta.Deprecated {
+		recordDeprecatedUse(route, "", agent, CurrentUserID(c))
+	}
+	for _, field := range fieldsUsed {
+		recordDeprecatedUse(route, field, agent, CurrentUserID(c))
+	}
+}
+
+// AdminDeprecationReport lists deprecated-surface usage, heaviest
+// callers first.
+func AdminDeprecationReport(c *gin.Context) {
+	deprecationStats.mu.Lock()
+	out := make([]deprecationUsage, 0, len(deprecationStats.usage))
+	for _, entry := range deprecationStats.usage {
+		out = append(out, *entry)
+	}
+	deprecationStats.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Count > out[j].Count })
+	RenderJSON(c, http.StatusOK, out)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bernardmuller/go-idle/api"
+	"github.com/gin-gonic/gin"
+)
+
+// Load balancer hints. POST /admin/drain flips /readyz to failing for
+// a window while in-flight and follow-up requests keep being served,
+// so a smart LB bleeds traffic off this instance before a deploy
+// instead of cutting it. Every response also carries an
+// X-Capacity-Remaining header (free request slots against
+// LB_MAX_IN_FLIGHT) so weighted balancers can route by headroom.
+
+var drainState = struct {
+	mu    sync.Mutex
+	until time.Time
+}{}
+
+const defaultDrainDuration = 60 * time.Second
+
+// draining reports whether a drain window is active.
+func draining() bool {
+	drainState.mu.Lock()
+	defer drainState.mu.Unlock()
+	return time.Now().Before(drainState.until)
+}
+
+// maxInFlight is the capacity advertised to load balancers; it is a
+// routing hint, not an enforced limit.
+func maxInFlight() int64 {
+	if v, err := strconv.ParseInt(os.Getenv("LB_MAX_IN_FLIGHT"), 10, 64); err == nil && v > 0 {
+		return v
+	}
+	return 512
+}
+
+// CapacityHints stamps the remaining-capacity header (and the drain
+// flag while one is active) on every response.
+func CapacityHints(c *gin.Context) {
+	remaining := maxInFlight() - atomic.LoadInt64(&metrics.inFlight)
+	if remaining < 0 {
+		remaining = 0
+	}
+	c.Header("X-Capacity-Remaining", strconv.FormatInt(remaining, 10))
+	if draining() {
+		c.Header("X-Draining", "true")
+	}
+	c.Next()
+}
+
+type DrainInput struct {
+	// Duration is how long readiness stays failing; Go duration string.
+	Duration string `json:"duration" binding:"omitempty"`
+}
+
+// AdminStartDrain begins a drain window. Repeated calls extend or
+// shorten it to the new duration.
+func AdminStartDrain(c *gin.Context) {
+	var input DrainInput
+	if !api.BindJSON(c, &input) {
+		return
+	}
+	duration := defaultDrainDuration
+	if input.Duration != "" {
+		d, err := time.ParseDuration(input.Duration)
+		if err != nil || d <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "duration must be a positive duration"})
+			return
+		}
+		duration = d
+	}
+
+	until := time.Now().Add(duration)
+	drainState.mu.Lock()
+	drainState.until = until
+	drainState.mu.Unlock()
+
+	RenderJSON(c, http.StatusOK, gin.H{"draining": true, "until": until})
+}
+
+// AdminStopDrain ends the drain window early.
+func AdminStopDrain(c *gin.Context) {
+	drainState.mu.Lock()
+	drainState.until = time.Time{}
+	drainState.mu.Unlock()
+	RenderJSON(c, http.StatusOK, gin.H{"draining": false})
+}
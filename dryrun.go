@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Dry-run mode for destructive admin operations. Appending
+// ?dry_run=true makes the operation report exactly what it would do
+// without writing anything, so sweeping actions can be sanity-checked
+// against production data first.
+
+func isDryRun(c *gin.Context) bool {
+	return c.Query("dry_run") == "true"
+}
+
+// AdminTriggerPurge runs the deletion purge on demand. With dry_run it
+// lists the accounts that would be anonymized instead of touching
+// them.
+func AdminTriggerPurge(c *gin.Context) {
+	cutoff := time.Now().Add(-deletionRecoveryWindow)
+
+	if isDryRun(c) {
+		var users []models.User
+		err := RequestDB(c).Where("deletion_requested_at IS NOT NULL AND deletion_requested_at < ? AND anonymized_at IS NULL", cutoff).
+			Find(&users).Error
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		would := make([]gin.H, 0, len(users))
+		for _, u := range users {
+			would = append(would, gin.H{"user_id": u.ID, "email": u.Email, "deletion_requested_at": u.DeletionRequestedAt})
+		}
+		RenderJSON(c, http.StatusOK, gin.H{"dry_run": true, "would_anonymize": would})
+		return
+	}
+
+	if err := PurgeExpiredDeletions(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, gin.H{"dry_run": false, "purged": true})
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// DSN failover. DATABASE_URLS holds a comma-separated, priority-ordered
+// list of DSNs (typically the two sides of a Postgres HA pair); the
+// pool dials the highest-priority endpoint that answers, new
+// connections slide to the next one when the active endpoint starts
+// refusing, and a background probe fails back once a higher-priority
+// endpoint is healthy again — no pgbouncer or HAProxy in between. The
+// switch happens at the database/sql connector layer, underneath gorm,
+// so the rest of the code keeps its single DB handle. With only
+// DATABASE_URL set nothing changes.
+
+const (
+	dsnProbeInterval = 15 * time.Second
+	// dsnDownCooldown is how long a failed endpoint is skipped before
+	// new connections try it again.
+	dsnDownCooldown = 30 * time.Second
+)
+
+// databaseDSNs returns the configured DSN list in priority order.
+func databaseDSNs() []string {
+	raw := os.Getenv("DATABASE_URLS")
+	if raw == "" {
+		return nil
+	}
+	var dsns []string
+	for _, dsn := range strings.Split(raw, ",") {
+		if dsn = strings.TrimSpace(dsn); dsn != "" {
+			dsns = append(dsns, dsn)
+		}
+	}
+	return dsns
+}
+
+type dsnEndpoint struct {
+	dsn       string
+	connector driver.Connector
+	downUntil time.Time
+}
+
+// failoverConnector is a driver.Connector fronting the endpoint list;
+// database/sql calls Connect whenever the pool needs a fresh
+// connection, which is where the switch happens.
+type failoverConnector struct {
+	mu        sync.Mutex
+	endpoints []*dsnEndpoint
+	active    int
+}
+
+var dbFailover *failoverConnector
+
+func newFailoverConnector(dsns []string) (*failoverConnector, error) {
+	fc := &failoverConnector{}
+	for _, dsn := range dsns {
+		cfg, err := pgx.ParseConfig(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("bad DSN in DATABASE_URLS: %w", err)
+		}
+		fc.endpoints = append(fc.endpoints, &dsnEndpoint{dsn: dsn, connector: stdlib.GetConnector(*cfg)})
+	}
+	return fc, nil
+}
+
+func (fc *failoverConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	fc.mu.Lock()
+	start := fc.active
+	n := len(fc.endpoints)
+	fc.mu.Unlock()
+
+	var lastErr error
+	for i := 0; i < n; i++ {
+		idx := (start + i) % n
+
+		fc.mu.Lock()
+		endpoint := fc.endpoints[idx]
+		skip := i > 0 && time.Now().Before(endpoint.downUntil)
+		fc.mu.Unlock()
+		if skip {
+			continue
+		}
+
+		conn, err := endpoint.connector.Connect(ctx)
+		fc.mu.Lock()
+		if err != nil {
+			endpoint.downUntil = time.Now().Add(dsnDownCooldown)
+			fc.mu.Unlock()
+			lastErr = err
+			continue
+		}
+		endpoint.downUntil = time.Time{}
+		if idx != fc.active {
+			log.Printf("database: failing over from endpoint %d to %d", fc.active, idx)
+			fc.active = idx
+		}
+		fc.mu.Unlock()
+		return conn, nil
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("all %d database endpoints are in cooldown", n)
+	}
+	return nil, lastErr
+}
+
+func (fc *failoverConnector) Driver() driver.Driver {
+	return fc.endpoints[0].connector.Driver()
+}
+
+// probe checks whether one endpoint accepts a connection right now.
+func (fc *failoverConnector) probe(endpoint *dsnEndpoint) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := endpoint.connector.Connect(ctx)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// failBack moves the active endpoint back to the highest-priority one
+// that answers; only endpoints ahead of the current active are probed,
+// so the steady state on the primary costs nothing.
+func (fc *failoverConnector) failBack() {
+	fc.mu.Lock()
+	active := fc.active
+	fc.mu.Unlock()
+
+	for idx := 0; idx < active; idx++ {
+		if !fc.probe(fc.endpoints[idx]) {
+			continue
+		}
+		fc.mu.Lock()
+		if idx < fc.active {
+			log.Printf("database: failing back from endpoint %d to %d", fc.active, idx)
+			fc.active = idx
+			fc.endpoints[idx].downUntil = time.Time{}
+		}
+		fc.mu.Unlock()
+		return
+	}
+}
+
+// StartDSNFailover runs the fail-back prober; a no-op without a DSN
+// list.
+func StartDSNFailover() {
+	if dbFailover == nil {
+		return
+	}
+	go func() {
+		for range time.Tick(dsnProbeInterval) {
+			dbFailover.failBack()
+		}
+	}()
+}
+
+// openFailoverDatabase connects through the failover pool; same
+// startup retry and pool limits as the single-DSN path.
+func openFailoverDatabase(dsns []string) (*gorm.DB, error) {
+	fc, err := newFailoverConnector(dsns)
+	if err != nil {
+		return nil, err
+	}
+	sqlDB := sql.OpenDB(fc)
+	configureConnectionPool(sqlDB)
+
+	var db *gorm.DB
+	err = waitForDependency("database", func() error {
+		var openErr error
+		db, openErr = gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{})
+		if openErr != nil {
+			return openErr
+		}
+		return sqlDB.Ping()
+	})
+	if err != nil {
+		return nil, err
+	}
+	dbFailover = fc
+	return db, nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bernardmuller/go-idle/api"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Emergency economy freeze. When an exploit is found, an admin can
+// switch off individual economy actions (gifts, conversions, challenge
+// entries) fleet-wide without a deploy: frozen actions live as rows,
+// every instance checks them through a short-lived cache, and with
+// Redis configured a freeze additionally publishes an invalidation on
+// the fan-out connection so the fleet converges in well under the
+// cache TTL. Frozen actions answer 503 with the admin's message, and
+// GET /game/allowances flags them so clients can grey out buttons.
+
+const (
+	economyFreezeTTL     = 5 * time.Second
+	economyFreezeChannel = "goidle:freeze"
+)
+
+// freezeCache mirrors maintenanceCache: the guard sits on the hot path
+// of every capped action, so it must not hit the database per request.
+var freezeCache struct {
+	mu      sync.Mutex
+	fetched time.Time
+	frozen  map[string]models.EconomyFreeze
+}
+
+// frozenEconomyActions returns the current freeze set, at most
+// economyFreezeTTL stale.
+func frozenEconomyActions() map[string]models.EconomyFreeze {
+	freezeCache.mu.Lock()
+	defer freezeCache.mu.Unlock()
+
+	if time.Since(freezeCache.fetched) < economyFreezeTTL {
+		return freezeCache.frozen
+	}
+	var rows []models.EconomyFreeze
+	if err := DB.Find(&rows).Error; err != nil {
+		return freezeCache.frozen
+	}
+	frozen := make(map[string]models.EconomyFreeze, len(rows))
+	for _, row := range rows {
+		frozen[row.Action] = row
+	}
+	freezeCache.frozen = frozen
+	freezeCache.fetched = time.Now()
+	return frozen
+}
+
+// frozenEconomyAction looks up one action in the freeze set.
+func frozenEconomyAction(action string) (models.EconomyFreeze, bool) {
+	freeze, ok := frozenEconomyActions()[action]
+	return freeze, ok
+}
+
+func invalidateFreezeCache() {
+	freezeCache.mu.Lock()
+	freezeCache.fetched = time.Time{}
+	freezeCache.mu.Unlock()
+}
+
+// publishFreezeInvalidation tells the rest of the fleet to drop its
+// freeze cache now instead of waiting out the TTL. Freezes are rare,
+// so a fresh connection per publish is fine; without Redis the TTL is
+// the propagation bound.
+func publishFreezeInvalidation() {
+	invalidateFreezeCache()
+	raw := os.Getenv("REDIS_URL")
+	if raw == "" {
+		return
+	}
+	client, err := newRedisCache(raw)
+	if err != nil {
+		log.Printf("freeze: %v", err)
+		return
+	}
+	if _, err := client.command("PUBLISH", economyFreezeChannel, instanceID); err != nil {
+		log.Printf("freeze: publish: %v", err)
+	}
+}
+
+type economyFreezeInput struct {
+	Action  string `json:"action" binding:"required,min=2,max=64"`
+	Message string `json:"message" binding:"max=200"`
+}
+
+// AdminListEconomyFreezes returns the actions currently frozen.
+func AdminListEconomyFreezes(c *gin.Context) {
+	var rows []models.EconomyFreeze
+	if err := RequestDB(c).Order("created_at").Find(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, gin.H{"frozen": rows})
+}
+
+// AdminFreezeEconomyAction switches one action off everywhere.
+func AdminFreezeEconomyAction(c *gin.Context) {
+	var input economyFreezeInput
+	if !api.BindJSON(c, &input) {
+		return
+	}
+
+	var existing models.EconomyFreeze
+	if err := RequestDB(c).Where("action = ?", input.Action).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "action is already frozen"})
+		return
+	}
+
+	actorID := CurrentUserID(c)
+	freeze := models.EconomyFreeze{Action: input.Action, Message: input.Message, FrozenBy: actorID}
+	if err := RequestDB(c).Create(&freeze).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(AuditEconomyFrozen, actorID, 0, c.ClientIP(), input.Action)
+	publishFreezeInvalidation()
+	RenderJSON(c, http.StatusCreated, freeze)
+}
+
+// AdminUnfreezeEconomyAction lifts a freeze.
+func AdminUnfreezeEconomyAction(c *gin.Context) {
+	action := c.Param("action")
+	res := RequestDB(c).Where("action = ?", action).Delete(&models.EconomyFreeze{})
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": res.Error.Error()})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "action is not frozen"})
+		return
+	}
+	recordAudit(AuditEconomyUnfrozen, CurrentUserID(c), 0, c.ClientIP(), action)
+	publishFreezeInvalidation()
+	c.Status(http.StatusNoContent)
+}
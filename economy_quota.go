@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Economy action caps. Game sinks — gifts, currency conversions,
+// challenge entries — get daily and weekly allowances enforced in one
+// place, so no individual handler reimplements (or forgets) the cap.
+// Caps live under "economy_caps" in the game config document and are
+// tuned with the same patch endpoint as every other game knob; the
+// counters are persisted rows, because an economy cap that resets on
+// redeploy is an exploit. A sink opts in by setting EconomyAction on
+// its route table row, and clients read remaining allowances from
+// GET /game/allowances to grey out buttons before the server says no.
+
+// economyCap is one action's allowance; zero means uncapped for that
+// window.
+type economyCap struct {
+	PerDay  int64 `json:"per_day"`
+	PerWeek int64 `json:"per_week"`
+}
+
+// economyCaps reads the cap table from the game config document.
+func economyCaps() map[string]economyCap {
+	cfg, err := loadGameConfig()
+	if err != nil {
+		return nil
+	}
+	var doc struct {
+		EconomyCaps map[string]economyCap `json:"economy_caps"`
+	}
+	if err := json.Unmarshal([]byte(cfg.Document), &doc); err != nil {
+		return nil
+	}
+	return doc.EconomyCaps
+}
+
+// economyPeriod is the counter bucket for a window: the date for
+// daily, the ISO week for weekly.
+func economyPeriod(window string, now time.Time) string {
+	now = now.UTC()
+	if window == "week" {
+		year, week := now.ISOWeek()
+		return strconv.Itoa(year) + "-W" + strconv.Itoa(week)
+	}
+	return now.Format("2006-01-02")
+}
+
+// bumpEconomyCounter increments one (user, action, window) counter if
+// it's under the limit, reporting the count after the attempt and
+// whether it was allowed. The guarded update makes concurrent spends
+// race-safe.
+func bumpEconomyCounter(db *gorm.DB, userID uint, action, window string, limit int64) (int64, bool, error) {
+	if limit <= 0 {
+		return 0, true, nil
+	}
+	period := economyPeriod(window, time.Now())
+
+	for attempt := 0; attempt < 2; attempt++ {
+		res := db.Model(&models.EconomyCounter{}).
+			Where("user_id = ? AND action = ? AND window = ? AND period = ? AND count < ?",
+				userID, action, window, period, limit).
+			Update("count", gorm.Expr("count + 1"))
+		if res.Error != nil {
+			return 0, false, res.Error
+		}
+		if res.RowsAffected > 0 {
+			break
+		}
+		// Either the row doesn't exist yet or the cap is reached.
+		var row models.EconomyCounter
+		err := db.Where("user_id = ? AND action = ? AND window = ? AND period = ?",
+			userID, action, window, period).First(&row).Error
+		if err == nil {
+			return row.Count, false, nil
+		}
+		create := db.Create(&models.EconomyCounter{UserID: userID, Action: action, Window: window, Period: period, Count: 1})
+		if create.Error == nil {
+			return 1, true, nil
+		}
+		// Lost the insert race; loop once more against the winner's row.
+	}
+
+	var row models.EconomyCounter
+	db.Where("user_id = ? AND action = ? AND window = ? AND period = ?", userID, action, window, period).First(&row)
+	return row.Count, true, nil
+}
+
+// economyUsage reads one counter without touching it.
+func economyUsage(db *gorm.DB, userID uint, action, window string) int64 {
+	var row models.EconomyCounter
+	err := db.Where("user_id = ? AND action = ? AND window = ? AND period = ?",
+		userID, action, window, economyPeriod(window, time.Now())).First(&row).Error
+	if err != nil {
+		return 0
+	}
+	return row.Count
+}
+
+// consumeEconomyAction spends one unit of an action's allowance,
+// checking the daily cap before the weekly one. Uncapped actions
+// always pass.
+func consumeEconomyAction(db *gorm.DB, userID uint, action string) (string, bool, error) {
+	cap, ok := economyCaps()[action]
+	if !ok {
+		return "", true, nil
+	}
+	if _, allowed, err := bumpEconomyCounter(db, userID, action, "day", cap.PerDay); err != nil {
+		return "", false, err
+	} else if !allowed {
+		return "day", false, nil
+	}
+	if _, allowed, err := bumpEconomyCounter(db, userID, action, "week", cap.PerWeek); err != nil {
+		return "", false, err
+	} else if !allowed {
+		return "week", false, nil
+	}
+	return "", true, nil
+}
+
+// EconomyQuotaGuard enforces an action's caps; sink routes pick it up
+// through the EconomyAction column of the route table.
+func EconomyQuotaGuard(action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// An emergency freeze trumps the allowance; see economy_freeze.go.
+		if freeze, frozen := frozenEconomyAction(action); frozen {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"error":   "this action is temporarily disabled",
+				"action":  action,
+				"frozen":  true,
+				"message": freeze.Message,
+			})
+			return
+		}
+		window, allowed, err := consumeEconomyAction(RequestDB(c), CurrentUserID(c), action)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !allowed {
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":  "allowance for " + action + " exhausted",
+				"action": action,
+				"window": window,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// GetGameAllowances reports every capped action's limits and what the
+// caller has left in the current windows.
+func GetGameAllowances(c *gin.Context) {
+	userID := CurrentUserID(c)
+	db := ReadRequestDB(c)
+
+	out := gin.H{}
+	for action, cap := range economyCaps() {
+		entry := gin.H{}
+		if cap.PerDay > 0 {
+			used := economyUsage(db, userID, action, "day")
+			entry["per_day"] = cap.PerDay
+			entry["remaining_today"] = maxInt64(cap.PerDay-used, 0)
+		}
+		if cap.PerWeek > 0 {
+			used := economyUsage(db, userID, action, "week")
+			entry["per_week"] = cap.PerWeek
+			entry["remaining_this_week"] = maxInt64(cap.PerWeek-used, 0)
+		}
+		out[action] = entry
+	}
+	// Frozen actions are flagged even when uncapped, so clients grey
+	// out the button instead of surfacing a 503.
+	for action, freeze := range frozenEconomyActions() {
+		entry, ok := out[action].(gin.H)
+		if !ok {
+			entry = gin.H{}
+		}
+		entry["frozen"] = true
+		if freeze.Message != "" {
+			entry["frozen_message"] = freeze.Message
+		}
+		out[action] = entry
+	}
+	RenderJSON(c, http.StatusOK, gin.H{"allowances": out})
+}
+
+func maxInt64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
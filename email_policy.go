@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// Registration email domain policy. REGISTRATION_DOMAIN_ALLOWLIST
+// restricts signups to the listed domains; otherwise
+// REGISTRATION_DOMAIN_BLOCKLIST rejects known throwaway providers.
+// Entries are comma-separated domains; a leading "*." matches
+// subdomains too.
+
+func domainMatches(domain, pattern string) bool {
+	domain = strings.ToLower(domain)
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	if pattern == "" {
+		return false
+	}
+	if sub, ok := strings.CutPrefix(pattern, "*."); ok {
+		return domain == sub || strings.HasSuffix(domain, "."+sub)
+	}
+	return domain == pattern
+}
+
+func domainInList(domain, envVar string) bool {
+	for _, pattern := range strings.Split(os.Getenv(envVar), ",") {
+		if domainMatches(domain, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// registrationEmailAllowed checks the email's domain against the
+// configured policy and returns a client-safe reason when rejected.
+func registrationEmailAllowed(email string) (bool, string) {
+	_, domain, found := strings.Cut(email, "@")
+	if !found || domain == "" {
+		return false, "email address is malformed"
+	}
+
+	if os.Getenv("REGISTRATION_DOMAIN_ALLOWLIST") != "" {
+		if !domainInList(domain, "REGISTRATION_DOMAIN_ALLOWLIST") {
+			return false, "email domain is not allowed to register"
+		}
+		return true, ""
+	}
+
+	if domainInList(domain, "REGISTRATION_DOMAIN_BLOCKLIST") {
+		return false, "email domain is not allowed to register"
+	}
+	return true, ""
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"text/template"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Email templates. Built-in defaults live in code; operators can
+// override subject and body per template through the admin API and
+// preview the rendered result with sample data before anything is
+// sent to a real user.
+
+// defaultEmailTemplates are the fallbacks used until an operator
+// overrides them.
+var defaultEmailTemplates = map[string]models.EmailTemplate{
+	"welcome": {
+		Name:    "welcome",
+		Subject: "Welcome to go-idle, {{.Name}}!",
+		Body:    "Hi {{.Name}},\n\nYour account is ready. Your empire earns gold even while you sleep.\n",
+	},
+	"password_reset": {
+		Name:    "password_reset",
+		Subject: "Reset your go-idle password",
+		Body:    "Hi {{.Name}},\n\nUse this link to reset your password: {{.Link}}\nIt expires in {{.ExpiresIn}}.\n",
+	},
+	"email_verification": {
+		Name:    "email_verification",
+		Subject: "Confirm your new go-idle email address",
+		Body:    "Hi {{.Name}},\n\nConfirm your new email address with this token: {{.Token}}\nUntil you do, your old address stays active.\n",
+	},
+	"login_otp": {
+		Name:    "login_otp",
+		Subject: "Your go-idle login code",
+		Body:    "Hi {{.Name}},\n\nYour one-time login code is {{.Code}}. It expires in 10 minutes.\nIf this wasn't you, change your password.\n",
+	},
+	"login_blocked": {
+		Name:    "login_blocked",
+		Subject: "A sign-in to your go-idle account was blocked",
+		Body:    "Hi {{.Name}},\n\nWe blocked a suspicious sign-in attempt from {{.IP}}. If this was you, try again later; otherwise consider changing your password.\n",
+	},
+	"inactivity_warning": {
+		Name:    "inactivity_warning",
+		Subject: "Your go-idle account has been idle for {{.DaysInactive}} days",
+		Body:    "Hi {{.Name}},\n\nYou haven't played in {{.DaysInactive}} days. Accounts inactive for {{.DeleteAfterDays}} days are anonymized; log in before {{.DeleteDate}} to keep yours.\n",
+	},
+	"account_deletion": {
+		Name:    "account_deletion",
+		Subject: "Your go-idle account is scheduled for deletion",
+		Body:    "Hi {{.Name}},\n\nYour account will be permanently deleted on {{.PurgeDate}}. Log in before then to keep it.\n",
+	},
+}
+
+// loadEmailTemplate returns the stored override or the built-in
+// default.
+func loadEmailTemplate(name string) (models.EmailTemplate, bool) {
+	var stored models.EmailTemplate
+	if err := DB.Where("name = ?", name).First(&stored).Error; err == nil {
+		return stored, true
+	}
+	def, ok := defaultEmailTemplates[name]
+	return def, ok
+}
+
+// renderEmail produces the subject and body for a template with data.
+func renderEmail(name string, data interface{}) (string, string, error) {
+	tmpl, ok := loadEmailTemplate(name)
+	if !ok {
+		return "", "", gorm.ErrRecordNotFound
+	}
+
+	render := func(text string) (string, error) {
+		t, err := template.New(name).Parse(text)
+		if err != nil {
+			return "", err
+		}
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, data); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+
+	subject, err := render(tmpl.Subject)
+	if err != nil {
+		return "", "", err
+	}
+	body, err := render(tmpl.Body)
+	if err != nil {
+		return "", "", err
+	}
+	return subject, body, nil
+}
+
+func AdminListEmailTemplates(c *gin.Context) {
+	out := make([]gin.H, 0, len(defaultEmailTemplates))
+	for name, def := range defaultEmailTemplates {
+		tmpl, _ := loadEmailTemplate(name)
+		out = append(out, gin.H{
+			"name":       name,
+			"subject":    tmpl.Subject,
+			"body":       tmpl.Body,
+			"overridden": tmpl.Subject != def.Subject || tmpl.Body != def.Body,
+		})
+	}
+	RenderJSON(c, http.StatusOK, out)
+}
+
+type EmailTemplateInput struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+func AdminUpdateEmailTemplate(c *gin.Context) {
+	name := c.Param("name")
+	if _, ok := defaultEmailTemplates[name]; !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown template"})
+		return
+	}
+
+	var input EmailTemplateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Reject templates that don't even parse; a broken reset email is
+	// found here, not when a user needs it.
+	for field, text := range map[string]string{"subject": input.Subject, "body": input.Body} {
+		if _, err := template.New(name).Parse(text); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "template does not parse", "field": field, "detail": err.Error()})
+			return
+		}
+	}
+
+	var stored models.EmailTemplate
+	err := RequestDB(c).Where("name = ?", name).First(&stored).Error
+	if err != nil {
+		stored = models.EmailTemplate{Name: name}
+	}
+	stored.Subject = input.Subject
+	stored.Body = input.Body
+	if err := RequestDB(c).Save(&stored).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, stored)
+}
+
+// AdminPreviewEmailTemplate renders a template with caller-supplied
+// sample data.
+func AdminPreviewEmailTemplate(c *gin.Context) {
+	name := c.Param("name")
+
+	var data map[string]interface{}
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	subject, body, err := renderEmail(name, data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, gin.H{"subject": subject, "body": body})
+}
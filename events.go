@@ -0,0 +1,48 @@
+package main
+
+import (
+	"sync"
+)
+
+// In-process event bus. Handlers publish lifecycle events by name
+// (user.registered, user.deleted, login.failed, ...) without knowing
+// who consumes them; subscribers are registered once at boot. The
+// webhook dispatcher is the first subscriber, so every published event
+// automatically reaches matching webhook subscriptions — new consumers
+// (analytics, cache invalidation) just subscribe here.
+
+// EventHandler receives one published event. Handlers run synchronously
+// on the publisher's goroutine, so anything slow must hand off — the
+// webhook subscriber, for example, only enqueues delivery jobs.
+type EventHandler func(eventType string, data map[string]interface{})
+
+var eventBus = struct {
+	mu sync.RWMutex
+	// subscribers by event type; "*" receives everything.
+	subs map[string][]EventHandler
+}{subs: map[string][]EventHandler{}}
+
+// SubscribeEvents registers a handler for one event type, or all of
+// them with "*".
+func SubscribeEvents(eventType string, handler EventHandler) {
+	eventBus.mu.Lock()
+	eventBus.subs[eventType] = append(eventBus.subs[eventType], handler)
+	eventBus.mu.Unlock()
+}
+
+// PublishEvent fans one event out to its subscribers.
+func PublishEvent(eventType string, data map[string]interface{}) {
+	eventBus.mu.RLock()
+	handlers := append([]EventHandler{}, eventBus.subs[eventType]...)
+	handlers = append(handlers, eventBus.subs["*"]...)
+	eventBus.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(eventType, data)
+	}
+}
+
+// registerEventSubscribers wires the boot-time consumers.
+func registerEventSubscribers() {
+	SubscribeEvents("*", EmitWebhookEvent)
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Active-passive failover. A secondary starts with STANDBY=true and
+// PRIMARY_URL pointing at the active deployment: it serves read-only
+// traffic (ReadOnlyGuard treats standbys like replicas), mirrors the
+// primary's non-secret configuration over /failover/config, and waits.
+// POST /admin/promote flips it to primary in place — it stops refusing
+// writes, reloads the JWT signing key staged on its disk, and takes
+// over the background schedulers — so a regional failover is one API
+// call instead of a redeploy.
+
+const configSyncInterval = time.Minute
+
+var failover struct {
+	mu             sync.Mutex
+	promoted       bool
+	promotedAt     time.Time
+	lastConfigSync time.Time
+	syncedKeys     int
+}
+
+// standbyMode reports whether this instance is still a waiting
+// secondary; a promoted standby behaves as the primary.
+func standbyMode() bool {
+	if os.Getenv("STANDBY") != "true" {
+		return false
+	}
+	failover.mu.Lock()
+	defer failover.mu.Unlock()
+	return !failover.promoted
+}
+
+// failoverToken is the shared secret standbys present when pulling
+// configuration; replication is off until it is set on both sides.
+func failoverToken() string {
+	return os.Getenv("FAILOVER_TOKEN")
+}
+
+// GetFailoverConfig serves the primary's non-secret configuration to
+// standbys. Secrets never cross this channel; they are provisioned on
+// the standby out of band. A wrong or missing token gets a 404 so the
+// endpoint doesn't advertise itself.
+func GetFailoverConfig(c *gin.Context) {
+	token := failoverToken()
+	if token == "" || !hmac.Equal([]byte(c.GetHeader("X-Failover-Token")), []byte(token)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	out := map[string]string{}
+	for _, entry := range configRegistry {
+		if entry.Secret {
+			continue
+		}
+		if v := os.Getenv(entry.Key); v != "" {
+			out[entry.Key] = v
+		}
+	}
+	RenderJSON(c, http.StatusOK, out)
+}
+
+// configReplicationSkip lists the keys that define this instance's
+// identity; replication must never flip a standby into thinking it is
+// the primary or point it at another database.
+var configReplicationSkip = map[string]bool{
+	"READ_ONLY":    true,
+	"STANDBY":      true,
+	"PRIMARY_URL":  true,
+	"PORT":         true,
+	"APP_ENV":      true,
+	"DATABASE_URL": true,
+}
+
+// syncConfigFromPrimary pulls the primary's knobs and applies them.
+// Nearly every knob is read from the environment at call time, so
+// setting it here changes behavior without a restart.
+func syncConfigFromPrimary() error {
+	req, err := http.NewRequest(http.MethodGet, os.Getenv("PRIMARY_URL")+"/failover/config", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Failover-Token", failoverToken())
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("primary answered %d", resp.StatusCode)
+	}
+
+	var remote map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&remote); err != nil {
+		return err
+	}
+
+	applied := 0
+	for key, value := range remote {
+		if configReplicationSkip[key] || os.Getenv(key) == value {
+			continue
+		}
+		os.Setenv(key, value)
+		applied++
+	}
+
+	failover.mu.Lock()
+	failover.lastConfigSync = time.Now()
+	failover.syncedKeys = len(remote)
+	failover.mu.Unlock()
+
+	if applied > 0 {
+		log.Printf("failover: applied %d configuration changes from primary", applied)
+	}
+	return nil
+}
+
+// StartConfigReplication keeps a standby's configuration in step with
+// the primary until it is promoted.
+func StartConfigReplication() {
+	if os.Getenv("STANDBY") != "true" || os.Getenv("PRIMARY_URL") == "" || failoverToken() == "" {
+		return
+	}
+	go func() {
+		for standbyMode() {
+			if err := syncConfigFromPrimary(); err != nil {
+				log.Printf("failover: config sync failed: %v", err)
+			}
+			time.Sleep(configSyncInterval)
+		}
+	}()
+}
+
+// AdminPromote flips a standby to primary: writes open up, the signing
+// key staged for this region takes over token issuance, and the job
+// schedulers start here. The old primary must be fenced off (stopped
+// or made read-only) by the operator first; this endpoint only handles
+// the standby's side of the handoff.
+func AdminPromote(c *gin.Context) {
+	if os.Getenv("STANDBY") != "true" {
+		c.JSON(http.StatusConflict, gin.H{"error": "this instance is not a standby"})
+		return
+	}
+
+	failover.mu.Lock()
+	if failover.promoted {
+		at := failover.promotedAt
+		failover.mu.Unlock()
+		c.JSON(http.StatusConflict, gin.H{"error": "already promoted", "promoted_at": at})
+		return
+	}
+	failover.promoted = true
+	failover.promotedAt = time.Now()
+	at := failover.promotedAt
+	failover.mu.Unlock()
+
+	reloadSigningKeys()
+	startPrimaryBackground()
+	recordAudit(AuditStandbyPromoted, CurrentUserID(c), 0, c.ClientIP(), "")
+	log.Printf("failover: standby promoted to primary by user %d", CurrentUserID(c))
+
+	RenderJSON(c, http.StatusOK, gin.H{"promoted": true, "promoted_at": at})
+}
+
+// AdminFailoverStatus reports this instance's role and, on standbys,
+// how fresh the replicated configuration is.
+func AdminFailoverStatus(c *gin.Context) {
+	failover.mu.Lock()
+	promoted := failover.promoted
+	promotedAt := failover.promotedAt
+	lastSync := failover.lastConfigSync
+	synced := failover.syncedKeys
+	failover.mu.Unlock()
+
+	role := "primary"
+	if readOnlyMode() {
+		role = "replica"
+	}
+	out := gin.H{"role": role, "standby": os.Getenv("STANDBY") == "true"}
+	if os.Getenv("STANDBY") == "true" {
+		if promoted {
+			out["role"] = "primary"
+			out["promoted_at"] = promotedAt
+		} else {
+			out["role"] = "standby"
+		}
+		if !lastSync.IsZero() {
+			out["last_config_sync"] = lastSync
+			out["synced_keys"] = synced
+		}
+	}
+	RenderJSON(c, http.StatusOK, out)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Dynamic filtering that can't be turned into SQL injection. Handlers
+// declare which fields may be filtered and with which operators;
+// column names and operators only ever come from that whitelist, and
+// values are always bound parameters. Clients pass repeatable
+// ?filter=field:op:value parameters.
+
+type FilterKind int
+
+const (
+	FilterString FilterKind = iota
+	FilterInt
+	FilterTime
+)
+
+type FilterField struct {
+	// Column is the SQL column the filter maps to; it is never taken
+	// from the request.
+	Column string
+	Kind   FilterKind
+	Ops    []string
+}
+
+var filterSQLOps = map[string]string{
+	"eq":   "=",
+	"ne":   "<>",
+	"lt":   "<",
+	"lte":  "<=",
+	"gt":   ">",
+	"gte":  ">=",
+	"like": "LIKE",
+}
+
+// ApplyFilters parses ?filter= parameters against the whitelist and
+// returns the query with the matching WHERE clauses applied. Invalid
+// filters produce a field-level 400 and a nil query.
+func ApplyFilters(c *gin.Context, query *gorm.DB, allowed map[string]FilterField) (*gorm.DB, bool) {
+	var errs []FieldError
+	for _, raw := range c.QueryArray("filter") {
+		parts := strings.SplitN(raw, ":", 3)
+		if len(parts) != 3 {
+			errs = append(errs, FieldError{Field: "filter", Message: fmt.Sprintf("%q must be field:op:value", raw)})
+			continue
+		}
+		field, op, value := parts[0], parts[1], parts[2]
+
+		spec, ok := allowed[field]
+		if !ok {
+			errs = append(errs, FieldError{Field: field, Message: "cannot be filtered"})
+			continue
+		}
+		if !opAllowed(spec.Ops, op) {
+			errs = append(errs, FieldError{Field: field, Message: fmt.Sprintf("operator %q not allowed; use one of: %s", op, strings.Join(spec.Ops, ", "))})
+			continue
+		}
+
+		bound, err := coerceFilterValue(spec.Kind, op, value)
+		if err != nil {
+			errs = append(errs, FieldError{Field: field, Message: err.Error()})
+			continue
+		}
+		query = query.Where(fmt.Sprintf("%s %s ?", spec.Column, filterSQLOps[op]), bound)
+	}
+
+	if len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "filter validation failed", "fields": errs})
+		return nil, false
+	}
+	return query, true
+}
+
+func opAllowed(ops []string, op string) bool {
+	for _, o := range ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+func coerceFilterValue(kind FilterKind, op, value string) (interface{}, error) {
+	switch kind {
+	case FilterInt:
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", value)
+		}
+		return v, nil
+	case FilterTime:
+		for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("%q is not an RFC 3339 timestamp or YYYY-MM-DD date", value)
+	default:
+		if op == "like" {
+			return "%" + value + "%", nil
+		}
+		return value, nil
+	}
+}
+
+var gameEventFilters = map[string]FilterField{
+	"player_id":  {Column: "player_id", Kind: FilterInt, Ops: []string{"eq"}},
+	"type":       {Column: "type", Kind: FilterString, Ops: []string{"eq", "ne"}},
+	"amount":     {Column: "amount", Kind: FilterInt, Ops: []string{"eq", "lt", "lte", "gt", "gte"}},
+	"created_at": {Column: "created_at", Kind: FilterTime, Ops: []string{"lt", "lte", "gt", "gte"}},
+}
+
+// AdminListGameEvents lists game events with whitelisted filtering.
+func AdminListGameEvents(c *gin.Context) {
+	q := ValidateQuery(c)
+	limit := q.Int("limit", 100, 1, 1000)
+	if !q.Valid() {
+		return
+	}
+
+	query, ok := ApplyFilters(c, RequestDB(c).Model(&models.GameEvent{}), gameEventFilters)
+	if !ok {
+		return
+	}
+
+	var events []models.GameEvent
+	if err := query.Order("created_at DESC").Limit(limit).Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, events)
+}
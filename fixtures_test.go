@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+)
+
+// Declarative fixtures. A YAML file under testdata/fixtures describes
+// users, roles, and game states by symbolic key; loadFixtures writes
+// them straight to the test database and hands back a set that
+// resolves keys to the created rows and mints login tokens on demand.
+// Emails are suffixed per run (the test database accumulates rows and
+// live emails are unique), so fixture files stay static while runs
+// stay independent.
+
+type fixtureFile struct {
+	Roles []struct {
+		Name        string   `yaml:"name"`
+		Permissions []string `yaml:"permissions"`
+	} `yaml:"roles"`
+	Users []struct {
+		Key      string `yaml:"key"`
+		Name     string `yaml:"name"`
+		Email    string `yaml:"email"`
+		Password string `yaml:"password"`
+		Role     string `yaml:"role"`
+	} `yaml:"users"`
+	Players []struct {
+		User     string `yaml:"user"`
+		Gold     int64  `yaml:"gold"`
+		GoldRate int64  `yaml:"gold_rate"`
+	} `yaml:"players"`
+}
+
+type fixtureSet struct {
+	users     map[string]models.User
+	passwords map[string]string
+	tokens    map[string]string
+}
+
+// loadFixtures applies one fixture file to the test database.
+func loadFixtures(t *testing.T, path string) *fixtureSet {
+	t.Helper()
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("fixtures: %v", err)
+	}
+	var file fixtureFile
+	if err := yaml.Unmarshal(raw, &file); err != nil {
+		t.Fatalf("fixtures %s: %v", path, err)
+	}
+
+	suffix := fmt.Sprintf("+%d", time.Now().UnixNano())
+	set := &fixtureSet{
+		users:     map[string]models.User{},
+		passwords: map[string]string{},
+		tokens:    map[string]string{},
+	}
+
+	for _, r := range file.Roles {
+		var role models.Role
+		if err := DB.Where("name = ? AND organization_id IS NULL", r.Name).First(&role).Error; err != nil {
+			role = models.Role{Name: r.Name}
+			if err := DB.Create(&role).Error; err != nil {
+				t.Fatalf("fixtures: role %s: %v", r.Name, err)
+			}
+		}
+		for _, name := range r.Permissions {
+			var perm models.Permission
+			if err := DB.Where("name = ?", name).First(&perm).Error; err != nil {
+				perm = models.Permission{Name: name}
+				DB.Create(&perm)
+			}
+			DB.Model(&role).Association("Permissions").Append(&perm)
+		}
+	}
+
+	for _, u := range file.Users {
+		hash, err := hashPassword(u.Password)
+		if err != nil {
+			t.Fatalf("fixtures: hash: %v", err)
+		}
+		user := models.User{Name: u.Name, Email: fixtureEmail(u.Email, suffix), Password: hash}
+		if u.Role != "" {
+			var role models.Role
+			if err := DB.Where("name = ? AND organization_id IS NULL", u.Role).First(&role).Error; err != nil {
+				t.Fatalf("fixtures: user %s references unknown role %q", u.Key, u.Role)
+			}
+			user.RoleID = &role.ID
+		}
+		if err := DB.Create(&user).Error; err != nil {
+			t.Fatalf("fixtures: user %s: %v", u.Key, err)
+		}
+		set.users[u.Key] = user
+		set.passwords[u.Key] = u.Password
+	}
+
+	for _, p := range file.Players {
+		user, ok := set.users[p.User]
+		if !ok {
+			t.Fatalf("fixtures: player references unknown user %q", p.User)
+		}
+		player := models.Player{UserID: user.ID, Gold: p.Gold, GoldRate: p.GoldRate, LastTickAt: time.Now()}
+		if err := DB.Create(&player).Error; err != nil {
+			t.Fatalf("fixtures: player for %s: %v", p.User, err)
+		}
+	}
+
+	return set
+}
+
+// fixtureEmail makes a fixture email unique per run while keeping the
+// local part recognisable.
+func fixtureEmail(email, suffix string) string {
+	for i := 0; i < len(email); i++ {
+		if email[i] == '@' {
+			return email[:i] + suffix + email[i:]
+		}
+	}
+	return email + suffix
+}
+
+// user returns the created row for a fixture key.
+func (s *fixtureSet) user(t *testing.T, key string) models.User {
+	t.Helper()
+	user, ok := s.users[key]
+	if !ok {
+		t.Fatalf("fixtures: no user %q", key)
+	}
+	return user
+}
+
+// token logs the fixture user in through the real endpoint and caches
+// the bearer token.
+func (s *fixtureSet) token(t *testing.T, router *gin.Engine, key string) string {
+	t.Helper()
+	if tok, ok := s.tokens[key]; ok {
+		return tok
+	}
+	user := s.user(t, key)
+	rec := doJSON(t, router, http.MethodPost, "/login", "", gin.H{
+		"email": user.Email, "password": s.passwords[key],
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("fixtures: login as %s: got %d: %s", key, rec.Code, rec.Body.String())
+	}
+	tok, _ := decodeBody(t, rec)["token"].(string)
+	if tok == "" {
+		t.Fatalf("fixtures: login as %s returned no token", key)
+	}
+	s.tokens[key] = tok
+	return tok
+}
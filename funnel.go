@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bernardmuller/go-idle/api"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+// Onboarding funnel. The registration → first-login → first-action
+// path is instrumented off the internal event bus: each funnel step
+// names the event type that completes it, and the first matching event
+// per user is recorded (later ones hit the unique index and vanish).
+// Step definitions live in the database and are edited through
+// /admin/funnel, so product can re-cut the funnel without a deploy;
+// the report endpoint buckets users into signup cohorts and shows how
+// far each cohort got.
+
+// funnelDefaultSteps seeds a fresh database; after that the stored
+// definition is authoritative.
+var funnelDefaultSteps = []models.FunnelStep{
+	{Position: 1, Name: "registered", EventType: "user.registered"},
+	{Position: 2, Name: "first_login", EventType: "login.succeeded"},
+	{Position: 3, Name: "first_action", EventType: "game.tick"},
+}
+
+// funnelStepCache avoids a steps query per event.
+var funnelStepCache = struct {
+	sync.Mutex
+	steps   []models.FunnelStep
+	loaded  time.Time
+	maxWait time.Duration
+}{maxWait: time.Minute}
+
+func funnelSteps() []models.FunnelStep {
+	funnelStepCache.Lock()
+	defer funnelStepCache.Unlock()
+	if time.Since(funnelStepCache.loaded) < funnelStepCache.maxWait && funnelStepCache.steps != nil {
+		return funnelStepCache.steps
+	}
+	var steps []models.FunnelStep
+	if err := DB.Order("position").Find(&steps).Error; err != nil {
+		return funnelStepCache.steps
+	}
+	funnelStepCache.steps = steps
+	funnelStepCache.loaded = time.Now()
+	return steps
+}
+
+func invalidateFunnelSteps() {
+	funnelStepCache.Lock()
+	funnelStepCache.steps = nil
+	funnelStepCache.Unlock()
+}
+
+// SeedFunnelSteps installs the default definition on first boot.
+func SeedFunnelSteps() {
+	var count int64
+	DB.Model(&models.FunnelStep{}).Count(&count)
+	if count > 0 {
+		return
+	}
+	for _, step := range funnelDefaultSteps {
+		DB.Create(&step)
+	}
+}
+
+// funnelSeen remembers user+step pairs already written so hot paths
+// (the tick callback fires on every game action) skip the insert after
+// the first one; the unique index stays the real guarantee.
+var funnelSeen sync.Map
+
+// funnelTouch records that a user completed whichever steps the event
+// type maps to; repeats are absorbed by the unique index.
+func funnelTouch(userID uint, eventType string) {
+	if userID == 0 {
+		return
+	}
+	for _, step := range funnelSteps() {
+		if step.EventType != eventType {
+			continue
+		}
+		key := fmt.Sprintf("%d:%s", userID, step.Name)
+		if _, done := funnelSeen.Load(key); done {
+			continue
+		}
+		err := DB.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.FunnelEvent{UserID: userID, Step: step.Name, CreatedAt: time.Now()}).Error
+		if err == nil {
+			funnelSeen.Store(key, struct{}{})
+		}
+	}
+}
+
+// registerFunnelSubscriber feeds the funnel from the event bus; game
+// ticks don't ride the bus (they'd spam the webhook dispatcher) and
+// reach funnelTouch directly from the tick callback.
+func registerFunnelSubscriber() {
+	SubscribeEvents("*", func(eventType string, data map[string]interface{}) {
+		userID, _ := data["user_id"].(uint)
+		funnelTouch(userID, eventType)
+	})
+}
+
+// AdminGetFunnel returns the current step definition.
+func AdminGetFunnel(c *gin.Context) {
+	RenderJSON(c, http.StatusOK, funnelSteps())
+}
+
+type funnelStepInput struct {
+	Name      string `json:"name" binding:"required,min=2,max=64"`
+	EventType string `json:"event_type" binding:"required"`
+}
+
+// AdminPutFunnel replaces the step definition wholesale; recorded
+// funnel events keep their step names, so renaming a step orphans its
+// history.
+func AdminPutFunnel(c *gin.Context) {
+	var input []funnelStepInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(input) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at least one step required"})
+		return
+	}
+
+	db := RequestDB(c)
+	if err := db.Where("1 = 1").Delete(&models.FunnelStep{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for i, step := range input {
+		db.Create(&models.FunnelStep{Position: i + 1, Name: step.Name, EventType: step.EventType})
+	}
+	invalidateFunnelSteps()
+	RenderJSON(c, http.StatusOK, funnelSteps())
+}
+
+type funnelCohortRow struct {
+	Cohort string
+	Step   string
+	Users  int64
+}
+
+// AdminFunnelReport shows, per signup cohort, how many users reached
+// each step and the conversion from the first step.
+func AdminFunnelReport(c *gin.Context) {
+	q := api.ValidateQuery(c)
+	from := q.Date("from")
+	to := q.Date("to")
+	bucket := q.Enum("cohort", "week", "day", "week", "month")
+	if !q.Valid() {
+		return
+	}
+	if from == nil || to == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to are required"})
+		return
+	}
+
+	var rows []funnelCohortRow
+	err := ReadRequestDB(c).Raw(`SELECT to_char(date_trunc(?, u.created_at), 'YYYY-MM-DD') AS cohort,
+			f.step AS step, COUNT(DISTINCT f.user_id) AS users
+		FROM funnel_events f JOIN users u ON u.id = f.user_id
+		WHERE u.created_at >= ? AND u.created_at < ?
+		GROUP BY 1, 2 ORDER BY 1`, bucket, from, to.AddDate(0, 0, 1)).Scan(&rows).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	steps := funnelSteps()
+	cohorts := map[string]map[string]int64{}
+	var order []string
+	for _, row := range rows {
+		if cohorts[row.Cohort] == nil {
+			cohorts[row.Cohort] = map[string]int64{}
+			order = append(order, row.Cohort)
+		}
+		cohorts[row.Cohort][row.Step] = row.Users
+	}
+
+	out := make([]gin.H, 0, len(order))
+	for _, cohort := range order {
+		counts := cohorts[cohort]
+		var base int64
+		if len(steps) > 0 {
+			base = counts[steps[0].Name]
+		}
+		stepOut := make([]gin.H, 0, len(steps))
+		for _, step := range steps {
+			entry := gin.H{"step": step.Name, "users": counts[step.Name]}
+			if base > 0 {
+				entry["conversion_pct"] = counts[step.Name] * 100 / base
+			}
+			stepOut = append(stepOut, entry)
+		}
+		out = append(out, gin.H{"cohort": cohort, "steps": stepOut})
+	}
+	RenderJSON(c, http.StatusOK, gin.H{"cohort_bucket": bucket, "cohorts": out})
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Game config document. Tunables (rates, multipliers, event toggles)
+// live in one versioned JSON document; admins edit it with RFC 6902
+// patches so a toggle flip doesn't mean resubmitting the whole thing.
+
+const defaultGameConfig = `{
+  "base_gold_rate": 1,
+  "prestige_multiplier": 1.5,
+  "offline_earning_cap_hours": 12,
+  "events": {},
+  "economy_caps": {
+    "gift": {"per_day": 20, "per_week": 100},
+    "conversion": {"per_day": 5, "per_week": 25},
+    "challenge_entry": {"per_day": 10, "per_week": 50}
+  }
+}`
+
+// loadGameConfig fetches the document, seeding the default on first
+// use.
+func loadGameConfig() (*models.GameConfig, error) {
+	var cfg models.GameConfig
+	err := DB.First(&cfg).Error
+	if err == nil {
+		return &cfg, nil
+	}
+	cfg = models.GameConfig{Document: defaultGameConfig, Version: 1}
+	if err := DB.Create(&cfg).Error; err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func AdminGetGameConfig(c *gin.Context) {
+	cfg, err := loadGameConfig()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var doc interface{}
+	json.Unmarshal([]byte(cfg.Document), &doc)
+	RenderJSON(c, http.StatusOK, gin.H{"version": cfg.Version, "document": doc})
+}
+
+// AdminPatchGameConfig applies an RFC 6902 patch to the document.
+// Failed patches (including failed test ops) leave it untouched.
+func AdminPatchGameConfig(c *gin.Context) {
+	cfg, err := loadGameConfig()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var ops []patchOp
+	if err := c.ShouldBindJSON(&ops); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal([]byte(cfg.Document), &doc); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "stored config is corrupt: " + err.Error()})
+		return
+	}
+
+	patched, err := applyJSONPatch(doc, ops)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	raw, err := json.Marshal(patched)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	cfg.Document = string(raw)
+	cfg.Version++
+	if err := RequestDB(c).Save(cfg).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, gin.H{"version": cfg.Version, "document": patched})
+}
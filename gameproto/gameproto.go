@@ -0,0 +1,167 @@
+// Package gameproto encodes the bandwidth-sensitive game endpoints as
+// protobuf. The schema lives in game.proto and is shared with the
+// mobile client; this file is hand-maintained against it with the
+// low-level protowire package instead of checked-in generated code, so
+// the dependency footprint stays at the wire helpers. Field numbers
+// here must match game.proto exactly and never be reused.
+package gameproto
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+)
+
+// ContentType is the media type the client sends in Accept (for
+// downloads) or Content-Type (for telemetry uploads).
+const ContentType = "application/x-protobuf"
+
+// Accepts reports whether an Accept header asks for protobuf.
+func Accepts(accept string) bool {
+	return strings.Contains(accept, ContentType)
+}
+
+// SyncMessage is one delivered broadcast inside a Sync.
+type SyncMessage struct {
+	ID        uint
+	Title     string
+	Body      string
+	CreatedAt time.Time
+}
+
+// Sync mirrors the delta-sync response; nil sections are omitted from
+// the wire, matching the JSON encoding's omitempty semantics.
+type Sync struct {
+	ServerTime    time.Time
+	Player        *models.Player
+	Messages      []SyncMessage
+	ConfigVersion *int
+}
+
+// TelemetryEvent is one decoded client event from a TelemetryBatch.
+type TelemetryEvent struct {
+	Name    string
+	Payload string
+}
+
+// MarshalPlayer encodes a Player message.
+func MarshalPlayer(p *models.Player) []byte {
+	return appendPlayer(nil, p)
+}
+
+func appendPlayer(buf []byte, p *models.Player) []byte {
+	buf = protowire.AppendTag(buf, 1, protowire.VarintType)
+	buf = protowire.AppendVarint(buf, uint64(p.ID))
+	buf = protowire.AppendTag(buf, 2, protowire.VarintType)
+	buf = protowire.AppendVarint(buf, uint64(p.UserID))
+	buf = protowire.AppendTag(buf, 3, protowire.VarintType)
+	buf = protowire.AppendVarint(buf, uint64(p.Gold))
+	buf = protowire.AppendTag(buf, 4, protowire.VarintType)
+	buf = protowire.AppendVarint(buf, uint64(p.GoldRate))
+	buf = protowire.AppendTag(buf, 5, protowire.VarintType)
+	buf = protowire.AppendVarint(buf, uint64(p.LastTickAt.UnixMilli()))
+	buf = protowire.AppendTag(buf, 6, protowire.VarintType)
+	buf = protowire.AppendVarint(buf, uint64(p.UpdatedAt.UnixMilli()))
+	return buf
+}
+
+// MarshalSync encodes a Sync message.
+func MarshalSync(s *Sync) []byte {
+	buf := protowire.AppendTag(nil, 1, protowire.VarintType)
+	buf = protowire.AppendVarint(buf, uint64(s.ServerTime.UnixMilli()))
+	if s.Player != nil {
+		buf = protowire.AppendTag(buf, 2, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, appendPlayer(nil, s.Player))
+	}
+	for i := range s.Messages {
+		buf = protowire.AppendTag(buf, 3, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, appendSyncMessage(nil, &s.Messages[i]))
+	}
+	if s.ConfigVersion != nil {
+		buf = protowire.AppendTag(buf, 4, protowire.VarintType)
+		buf = protowire.AppendVarint(buf, uint64(*s.ConfigVersion))
+	}
+	return buf
+}
+
+func appendSyncMessage(buf []byte, m *SyncMessage) []byte {
+	buf = protowire.AppendTag(buf, 1, protowire.VarintType)
+	buf = protowire.AppendVarint(buf, uint64(m.ID))
+	buf = protowire.AppendTag(buf, 2, protowire.BytesType)
+	buf = protowire.AppendString(buf, m.Title)
+	buf = protowire.AppendTag(buf, 3, protowire.BytesType)
+	buf = protowire.AppendString(buf, m.Body)
+	buf = protowire.AppendTag(buf, 4, protowire.VarintType)
+	buf = protowire.AppendVarint(buf, uint64(m.CreatedAt.UnixMilli()))
+	return buf
+}
+
+// UnmarshalTelemetry decodes a TelemetryBatch body. Unknown fields are
+// skipped so an older server tolerates a newer client.
+func UnmarshalTelemetry(data []byte) ([]TelemetryEvent, error) {
+	var events []TelemetryEvent
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+		if num == 1 && typ == protowire.BytesType {
+			raw, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+			event, err := unmarshalTelemetryEvent(raw)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, event)
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, data)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+	}
+	return events, nil
+}
+
+func unmarshalTelemetryEvent(data []byte) (TelemetryEvent, error) {
+	var event TelemetryEvent
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return event, protowire.ParseError(n)
+		}
+		data = data[n:]
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			value, n := protowire.ConsumeString(data)
+			if n < 0 {
+				return event, protowire.ParseError(n)
+			}
+			event.Name = value
+			data = data[n:]
+		case num == 2 && typ == protowire.BytesType:
+			value, n := protowire.ConsumeString(data)
+			if n < 0 {
+				return event, protowire.ParseError(n)
+			}
+			event.Payload = value
+			data = data[n:]
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return event, fmt.Errorf("telemetry event field %d: %w", num, protowire.ParseError(n))
+			}
+			data = data[n:]
+		}
+	}
+	return event, nil
+}
@@ -0,0 +1,107 @@
+package gameproto
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+	"gorm.io/gorm"
+)
+
+// decodeVarintFields walks a message and collects every varint field by
+// number, which is enough to assert the numeric schema without pulling
+// in the full runtime.
+func decodeVarintFields(t *testing.T, data []byte) map[protowire.Number]uint64 {
+	t.Helper()
+	fields := map[protowire.Number]uint64{}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			t.Fatalf("bad tag: %v", protowire.ParseError(n))
+		}
+		data = data[n:]
+		if typ == protowire.VarintType {
+			value, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				t.Fatalf("bad varint: %v", protowire.ParseError(n))
+			}
+			fields[num] = value
+			data = data[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, data)
+		if n < 0 {
+			t.Fatalf("bad field %d: %v", num, protowire.ParseError(n))
+		}
+		data = data[n:]
+	}
+	return fields
+}
+
+func TestMarshalPlayerFieldNumbers(t *testing.T) {
+	tick := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	player := &models.Player{
+		Model:      gorm.Model{ID: 7, UpdatedAt: tick},
+		UserID:     9,
+		Gold:       1234,
+		GoldRate:   5,
+		LastTickAt: tick,
+	}
+
+	fields := decodeVarintFields(t, MarshalPlayer(player))
+	if fields[1] != 7 || fields[2] != 9 || fields[3] != 1234 || fields[4] != 5 {
+		t.Fatalf("unexpected field values: %v", fields)
+	}
+	if fields[5] != uint64(tick.UnixMilli()) {
+		t.Fatalf("last_tick_at_ms = %d, want %d", fields[5], tick.UnixMilli())
+	}
+}
+
+func TestMarshalSyncOmitsEmptySections(t *testing.T) {
+	data := MarshalSync(&Sync{ServerTime: time.Now()})
+	fields := decodeVarintFields(t, data)
+	if _, ok := fields[1]; !ok {
+		t.Fatal("server_time_ms missing")
+	}
+	if len(fields) != 1 {
+		t.Fatalf("empty sync should carry only server_time_ms, got %v", fields)
+	}
+
+	version := 3
+	data = MarshalSync(&Sync{
+		ServerTime:    time.Now(),
+		Player:        &models.Player{UserID: 1},
+		Messages:      []SyncMessage{{ID: 2, Title: "t", Body: "b", CreatedAt: time.Now()}},
+		ConfigVersion: &version,
+	})
+	if fields := decodeVarintFields(t, data); fields[4] != 3 {
+		t.Fatalf("config_version = %d, want 3", fields[4])
+	}
+}
+
+func TestTelemetryRoundTrip(t *testing.T) {
+	event := protowire.AppendTag(nil, 1, protowire.BytesType)
+	event = protowire.AppendString(event, "fps_drop")
+	event = protowire.AppendTag(event, 2, protowire.BytesType)
+	event = protowire.AppendString(event, `{"fps":12}`)
+	// An unknown field a newer client might send must be skipped.
+	event = protowire.AppendTag(event, 9, protowire.VarintType)
+	event = protowire.AppendVarint(event, 42)
+
+	batch := protowire.AppendTag(nil, 1, protowire.BytesType)
+	batch = protowire.AppendBytes(batch, event)
+
+	events, err := UnmarshalTelemetry(batch)
+	if err != nil {
+		t.Fatalf("UnmarshalTelemetry: %v", err)
+	}
+	if len(events) != 1 || events[0].Name != "fps_drop" || events[0].Payload != `{"fps":12}` {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+
+	if _, err := UnmarshalTelemetry([]byte{0xff}); err == nil {
+		t.Fatal("truncated batch should fail")
+	}
+}
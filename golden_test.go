@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Golden-file API tests. Each case hits one endpoint with fixture
+// data and compares the normalised response against a snapshot under
+// testdata/golden; a behaviour change shows up as a readable diff in
+// the golden file rather than a distant assertion failure. Volatile
+// fields (ids, timestamps, tokens, per-run emails) are replaced with
+// placeholders before comparison so the snapshots stay stable across
+// runs. Regenerate after an intentional change with:
+//
+//	go test -run TestGolden -update
+var updateGolden = flag.Bool("update", false, "rewrite golden files with current responses")
+
+// volatileFields are scrubbed wherever they appear, at any depth. The
+// capitalized names are gorm.Model's embedded fields, which carry no
+// json tags and so marshal under their Go names.
+var volatileFields = map[string]bool{
+	"id": true, "user_id": true, "role_id": true, "player_id": true,
+	"created_at": true, "updated_at": true, "deleted_at": true,
+	"last_tick_at": true, "last_active_at": true, "last_seen_at": true,
+	"exported_at": true, "email": true, "token": true, "expires_at": true,
+	"ip": true, "ID": true, "CreatedAt": true, "UpdatedAt": true, "DeletedAt": true,
+}
+
+// normalise walks decoded JSON and blanks volatile values, keeping
+// their type visible so a field changing type still fails.
+func normalise(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			if volatileFields[k] && item != nil {
+				switch item.(type) {
+				case float64:
+					out[k] = "<number>"
+				default:
+					out[k] = "<string>"
+				}
+				continue
+			}
+			out[k] = normalise(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = normalise(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// checkGolden compares one response body against its snapshot.
+func checkGolden(t *testing.T, name string, body []byte) {
+	t.Helper()
+	var decoded interface{}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatalf("%s: response is not JSON: %v: %s", name, err, body)
+	}
+	got, err := json.MarshalIndent(normalise(decoded), "", "  ")
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	got = append(got, '\n')
+
+	path := filepath.Join("testdata", "golden", name+".json")
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%s: no golden file (run with -update to create it): %v", name, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s: response drifted from golden file %s\ngot:\n%s\nwant:\n%s", name, path, got, want)
+	}
+}
+
+// TestGoldenEndpoints snapshots the read surface a client sees right
+// after onboarding.
+func TestGoldenEndpoints(t *testing.T) {
+	router := requireRouter(t)
+	fixtures := loadFixtures(t, filepath.Join("testdata", "fixtures", "base.yaml"))
+	token := fixtures.token(t, router, "alice")
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"me", http.MethodGet, "/me"},
+		{"game_state", http.MethodGet, "/game/state"},
+		{"game_allowances", http.MethodGet, "/game/allowances"},
+		{"me_mailbox", http.MethodGet, "/me/mailbox"},
+		{"me_sessions", http.MethodGet, "/me/sessions"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := doJSON(t, router, tc.method, tc.path, token, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("%s %s: got %d: %s", tc.method, tc.path, rec.Code, rec.Body.String())
+			}
+			checkGolden(t, tc.name, rec.Body.Bytes())
+		})
+	}
+}
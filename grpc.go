@@ -0,0 +1,350 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	"github.com/bernardmuller/go-idle/internal/handlers"
+	"github.com/bernardmuller/go-idle/internal/repository"
+	"github.com/bernardmuller/go-idle/internal/services"
+)
+
+// gRPC interface for internal services (proto/goidle.proto). With
+// GRPC_PORT set, a second listener serves goidle.v1.GoIdle —
+// ValidateToken, GetUser, ListUsers — backed by the same service layer
+// and token rules as the HTTP handlers. Like the other integrations,
+// this speaks the wire protocol directly: gRPC is length-prefixed
+// protobuf frames over HTTP/2 with the status in trailers, and the
+// three messages involved are simple enough to encode by hand, so no
+// grpc or protoc toolchain enters the build. The cleartext HTTP/2
+// (h2c) handler comes from x/net, which the module already carries.
+
+// gRPC status codes used here.
+const (
+	grpcOK              = 0
+	grpcInvalidArgument = 3
+	grpcNotFound        = 5
+	grpcInternal        = 13
+	grpcUnauthenticated = 16
+)
+
+type grpcError struct {
+	code    int
+	message string
+}
+
+func (e *grpcError) Error() string { return e.message }
+
+func grpcErrorf(code int, format string, args ...interface{}) error {
+	return &grpcError{code: code, message: fmt.Sprintf(format, args...)}
+}
+
+// --- protobuf wire helpers -------------------------------------------
+
+// Wire types: 0 varint, 2 length-delimited.
+func pbKey(field, wire int) byte { return byte(field<<3 | wire) }
+
+func pbAppendVarint(buf []byte, v uint64) []byte {
+	for v >= 0x80 {
+		buf = append(buf, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(buf, byte(v))
+}
+
+func pbAppendUint(buf []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return buf
+	}
+	return pbAppendVarint(append(buf, pbKey(field, 0)), v)
+}
+
+func pbAppendBool(buf []byte, field int, v bool) []byte {
+	if !v {
+		return buf
+	}
+	return append(buf, pbKey(field, 0), 1)
+}
+
+func pbAppendString(buf []byte, field int, s string) []byte {
+	if s == "" {
+		return buf
+	}
+	buf = pbAppendVarint(append(buf, pbKey(field, 2)), uint64(len(s)))
+	return append(buf, s...)
+}
+
+func pbAppendMessage(buf []byte, field int, msg []byte) []byte {
+	buf = pbAppendVarint(append(buf, pbKey(field, 2)), uint64(len(msg)))
+	return append(buf, msg...)
+}
+
+// pbFields decodes a message into its fields, keeping the last value
+// for scalars as proto3 requires. Unknown and non-scalar wire types
+// are skipped.
+func pbFields(data []byte) (map[int]uint64, map[int][]byte, error) {
+	varints := map[int]uint64{}
+	blobs := map[int][]byte{}
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, nil, fmt.Errorf("malformed field key")
+		}
+		data = data[n:]
+		field, wire := int(key>>3), int(key&0x7)
+		switch wire {
+		case 0:
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				return nil, nil, fmt.Errorf("malformed varint")
+			}
+			varints[field] = v
+			data = data[n:]
+		case 2:
+			length, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < length {
+				return nil, nil, fmt.Errorf("malformed length-delimited field")
+			}
+			blobs[field] = data[n : n+int(length)]
+			data = data[n+int(length):]
+		case 5:
+			if len(data) < 4 {
+				return nil, nil, fmt.Errorf("truncated fixed32")
+			}
+			data = data[4:]
+		case 1:
+			if len(data) < 8 {
+				return nil, nil, fmt.Errorf("truncated fixed64")
+			}
+			data = data[8:]
+		default:
+			return nil, nil, fmt.Errorf("unsupported wire type %d", wire)
+		}
+	}
+	return varints, blobs, nil
+}
+
+// --- service ---------------------------------------------------------
+
+// grpcUserService is wired at server start; it reuses the HTTP side's
+// repository and cache path.
+var grpcUserService *services.UserService
+
+func encodeGRPCUser(user *handlers.UserResponse) []byte {
+	var buf []byte
+	buf = pbAppendUint(buf, 1, uint64(user.ID))
+	buf = pbAppendString(buf, 2, user.Name)
+	buf = pbAppendString(buf, 3, user.Email)
+	buf = pbAppendString(buf, 4, user.Role)
+	buf = pbAppendString(buf, 5, user.CreatedAt.Format(time.RFC3339))
+	return buf
+}
+
+func grpcValidateToken(_ context.Context, _ uint, req []byte) ([]byte, error) {
+	_, blobs, err := pbFields(req)
+	if err != nil {
+		return nil, grpcErrorf(grpcInvalidArgument, "malformed request: %v", err)
+	}
+	userID, tokenID, _, err := parseAccessToken(string(blobs[1]))
+
+	var buf []byte
+	if err == nil {
+		buf = pbAppendBool(buf, 1, true)
+		buf = pbAppendUint(buf, 2, uint64(userID))
+		buf = pbAppendString(buf, 3, tokenID)
+	}
+	// An invalid token is a valid=false response, not an RPC error.
+	return buf, nil
+}
+
+func grpcGetUser(ctx context.Context, _ uint, req []byte) ([]byte, error) {
+	varints, _, err := pbFields(req)
+	if err != nil {
+		return nil, grpcErrorf(grpcInvalidArgument, "malformed request: %v", err)
+	}
+	id := varints[1]
+	if id == 0 {
+		return nil, grpcErrorf(grpcInvalidArgument, "id is required")
+	}
+
+	user, err := grpcUserService.Get(ctx, uint(id))
+	if err != nil {
+		return nil, grpcErrorf(grpcNotFound, "user not found")
+	}
+	resp := handlers.NewUserResponse(user)
+	return encodeGRPCUser(&resp), nil
+}
+
+func grpcListUsers(ctx context.Context, _ uint, req []byte) ([]byte, error) {
+	varints, _, err := pbFields(req)
+	if err != nil {
+		return nil, grpcErrorf(grpcInvalidArgument, "malformed request: %v", err)
+	}
+	page := int(varints[1])
+	if page < 1 {
+		page = 1
+	}
+	perPage := int(varints[2])
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	users, total, err := grpcUserService.List(ctx, repository.UserListOptions{
+		Offset: (page - 1) * perPage,
+		Limit:  perPage,
+	})
+	if err != nil {
+		return nil, grpcErrorf(grpcInternal, "listing users failed")
+	}
+
+	var buf []byte
+	for i := range users {
+		resp := handlers.NewUserResponse(users[i])
+		buf = pbAppendMessage(buf, 1, encodeGRPCUser(&resp))
+	}
+	buf = pbAppendUint(buf, 2, uint64(total))
+	return buf, nil
+}
+
+// grpcMethod is one RPC: its handler and whether it needs a caller
+// token, mirroring which HTTP routes sit behind Authenticate.
+type grpcMethod struct {
+	handler func(ctx context.Context, callerID uint, req []byte) ([]byte, error)
+	auth    bool
+}
+
+var grpcMethods = map[string]grpcMethod{
+	"/goidle.v1.GoIdle/ValidateToken": {handler: grpcValidateToken},
+	"/goidle.v1.GoIdle/GetUser":       {handler: grpcGetUser, auth: true},
+	"/goidle.v1.GoIdle/ListUsers":     {handler: grpcListUsers, auth: true},
+}
+
+// --- server ----------------------------------------------------------
+
+// writeGRPCStatus sets the trailers; gRPC carries its status there,
+// after the body.
+func writeGRPCStatus(w http.ResponseWriter, code int, message string) {
+	w.Header().Set(http.TrailerPrefix+"Grpc-Status", fmt.Sprintf("%d", code))
+	if message != "" {
+		w.Header().Set(http.TrailerPrefix+"Grpc-Message", message)
+	}
+}
+
+// handleGRPC serves one unary RPC.
+func handleGRPC(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	w.Header().Set("Content-Type", "application/grpc")
+
+	code, message := grpcOK, ""
+	var callerID uint
+	defer func() {
+		// The logging interceptor: one structured line per RPC, same
+		// shape as the HTTP access log.
+		attrs := []any{
+			"transport", "grpc",
+			"method", r.URL.Path,
+			"grpc_status", code,
+			"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+			"ip", r.RemoteAddr,
+		}
+		if callerID != 0 {
+			attrs = append(attrs, "user_id", callerID)
+		}
+		level := slog.LevelInfo
+		if code == grpcInternal {
+			level = slog.LevelError
+		}
+		requestLogger.Log(r.Context(), level, "request", attrs...)
+	}()
+
+	method, ok := grpcMethods[r.URL.Path]
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") || !ok {
+		code, message = grpcNotFound, "unknown method"
+		writeGRPCStatus(w, code, message)
+		return
+	}
+
+	// The auth interceptor mirrors the Authenticate middleware.
+	if method.auth {
+		token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		id, _, _, err := parseAccessToken(token)
+		if err != nil {
+			code, message = grpcUnauthenticated, "invalid token"
+			writeGRPCStatus(w, code, message)
+			return
+		}
+		callerID = id
+	}
+
+	// One request frame: compression flag + 4-byte length + message.
+	var prefix [5]byte
+	if _, err := io.ReadFull(r.Body, prefix[:]); err != nil {
+		code, message = grpcInvalidArgument, "missing request frame"
+		writeGRPCStatus(w, code, message)
+		return
+	}
+	if prefix[0] != 0 {
+		code, message = grpcInvalidArgument, "compressed requests are not supported"
+		writeGRPCStatus(w, code, message)
+		return
+	}
+	req := make([]byte, binary.BigEndian.Uint32(prefix[1:]))
+	if _, err := io.ReadFull(r.Body, req); err != nil {
+		code, message = grpcInvalidArgument, "truncated request frame"
+		writeGRPCStatus(w, code, message)
+		return
+	}
+
+	resp, err := method.handler(r.Context(), callerID, req)
+	if err != nil {
+		if ge, ok := err.(*grpcError); ok {
+			code, message = ge.code, ge.message
+		} else {
+			code, message = grpcInternal, "internal error"
+		}
+		writeGRPCStatus(w, code, message)
+		return
+	}
+
+	var out [5]byte
+	binary.BigEndian.PutUint32(out[1:], uint32(len(resp)))
+	w.Write(out[:])
+	w.Write(resp)
+	writeGRPCStatus(w, grpcOK, "")
+}
+
+// StartGRPCServer serves the gRPC interface on its own port; off
+// unless GRPC_PORT is set.
+func StartGRPCServer() {
+	port := os.Getenv("GRPC_PORT")
+	if port == "" {
+		return
+	}
+
+	grpcUserService = &services.UserService{
+		Users:      &repository.UserRepository{DB: DB},
+		LoadCached: loadUserCached,
+	}
+
+	srv := &http.Server{
+		Addr:    ":" + port,
+		Handler: h2c.NewHandler(http.HandlerFunc(handleGRPC), &http2.Server{}),
+	}
+	go func() {
+		log.Printf("grpc: listening on :%s", port)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("grpc: server stopped: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Kubernetes probes. /healthz is liveness: static and allocation-free,
+// it only proves the process is serving. /readyz is readiness: it
+// pings Postgres with a short deadline and reports each dependency, so
+// the load balancer stops routing here the moment the database drops.
+
+const readinessTimeout = 2 * time.Second
+
+func Healthz(c *gin.Context) {
+	c.String(http.StatusOK, "ok")
+}
+
+func Readyz(c *gin.Context) {
+	// A drain window fails readiness on purpose; the instance is
+	// healthy but wants the LB to route new traffic elsewhere.
+	if draining() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"ready": false, "draining": true})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+	defer cancel()
+
+	dbOK := false
+	if sqlDB, err := RequestDB(c).DB(); err == nil {
+		dbOK = sqlDB.PingContext(ctx) == nil
+	}
+	deps := gin.H{
+		"database": dbOK,
+		"search":   searchHealthy(),
+	}
+
+	status := http.StatusOK
+	ready := true
+	for _, ok := range deps {
+		if ok != true {
+			status = http.StatusServiceUnavailable
+			ready = false
+		}
+	}
+	c.JSON(status, gin.H{"ready": ready, "dependencies": deps})
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Activity heartbeats. Clients ping POST /users/me/heartbeat every
+// minute or so; instead of an UPDATE per ping, the latest timestamp
+// per user is coalesced in memory and persisted in one pass on a
+// timer. A lost flush costs at most one interval of precision, which
+// is fine for "last seen" data.
+
+const heartbeatFlushInterval = 30 * time.Second
+
+type heartbeatBuffer struct {
+	mu      sync.Mutex
+	pending map[uint]time.Time
+}
+
+var heartbeats = heartbeatBuffer{pending: make(map[uint]time.Time)}
+
+func (b *heartbeatBuffer) mark(userID uint, at time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if existing, ok := b.pending[userID]; !ok || at.After(existing) {
+		b.pending[userID] = at
+	}
+}
+
+func (b *heartbeatBuffer) flush() {
+	b.mu.Lock()
+	pending := b.pending
+	b.pending = make(map[uint]time.Time)
+	b.mu.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+
+	// Group users whose heartbeats round to the same second so one
+	// UPDATE covers the whole cohort.
+	cohorts := make(map[time.Time][]uint)
+	for userID, at := range pending {
+		key := at.Truncate(time.Second)
+		cohorts[key] = append(cohorts[key], userID)
+	}
+	for at, ids := range cohorts {
+		err := DB.Model(&models.User{}).Where("id IN ?", ids).Update("last_active_at", at).Error
+		if err != nil {
+			log.Printf("heartbeat: flush failed for %d users: %v", len(ids), err)
+		}
+	}
+}
+
+func StartHeartbeatFlusher() {
+	go func() {
+		for {
+			time.Sleep(heartbeatFlushInterval)
+			heartbeats.flush()
+		}
+	}()
+}
+
+// Heartbeat records that the caller is active right now.
+func Heartbeat(c *gin.Context) {
+	heartbeats.mark(CurrentUserID(c), time.Now())
+	c.Status(http.StatusNoContent)
+}
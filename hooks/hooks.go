@@ -0,0 +1,91 @@
+// Package hooks is the compiled-in extension point for
+// deployment-specific logic. A deployment drops its integrations —
+// syncing new registrations to a CRM, pushing logins to an analytics
+// sink, vetoing token issuance for quarantined accounts — into the
+// plugins package, which registers them here from init; the server
+// blank-imports plugins, so company-specific behavior ships in one
+// small package instead of a fork.
+//
+// Hooks must be registered during init or boot, before the server
+// starts serving; registration is not synchronized. Hooks run
+// synchronously on the request path, so anything slow (an outbound
+// HTTP call to a CRM, say) should hand off to a goroutine or queue
+// inside the hook.
+package hooks
+
+import "github.com/bernardmuller/go-idle/internal/models"
+
+// UserHook observes a user-lifecycle event. The user row is the
+// caller's copy; hooks must not mutate it.
+type UserHook func(user *models.User)
+
+// TokenHook runs before an access token is signed. It may read and add
+// claims; returning an error aborts issuance. Reserved claims (sub,
+// exp, iat, jti, iss) are reasserted by the caller afterwards, so a
+// hook cannot forge them.
+type TokenHook func(userID uint, claims map[string]interface{}) error
+
+// GameActionHook observes a game-state change such as a tick.
+type GameActionHook func(userID uint, action string, amount int64)
+
+var (
+	userRegisteredHooks []UserHook
+	loginHooks          []UserHook
+	tokenIssueHooks     []TokenHook
+	gameActionHooks     []GameActionHook
+)
+
+// OnUserRegistered registers a hook run after a user account is
+// created.
+func OnUserRegistered(hook UserHook) {
+	userRegisteredHooks = append(userRegisteredHooks, hook)
+}
+
+// OnLogin registers a hook run after a successful login, on every
+// credential path (password, two-factor, passkey).
+func OnLogin(hook UserHook) {
+	loginHooks = append(loginHooks, hook)
+}
+
+// BeforeTokenIssue registers a hook run before each access token is
+// signed.
+func BeforeTokenIssue(hook TokenHook) {
+	tokenIssueHooks = append(tokenIssueHooks, hook)
+}
+
+// OnGameAction registers a hook run after each game action.
+func OnGameAction(hook GameActionHook) {
+	gameActionHooks = append(gameActionHooks, hook)
+}
+
+// RunUserRegistered invokes the registration hooks.
+func RunUserRegistered(user *models.User) {
+	for _, hook := range userRegisteredHooks {
+		hook(user)
+	}
+}
+
+// RunLogin invokes the login hooks.
+func RunLogin(user *models.User) {
+	for _, hook := range loginHooks {
+		hook(user)
+	}
+}
+
+// RunBeforeTokenIssue invokes the token hooks; the first error wins
+// and aborts issuance.
+func RunBeforeTokenIssue(userID uint, claims map[string]interface{}) error {
+	for _, hook := range tokenIssueHooks {
+		if err := hook(userID, claims); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunGameAction invokes the game-action hooks.
+func RunGameAction(userID uint, action string, amount int64) {
+	for _, hook := range gameActionHooks {
+		hook(userID, action, amount)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"time"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+)
+
+// Hot-path JSON. Login and state sync are the highest-volume endpoints
+// we serve, so their responses are concrete structs rather than gin.H:
+// the default build encodes them with the stock renderer, while the
+// fastjson build tag swaps in pooled buffers and a hand-written append
+// encoder (see hotjson_fast.go). The hot path always emits snake_case
+// and skips the ?naming= recasing machinery on purpose.
+
+type loginResponse struct {
+	Token string `json:"token"`
+
+	// Set only while a pending account deletion is still recoverable.
+	DeletionScheduled bool       `json:"deletion_scheduled,omitempty"`
+	RecoverableUntil  *time.Time `json:"recoverable_until,omitempty"`
+}
+
+type syncMessage struct {
+	ID        uint      `json:"id"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type syncResponse struct {
+	ServerTime    time.Time      `json:"server_time"`
+	Player        *models.Player `json:"player,omitempty"`
+	Messages      []syncMessage  `json:"messages,omitempty"`
+	ConfigVersion *int           `json:"config_version,omitempty"`
+}
@@ -0,0 +1,169 @@
+//go:build fastjson
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+)
+
+// Fast encoder for the hot response types: pooled buffers plus
+// hand-written appenders, so a login or sync response costs zero heap
+// allocations once the pool is warm. Types without an appender fall
+// back to the stock renderer.
+
+var hotBufPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, 0, 512)
+		return &buf
+	},
+}
+
+func renderHot(c *gin.Context, status int, v interface{}) {
+	bufp := hotBufPool.Get().(*[]byte)
+	buf := (*bufp)[:0]
+
+	switch resp := v.(type) {
+	case *loginResponse:
+		buf = appendLoginJSON(buf, resp)
+	case *syncResponse:
+		buf = appendSyncJSON(buf, resp)
+	default:
+		hotBufPool.Put(bufp)
+		RenderJSON(c, status, v)
+		return
+	}
+
+	c.Data(status, "application/json; charset=utf-8", buf)
+	*bufp = buf
+	hotBufPool.Put(bufp)
+}
+
+func appendLoginJSON(buf []byte, resp *loginResponse) []byte {
+	buf = append(buf, `{"token":`...)
+	buf = appendJSONString(buf, resp.Token)
+	if resp.DeletionScheduled {
+		buf = append(buf, `,"deletion_scheduled":true`...)
+	}
+	if resp.RecoverableUntil != nil {
+		buf = append(buf, `,"recoverable_until":`...)
+		buf = appendJSONTime(buf, *resp.RecoverableUntil)
+	}
+	return append(buf, '}')
+}
+
+func appendSyncJSON(buf []byte, resp *syncResponse) []byte {
+	buf = append(buf, `{"server_time":`...)
+	buf = appendJSONTime(buf, resp.ServerTime)
+	if resp.Player != nil {
+		buf = append(buf, `,"player":`...)
+		buf = appendPlayerJSON(buf, resp.Player)
+	}
+	if len(resp.Messages) > 0 {
+		buf = append(buf, `,"messages":[`...)
+		for i := range resp.Messages {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			buf = appendSyncMessageJSON(buf, &resp.Messages[i])
+		}
+		buf = append(buf, ']')
+	}
+	if resp.ConfigVersion != nil {
+		buf = append(buf, `,"config_version":`...)
+		buf = appendJSONInt(buf, int64(*resp.ConfigVersion))
+	}
+	return append(buf, '}')
+}
+
+// appendPlayerJSON mirrors what encoding/json produces for
+// models.Player, including the embedded gorm.Model fields.
+func appendPlayerJSON(buf []byte, p *models.Player) []byte {
+	buf = append(buf, `{"ID":`...)
+	buf = appendJSONInt(buf, int64(p.ID))
+	buf = append(buf, `,"CreatedAt":`...)
+	buf = appendJSONTime(buf, p.CreatedAt)
+	buf = append(buf, `,"UpdatedAt":`...)
+	buf = appendJSONTime(buf, p.UpdatedAt)
+	buf = append(buf, `,"DeletedAt":`...)
+	if p.DeletedAt.Valid {
+		buf = appendJSONTime(buf, p.DeletedAt.Time)
+	} else {
+		buf = append(buf, `null`...)
+	}
+	buf = append(buf, `,"user_id":`...)
+	buf = appendJSONInt(buf, int64(p.UserID))
+	buf = append(buf, `,"gold":`...)
+	buf = appendJSONInt(buf, p.Gold)
+	buf = append(buf, `,"gold_rate":`...)
+	buf = appendJSONInt(buf, p.GoldRate)
+	buf = append(buf, `,"last_tick_at":`...)
+	buf = appendJSONTime(buf, p.LastTickAt)
+	return append(buf, '}')
+}
+
+func appendSyncMessageJSON(buf []byte, m *syncMessage) []byte {
+	buf = append(buf, `{"id":`...)
+	buf = appendJSONInt(buf, int64(m.ID))
+	buf = append(buf, `,"title":`...)
+	buf = appendJSONString(buf, m.Title)
+	buf = append(buf, `,"body":`...)
+	buf = appendJSONString(buf, m.Body)
+	buf = append(buf, `,"created_at":`...)
+	buf = appendJSONTime(buf, m.CreatedAt)
+	return append(buf, '}')
+}
+
+func appendJSONInt(buf []byte, v int64) []byte {
+	if v < 0 {
+		buf = append(buf, '-')
+		v = -v
+	}
+	if v == 0 {
+		return append(buf, '0')
+	}
+	var tmp [20]byte
+	i := len(tmp)
+	for v > 0 {
+		i--
+		tmp[i] = byte('0' + v%10)
+		v /= 10
+	}
+	return append(buf, tmp[i:]...)
+}
+
+func appendJSONTime(buf []byte, t time.Time) []byte {
+	buf = append(buf, '"')
+	buf = t.AppendFormat(buf, time.RFC3339Nano)
+	return append(buf, '"')
+}
+
+// appendJSONString writes a quoted, escaped string. Multi-byte UTF-8
+// passes through untouched; only quotes, backslashes, and control
+// bytes need escaping.
+func appendJSONString(buf []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	buf = append(buf, '"')
+	for i := 0; i < len(s); i++ {
+		b := s[i]
+		switch {
+		case b == '"' || b == '\\':
+			buf = append(buf, '\\', b)
+		case b >= 0x20:
+			buf = append(buf, b)
+		case b == '\n':
+			buf = append(buf, '\\', 'n')
+		case b == '\r':
+			buf = append(buf, '\\', 'r')
+		case b == '\t':
+			buf = append(buf, '\\', 't')
+		default:
+			buf = append(buf, '\\', 'u', '0', '0', hex[b>>4], hex[b&0xf])
+		}
+	}
+	return append(buf, '"')
+}
@@ -0,0 +1,100 @@
+//go:build fastjson
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+)
+
+func sampleSyncResponse() *syncResponse {
+	now := time.Date(2024, 3, 1, 12, 30, 45, 123456789, time.UTC)
+	version := 7
+	return &syncResponse{
+		ServerTime: now,
+		Player: &models.Player{
+			Model:      gorm.Model{ID: 9, CreatedAt: now, UpdatedAt: now},
+			UserID:     9,
+			Gold:       123456,
+			GoldRate:   12,
+			LastTickAt: now,
+		},
+		Messages: []syncMessage{
+			{ID: 1, Title: "Patch notes", Body: "Gold \"rush\" weekend\nstarts now", CreatedAt: now},
+			{ID: 2, Title: "Maintenance", Body: "Short downtime tomorrow", CreatedAt: now},
+		},
+		ConfigVersion: &version,
+	}
+}
+
+// TestAppendEncodersMatchStdlib pins the hand-written encoders to the
+// exact bytes encoding/json produces, escaping included.
+func TestAppendEncodersMatchStdlib(t *testing.T) {
+	until := time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC)
+	cases := []interface{}{
+		&loginResponse{Token: "abc.def.ghi"},
+		&loginResponse{Token: "with \"quotes\" and \\slashes\\", DeletionScheduled: true, RecoverableUntil: &until},
+		sampleSyncResponse(),
+		&syncResponse{ServerTime: until},
+	}
+	for _, v := range cases {
+		want, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		var got []byte
+		switch resp := v.(type) {
+		case *loginResponse:
+			got = appendLoginJSON(nil, resp)
+		case *syncResponse:
+			got = appendSyncJSON(nil, resp)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("encoder mismatch:\n got %s\nwant %s", got, want)
+		}
+	}
+}
+
+func BenchmarkLoginJSONStdlib(b *testing.B) {
+	resp := &loginResponse{Token: "eyJhbGciOiJIUzI1NiJ9.payload.signature"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := json.Marshal(resp); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLoginJSONFast(b *testing.B) {
+	resp := &loginResponse{Token: "eyJhbGciOiJIUzI1NiJ9.payload.signature"}
+	buf := make([]byte, 0, 512)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf = appendLoginJSON(buf[:0], resp)
+	}
+}
+
+func BenchmarkSyncJSONStdlib(b *testing.B) {
+	resp := sampleSyncResponse()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := json.Marshal(resp); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSyncJSONFast(b *testing.B) {
+	resp := sampleSyncResponse()
+	buf := make([]byte, 0, 1024)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf = appendSyncJSON(buf[:0], resp)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !fastjson
+
+package main
+
+import "github.com/gin-gonic/gin"
+
+// renderHot is the stock encoder unless the fastjson build tag is set.
+func renderHot(c *gin.Context, status int, v interface{}) {
+	RenderJSON(c, status, v)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Inactive-account cleanup. A daily pass walks accounts by last
+// activity and climbs a warning ladder: an email at 60, 80, and 90 days
+// idle, then anonymization once INACTIVE_DELETE_AFTER_DAYS is reached.
+// Setting the threshold to 0 opts a deployment out entirely. Admins can
+// preview who is on the ladder via GET /admin/inactivity.
+
+// inactivityNoticeDays are the ladder rungs, in days idle.
+var inactivityNoticeDays = []int{60, 80, 90}
+
+// inactiveDeleteAfterDays is the anonymization threshold; 0 disables
+// the whole cleanup.
+func inactiveDeleteAfterDays() int {
+	if v, err := strconv.Atoi(os.Getenv("INACTIVE_DELETE_AFTER_DAYS")); err == nil && v >= 0 {
+		return v
+	}
+	return 0
+}
+
+// lastActivity is the timestamp inactivity is measured from: the
+// heartbeat if one was ever recorded, account creation otherwise.
+func lastActivity(user *models.User) time.Time {
+	if user.LastActiveAt != nil {
+		return *user.LastActiveAt
+	}
+	return user.CreatedAt
+}
+
+// inactivityStage returns the highest ladder rung the user has passed,
+// or 0 if none.
+func inactivityStage(daysIdle int) int {
+	stage := 0
+	for _, rung := range inactivityNoticeDays {
+		if daysIdle >= rung {
+			stage = rung
+		}
+	}
+	return stage
+}
+
+// runInactivityPass warns and anonymizes one batch. Accounts already
+// in the staged-deletion flow are left to it; this pass only handles
+// accounts that went quiet without asking for deletion.
+func runInactivityPass() error {
+	threshold := inactiveDeleteAfterDays()
+	if threshold == 0 {
+		return nil
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -inactivityNoticeDays[0])
+	var users []models.User
+	err := DB.Where("anonymized_at IS NULL AND deletion_requested_at IS NULL").
+		Where("(last_active_at IS NOT NULL AND last_active_at < ?) OR (last_active_at IS NULL AND created_at < ?)", cutoff, cutoff).
+		Find(&users).Error
+	if err != nil {
+		return err
+	}
+
+	for i := range users {
+		user := &users[i]
+		daysIdle := int(time.Since(lastActivity(user)).Hours() / 24)
+
+		if daysIdle >= threshold {
+			if err := anonymizeUser(user); err != nil {
+				return err
+			}
+			continue
+		}
+
+		stage := inactivityStage(daysIdle)
+		if stage == 0 {
+			continue
+		}
+		var sent int64
+		DB.Model(&models.InactivityNotice{}).Where("user_id = ? AND stage = ?", user.ID, stage).Count(&sent)
+		if sent > 0 {
+			continue
+		}
+
+		deleteDate := lastActivity(user).AddDate(0, 0, threshold)
+		subject, body, err := renderEmail("inactivity_warning", gin.H{
+			"Name":            user.Name,
+			"DaysInactive":    daysIdle,
+			"DeleteAfterDays": threshold,
+			"DeleteDate":      deleteDate.Format("2006-01-02"),
+		})
+		if err == nil {
+			sendEmail(user.Email, subject, body)
+		}
+		DB.Create(&models.InactivityNotice{UserID: user.ID, Stage: stage, SentAt: time.Now()})
+	}
+	return nil
+}
+
+// StartInactivityCleanup runs the pass daily.
+func StartInactivityCleanup() {
+	if inactiveDeleteAfterDays() == 0 {
+		return
+	}
+	go func() {
+		for {
+			if err := runInactivityPass(); err != nil {
+				log.Printf("inactivity cleanup: %v", err)
+			}
+			time.Sleep(24 * time.Hour)
+		}
+	}()
+}
+
+// AdminInactivityReport lists accounts on the warning ladder and when
+// each would be anonymized, so upcoming deletions are visible before
+// they happen.
+func AdminInactivityReport(c *gin.Context) {
+	threshold := inactiveDeleteAfterDays()
+	if threshold == 0 {
+		RenderJSON(c, http.StatusOK, gin.H{"enabled": false, "upcoming": []gin.H{}})
+		return
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -inactivityNoticeDays[0])
+	var users []models.User
+	err := RequestDB(c).Where("anonymized_at IS NULL AND deletion_requested_at IS NULL").
+		Where("(last_active_at IS NOT NULL AND last_active_at < ?) OR (last_active_at IS NULL AND created_at < ?)", cutoff, cutoff).
+		Find(&users).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	upcoming := make([]gin.H, 0, len(users))
+	for i := range users {
+		daysIdle := int(time.Since(lastActivity(&users[i])).Hours() / 24)
+		upcoming = append(upcoming, gin.H{
+			"user_id":       users[i].ID,
+			"email":         users[i].Email,
+			"days_inactive": daysIdle,
+			"stage":         inactivityStage(daysIdle),
+			"anonymize_on":  lastActivity(&users[i]).AddDate(0, 0, threshold),
+		})
+	}
+	RenderJSON(c, http.StatusOK, gin.H{"enabled": true, "delete_after_days": threshold, "upcoming": upcoming})
+}
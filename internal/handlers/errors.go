@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/bernardmuller/go-idle/internal/services"
+)
+
+// RenderError is the single place service errors become HTTP
+// responses. Domain errors carry their own status; anything else is an
+// internal error that gets logged and answered with a generic 500 so
+// storage details never leak to clients.
+func RenderError(c *gin.Context, err error) {
+	// A query cancelled by the request deadline is the gateway's
+	// timeout, not our bug.
+	if errors.Is(err, context.DeadlineExceeded) {
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "request deadline exceeded"})
+		return
+	}
+
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrConflict):
+		status = http.StatusConflict
+	case errors.Is(err, services.ErrUnauthorized):
+		status = http.StatusUnauthorized
+	case errors.Is(err, services.ErrForbidden):
+		status = http.StatusForbidden
+	case errors.Is(err, services.ErrInvalid):
+		status = http.StatusBadRequest
+	}
+
+	if status == http.StatusInternalServerError {
+		slog.Error("handler error", "path", c.FullPath(), "error", err)
+		c.JSON(status, gin.H{"error": "internal error"})
+		return
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
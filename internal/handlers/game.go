@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/bernardmuller/go-idle/api"
+	"github.com/bernardmuller/go-idle/gameproto"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/bernardmuller/go-idle/internal/services"
+)
+
+// GameHandlers binds the game endpoints to the game service.
+type GameHandlers struct {
+	Service *services.GameService
+}
+
+func (h *GameHandlers) GetGameState(c *gin.Context) {
+	player, err := h.Service.State(c.Request.Context(), api.CurrentUserID(c))
+	if err != nil {
+		RenderError(c, err)
+		return
+	}
+	renderPlayer(c, player)
+}
+
+func (h *GameHandlers) Tick(c *gin.Context) {
+	player, err := h.Service.Tick(c.Request.Context(), api.CurrentUserID(c))
+	if err != nil {
+		RenderError(c, err)
+		return
+	}
+	renderPlayer(c, player)
+}
+
+// renderPlayer writes the player as protobuf when the client asks for
+// it (mobile clients on cellular links), JSON otherwise.
+func renderPlayer(c *gin.Context, player models.Player) {
+	if gameproto.Accepts(c.GetHeader("Accept")) {
+		c.Data(http.StatusOK, gameproto.ContentType, gameproto.MarshalPlayer(&player))
+		return
+	}
+	api.RenderJSON(c, http.StatusOK, player)
+}
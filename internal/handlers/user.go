@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/bernardmuller/go-idle/api"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/bernardmuller/go-idle/internal/repository"
+	"github.com/bernardmuller/go-idle/internal/services"
+)
+
+// UserHandlers binds the user endpoints to the user service.
+type UserHandlers struct {
+	Service *services.UserService
+
+	// HasPermission is wired by the composition root; it gates the
+	// admin-only ?include_deleted listing flag.
+	HasPermission func(userID uint, permission string) bool
+
+	// SharesOrg is wired by the composition root; it reports whether the
+	// target user belongs to one of the caller's organizations and backs
+	// the tenancy check on single-user reads.
+	SharesOrg func(orgIDs []uint, targetID uint) bool
+}
+
+// UserResponse is the only shape a user leaves the API in: an explicit
+// whitelist, so new columns (and anything like password hashes or
+// soft-delete bookkeeping) stay internal unless deliberately added
+// here.
+type UserResponse struct {
+	ID           uint       `json:"id"`
+	Name         string     `json:"name"`
+	Email        string     `json:"email"`
+	Role         string     `json:"role,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	LastActiveAt *time.Time `json:"last_active_at,omitempty"`
+	// DeletedAt only appears in admin listings that opted into deleted
+	// rows; live users never carry it.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+func NewUserResponse(user models.User) UserResponse {
+	resp := UserResponse{
+		ID:           user.ID,
+		Name:         user.Name,
+		Email:        user.Email,
+		CreatedAt:    user.CreatedAt,
+		LastActiveAt: user.LastActiveAt,
+	}
+	if user.Role != nil {
+		resp.Role = user.Role.Name
+	}
+	if user.DeletedAt.Valid {
+		deleted := user.DeletedAt.Time
+		resp.DeletedAt = &deleted
+	}
+	return resp
+}
+
+func NewUserResponses(users []models.User) []UserResponse {
+	out := make([]UserResponse, 0, len(users))
+	for _, u := range users {
+		out = append(out, NewUserResponse(u))
+	}
+	return out
+}
+
+// GetUsers lists users one page at a time with optional name/email
+// substring filters and whitelisted sorting; the response carries
+// total-count metadata alongside the page.
+func (h *UserHandlers) GetUsers(c *gin.Context) {
+	q := api.ValidateQuery(c)
+	page := q.Int("page", 1, 1, 1000000)
+	perPage := q.Int("per_page", 50, 1, 200)
+	sort := q.Enum("sort", "created_at", "created_at", "name")
+	order := q.Enum("order", "asc", "asc", "desc")
+	if !q.Valid() {
+		return
+	}
+
+	includeDeleted := c.Query("include_deleted") == "true"
+	if includeDeleted && (h.HasPermission == nil || !h.HasPermission(api.CurrentUserID(c), "admin")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "include_deleted requires the admin permission"})
+		return
+	}
+
+	opts := repository.UserListOptions{
+		Name:           c.Query("name"),
+		Email:          c.Query("email"),
+		Sort:           sort,
+		Order:          order,
+		Offset:         (page - 1) * perPage,
+		Limit:          perPage,
+		IncludeDeleted: includeDeleted,
+	}
+	// Org members only see their own tenants' users; accounts outside
+	// any org and global admins keep the unscoped listing.
+	if h.HasPermission == nil || !h.HasPermission(api.CurrentUserID(c), "admin") {
+		opts.OrgIDs = api.TokenOrgIDs(c)
+	}
+	users, total, err := h.Service.List(c.Request.Context(), opts)
+	if err != nil {
+		RenderError(c, err)
+		return
+	}
+
+	totalPages := (total + int64(perPage) - 1) / int64(perPage)
+	api.RenderJSON(c, http.StatusOK, gin.H{
+		"data": NewUserResponses(users),
+		"meta": gin.H{
+			"page":        page,
+			"per_page":    perPage,
+			"total":       total,
+			"total_pages": totalPages,
+		},
+	})
+}
+
+func (h *UserHandlers) GetUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	// The same tenancy boundary as the listing: an org-scoped caller may
+	// read themselves and co-members, nobody else. 404 rather than 403
+	// so other tenants' user ids aren't confirmed to exist.
+	orgIDs := api.TokenOrgIDs(c)
+	if len(orgIDs) > 0 && uint(id) != api.CurrentUserID(c) &&
+		(h.HasPermission == nil || !h.HasPermission(api.CurrentUserID(c), "admin")) &&
+		(h.SharesOrg == nil || !h.SharesOrg(orgIDs, uint(id))) {
+		RenderError(c, services.Errorf(services.ErrNotFound, "user %d", id))
+		return
+	}
+
+	user, err := h.Service.Get(c.Request.Context(), uint(id))
+	if err != nil {
+		RenderError(c, err)
+		return
+	}
+	api.RenderJSON(c, http.StatusOK, NewUserResponse(user))
+}
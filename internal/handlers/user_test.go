@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+)
+
+// fullUser has every sensitive field populated so a serialization leak
+// would show up in the marshalled output.
+func fullUser() models.User {
+	now := time.Now()
+	return models.User{
+		Model: gorm.Model{
+			ID:        42,
+			CreatedAt: now,
+			UpdatedAt: now,
+			DeletedAt: gorm.DeletedAt{Time: now, Valid: true},
+		},
+		Name:                 "Alice",
+		Email:                "alice@example.com",
+		Password:             "$2a$10$secrethash",
+		Role:                 &models.Role{Name: "admin"},
+		DeletionRequestedAt:  &now,
+		AnonymizedAt:         &now,
+		LastActiveAt:         &now,
+		EmailVerifyTokenHash: "deadbeef",
+	}
+}
+
+func marshalKeys(t *testing.T, v interface{}) map[string]json.RawMessage {
+	t.Helper()
+	raw, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &keys); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	return keys
+}
+
+// TestUserResponseShape pins down exactly which fields a user response
+// exposes; anything else reaching a client is a regression.
+func TestUserResponseShape(t *testing.T) {
+	keys := marshalKeys(t, NewUserResponse(fullUser()))
+
+	// fullUser is soft-deleted, so deleted_at is part of the expected
+	// shape here; live users must not carry it (checked below).
+	want := []string{"id", "name", "email", "role", "created_at", "last_active_at", "deleted_at"}
+	for _, key := range want {
+		if _, ok := keys[key]; !ok {
+			t.Errorf("response missing expected field %q", key)
+		}
+	}
+	if len(keys) != len(want) {
+		t.Errorf("response has %d fields, want %d: %v", len(keys), len(want), keys)
+	}
+	for _, key := range []string{"password", "Password", "DeletedAt", "UpdatedAt"} {
+		if _, ok := keys[key]; ok {
+			t.Errorf("response leaks internal field %q", key)
+		}
+	}
+
+	live := fullUser()
+	live.DeletedAt = gorm.DeletedAt{}
+	if _, ok := marshalKeys(t, NewUserResponse(live))["deleted_at"]; ok {
+		t.Errorf("live user response carries deleted_at")
+	}
+}
+
+// TestUserModelHidesSecrets guards the struct tags themselves: even
+// code that marshals a raw models.User must not expose credential
+// material.
+func TestUserModelHidesSecrets(t *testing.T) {
+	keys := marshalKeys(t, fullUser())
+
+	for _, key := range []string{"password", "Password", "email_verify_token_hash", "EmailVerifyTokenHash"} {
+		if _, ok := keys[key]; ok {
+			t.Errorf("models.User marshals secret field %q", key)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Catalog entities: game content defined by admins rather than
+// players. They share CRUD scaffolding (see catalog.go in the server),
+// so a new catalog type only needs a struct here with binding tags for
+// validation.
+
+// Item is a purchasable in the game shop.
+type Item struct {
+	gorm.Model
+	Stamps
+	Name        string `json:"name" binding:"required,min=2,max=100"`
+	Description string `json:"description" binding:"max=1000"`
+	Cost        int64  `json:"cost" binding:"min=0"`
+}
+
+// Upgrade permanently improves a player's gold rate.
+type Upgrade struct {
+	gorm.Model
+	Stamps
+	Name        string `json:"name" binding:"required,min=2,max=100"`
+	Description string `json:"description" binding:"max=1000"`
+	Cost        int64  `json:"cost" binding:"min=0"`
+	Multiplier  int64  `json:"multiplier" binding:"required,min=1"`
+}
+
+// Achievement unlocks at a gold threshold.
+type Achievement struct {
+	gorm.Model
+	Stamps
+	Name        string `json:"name" binding:"required,min=2,max=100"`
+	Description string `json:"description" binding:"max=1000"`
+	Threshold   int64  `json:"threshold" binding:"min=0"`
+}
+
+// Announcement is in-game news shown to all players.
+type Announcement struct {
+	gorm.Model
+	Stamps
+	Name        string     `json:"name" binding:"required,min=2,max=100"`
+	Body        string     `json:"body" binding:"required,max=5000"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+}
+
+// CatalogChange is the audit trail for catalog edits.
+type CatalogChange struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	Entity    string    `json:"entity" gorm:"index"`
+	EntityID  uint      `json:"entity_id"`
+	Action    string    `json:"action"`
+	ActorID   uint      `json:"actor_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
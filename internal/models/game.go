@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Player holds the idle-game state that belongs to a user.
+type Player struct {
+	gorm.Model
+	UserID     uint      `json:"user_id" gorm:"uniqueIndex"`
+	Gold       int64     `json:"gold"`
+	GoldRate   int64     `json:"gold_rate"`
+	LastTickAt time.Time `json:"last_tick_at"`
+}
+
+// TelemetryEvent is a client-reported metric or gameplay signal. It
+// deliberately avoids gorm.Model: these rows are append-only and never
+// soft-deleted.
+type TelemetryEvent struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	UserID    uint      `json:"user_id" gorm:"index"`
+	Name      string    `json:"name"`
+	Payload   string    `json:"payload"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+// EconomyCounter is one user's spend of a capped game action within a
+// window bucket; see economy_quota.go. Counters persist so caps
+// survive restarts.
+type EconomyCounter struct {
+	ID     uint   `json:"id" gorm:"primarykey"`
+	UserID uint   `json:"user_id" gorm:"uniqueIndex:idx_economy_counter"`
+	Action string `json:"action" gorm:"uniqueIndex:idx_economy_counter"`
+	Window string `json:"window" gorm:"uniqueIndex:idx_economy_counter"`
+	Period string `json:"period" gorm:"uniqueIndex:idx_economy_counter"`
+	Count  int64  `json:"count"`
+}
+
+// GameEvent records a server-side game state change (ticks, purchases,
+// prestige) for auditing and aggregates.
+type GameEvent struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	PlayerID  uint      `json:"player_id" gorm:"index"`
+	Type      string    `json:"type"`
+	Amount    int64     `json:"amount"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+// LeaderboardEntry mirrors one row of the leaderboard read model.
+type LeaderboardEntry struct {
+	Rank     int    `json:"rank"`
+	PlayerID uint   `json:"player_id" gorm:"primarykey"`
+	Name     string `json:"name"`
+	Gold     int64  `json:"gold"`
+}
+
+func (LeaderboardEntry) TableName() string { return "leaderboard_gold" }
+
+// GameConfig stores the tunables document edited via JSON Patch.
+type GameConfig struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	Document  string    `json:"document"`
+	Version   int       `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
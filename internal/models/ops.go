@@ -0,0 +1,279 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Stamps records which account created and last changed a row. The
+// columns are filled automatically by the stamping callbacks (see
+// stamping.go in the server); embedding this is all a model needs.
+type Stamps struct {
+	CreatedBy uint `json:"created_by,omitempty"`
+	UpdatedBy uint `json:"updated_by,omitempty"`
+}
+
+// AdminMessage is a broadcast sent to a player segment.
+type AdminMessage struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	Segment   string    `json:"segment"`
+	Sent      int       `json:"sent"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserMessage is one recipient's copy of a broadcast.
+type UserMessage struct {
+	ID             uint      `json:"id" gorm:"primarykey"`
+	AdminMessageID uint      `json:"admin_message_id" gorm:"index"`
+	UserID         uint      `json:"user_id" gorm:"index"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// MailboxMessage is one message in a player's mailbox, with optional
+// reward attachments (gold and/or a gold-rate bonus) paid out once on
+// claim; see mailbox.go.
+type MailboxMessage struct {
+	ID        uint       `json:"id" gorm:"primarykey"`
+	UserID    uint       `json:"user_id" gorm:"index"`
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+	Gold      int64      `json:"gold,omitempty"`
+	GoldRate  int64      `json:"gold_rate,omitempty"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+	ClaimedAt *time.Time `json:"claimed_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" gorm:"index"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type EmailTemplate struct {
+	gorm.Model
+	Stamps
+	Name    string `json:"name" gorm:"uniqueIndex"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Job is one persisted unit of background work.
+type Job struct {
+	ID       uint      `json:"id" gorm:"primarykey"`
+	Type     string    `json:"type" gorm:"index"`
+	Payload  string    `json:"payload"`
+	Status   string    `json:"status" gorm:"index;default:pending"`
+	RunAt    time.Time `json:"run_at" gorm:"index"`
+	Attempts int       `json:"attempts"`
+	// Recurrence reschedules the job after each successful run: a Go
+	// duration ("2h") or "weekly <weekday> <HH:MM>"; empty for one-shot.
+	Recurrence  string    `json:"recurrence,omitempty"`
+	MaxAttempts int       `json:"max_attempts"`
+	LastError   string    `json:"last_error,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// SagaState is the lifecycle of a persisted saga.
+type SagaState string
+
+const (
+	SagaRunning      SagaState = "running"
+	SagaCompleted    SagaState = "completed"
+	SagaCompensating SagaState = "compensating"
+	SagaCompensated  SagaState = "compensated"
+	SagaFailed       SagaState = "failed"
+)
+
+// Saga is one multi-system flow with its step cursor persisted, so a
+// crash mid-flow can be compensated at the next boot.
+type Saga struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	Name      string    `json:"name" gorm:"index"`
+	State     SagaState `json:"state" gorm:"index"`
+	Step      int       `json:"step"`
+	Payload   string    `json:"payload"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MaintenanceWindow is a scheduled downtime announcement; while one
+// is active the API serves 503s outside the admin surface.
+type MaintenanceWindow struct {
+	gorm.Model
+	Stamps
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+	Message  string    `json:"message"`
+}
+
+// WebhookSubscription is an admin-defined receiver for server-side
+// events. Template, Headers, and Filter let a subscription adapt its
+// deliveries to what the receiver expects without a glue service.
+type WebhookSubscription struct {
+	gorm.Model
+	Stamps
+	URL    string `json:"url"`
+	Secret string `json:"-"`
+	// Events is a comma-separated list of event types; "*" matches all.
+	Events string `json:"events"`
+	// Template is an optional Go template rendered over the event to
+	// produce the request body; empty means the default JSON envelope.
+	Template string `json:"template,omitempty"`
+	// Headers holds extra request headers as a JSON object.
+	Headers string `json:"headers,omitempty"`
+	// Filter is an optional field:op:value expression (same syntax as
+	// list filtering) evaluated against the event data.
+	Filter string `json:"filter,omitempty"`
+	Active bool   `json:"active"`
+}
+
+// SandboxKey lets an integrator route requests at an isolated sandbox
+// dataset instead of live data.
+type SandboxKey struct {
+	gorm.Model
+	UserID     uint       `json:"user_id" gorm:"index"`
+	Label      string     `json:"label"`
+	KeyHash    string     `json:"-" gorm:"uniqueIndex"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// Notification is one logical notification; the in-app channel stores
+// it here and clients read it back until marked read.
+type Notification struct {
+	ID        uint       `json:"id" gorm:"primarykey"`
+	UserID    uint       `json:"user_id" gorm:"index"`
+	Type      string     `json:"type"`
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// NotificationDelivery tracks one channel's attempt at one
+// notification.
+type NotificationDelivery struct {
+	ID             uint      `json:"id" gorm:"primarykey"`
+	NotificationID uint      `json:"notification_id" gorm:"index"`
+	UserID         uint      `json:"user_id" gorm:"index"`
+	Channel        string    `json:"channel"`
+	Status         string    `json:"status"`
+	Error          string    `json:"error,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Backup records one database backup attempt and what became of it.
+type Backup struct {
+	ID         uint       `json:"id" gorm:"primarykey"`
+	Location   string     `json:"location"`
+	SizeBytes  int64      `json:"size_bytes"`
+	Status     string     `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	VerifiedAt *time.Time `json:"verified_at,omitempty"`
+}
+
+// Incident is an admin-authored entry on the public status page. It
+// stays listed until its status reaches "resolved".
+type Incident struct {
+	gorm.Model
+	Stamps
+	Title      string     `json:"title"`
+	Body       string     `json:"body"`
+	Severity   string     `json:"severity"`
+	Status     string     `json:"status"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+// SaveCheckpoint records the newest signed save checkpoint per user;
+// validation compares a submitted save against it to catch forks.
+type SaveCheckpoint struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	UserID    uint      `json:"user_id" gorm:"uniqueIndex"`
+	StateHash string    `json:"state_hash"`
+	IssuedAt  time.Time `json:"issued_at"`
+}
+
+// AuditLog is the append-only trail of security-sensitive events:
+// logins, registrations, credential changes, role assignments, and
+// deletions. Rows are never updated or soft-deleted.
+type AuditLog struct {
+	ID       uint   `json:"id" gorm:"primarykey"`
+	Action   string `json:"action" gorm:"index"`
+	ActorID  uint   `json:"actor_id" gorm:"index"`
+	TargetID uint   `json:"target_id,omitempty" gorm:"index"`
+	IP       string `json:"ip,omitempty"`
+	// Detail carries action-specific context (an email, a role name);
+	// never secrets.
+	Detail    string    `json:"detail,omitempty"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+// InactivityNotice records one rung of the inactivity warning ladder
+// having been sent, so a user is warned once per stage.
+type InactivityNotice struct {
+	ID     uint      `json:"id" gorm:"primarykey"`
+	UserID uint      `json:"user_id" gorm:"index:idx_inactivity_user_stage,unique"`
+	Stage  int       `json:"stage" gorm:"index:idx_inactivity_user_stage,unique"`
+	SentAt time.Time `json:"sent_at"`
+}
+
+// ProfileShare is a public, expiring link to a read-only snapshot of a
+// user's profile and game stats. Only the token's hash is stored; the
+// plaintext is shown once at creation.
+type ProfileShare struct {
+	gorm.Model
+	UserID    uint       `json:"user_id" gorm:"index"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	Views     int64      `json:"views"`
+}
+
+// FunnelStep is one step of the onboarding funnel, completed by the
+// named event type; the definition is edited at runtime through
+// /admin/funnel.
+type FunnelStep struct {
+	ID        uint   `json:"id" gorm:"primarykey"`
+	Position  int    `json:"position"`
+	Name      string `json:"name" gorm:"uniqueIndex"`
+	EventType string `json:"event_type"`
+}
+
+// FunnelEvent records the first time a user completed a funnel step.
+type FunnelEvent struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	UserID    uint      `json:"user_id" gorm:"uniqueIndex:idx_funnel_user_step"`
+	Step      string    `json:"step" gorm:"uniqueIndex:idx_funnel_user_step"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CacheProfile persists one hot cache entry between restarts.
+type CacheProfile struct {
+	ID     uint   `json:"id" gorm:"primarykey"`
+	Entity string `json:"entity" gorm:"uniqueIndex:idx_cache_profile"`
+	Key    uint   `json:"key" gorm:"uniqueIndex:idx_cache_profile"`
+	Hits   int64  `json:"hits"`
+}
+
+// EconomyFreeze disables one economy action everywhere while an
+// exploit is investigated; the row existing is the switch.
+type EconomyFreeze struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	Action    string    `json:"action" gorm:"uniqueIndex"`
+	Message   string    `json:"message"`
+	FrozenBy  uint      `json:"frozen_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserDataKey is one user's wrapped PII encryption key; shredding
+// clears WrappedKey and the row remains as the record of it. See
+// crypto_shred.go.
+type UserDataKey struct {
+	ID         uint       `json:"id" gorm:"primarykey"`
+	UserID     uint       `json:"user_id" gorm:"uniqueIndex"`
+	WrappedKey string     `json:"-"`
+	ShreddedAt *time.Time `json:"shredded_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Organization is a tenant; users belong to it through Memberships.
+type Organization struct {
+	gorm.Model
+	Name string `json:"name"`
+	Plan string `json:"plan" gorm:"default:free"`
+
+	// Residency pins the tenant's data to a region; queries for an EU
+	// tenant are routed to the EU database.
+	Residency string `json:"residency" gorm:"default:global"`
+
+	// StripeCustomerID links the tenant to Stripe for metered billing;
+	// usage sync skips organizations without one.
+	StripeCustomerID string `json:"stripe_customer_id,omitempty"`
+
+	// APIKeyRotationDays forces members' API keys to rotate every N
+	// days; 0 means no policy. See apikey_rotation.go.
+	APIKeyRotationDays int `json:"api_key_rotation_days,omitempty"`
+}
+
+type Membership struct {
+	gorm.Model
+	OrganizationID uint  `json:"organization_id" gorm:"uniqueIndex:idx_membership"`
+	UserID         uint  `json:"user_id" gorm:"uniqueIndex:idx_membership"`
+	Admin          bool  `json:"admin"`
+	RoleID         *uint `json:"role_id"`
+	Role           *Role `json:"role"`
+}
+
+// UsageLineItem is one metric's billable quantity for one organization
+// and month. Counters (api_calls) are incremented as meters flush;
+// gauges (active_players, storage_bytes) are overwritten on recompute.
+type UsageLineItem struct {
+	ID             uint       `json:"id" gorm:"primarykey"`
+	OrganizationID uint       `json:"organization_id" gorm:"uniqueIndex:idx_usage_item"`
+	Period         string     `json:"period" gorm:"uniqueIndex:idx_usage_item"`
+	Metric         string     `json:"metric" gorm:"uniqueIndex:idx_usage_item"`
+	Quantity       int64      `json:"quantity"`
+	StripeSyncedAt *time.Time `json:"stripe_synced_at,omitempty"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
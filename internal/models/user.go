@@ -0,0 +1,178 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type User struct {
+	gorm.Model
+	Name     string `json:"name"`
+	Email    string `json:"email" gorm:"uniqueIndex:idx_users_email_live,where:deleted_at IS NULL"`
+	Password string `json:"-"`
+	RoleID   *uint  `json:"role_id"`
+	Role     *Role  `json:"role"`
+
+	// Staged deletion: set when the user asks for deletion, cleared on
+	// reactivation; AnonymizedAt marks the PII purge as done.
+	DeletionRequestedAt *time.Time `json:"deletion_requested_at,omitempty"`
+	AnonymizedAt        *time.Time `json:"anonymized_at,omitempty"`
+
+	// LastActiveAt is maintained by the heartbeat endpoint, written in
+	// batches rather than per request.
+	LastActiveAt *time.Time `json:"last_active_at,omitempty"`
+
+	// A requested email change stays pending until the verification
+	// token sent to the new address is redeemed.
+	PendingEmail         *string `json:"pending_email,omitempty"`
+	EmailVerifyTokenHash string  `json:"-"`
+
+	// TOTP two-factor auth. The secret is staged by setup and only
+	// counts once verify has confirmed a code sets TOTPEnabledAt.
+	TOTPSecret    string     `json:"-"`
+	TOTPEnabledAt *time.Time `json:"-"`
+
+	// TokenVersion is bumped on password changes so tokens minted
+	// before the change stop verifying; see session_revocation.go.
+	TokenVersion      uint       `json:"-" gorm:"default:0"`
+	PasswordChangedAt *time.Time `json:"-"`
+
+	// Residency mirrors the user's organization region so row routing
+	// doesn't need a join; see residency.go.
+	Residency string `json:"residency" gorm:"default:global"`
+
+	// Consent flags. Analytics defaults on (product telemetry);
+	// marketing and third-party sharing are strictly opt-in. Every
+	// change is recorded as a ConsentChange.
+	ConsentAnalytics  bool `json:"consent_analytics" gorm:"default:true"`
+	ConsentMarketing  bool `json:"consent_marketing"`
+	ConsentThirdParty bool `json:"consent_third_party"`
+}
+
+// ConsentChange is the append-only audit trail of consent flips.
+type ConsentChange struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	UserID    uint      `json:"user_id" gorm:"index"`
+	Flag      string    `json:"flag"`
+	OldValue  bool      `json:"old_value"`
+	NewValue  bool      `json:"new_value"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Role struct {
+	gorm.Model
+	Stamps
+	Name        string       `json:"name" gorm:"uniqueIndex:idx_role_name_org"`
+	Permissions []Permission `json:"permissions" gorm:"many2many:role_permissions"`
+
+	// OrganizationID is nil for the built-in global roles; custom roles
+	// defined by org admins are scoped to their organization.
+	OrganizationID *uint `json:"organization_id,omitempty" gorm:"uniqueIndex:idx_role_name_org"`
+}
+
+type Permission struct {
+	gorm.Model
+	Name        string `json:"name" gorm:"uniqueIndex"`
+	Description string `json:"description,omitempty"`
+
+	// DeprecatedAt marks a stored permission the code catalog no longer
+	// defines; see permission_catalog.go.
+	DeprecatedAt *time.Time `json:"deprecated_at,omitempty"`
+}
+
+// RoleApproval is a parked sensitive role grant waiting for a second
+// admin's decision; see approvals.go. RoleName is denormalized so the
+// queue stays readable if the role is later renamed or deleted.
+type RoleApproval struct {
+	ID          uint       `json:"id" gorm:"primarykey"`
+	UserID      uint       `json:"user_id" gorm:"index"`
+	RoleID      uint       `json:"role_id"`
+	RoleName    string     `json:"role_name"`
+	RequestedBy uint       `json:"requested_by"`
+	Status      string     `json:"status" gorm:"index"`
+	DecidedBy   *uint      `json:"decided_by,omitempty"`
+	DecidedAt   *time.Time `json:"decided_at,omitempty"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// SocialAccount links a third-party identity (e.g. Apple) to a user.
+type SocialAccount struct {
+	gorm.Model
+	UserID     uint   `json:"user_id" gorm:"index"`
+	Provider   string `json:"provider" gorm:"uniqueIndex:idx_provider_id"`
+	ProviderID string `json:"provider_id" gorm:"uniqueIndex:idx_provider_id"`
+	Email      string `json:"email"`
+}
+
+// Session tracks one issued token.
+type Session struct {
+	ID         uint       `json:"id" gorm:"primarykey"`
+	UserID     uint       `json:"user_id" gorm:"index"`
+	TokenID    string     `json:"token_id" gorm:"uniqueIndex"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// WebauthnCredential is one registered passkey. PublicKey holds the
+// raw COSE key bytes from attestation; SignCount tracks the
+// authenticator's counter so cloned credentials can be spotted.
+type WebauthnCredential struct {
+	ID           uint       `json:"id" gorm:"primarykey"`
+	UserID       uint       `json:"user_id" gorm:"index"`
+	CredentialID string     `json:"credential_id" gorm:"uniqueIndex"`
+	PublicKey    []byte     `json:"-"`
+	SignCount    uint32     `json:"-"`
+	Label        string     `json:"label"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// APIKey is a long-lived machine credential. Only the hash of the key
+// material is stored; Prefix keeps enough plaintext to tell keys apart
+// in a listing. Scopes is a comma-separated list of route tags the key
+// may reach, empty meaning unrestricted.
+type APIKey struct {
+	ID         uint       `json:"id" gorm:"primarykey"`
+	UserID     uint       `json:"user_id" gorm:"index"`
+	Name       string     `json:"name"`
+	KeyHash    string     `json:"-" gorm:"uniqueIndex"`
+	Prefix     string     `json:"prefix"`
+	Scopes     string     `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+
+	// ExpiryNotifiedAt marks the expiry warning as sent so the owner
+	// is nagged once; see apikey_rotation.go.
+	ExpiryNotifiedAt *time.Time `json:"-"`
+}
+
+// TwoFactorBackupCode is one single-use recovery code for an account
+// with TOTP enabled; only the hash is stored.
+type TwoFactorBackupCode struct {
+	ID        uint       `json:"id" gorm:"primarykey"`
+	UserID    uint       `json:"user_id" gorm:"index"`
+	CodeHash  string     `json:"-" gorm:"uniqueIndex"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type PasswordResetToken struct {
+	ID        uint       `json:"id" gorm:"primarykey"`
+	UserID    uint       `json:"user_id" gorm:"index"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	// Where the reset was requested from; with RESET_TOKEN_BINDING on,
+	// redemption must come from the same user agent and IP family.
+	IssuedUA string `json:"-"`
+	IssuedIP string `json:"-"`
+}
@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+)
+
+// PlayerRepository owns all player-table access.
+type PlayerRepository struct {
+	DB *gorm.DB
+}
+
+func (r *PlayerRepository) ByUserID(ctx context.Context, userID uint) (models.Player, error) {
+	var player models.Player
+	err := r.DB.WithContext(ctx).Where("user_id = ?", userID).First(&player).Error
+	return player, err
+}
+
+func (r *PlayerRepository) Save(ctx context.Context, player *models.Player) error {
+	return r.DB.WithContext(ctx).Save(player).Error
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+)
+
+// UserRepository owns all user-table access so handlers and services
+// never build queries themselves.
+type UserRepository struct {
+	DB *gorm.DB
+}
+
+// UserListOptions narrows and orders a user listing. Sort must be one
+// of the keys of userSortColumns; anything else falls back to
+// created_at.
+type UserListOptions struct {
+	Name   string
+	Email  string
+	Sort   string
+	Order  string
+	Offset int
+	Limit  int
+	// IncludeDeleted lifts the default soft-delete filter; the handler
+	// only sets it for admins.
+	IncludeDeleted bool
+	// OrgIDs, when set, restricts the listing to members of those
+	// organizations — the tenancy boundary for org-scoped callers.
+	OrgIDs []uint
+}
+
+// userSortColumns whitelists the ORDER BY targets so column names
+// never come from the request.
+var userSortColumns = map[string]string{
+	"created_at": "created_at",
+	"name":       "name",
+}
+
+// List returns one page of users plus the total row count for the
+// applied filters.
+func (r *UserRepository) List(ctx context.Context, opts UserListOptions) ([]models.User, int64, error) {
+	query := r.DB.WithContext(ctx).Model(&models.User{})
+	if opts.IncludeDeleted {
+		query = query.Unscoped()
+	}
+	if opts.Name != "" {
+		query = query.Where("name LIKE ?", "%"+opts.Name+"%")
+	}
+	if opts.Email != "" {
+		query = query.Where("email LIKE ?", "%"+opts.Email+"%")
+	}
+	if len(opts.OrgIDs) > 0 {
+		query = query.Where("users.id IN (?)",
+			r.DB.Model(&models.Membership{}).Select("user_id").Where("organization_id IN ?", opts.OrgIDs))
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	column, ok := userSortColumns[opts.Sort]
+	if !ok {
+		column = "created_at"
+	}
+	direction := "ASC"
+	if opts.Order == "desc" {
+		direction = "DESC"
+	}
+
+	var users []models.User
+	err := query.Preload("Role.Permissions").
+		Order(column + " " + direction).
+		Offset(opts.Offset).
+		Limit(opts.Limit).
+		Find(&users).Error
+	return users, total, err
+}
+
+func (r *UserRepository) Get(ctx context.Context, id uint) (models.User, error) {
+	var user models.User
+	err := r.DB.WithContext(ctx).Preload("Role.Permissions").First(&user, id).Error
+	return user, err
+}
+
+// ByEmail fetches a user by address for credential checks. The result
+// carries the password hash, so it must never be rendered as-is.
+func (r *UserRepository) ByEmail(ctx context.Context, email string) (models.User, error) {
+	var user models.User
+	err := r.DB.WithContext(ctx).Where("email = ?", email).First(&user).Error
+	return user, err
+}
+
+// CreateWithPlayer inserts a user and their player row in one
+// transaction so a failure on either side (including losing the
+// unique-email race) leaves no half-registered account behind.
+func (r *UserRepository) CreateWithPlayer(ctx context.Context, user *models.User, player *models.Player) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		player.UserID = user.ID
+		return tx.Create(player).Error
+	})
+}
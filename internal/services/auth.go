@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/bernardmuller/go-idle/internal/repository"
+)
+
+// AuthService owns the credential rules independent of transport:
+// registration creates the account and its starting player atomically,
+// and Authenticate checks a password without revealing which step
+// failed. Everything HTTP-shaped — rate limits, risk scoring, session
+// and cookie handling — stays with the caller.
+type AuthService struct {
+	Users *repository.UserRepository
+
+	// HashPassword is injected so the service doesn't carry the bcrypt
+	// cost policy; the composition root wires the shared helper.
+	HashPassword func(password string) (string, error)
+}
+
+// Register creates the account plus its default player state.
+func (s *AuthService) Register(ctx context.Context, name, email, password string) (models.User, error) {
+	hash, err := s.HashPassword(password)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	user := models.User{Name: name, Email: email, Password: hash}
+	player := models.Player{GoldRate: 1, LastTickAt: time.Now()}
+	if err := s.Users.CreateWithPlayer(ctx, &user, &player); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) || strings.Contains(err.Error(), "duplicate key") {
+			return models.User{}, Errorf(ErrConflict, "email already registered")
+		}
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+// Authenticate verifies credentials. An unknown address, a wrong
+// password and an anonymized account all fail with the same
+// ErrUnauthorized so the response can't be used to probe for accounts.
+// On a wrong password the matched user is still returned alongside the
+// error so callers can attribute the failure in their audit trail; a
+// zero user means the address was unknown.
+func (s *AuthService) Authenticate(ctx context.Context, email, password string) (models.User, error) {
+	user, err := s.Users.ByEmail(ctx, email)
+	if err != nil {
+		return models.User{}, Errorf(ErrUnauthorized, "invalid credentials")
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+		return user, Errorf(ErrUnauthorized, "invalid credentials")
+	}
+	if user.AnonymizedAt != nil {
+		return models.User{}, Errorf(ErrUnauthorized, "invalid credentials")
+	}
+	return user, nil
+}
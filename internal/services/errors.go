@@ -0,0 +1,41 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Domain errors. Services return these (optionally wrapped with
+// context via Errorf) instead of hand-picking HTTP status codes;
+// handlers map them to responses in one place, so a service can't
+// accidentally answer an error with 200 OK.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrInvalid      = errors.New("invalid input")
+)
+
+// Errorf wraps a domain error with a human-readable message, keeping
+// errors.Is matching intact: Errorf(ErrNotFound, "user %d", id).
+func Errorf(kind error, format string, args ...interface{}) error {
+	return fmt.Errorf("%s: %w", fmt.Sprintf(format, args...), kind)
+}
+
+// translateDBError folds storage-level errors into domain errors so
+// nothing above the service layer needs to know about gorm.
+func translateDBError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrConflict
+	default:
+		return err
+	}
+}
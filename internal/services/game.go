@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/bernardmuller/go-idle/internal/repository"
+)
+
+// GameService holds the idle-game rules: how gold accrues between
+// ticks and what happens when a tick is credited.
+type GameService struct {
+	Players *repository.PlayerRepository
+
+	// OnTick observes every credited tick; the composition root wires
+	// it to the bulk event writer and the long-poll notifier.
+	OnTick func(player *models.Player, earned int64)
+
+	// AppendEvent, when set, writes the tick to the event log before
+	// the projection is updated (event-sourced mode). A failed append
+	// aborts the tick so the log stays the source of truth.
+	AppendEvent func(ctx context.Context, player *models.Player, earned int64) error
+}
+
+func (s *GameService) State(ctx context.Context, userID uint) (models.Player, error) {
+	player, err := s.Players.ByUserID(ctx, userID)
+	if err := translateDBError(err); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return models.Player{}, Errorf(ErrNotFound, "player for user %d", userID)
+		}
+		return models.Player{}, err
+	}
+	return player, nil
+}
+
+// Tick credits the player with the gold accrued since the last tick.
+func (s *GameService) Tick(ctx context.Context, userID uint) (models.Player, error) {
+	player, err := s.State(ctx, userID)
+	if err != nil {
+		return models.Player{}, err
+	}
+
+	now := time.Now()
+	elapsed := int64(now.Sub(player.LastTickAt).Seconds())
+	if elapsed > 0 {
+		earned := elapsed * player.GoldRate
+		player.Gold += earned
+		player.LastTickAt = now
+		if s.AppendEvent != nil {
+			if err := s.AppendEvent(ctx, &player, earned); err != nil {
+				return models.Player{}, err
+			}
+		}
+		if err := translateDBError(s.Players.Save(ctx, &player)); err != nil {
+			return models.Player{}, err
+		}
+		if s.OnTick != nil {
+			s.OnTick(&player, earned)
+		}
+	}
+	return player, nil
+}
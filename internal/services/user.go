@@ -0,0 +1,40 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/bernardmuller/go-idle/internal/repository"
+)
+
+// UserService sits between the HTTP handlers and the user repository.
+type UserService struct {
+	Users *repository.UserRepository
+
+	// LoadCached, when set, is the read-through cache for single-user
+	// lookups; the composition root wires it to the user read cache.
+	LoadCached func(id uint) (models.User, error)
+}
+
+func (s *UserService) List(ctx context.Context, opts repository.UserListOptions) ([]models.User, int64, error) {
+	users, total, err := s.Users.List(ctx, opts)
+	return users, total, translateDBError(err)
+}
+
+func (s *UserService) Get(ctx context.Context, id uint) (models.User, error) {
+	var user models.User
+	var err error
+	if s.LoadCached != nil {
+		user, err = s.LoadCached(id)
+	} else {
+		user, err = s.Users.Get(ctx, id)
+	}
+	if err := translateDBError(err); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return models.User{}, Errorf(ErrNotFound, "user %d", id)
+		}
+		return models.User{}, err
+	}
+	return user, nil
+}
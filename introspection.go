@@ -0,0 +1,84 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// RFC 7662 token introspection. Sibling services POST a token and get
+// back its claims (or {"active": false}); callers authenticate with
+// client credentials from INTROSPECTION_CLIENTS ("id:secret" pairs,
+// comma-separated). Per the RFC, an invalid token is a 200 with
+// active=false, never an error.
+
+func introspectionClientOK(id, secret string) bool {
+	for _, pair := range strings.Split(os.Getenv("INTROSPECTION_CLIENTS"), ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		idMatch := subtle.ConstantTimeCompare([]byte(parts[0]), []byte(id)) == 1
+		secretMatch := subtle.ConstantTimeCompare([]byte(parts[1]), []byte(secret)) == 1
+		if idMatch && secretMatch {
+			return true
+		}
+	}
+	return false
+}
+
+// IntrospectToken implements POST /oauth/introspect.
+func IntrospectToken(c *gin.Context) {
+	id, secret, ok := c.Request.BasicAuth()
+	if !ok || !introspectionClientOK(id, secret) {
+		c.Header("WWW-Authenticate", `Basic realm="introspection"`)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_client"})
+		return
+	}
+
+	tokenString := c.PostForm("token")
+	if tokenString == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token parameter is required"})
+		return
+	}
+
+	token, err := jwt.Parse(tokenString, jwtKeyfunc, jwt.WithValidMethods(jwtValidMethods()))
+	if err != nil || !token.Valid {
+		c.JSON(http.StatusOK, gin.H{"active": false})
+		return
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{"active": false})
+		return
+	}
+
+	// Revocation checks mirror parseAccessToken: a token whose session
+	// was deleted or whose version was bumped must read as inactive, or
+	// sibling services keep honoring it after logout-all or a password
+	// reset.
+	if jti, ok := claims["jti"].(string); ok && !sessionActive(jti) {
+		c.JSON(http.StatusOK, gin.H{"active": false})
+		return
+	}
+	if sub, ok := claims["sub"].(float64); ok && !tokenVersionCurrent(uint(sub), claims) {
+		c.JSON(http.StatusOK, gin.H{"active": false})
+		return
+	}
+
+	response := gin.H{
+		"active":     true,
+		"token_type": "Bearer",
+	}
+	for _, k := range []string{"sub", "exp", "iat", "iss", "aud", "scope"} {
+		if v, present := claims[k]; present {
+			response[k] = v
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Persistent job queue. Jobs survive restarts in the jobs table;
+// workers claim them with row locking, retry with exponential backoff,
+// and park jobs that keep failing in a dead-letter state an admin can
+// inspect and retry. Delayed and recurring work rides the same table:
+// run_at is the timer (polled, so restarts need no timer rebuild) and
+// a recurrence re-enqueues the job after each successful run.
+
+const (
+	JobStatusPending = "pending"
+	JobStatusRunning = "running"
+	JobStatusDone    = "done"
+	JobStatusDead    = "dead"
+
+	jobPollInterval   = 2 * time.Second
+	jobDefaultRetries = 5
+	jobBaseBackoff    = 10 * time.Second
+)
+
+// JobHandler runs one job; returning an error schedules a retry.
+type JobHandler func(payload string) error
+
+var jobHandlers = map[string]JobHandler{}
+
+func RegisterJobHandler(jobType string, handler JobHandler) {
+	jobHandlers[jobType] = handler
+}
+
+// EnqueueJob persists a job for the workers to pick up.
+func EnqueueJob(jobType, payload string, runAt time.Time) (*models.Job, error) {
+	if _, ok := jobHandlers[jobType]; !ok {
+		return nil, fmt.Errorf("no handler registered for job type %q", jobType)
+	}
+	job := models.Job{Type: jobType, Payload: payload, Status: JobStatusPending, RunAt: runAt, MaxAttempts: jobDefaultRetries}
+	if err := DB.Create(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ScheduleJobIn enqueues a one-shot job to run after a delay — the
+// "expire this boost at T+2h" shape.
+func ScheduleJobIn(jobType, payload string, delay time.Duration) (*models.Job, error) {
+	return EnqueueJob(jobType, payload, time.Now().Add(delay))
+}
+
+// ScheduleRecurringJob enqueues a job that re-arms itself after each
+// successful run. The recurrence is either a Go duration ("30m") or
+// "weekly <weekday> <HH:MM>" in UTC ("weekly friday 20:00").
+func ScheduleRecurringJob(jobType, payload, recurrence string) (*models.Job, error) {
+	next, err := nextRecurrence(recurrence, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := jobHandlers[jobType]; !ok {
+		return nil, fmt.Errorf("no handler registered for job type %q", jobType)
+	}
+	job := models.Job{Type: jobType, Payload: payload, Status: JobStatusPending, RunAt: next,
+		MaxAttempts: jobDefaultRetries, Recurrence: recurrence}
+	if err := DB.Create(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// EnsureRecurringJob is ScheduleRecurringJob made idempotent for
+// startup registration: an existing live job of the type is left
+// alone.
+func EnsureRecurringJob(jobType, payload, recurrence string) error {
+	var count int64
+	err := DB.Model(&models.Job{}).
+		Where("type = ? AND status IN ?", jobType, []string{JobStatusPending, JobStatusRunning}).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	_, err = ScheduleRecurringJob(jobType, payload, recurrence)
+	return err
+}
+
+// CancelJob drops a job that hasn't run yet; used when the thing it
+// was going to act on no longer exists.
+func CancelJob(jobID uint) error {
+	res := DB.Where("id = ? AND status = ?", jobID, JobStatusPending).Delete(&models.Job{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("job %d is not pending", jobID)
+	}
+	return nil
+}
+
+// nextRecurrence computes the first occurrence strictly after the
+// given time.
+func nextRecurrence(spec string, after time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(spec); err == nil {
+		if d < time.Minute {
+			return time.Time{}, fmt.Errorf("recurrence below one minute")
+		}
+		return after.Add(d), nil
+	}
+
+	var weekday, clock string
+	if _, err := fmt.Sscanf(spec, "weekly %s %s", &weekday, &clock); err != nil {
+		return time.Time{}, fmt.Errorf("recurrence %q is neither a duration nor weekly <weekday> <HH:MM>", spec)
+	}
+	target, ok := weekdays[strings.ToLower(weekday)]
+	if !ok {
+		return time.Time{}, fmt.Errorf("unknown weekday %q", weekday)
+	}
+	at, err := time.Parse("15:04", clock)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("malformed time %q", clock)
+	}
+
+	after = after.UTC()
+	next := time.Date(after.Year(), after.Month(), after.Day(), at.Hour(), at.Minute(), 0, 0, time.UTC)
+	for next.Weekday() != target || !next.After(after) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next, nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+}
+
+// claimJob picks the oldest runnable job under a row lock so multiple
+// workers never run the same job twice.
+func claimJob() (*models.Job, error) {
+	var job models.Job
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		query := tx.Where("status = ? AND run_at <= ?", JobStatusPending, time.Now()).Order("run_at")
+		if usingPostgres() {
+			query = query.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+		}
+		if err := query.First(&job).Error; err != nil {
+			return err
+		}
+		return tx.Model(&job).Updates(map[string]interface{}{
+			"status":   JobStatusRunning,
+			"attempts": job.Attempts + 1,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	job.Attempts++
+	return &job, nil
+}
+
+func runJob(job *models.Job) {
+	handler, ok := jobHandlers[job.Type]
+	if !ok {
+		failJob(job, fmt.Errorf("no handler registered for %q", job.Type))
+		return
+	}
+
+	if err := handler(job.Payload); err != nil {
+		failJob(job, err)
+		return
+	}
+
+	if job.Recurrence != "" {
+		// Re-arm instead of completing. A recurrence that no longer
+		// parses (handler changed the rules) dead-letters for an admin.
+		next, err := nextRecurrence(job.Recurrence, time.Now())
+		if err != nil {
+			DB.Model(job).Updates(map[string]interface{}{"status": JobStatusDead, "last_error": err.Error()})
+			return
+		}
+		DB.Model(job).Updates(map[string]interface{}{
+			"status": JobStatusPending, "run_at": next, "attempts": 0, "last_error": "",
+		})
+		return
+	}
+	DB.Model(job).Updates(map[string]interface{}{"status": JobStatusDone, "last_error": ""})
+}
+
+// failJob either schedules a retry with exponential backoff or
+// dead-letters the job.
+func failJob(job *models.Job, cause error) {
+	updates := map[string]interface{}{"last_error": cause.Error()}
+	if job.Attempts >= job.MaxAttempts {
+		updates["status"] = JobStatusDead
+		log.Printf("jobs: %s #%d dead after %d attempts: %v", job.Type, job.ID, job.Attempts, cause)
+	} else {
+		backoff := time.Duration(math.Pow(2, float64(job.Attempts))) * jobBaseBackoff
+		updates["status"] = JobStatusPending
+		updates["run_at"] = time.Now().Add(backoff)
+	}
+	DB.Model(job).Updates(updates)
+}
+
+// StartJobWorker polls for runnable jobs.
+func StartJobWorker() {
+	go func() {
+		for {
+			job, err := claimJob()
+			if err != nil {
+				time.Sleep(jobPollInterval)
+				continue
+			}
+			runJob(job)
+		}
+	}()
+}
+
+func AdminListJobs(c *gin.Context) {
+	q := ValidateQuery(c)
+	status := q.Enum("status", "", JobStatusPending, JobStatusRunning, JobStatusDone, JobStatusDead)
+	limit := q.Int("limit", 100, 1, 1000)
+	if !q.Valid() {
+		return
+	}
+
+	query := RequestDB(c).Model(&models.Job{}).Order("run_at DESC").Limit(limit)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var jobs []models.Job
+	if err := query.Find(&jobs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, jobs)
+}
+
+// AdminRetryJob resurrects a dead job.
+func AdminRetryJob(c *gin.Context) {
+	var job models.Job
+	if err := DB.First(&job, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+		return
+	}
+	if job.Status != JobStatusDead {
+		c.JSON(http.StatusConflict, gin.H{"error": "only dead jobs can be retried"})
+		return
+	}
+
+	err := DB.Model(&job).Updates(map[string]interface{}{
+		"status":   JobStatusPending,
+		"attempts": 0,
+		"run_at":   time.Now(),
+	}).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, job)
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RFC 6902 JSON Patch, used for fine-grained edits to the game config
+// document. Only the document structures encoding/json produces
+// (map[string]interface{}, []interface{}) are supported, which is all
+// we store.
+
+type patchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into unescaped tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("pointer %q must start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		t = strings.ReplaceAll(t, "~1", "/")
+		t = strings.ReplaceAll(t, "~0", "~")
+		tokens[i] = t
+	}
+	return tokens, nil
+}
+
+// getAtPointer resolves a pointer to its value.
+func getAtPointer(doc interface{}, tokens []string) (interface{}, error) {
+	current := doc
+	for _, token := range tokens {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			v, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("path element %q not found", token)
+			}
+			current = v
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, fmt.Errorf("invalid array index %q", token)
+			}
+			current = node[i]
+		default:
+			return nil, fmt.Errorf("cannot descend into %T at %q", current, token)
+		}
+	}
+	return current, nil
+}
+
+// setAtPointer writes value at the pointer, inserting for arrays when
+// insert is true (add semantics) and replacing otherwise.
+func setAtPointer(doc interface{}, tokens []string, value interface{}, insert bool) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	parent, err := getAtPointer(doc, tokens[:len(tokens)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := tokens[len(tokens)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+		return doc, nil
+	case []interface{}:
+		if insert && last == "-" {
+			node = append(node, value)
+		} else {
+			i, err := strconv.Atoi(last)
+			if err != nil || i < 0 || i > len(node) {
+				return nil, fmt.Errorf("invalid array index %q", last)
+			}
+			if insert {
+				if i == len(node) {
+					node = append(node, value)
+				} else {
+					node = append(node[:i+1], node[i:]...)
+					node[i] = value
+				}
+			} else {
+				if i == len(node) {
+					return nil, fmt.Errorf("invalid array index %q", last)
+				}
+				node[i] = value
+			}
+		}
+		// The slice header may have moved; write it back into the
+		// grandparent.
+		return setAtPointer(doc, tokens[:len(tokens)-1], node, false)
+	default:
+		return nil, fmt.Errorf("cannot write into %T", parent)
+	}
+}
+
+// removeAtPointer deletes the value at the pointer.
+func removeAtPointer(doc interface{}, tokens []string) (interface{}, error) {
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("cannot remove the document root")
+	}
+	parent, err := getAtPointer(doc, tokens[:len(tokens)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := tokens[len(tokens)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		if _, ok := node[last]; !ok {
+			return nil, fmt.Errorf("path element %q not found", last)
+		}
+		delete(node, last)
+		return doc, nil
+	case []interface{}:
+		i, err := strconv.Atoi(last)
+		if err != nil || i < 0 || i >= len(node) {
+			return nil, fmt.Errorf("invalid array index %q", last)
+		}
+		node = append(node[:i], node[i+1:]...)
+		return setAtPointer(doc, tokens[:len(tokens)-1], node, false)
+	default:
+		return nil, fmt.Errorf("cannot remove from %T", parent)
+	}
+}
+
+func jsonEqual(a, b interface{}) bool {
+	ra, _ := json.Marshal(a)
+	rb, _ := json.Marshal(b)
+	return string(ra) == string(rb)
+}
+
+// applyJSONPatch applies a full patch atomically: any failing op
+// aborts the whole patch.
+func applyJSONPatch(doc interface{}, ops []patchOp) (interface{}, error) {
+	for i, op := range ops {
+		tokens, err := parsePointer(op.Path)
+		if err != nil {
+			return nil, fmt.Errorf("op %d: %w", i, err)
+		}
+
+		switch op.Op {
+		case "add", "replace":
+			var value interface{}
+			if err := json.Unmarshal(op.Value, &value); err != nil {
+				return nil, fmt.Errorf("op %d: invalid value: %w", i, err)
+			}
+			if op.Op == "replace" {
+				if _, err := getAtPointer(doc, tokens); err != nil {
+					return nil, fmt.Errorf("op %d: %w", i, err)
+				}
+			}
+			doc, err = setAtPointer(doc, tokens, value, op.Op == "add")
+		case "remove":
+			doc, err = removeAtPointer(doc, tokens)
+		case "move", "copy":
+			fromTokens, ferr := parsePointer(op.From)
+			if ferr != nil {
+				return nil, fmt.Errorf("op %d: %w", i, ferr)
+			}
+			value, gerr := getAtPointer(doc, fromTokens)
+			if gerr != nil {
+				return nil, fmt.Errorf("op %d: %w", i, gerr)
+			}
+			if op.Op == "move" {
+				if doc, err = removeAtPointer(doc, fromTokens); err != nil {
+					return nil, fmt.Errorf("op %d: %w", i, err)
+				}
+			}
+			doc, err = setAtPointer(doc, tokens, value, true)
+		case "test":
+			var expected interface{}
+			if err := json.Unmarshal(op.Value, &expected); err != nil {
+				return nil, fmt.Errorf("op %d: invalid value: %w", i, err)
+			}
+			actual, gerr := getAtPointer(doc, tokens)
+			if gerr != nil {
+				return nil, fmt.Errorf("op %d: %w", i, gerr)
+			}
+			if !jsonEqual(actual, expected) {
+				return nil, fmt.Errorf("op %d: test failed at %q", i, op.Path)
+			}
+		default:
+			return nil, fmt.Errorf("op %d: unsupported op %q", i, op.Op)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("op %d: %w", i, err)
+		}
+	}
+	return doc, nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKS publication for sibling services. The key set carries the
+// active signing key (see jwt_keys.go) plus any previous keys so
+// consumers verify tokens locally across rotations.
+//
+// JWT_PUBLIC_KEY_FILES is a comma-separated list of PEM files; the
+// first entry is the current key, the rest are previous keys kept
+// around so tokens signed before a rollover stay verifiable.
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+var publicJWKs = loadPublicJWKs()
+
+func loadPublicJWKs() []JWK {
+	files := os.Getenv("JWT_PUBLIC_KEY_FILES")
+	if files == "" {
+		return nil
+	}
+
+	var keys []JWK
+	for _, path := range strings.Split(files, ",") {
+		jwk, err := loadPublicJWK(strings.TrimSpace(path))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "jwks: skipping %s: %v\n", path, err)
+			continue
+		}
+		keys = append(keys, jwk)
+	}
+	return keys
+}
+
+func loadPublicJWK(path string) (JWK, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return JWK{}, err
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return JWK{}, fmt.Errorf("no PEM block found")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return JWK{}, err
+	}
+	return publicKeyToJWK(pub)
+}
+
+func publicKeyToJWK(pub interface{}) (JWK, error) {
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			Kid: keyID(key.N.Bytes()),
+			Use: "sig",
+			Alg: "RS256",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}, nil
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Kid: keyID(key),
+			Use: "sig",
+			Alg: "EdDSA",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(key),
+		}, nil
+	default:
+		return JWK{}, fmt.Errorf("unsupported public key type %T", pub)
+	}
+}
+
+// keyID derives a stable kid from the key material so rollovers don't
+// need coordinated naming.
+func keyID(material []byte) string {
+	sum := sha256.Sum256(material)
+	return base64.RawURLEncoding.EncodeToString(sum[:8])
+}
+
+// GetJWKS serves the published key set with cache headers sized so
+// consumers re-fetch within an hour of a rollover.
+func GetJWKS(c *gin.Context) {
+	published := publishedJWKs()
+	if len(published) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "asymmetric signing is not enabled"})
+		return
+	}
+
+	set := JWKSet{Keys: published}
+	body, _ := json.Marshal(set)
+	sum := sha256.Sum256(body)
+	etag := fmt.Sprintf("%q", base64.RawURLEncoding.EncodeToString(sum[:8]))
+
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.Header("ETag", etag)
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json", body)
+}
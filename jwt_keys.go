@@ -0,0 +1,210 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Asymmetric token signing. With JWT_SIGNING_ALG set to RS256 or EdDSA
+// and JWT_PRIVATE_KEY_FILE pointing at a PKCS#8 PEM key, new tokens are
+// signed asymmetrically and carry a kid header; sibling services verify
+// them against /.well-known/jwks.json without ever seeing the HMAC
+// secret. Verification accepts the active key, every key listed in
+// JWT_PUBLIC_KEY_FILES (so a rotation keeps old tokens valid until they
+// expire), and HS256 tokens minted before the cutover.
+
+type signingKey struct {
+	Alg     string
+	Kid     string
+	Private crypto.PrivateKey
+	Public  interface{}
+}
+
+var (
+	signingKeyOnce sync.Once
+	activeKey      *signingKey
+	// verificationKeys maps kid to public key for asymmetric tokens.
+	verificationKeys map[string]interface{}
+)
+
+// loadSigningKeys reads the configured private key once and builds the
+// kid-indexed verification set. A missing or bad key logs and leaves
+// the server on HS256 rather than failing startup.
+func loadSigningKeys() {
+	verificationKeys = make(map[string]interface{})
+
+	alg := os.Getenv("JWT_SIGNING_ALG")
+	path := os.Getenv("JWT_PRIVATE_KEY_FILE")
+	if alg != "" && alg != "HS256" && path != "" {
+		key, err := loadPrivateSigningKey(alg, path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "jwt: staying on HS256: %v\n", err)
+		} else {
+			activeKey = key
+			verificationKeys[key.Kid] = key.Public
+		}
+	}
+
+	// Previous keys published for JWKS stay valid for verification
+	// through the rotation window.
+	for _, jwk := range publicJWKs {
+		if _, ok := verificationKeys[jwk.Kid]; ok {
+			continue
+		}
+		if pub, err := jwkPublicKey(jwk); err == nil {
+			verificationKeys[jwk.Kid] = pub
+		}
+	}
+}
+
+func loadPrivateSigningKey(alg, path string) (*signingKey, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block found", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		if alg != "RS256" {
+			return nil, fmt.Errorf("%s holds an RSA key but JWT_SIGNING_ALG is %s", path, alg)
+		}
+		return &signingKey{Alg: alg, Kid: keyID(key.N.Bytes()), Private: key, Public: &key.PublicKey}, nil
+	case ed25519.PrivateKey:
+		if alg != "EdDSA" {
+			return nil, fmt.Errorf("%s holds an Ed25519 key but JWT_SIGNING_ALG is %s", path, alg)
+		}
+		pub := key.Public().(ed25519.PublicKey)
+		return &signingKey{Alg: alg, Kid: keyID(pub), Private: key, Public: pub}, nil
+	default:
+		return nil, fmt.Errorf("%s: unsupported private key type %T", path, parsed)
+	}
+}
+
+// reloadSigningKeys re-reads the key material. Promotion calls this so
+// a standby flipped to primary starts issuing tokens with the signing
+// key staged on its own disk.
+func reloadSigningKeys() {
+	signingKeyOnce.Do(func() {})
+	activeKey = nil
+	loadSigningKeys()
+}
+
+// currentSigningKey returns the active asymmetric key, or nil when the
+// server signs with HS256.
+func currentSigningKey() *signingKey {
+	signingKeyOnce.Do(loadSigningKeys)
+	return activeKey
+}
+
+// jwtValidMethods lists every algorithm a presented token may name.
+// HS256 stays accepted through the cutover so outstanding tokens keep
+// working.
+func jwtValidMethods() []string {
+	methods := []string{"HS256"}
+	signingKeyOnce.Do(loadSigningKeys)
+	seen := map[string]bool{}
+	if activeKey != nil {
+		methods = append(methods, activeKey.Alg)
+		seen[activeKey.Alg] = true
+	}
+	for _, jwk := range publicJWKs {
+		if jwk.Alg != "" && jwk.Alg != "HS256" && !seen[jwk.Alg] {
+			methods = append(methods, jwk.Alg)
+			seen[jwk.Alg] = true
+		}
+	}
+	return methods
+}
+
+// jwtKeyfunc resolves the verification key for a presented token: the
+// HMAC secret for HS256, otherwise the public key named by the kid
+// header.
+func jwtKeyfunc(t *jwt.Token) (interface{}, error) {
+	signingKeyOnce.Do(loadSigningKeys)
+
+	if _, ok := t.Method.(*jwt.SigningMethodHMAC); ok {
+		return jwtSecret, nil
+	}
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" {
+		return nil, fmt.Errorf("asymmetric token missing kid header")
+	}
+	pub, ok := verificationKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return pub, nil
+}
+
+// jwkPublicKey rebuilds a verification key from a published JWK entry.
+func jwkPublicKey(jwk JWK) (interface{}, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported JWK type %q", jwk.Kty)
+	}
+}
+
+// publishedJWKs is what /.well-known/jwks.json serves: the active key
+// first, then every key kept around from JWT_PUBLIC_KEY_FILES.
+func publishedJWKs() []JWK {
+	signingKeyOnce.Do(loadSigningKeys)
+	if activeKey == nil {
+		return publicJWKs
+	}
+	for _, jwk := range publicJWKs {
+		if jwk.Kid == activeKey.Kid {
+			return publicJWKs
+		}
+	}
+	jwk, err := publicKeyToJWK(activeKey.Public)
+	if err != nil {
+		return publicJWKs
+	}
+	return append([]JWK{jwk}, publicJWKs...)
+}
+
+// signToken signs claims with the active key, stamping the kid header
+// on asymmetric tokens.
+func signToken(claims jwt.MapClaims) (string, error) {
+	key := currentSigningKey()
+	if key == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtSecret)
+	}
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Alg), claims)
+	token.Header["kid"] = key.Kid
+	return token.SignedString(key.Private)
+}
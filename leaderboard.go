@@ -0,0 +1,86 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Leaderboards are served from a derived read model instead of ranking
+// the players table per request. On Postgres that model is a
+// materialized view refreshed concurrently in the background; on other
+// dialects we fall back to rebuilding a plain rollup table.
+
+const leaderboardRefreshInterval = time.Minute
+
+const leaderboardQuery = `
+SELECT ROW_NUMBER() OVER (ORDER BY players.gold DESC, players.id) AS rank,
+       players.id AS player_id,
+       users.name AS name,
+       players.gold AS gold
+FROM players
+JOIN users ON users.id = players.user_id
+WHERE players.deleted_at IS NULL AND users.anonymized_at IS NULL`
+
+func usingPostgres() bool {
+	return DB.Dialector.Name() == "postgres"
+}
+
+// EnsureLeaderboard creates the read model if it does not exist yet.
+func EnsureLeaderboard() error {
+	if usingPostgres() {
+		if err := DB.Exec("CREATE MATERIALIZED VIEW IF NOT EXISTS leaderboard_gold AS " + leaderboardQuery).Error; err != nil {
+			return err
+		}
+		// A unique index is required for REFRESH ... CONCURRENTLY.
+		return DB.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_leaderboard_gold_player ON leaderboard_gold (player_id)").Error
+	}
+	return DB.AutoMigrate(&models.LeaderboardEntry{})
+}
+
+// RefreshLeaderboard rebuilds the read model from the source tables.
+func RefreshLeaderboard() error {
+	if usingPostgres() {
+		return DB.Exec("REFRESH MATERIALIZED VIEW CONCURRENTLY leaderboard_gold").Error
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM leaderboard_gold").Error; err != nil {
+			return err
+		}
+		return tx.Exec("INSERT INTO leaderboard_gold (rank, player_id, name, gold) " + leaderboardQuery).Error
+	})
+}
+
+func StartLeaderboardRefresh() {
+	if err := EnsureLeaderboard(); err != nil {
+		log.Printf("leaderboard: ensure failed: %v", err)
+		return
+	}
+	go func() {
+		for {
+			time.Sleep(leaderboardRefreshInterval)
+			if err := RefreshLeaderboard(); err != nil {
+				log.Printf("leaderboard: refresh failed: %v", err)
+			}
+		}
+	}()
+}
+
+func GetLeaderboard(c *gin.Context) {
+	q := ValidateQuery(c)
+	limit := q.Int("limit", 50, 1, 200)
+	if !q.Valid() {
+		return
+	}
+
+	var entries []models.LeaderboardEntry
+	if err := ReadRequestDB(c).Order("rank").Limit(limit).Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, entries)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Long-polling fallback for state changes. Clients that can't hold a
+// WebSocket open call GET /game/poll with the version they last saw;
+// the request parks until the player's state version moves past it or
+// the poll window expires with a 304.
+
+const (
+	longPollTimeout  = 25 * time.Second
+	longPollInterval = 500 * time.Millisecond
+)
+
+type stateNotifier struct {
+	mu          sync.Mutex
+	versions    map[uint]uint64
+	waiters     map[uint][]chan uint64
+	subscribers map[uint]map[chan uint64]bool
+}
+
+var notifier = stateNotifier{
+	versions:    make(map[uint]uint64),
+	waiters:     make(map[uint][]chan uint64),
+	subscribers: make(map[uint]map[chan uint64]bool),
+}
+
+// Bump marks the user's state as changed, wakes any parked polls and
+// live subscriptions here, and fans the bump out to the other
+// instances (see ws_fanout.go).
+func (n *stateNotifier) Bump(userID uint) uint64 {
+	v := n.bump(userID)
+	publishBump(userID)
+	return v
+}
+
+// bump is the local half of Bump; remote bumps arriving over the
+// fan-out land here so they don't echo back out.
+func (n *stateNotifier) bump(userID uint) uint64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.versions[userID]++
+	v := n.versions[userID]
+	for _, ch := range n.waiters[userID] {
+		select {
+		case ch <- v:
+		default:
+		}
+	}
+	delete(n.waiters, userID)
+
+	for ch := range n.subscribers[userID] {
+		select {
+		case ch <- v:
+		default:
+		}
+	}
+	return v
+}
+
+// Subscribe returns a channel fed every state bump for the user until
+// the returned cancel function runs.
+func (n *stateNotifier) Subscribe(userID uint) (chan uint64, func()) {
+	ch := make(chan uint64, 16)
+	n.mu.Lock()
+	if n.subscribers[userID] == nil {
+		n.subscribers[userID] = make(map[chan uint64]bool)
+	}
+	n.subscribers[userID][ch] = true
+	n.mu.Unlock()
+
+	return ch, func() {
+		n.mu.Lock()
+		delete(n.subscribers[userID], ch)
+		n.mu.Unlock()
+	}
+}
+
+func (n *stateNotifier) Version(userID uint) uint64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.versions[userID]
+}
+
+func (n *stateNotifier) wait(userID uint) chan uint64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	ch := make(chan uint64, 1)
+	n.waiters[userID] = append(n.waiters[userID], ch)
+	return ch
+}
+
+// PollGameState parks until the caller's state version advances beyond
+// ?version= or the window lapses.
+func PollGameState(c *gin.Context) {
+	q := ValidateQuery(c)
+	since := q.Int("version", 0, 0, 1<<30)
+	if !q.Valid() {
+		return
+	}
+
+	userID := CurrentUserID(c)
+	current := notifier.Version(userID)
+	if current > uint64(since) {
+		respondWithState(c, current)
+		return
+	}
+
+	ch := notifier.wait(userID)
+	select {
+	case v := <-ch:
+		respondWithState(c, v)
+	case <-time.After(longPollTimeout):
+		c.Status(http.StatusNotModified)
+	case <-c.Request.Context().Done():
+		c.Status(http.StatusNotModified)
+	}
+}
+
+func respondWithState(c *gin.Context, version uint64) {
+	var player models.Player
+	if err := RequestDB(c).Where("user_id = ?", CurrentUserID(c)).First(&player).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "player not found"})
+		return
+	}
+	RenderJSON(c, http.StatusOK, gin.H{"version": version, "state": player})
+}
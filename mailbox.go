@@ -0,0 +1,200 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/bernardmuller/go-idle/api"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Player mailbox. Unlike the broadcast copies in messaging.go (plain
+// text, no state), mailbox messages are first-class per-user rows with
+// read/unread state, optional expiry, and reward attachments: gold is
+// credited to the player's wallet and a rate bonus to their gold rate
+// when the message is claimed, each exactly once via a guarded update.
+// Admin campaigns fan a templated message out over the same player
+// segments the broadcast system uses.
+
+// GetMyMailbox pages through the caller's live (unexpired) messages,
+// newest first, with the unread count alongside so clients can badge.
+func GetMyMailbox(c *gin.Context) {
+	q := ValidateQuery(c)
+	page := q.Int("page", 1, 1, 10000)
+	perPage := q.Int("per_page", 50, 1, 200)
+	unreadOnly := q.Enum("filter", "all", "all", "unread") == "unread"
+	if !q.Valid() {
+		return
+	}
+
+	userID := CurrentUserID(c)
+	now := time.Now()
+	liveCond := "user_id = ? AND (expires_at IS NULL OR expires_at > ?)"
+	db := RequestDB(c)
+
+	var unread int64
+	db.Model(&models.MailboxMessage{}).
+		Where(liveCond, userID, now).Where("read_at IS NULL").Count(&unread)
+
+	listed := db.Model(&models.MailboxMessage{}).Where(liveCond, userID, now)
+	if unreadOnly {
+		listed = listed.Where("read_at IS NULL")
+	}
+	// Session so the chain can serve both the count and the page fetch.
+	listed = listed.Session(&gorm.Session{})
+	var total int64
+	listed.Count(&total)
+
+	var messages []models.MailboxMessage
+	err := listed.Order("created_at DESC").
+		Offset((page - 1) * perPage).Limit(perPage).
+		Find(&messages).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, gin.H{
+		"messages": messages,
+		"meta":     gin.H{"page": page, "per_page": perPage, "total": total, "unread": unread},
+	})
+}
+
+// ReadMailboxMessage marks one message read; reading again is a no-op.
+func ReadMailboxMessage(c *gin.Context) {
+	id := c.Param("id")
+	res := RequestDB(c).Model(&models.MailboxMessage{}).
+		Where("id = ? AND user_id = ? AND read_at IS NULL", id, CurrentUserID(c)).
+		Update("read_at", time.Now())
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": res.Error.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, gin.H{"read": res.RowsAffected > 0})
+}
+
+// ClaimMailboxMessage claims a message's attachments. The claim is
+// burned first under a guard on claimed_at and expiry, so a double
+// claim (or a claim racing expiry) pays out nothing.
+func ClaimMailboxMessage(c *gin.Context) {
+	userID := CurrentUserID(c)
+
+	var message models.MailboxMessage
+	err := RequestDB(c).
+		Where("id = ? AND user_id = ?", c.Param("id"), userID).
+		First(&message).Error
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "message not found"})
+		return
+	}
+	if message.Gold == 0 && message.GoldRate == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "message has no attachment"})
+		return
+	}
+
+	now := time.Now()
+	res := RequestDB(c).Model(&models.MailboxMessage{}).
+		Where("id = ? AND claimed_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", message.ID, now).
+		Update("claimed_at", now)
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": res.Error.Error()})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "message already claimed or expired"})
+		return
+	}
+
+	var player models.Player
+	if err := RequestDB(c).Where("user_id = ?", userID).First(&player).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no player state; start the game first"})
+		return
+	}
+	err = RequestDB(c).Model(&models.Player{}).Where("id = ?", player.ID).
+		Updates(map[string]interface{}{
+			"gold":      gorm.Expr("gold + ?", message.Gold),
+			"gold_rate": gorm.Expr("gold_rate + ?", message.GoldRate),
+		}).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if message.Gold != 0 {
+		bulk.AddGameEvent(models.GameEvent{PlayerID: player.ID, Type: "mailbox_claim", Amount: message.Gold, CreatedAt: now})
+	}
+	RenderJSON(c, http.StatusOK, gin.H{"claimed": true, "gold": message.Gold, "gold_rate": message.GoldRate})
+}
+
+type mailboxCampaignInput struct {
+	Title         string `json:"title" binding:"required,min=2,max=200"`
+	Body          string `json:"body" binding:"required,max=5000"`
+	Segment       string `json:"segment" binding:"required"`
+	Gold          int64  `json:"gold" binding:"min=0"`
+	GoldRate      int64  `json:"gold_rate" binding:"min=0"`
+	ExpiresInDays int    `json:"expires_in_days" binding:"min=0,max=365"`
+}
+
+// AdminSendMailboxCampaign fans a mailbox message (with optional
+// attachments and expiry) out to a player segment.
+func AdminSendMailboxCampaign(c *gin.Context) {
+	var input mailboxCampaignInput
+	if !api.BindJSON(c, &input) {
+		return
+	}
+
+	ids, err := segmentUserIDs(input.Segment)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if isDryRun(c) {
+		RenderJSON(c, http.StatusOK, gin.H{"dry_run": true, "segment": input.Segment, "would_send": len(ids)})
+		return
+	}
+
+	now := time.Now()
+	var expiresAt *time.Time
+	if input.ExpiresInDays > 0 {
+		t := now.AddDate(0, 0, input.ExpiresInDays)
+		expiresAt = &t
+	}
+	messages := make([]models.MailboxMessage, 0, len(ids))
+	for _, id := range ids {
+		messages = append(messages, models.MailboxMessage{
+			UserID:    id,
+			Title:     input.Title,
+			Body:      input.Body,
+			Gold:      input.Gold,
+			GoldRate:  input.GoldRate,
+			ExpiresAt: expiresAt,
+			CreatedAt: now,
+		})
+	}
+	if len(messages) > 0 {
+		if err := RequestDB(c).CreateInBatches(messages, bulkInsertBatch).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	RenderJSON(c, http.StatusCreated, gin.H{"segment": input.Segment, "sent": len(messages)})
+}
+
+// SendMailboxMessage delivers one system message to one user; internal
+// callers (reward flows, support tooling) use this rather than writing
+// rows themselves.
+func SendMailboxMessage(userID uint, title, body string, gold, goldRate int64, ttl time.Duration) error {
+	message := models.MailboxMessage{
+		UserID:    userID,
+		Title:     title,
+		Body:      body,
+		Gold:      gold,
+		GoldRate:  goldRate,
+		CreatedAt: time.Now(),
+	}
+	if ttl > 0 {
+		t := time.Now().Add(ttl)
+		message.ExpiresAt = &t
+	}
+	return DB.Create(&message).Error
+}
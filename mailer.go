@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+)
+
+// Outbound email. With SMTP_ADDR configured mail goes out over SMTP
+// (optionally authenticated); otherwise messages are logged, which
+// keeps dev flows like password reset usable without a mail server.
+
+func sendEmail(to, subject, body string) error {
+	addr := os.Getenv("SMTP_ADDR")
+	from := os.Getenv("SMTP_FROM")
+	if from == "" {
+		from = "noreply@go-idle.local"
+	}
+
+	if addr == "" {
+		log.Printf("mailer: (no SMTP configured) to=%s subject=%q\n%s", to, subject, body)
+		return nil
+	}
+
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s", from, to, subject, body)
+
+	var auth smtp.Auth
+	if user := os.Getenv("SMTP_USER"); user != "" {
+		host, _, _ := splitHostPort(addr)
+		auth = smtp.PlainAuth("", user, os.Getenv("SMTP_PASSWORD"), host)
+	}
+	return smtp.SendMail(addr, auth, from, []string{to}, []byte(msg))
+}
+
+func splitHostPort(addr string) (string, string, error) {
+	for i := len(addr) - 1; i >= 0; i-- {
+		if addr[i] == ':' {
+			return addr[:i], addr[i+1:], nil
+		}
+	}
+	return addr, "", nil
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"log"
+	"os"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/bernardmuller/go-idle/config"
+	"github.com/bernardmuller/go-idle/hooks"
+	"github.com/bernardmuller/go-idle/internal/handlers"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/bernardmuller/go-idle/internal/repository"
+	"github.com/bernardmuller/go-idle/internal/services"
+
+	// Deployment-specific plugins register themselves via the hooks
+	// package from init; see plugins/plugins.go.
+	_ "github.com/bernardmuller/go-idle/plugins"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	jwtSecret = []byte(cfg.JWTSecret)
+
+	if len(os.Args) > 2 && os.Args[1] == "db" {
+		switch os.Args[2] {
+		case "diff":
+			runDBDiff()
+		case "migrate":
+			runDBMigrate()
+		case "rollback":
+			runDBRollback()
+		default:
+			log.Fatalf("unknown db subcommand %q (want diff, migrate, or rollback)", os.Args[2])
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "clients" {
+		runClientsCommand(os.Args[2:])
+		return
+	}
+	for _, arg := range os.Args[1:] {
+		if arg == "--mock" {
+			runMockServer(cfg)
+			return
+		}
+	}
+
+	ConnectDatabase()
+	ConnectReadReplica()
+	ConnectResidencyDatabases()
+	ConnectSandboxDatabase()
+	enforceSchemaDrift()
+	registerDefaultClaimsHooks()
+	registerConsistencyJob()
+	registerWebhookJob()
+	registerEventSubscribers()
+	registerFunnelSubscriber()
+	registerBillingSaga()
+	registerBackupJob()
+	SeedDefaultRoles()
+	SyncPermissionCatalog()
+	SeedFunnelSteps()
+	WarmCachesFromProfile()
+	notificationRouter = NewNotificationRouter(inAppChannel{}, emailChannel{}, pushChannel{}, wsChannel{})
+
+	r := buildRouter(cfg)
+	StartAdminMTLSListener(r)
+
+	// The health sampler only reads, so it runs everywhere.
+	StartHealthSampler()
+	StartDSNFailover()
+	StartSIEMExporter()
+	StartConfigReplication()
+	StartGRPCServer()
+	StartTraceExporter()
+	StartWSFanout()
+
+	// Background writers stay off on read-only replicas and unpromoted
+	// standbys; the primary owns purges and view refreshes.
+	if !readOnlyMode() {
+		startPrimaryBackground()
+	}
+
+	runServer(cfg, r)
+}
+
+// primaryBackgroundOnce keeps the write-side loops single-start even
+// when a standby is promoted after boot.
+var primaryBackgroundOnce sync.Once
+
+// startPrimaryBackground launches the background work only the primary
+// may do; it runs at boot on the primary and again on promotion.
+func startPrimaryBackground() {
+	primaryBackgroundOnce.Do(func() {
+		RecoverSagas()
+		StartDeletionPurge()
+		StartInactivityCleanup()
+		StartBulkWriter()
+		StartLeaderboardRefresh()
+		StartHeartbeatFlusher()
+		StartJobWorker()
+		StartCacheProfilePersist()
+		StartPartitionMaintenance()
+		StartBackupScheduler()
+		StartTelemetryTiering()
+		StartUsageMetering()
+		StartAPIKeyRotation()
+	})
+}
+
+// buildRouter wires repositories, services and handlers onto a fresh
+// engine. It is the single place the route table lives, shared by the
+// real server and the integration tests, and assumes the database is
+// already connected.
+func buildRouter(cfg *config.Config) *gin.Engine {
+	// Layered wiring: repositories own the queries, services own the
+	// rules, and main connects them to the cross-cutting machinery
+	// (caching, bulk writes, long-poll notifications) that stays here.
+	userHandlers := &handlers.UserHandlers{
+		// User listings are read-only and tolerate replica lag, so the
+		// repository behind them reads from the replica when one is
+		// configured.
+		Service: &services.UserService{
+			Users:      &repository.UserRepository{DB: ReadDB()},
+			LoadCached: loadUserCached,
+		},
+		HasPermission: userHasPermission,
+		SharesOrg:     userSharesOrg,
+	}
+	gameService := &services.GameService{
+		Players: &repository.PlayerRepository{DB: DB},
+		OnTick: func(player *models.Player, earned int64) {
+			bulk.AddGameEvent(models.GameEvent{PlayerID: player.ID, Type: "tick", Amount: earned, CreatedAt: player.LastTickAt})
+			notifier.Bump(player.UserID)
+			funnelTouch(player.UserID, "game.tick")
+			hooks.RunGameAction(player.UserID, "tick", earned)
+		},
+	}
+	if eventSourcingEnabled() {
+		// The log is written synchronously and becomes the source of
+		// truth; OnTick only has to notify.
+		gameService.AppendEvent = appendGameEvent
+		gameService.OnTick = func(player *models.Player, earned int64) {
+			notifier.Bump(player.UserID)
+			funnelTouch(player.UserID, "game.tick")
+			hooks.RunGameAction(player.UserID, "tick", earned)
+		}
+	}
+	gameHandlers := &handlers.GameHandlers{Service: gameService}
+	authService = &services.AuthService{
+		Users:        &repository.UserRepository{DB: DB},
+		HashPassword: hashPassword,
+	}
+
+	r := gin.New()
+	r.Use(gin.Recovery())
+	r.Use(RequestID)
+	r.Use(TracingMiddleware)
+	r.Use(MetricsMiddleware)
+	r.Use(ServerTimingMiddleware)
+	r.Use(CapacityHints)
+	if len(cfg.CORSOrigins) > 0 {
+		r.Use(CORSMiddleware)
+	}
+	r.Use(LogRequests)
+	r.Use(ChaosMiddleware)
+	r.Use(ClientVersionGate)
+	r.Use(MaintenanceGuard)
+	r.Use(StatementBudgetGuard)
+	r.Use(ReadOnlyGuard)
+	r.Use(RecordExchanges)
+	r.Use(SchemaCheckMiddleware)
+	r.Use(ResponseSizeLimit)
+	r.Use(RequestTimeouts)
+	r.Use(VerifyRequestSignature)
+	r.Use(SandboxRouter)
+	r.Use(DeprecationTelemetry)
+
+	authorized := r.Group("/", Authenticate, RateLimitByPlan, OrgScope)
+	table := routeTable(userHandlers, gameHandlers)
+	verifyRoutePermissions(table)
+	mountRoutes(r, authorized, table)
+
+	// The catalog CRUD scaffolding registers its own routes per entity.
+	admin := authorized.Group("/admin", RequirePermission("admin"), AdminMTLSGuard)
+	mountCatalog[models.Item](admin, "items")
+	mountCatalog[models.Upgrade](admin, "upgrades")
+	mountCatalog[models.Achievement](admin, "achievements")
+	mountCatalog[models.Announcement](admin, "announcements")
+
+	appRouter = r
+	return r
+}
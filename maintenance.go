@@ -0,0 +1,178 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bernardmuller/go-idle/api"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Scheduled maintenance. Admins announce windows ahead of time; the
+// public GET /status endpoint exposes the next one with a countdown,
+// WebSocket clients get countdown pushes, and once the window starts
+// the API flips into maintenance mode by itself: everything but
+// /status and the admin surface answers 503 until the window ends.
+
+const maintenanceCacheTTL = 30 * time.Second
+
+// maintenanceCache keeps the middleware off the database; windows
+// change rarely and a 30s lag on schedule edits is fine.
+var maintenanceCache struct {
+	mu      sync.Mutex
+	fetched time.Time
+	windows []models.MaintenanceWindow
+}
+
+func maintenanceWindows() []models.MaintenanceWindow {
+	maintenanceCache.mu.Lock()
+	defer maintenanceCache.mu.Unlock()
+
+	if time.Since(maintenanceCache.fetched) < maintenanceCacheTTL {
+		return maintenanceCache.windows
+	}
+	var windows []models.MaintenanceWindow
+	if err := DB.Where("ends_at > ?", time.Now()).Order("starts_at").Find(&windows).Error; err != nil {
+		return maintenanceCache.windows
+	}
+	maintenanceCache.windows = windows
+	maintenanceCache.fetched = time.Now()
+	return windows
+}
+
+func invalidateMaintenanceCache() {
+	maintenanceCache.mu.Lock()
+	maintenanceCache.fetched = time.Time{}
+	maintenanceCache.mu.Unlock()
+}
+
+// nextMaintenanceWindow returns the active window, or failing that the
+// nearest upcoming one.
+func nextMaintenanceWindow() *models.MaintenanceWindow {
+	now := time.Now()
+	windows := maintenanceWindows()
+	var upcoming *models.MaintenanceWindow
+	for i := range windows {
+		w := &windows[i]
+		if !w.StartsAt.After(now) && w.EndsAt.After(now) {
+			return w
+		}
+		if w.StartsAt.After(now) && upcoming == nil {
+			upcoming = w
+		}
+	}
+	return upcoming
+}
+
+func maintenanceActive(w *models.MaintenanceWindow) bool {
+	if w == nil {
+		return false
+	}
+	now := time.Now()
+	return !w.StartsAt.After(now) && w.EndsAt.After(now)
+}
+
+// GetStatus is the public status endpoint: current mode, per-component
+// health over the last day, open incidents, plus the upcoming
+// maintenance window with a countdown, if any.
+func GetStatus(c *gin.Context) {
+	w := nextMaintenanceWindow()
+	resp := gin.H{
+		"status":      "ok",
+		"server_time": time.Now(),
+		"components":  componentStatus(),
+		"incidents":   openIncidents(),
+	}
+	if w != nil {
+		resp["maintenance"] = gin.H{
+			"starts_at": w.StartsAt,
+			"ends_at":   w.EndsAt,
+			"message":   w.Message,
+		}
+		if maintenanceActive(w) {
+			resp["status"] = "maintenance"
+			resp["seconds_until_end"] = int64(time.Until(w.EndsAt).Seconds())
+		} else {
+			resp["seconds_until_start"] = int64(time.Until(w.StartsAt).Seconds())
+		}
+	}
+	RenderJSON(c, http.StatusOK, resp)
+}
+
+// MaintenanceGuard serves 503 during an active window. /status stays
+// up so clients can show the countdown, and the admin surface stays up
+// so operators can work during the window.
+func MaintenanceGuard(c *gin.Context) {
+	path := c.Request.URL.Path
+	if path == "/status" || path == "/healthz" || path == "/readyz" || path == "/metrics" || strings.HasPrefix(path, "/admin") {
+		c.Next()
+		return
+	}
+	w := nextMaintenanceWindow()
+	if !maintenanceActive(w) {
+		c.Next()
+		return
+	}
+	c.Header("Retry-After", strconv.FormatInt(int64(time.Until(w.EndsAt).Seconds())+1, 10))
+	c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+		"error":   "down for scheduled maintenance",
+		"message": w.Message,
+		"ends_at": w.EndsAt,
+	})
+}
+
+type MaintenanceInput struct {
+	StartsAt time.Time `json:"starts_at" binding:"required"`
+	EndsAt   time.Time `json:"ends_at" binding:"required"`
+	Message  string    `json:"message"`
+}
+
+func AdminListMaintenance(c *gin.Context) {
+	var windows []models.MaintenanceWindow
+	if err := RequestDB(c).Order("starts_at").Find(&windows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, windows)
+}
+
+func AdminScheduleMaintenance(c *gin.Context) {
+	var input MaintenanceInput
+	if !api.BindJSON(c, &input) {
+		return
+	}
+	if !input.EndsAt.After(input.StartsAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ends_at must be after starts_at"})
+		return
+	}
+	if input.EndsAt.Before(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "window is entirely in the past"})
+		return
+	}
+
+	window := models.MaintenanceWindow{StartsAt: input.StartsAt, EndsAt: input.EndsAt, Message: input.Message}
+	if err := RequestDB(c).Create(&window).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	invalidateMaintenanceCache()
+	RenderJSON(c, http.StatusCreated, window)
+}
+
+func AdminCancelMaintenance(c *gin.Context) {
+	var window models.MaintenanceWindow
+	if err := RequestDB(c).First(&window, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "maintenance window not found"})
+		return
+	}
+	if err := RequestDB(c).Delete(&window).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	invalidateMaintenanceCache()
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Admin bulk messaging. An admin posts a message against a player
+// segment; the segment is resolved to user ids up front and a delivery
+// row is fanned out per recipient so clients can fetch what's theirs.
+
+type BulkMessageInput struct {
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+	Segment string `json:"segment"`
+
+	// Marketing messages only reach users who opted in; operational
+	// announcements go to the whole segment.
+	Marketing bool `json:"marketing"`
+}
+
+// segmentUserIDs resolves a segment expression to user ids. Supported:
+// "all", "active_7d", "inactive_30d", and "plan:<name>".
+func segmentUserIDs(segment string) ([]uint, error) {
+	var ids []uint
+	base := DB.Model(&models.User{}).Where("anonymized_at IS NULL")
+
+	switch {
+	case segment == "all":
+		// no extra filter
+	case segment == "active_7d":
+		base = base.Where("id IN (?)",
+			DB.Model(&models.Player{}).Select("user_id").Where("last_tick_at >= ?", time.Now().AddDate(0, 0, -7)))
+	case segment == "inactive_30d":
+		base = base.Where("id IN (?)",
+			DB.Model(&models.Player{}).Select("user_id").Where("last_tick_at < ?", time.Now().AddDate(0, 0, -30)))
+	case strings.HasPrefix(segment, "plan:"):
+		plan := strings.TrimPrefix(segment, "plan:")
+		if _, ok := planQuotas[plan]; !ok && plan != "free" {
+			return nil, fmt.Errorf("unknown plan %q", plan)
+		}
+		base = base.Where("id IN (?)",
+			DB.Model(&models.Membership{}).Select("memberships.user_id").
+				Joins("JOIN organizations ON organizations.id = memberships.organization_id").
+				Where("organizations.plan = ?", plan))
+	default:
+		return nil, fmt.Errorf("unknown segment %q", segment)
+	}
+
+	err := base.Pluck("id", &ids).Error
+	return ids, err
+}
+
+// AdminSendBulkMessage fans a message out to every user in a segment.
+func AdminSendBulkMessage(c *gin.Context) {
+	var input BulkMessageInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Title == "" || input.Body == "" || input.Segment == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title, body, and segment are required"})
+		return
+	}
+
+	ids, err := segmentUserIDs(input.Segment)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Marketing {
+		var consented []uint
+		if err := RequestDB(c).Model(&models.User{}).
+			Where("id IN ? AND consent_marketing = ?", ids, true).
+			Pluck("id", &consented).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		ids = consented
+	}
+
+	if isDryRun(c) {
+		RenderJSON(c, http.StatusOK, gin.H{
+			"dry_run":    true,
+			"segment":    input.Segment,
+			"would_send": len(ids),
+		})
+		return
+	}
+
+	message := models.AdminMessage{Title: input.Title, Body: input.Body, Segment: input.Segment, Sent: len(ids)}
+	if err := RequestDB(c).Create(&message).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	deliveries := make([]models.UserMessage, 0, len(ids))
+	for _, id := range ids {
+		deliveries = append(deliveries, models.UserMessage{AdminMessageID: message.ID, UserID: id, CreatedAt: now})
+	}
+	if len(deliveries) > 0 {
+		if err := RequestDB(c).CreateInBatches(deliveries, bulkInsertBatch).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	RenderJSON(c, http.StatusCreated, message)
+}
+
+// GetMyMessages returns the caller's copies of admin broadcasts.
+func GetMyMessages(c *gin.Context) {
+	q := ValidateQuery(c)
+	limit := q.Int("limit", 50, 1, 200)
+	if !q.Valid() {
+		return
+	}
+
+	type messageView struct {
+		ID        uint      `json:"id"`
+		Title     string    `json:"title"`
+		Body      string    `json:"body"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+	var messages []messageView
+	err := RequestDB(c).Model(&models.UserMessage{}).
+		Select("user_messages.id, admin_messages.title, admin_messages.body, user_messages.created_at").
+		Joins("JOIN admin_messages ON admin_messages.id = user_messages.admin_message_id").
+		Where("user_messages.user_id = ?", CurrentUserID(c)).
+		Order("user_messages.created_at DESC").
+		Limit(limit).
+		Scan(&messages).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, messages)
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Prometheus metrics. MetricsMiddleware sits on every route, so new
+// handlers are instrumented for free: per-route request counts and
+// latency histograms, an in-flight gauge, and login outcome counters.
+// /metrics renders the text exposition format directly — like the
+// search and OAuth integrations, this speaks the wire protocol rather
+// than pulling in a client library. Connection pool stats are read
+// from database/sql at scrape time.
+
+// latencyBuckets are the histogram upper bounds in seconds.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type routeHistogram struct {
+	bucketCounts []uint64
+	sum          float64
+	count        uint64
+}
+
+type metricsState struct {
+	mu sync.Mutex
+
+	// requests counts by "METHOD path status tag".
+	requests map[string]uint64
+	// latency histograms by "METHOD path".
+	latency map[string]*routeHistogram
+
+	inFlight     int64
+	loginSuccess uint64
+	loginFailure uint64
+}
+
+var metrics = metricsState{
+	requests: make(map[string]uint64),
+	latency:  make(map[string]*routeHistogram),
+}
+
+// Cardinality controls. METRICS_LABELS chooses which of
+// method/path/status are kept (dropped ones collapse into "_"), and
+// METRICS_MAX_SERIES caps how many distinct route series exist: once
+// the cap is hit, previously unseen routes fold into path="other"
+// instead of minting new series. Paths are always the route template
+// (c.FullPath), never the raw URL, so IDs can't explode the label set.
+
+const defaultMetricsMaxSeries = 1000
+
+func metricsMaxSeries() int {
+	if v, err := strconv.Atoi(os.Getenv("METRICS_MAX_SERIES")); err == nil && v > 0 {
+		return v
+	}
+	return defaultMetricsMaxSeries
+}
+
+// metricsLabelKept reports whether a label is in the configured set;
+// all are kept when METRICS_LABELS is unset.
+func metricsLabelKept(label string) bool {
+	raw := os.Getenv("METRICS_LABELS")
+	if raw == "" {
+		return true
+	}
+	for _, name := range strings.Split(raw, ",") {
+		if strings.TrimSpace(name) == label {
+			return true
+		}
+	}
+	return false
+}
+
+// metricsLabelValues applies the label configuration.
+func metricsLabelValues(method, path string, status int) (string, string, string) {
+	statusLabel := strconv.Itoa(status)
+	if !metricsLabelKept("method") {
+		method = "_"
+	}
+	if !metricsLabelKept("path") {
+		path = "_"
+	}
+	if !metricsLabelKept("status") {
+		statusLabel = "_"
+	}
+	return method, path, statusLabel
+}
+
+func (m *metricsState) observe(method, path string, status int, seconds float64) {
+	// The tag label comes from the route table, so dashboards can group
+	// by feature area without path regexes.
+	tag := routeTags[method+" "+path]
+	if tag == "" {
+		tag = "other"
+	}
+	if !metricsLabelKept("tag") {
+		tag = "_"
+	}
+	method, path, statusLabel := metricsLabelValues(method, path, status)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Cardinality guard: a route that would mint a new series past the
+	// cap is folded into "other" rather than growing the map forever.
+	key := method + " " + path
+	if _, seen := m.latency[key]; !seen && len(m.latency) >= metricsMaxSeries() {
+		path = "other"
+		key = method + " " + path
+	}
+
+	m.requests[method+" "+path+" "+statusLabel+" "+tag]++
+
+	hist, ok := m.latency[key]
+	if !ok {
+		hist = &routeHistogram{bucketCounts: make([]uint64, len(latencyBuckets))}
+		m.latency[key] = hist
+	}
+	for i, upper := range latencyBuckets {
+		if seconds <= upper {
+			hist.bucketCounts[i]++
+		}
+	}
+	hist.sum += seconds
+	hist.count++
+}
+
+func recordLoginOutcome(success bool) {
+	metrics.mu.Lock()
+	if success {
+		metrics.loginSuccess++
+	} else {
+		metrics.loginFailure++
+	}
+	metrics.mu.Unlock()
+}
+
+// MetricsMiddleware instruments every request.
+func MetricsMiddleware(c *gin.Context) {
+	atomic.AddInt64(&metrics.inFlight, 1)
+	start := time.Now()
+
+	c.Next()
+
+	atomic.AddInt64(&metrics.inFlight, -1)
+	path := c.FullPath()
+	if path == "" {
+		path = "unmatched"
+	}
+	metrics.observe(c.Request.Method, path, c.Writer.Status(), time.Since(start).Seconds())
+}
+
+// GetMetrics renders the Prometheus text format.
+func GetMetrics(c *gin.Context) {
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+
+	var b strings.Builder
+
+	b.WriteString("# HELP http_requests_total Requests served, by route and status.\n")
+	b.WriteString("# TYPE http_requests_total counter\n")
+	for _, key := range sortedKeys(metrics.requests) {
+		parts := strings.SplitN(key, " ", 4)
+		fmt.Fprintf(&b, "http_requests_total{method=%q,path=%q,status=%q,tag=%q} %d\n",
+			parts[0], parts[1], parts[2], parts[3], metrics.requests[key])
+	}
+
+	b.WriteString("# HELP http_request_duration_seconds Request latency, by route.\n")
+	b.WriteString("# TYPE http_request_duration_seconds histogram\n")
+	latencyKeys := make([]string, 0, len(metrics.latency))
+	for key := range metrics.latency {
+		latencyKeys = append(latencyKeys, key)
+	}
+	sort.Strings(latencyKeys)
+	for _, key := range latencyKeys {
+		parts := strings.SplitN(key, " ", 2)
+		hist := metrics.latency[key]
+		for i, upper := range latencyBuckets {
+			fmt.Fprintf(&b, "http_request_duration_seconds_bucket{method=%q,path=%q,le=%q} %d\n",
+				parts[0], parts[1], strconv.FormatFloat(upper, 'g', -1, 64), hist.bucketCounts[i])
+		}
+		fmt.Fprintf(&b, "http_request_duration_seconds_bucket{method=%q,path=%q,le=\"+Inf\"} %d\n", parts[0], parts[1], hist.count)
+		fmt.Fprintf(&b, "http_request_duration_seconds_sum{method=%q,path=%q} %g\n", parts[0], parts[1], hist.sum)
+		fmt.Fprintf(&b, "http_request_duration_seconds_count{method=%q,path=%q} %d\n", parts[0], parts[1], hist.count)
+	}
+
+	b.WriteString("# HELP http_in_flight_requests Requests currently being served.\n")
+	b.WriteString("# TYPE http_in_flight_requests gauge\n")
+	fmt.Fprintf(&b, "http_in_flight_requests %d\n", atomic.LoadInt64(&metrics.inFlight))
+
+	b.WriteString("# HELP login_attempts_total Login outcomes.\n")
+	b.WriteString("# TYPE login_attempts_total counter\n")
+	fmt.Fprintf(&b, "login_attempts_total{result=\"success\"} %d\n", metrics.loginSuccess)
+	fmt.Fprintf(&b, "login_attempts_total{result=\"failure\"} %d\n", metrics.loginFailure)
+
+	if fanoutQueue != nil {
+		b.WriteString("# HELP ws_fanout_messages_total Clustered bump fan-out, by outcome.\n")
+		b.WriteString("# TYPE ws_fanout_messages_total counter\n")
+		fmt.Fprintf(&b, "ws_fanout_messages_total{outcome=\"published\"} %d\n", atomic.LoadInt64(&fanoutPublished))
+		fmt.Fprintf(&b, "ws_fanout_messages_total{outcome=\"received\"} %d\n", atomic.LoadInt64(&fanoutReceived))
+		fmt.Fprintf(&b, "ws_fanout_messages_total{outcome=\"dropped\"} %d\n", atomic.LoadInt64(&fanoutDropped))
+		fmt.Fprintf(&b, "ws_fanout_messages_total{outcome=\"self_skipped\"} %d\n", atomic.LoadInt64(&fanoutSelfSkipped))
+	}
+
+	if sqlDB, err := RequestDB(c).DB(); err == nil {
+		stats := sqlDB.Stats()
+		b.WriteString("# HELP db_pool_connections Connection pool state.\n")
+		b.WriteString("# TYPE db_pool_connections gauge\n")
+		fmt.Fprintf(&b, "db_pool_connections{state=\"open\"} %d\n", stats.OpenConnections)
+		fmt.Fprintf(&b, "db_pool_connections{state=\"in_use\"} %d\n", stats.InUse)
+		fmt.Fprintf(&b, "db_pool_connections{state=\"idle\"} %d\n", stats.Idle)
+		b.WriteString("# HELP db_pool_wait_count_total Times a connection was waited for.\n")
+		b.WriteString("# TYPE db_pool_wait_count_total counter\n")
+		fmt.Fprintf(&b, "db_pool_wait_count_total %d\n", stats.WaitCount)
+		b.WriteString("# HELP db_pool_wait_seconds_total Total time spent waiting for connections.\n")
+		b.WriteString("# TYPE db_pool_wait_seconds_total counter\n")
+		fmt.Fprintf(&b, "db_pool_wait_seconds_total %g\n", stats.WaitDuration.Seconds())
+	}
+
+	writeSIEMMetrics(&b)
+
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(b.String()))
+}
+
+func sortedKeys(m map[string]uint64) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
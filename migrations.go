@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/bernardmuller/go-idle/config"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"gorm.io/gorm"
+)
+
+// Versioned schema migrations. Each migration has an id, a forward
+// step, and a rollback; applied ids are recorded in schema_migrations
+// so every environment walks the same ordered history. `go-idle db
+// migrate` applies pending migrations, `go-idle db rollback` undoes
+// the most recent one. AutoMigrate still exists but only as a dev
+// convenience (AUTO_MIGRATE=true, always on outside production);
+// production schemas change through migrations only.
+
+type migration struct {
+	ID       string
+	Migrate  func(*gorm.DB) error
+	Rollback func(*gorm.DB) error
+}
+
+// schemaMigration is the bookkeeping row for one applied migration.
+type schemaMigration struct {
+	ID        string `gorm:"primarykey"`
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string { return "schema_migrations" }
+
+func dropTables(names ...string) func(*gorm.DB) error {
+	return func(db *gorm.DB) error {
+		for _, name := range names {
+			if err := db.Migrator().DropTable(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// migrations is the ordered history. Append only; never edit or
+// reorder an entry that has shipped.
+var migrations = []migration{
+	{
+		ID: "0001_users",
+		Migrate: func(db *gorm.DB) error {
+			return db.Migrator().AutoMigrate(&models.User{}, &models.PasswordResetToken{}, &models.ConsentChange{})
+		},
+		Rollback: dropTables("consent_changes", "password_reset_tokens", "users"),
+	},
+	{
+		ID: "0002_roles_permissions",
+		Migrate: func(db *gorm.DB) error {
+			return db.Migrator().AutoMigrate(&models.Role{}, &models.Permission{})
+		},
+		Rollback: dropTables("role_permissions", "permissions", "roles"),
+	},
+	{
+		ID: "0003_sessions",
+		Migrate: func(db *gorm.DB) error {
+			return db.Migrator().AutoMigrate(&models.Session{}, &models.SocialAccount{})
+		},
+		Rollback: dropTables("social_accounts", "sessions"),
+	},
+	{
+		// Email uniqueness only applies to live rows so a soft-deleted
+		// account doesn't hold its address hostage forever.
+		ID: "0004_partial_email_unique",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.Exec("DROP INDEX IF EXISTS idx_users_email").Error; err != nil {
+				return err
+			}
+			return db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_live ON users (email) WHERE deleted_at IS NULL").Error
+		},
+		Rollback: func(db *gorm.DB) error {
+			if err := db.Exec("DROP INDEX IF EXISTS idx_users_email_live").Error; err != nil {
+				return err
+			}
+			return db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email)").Error
+		},
+	},
+}
+
+// appliedMigrations returns the ids already recorded, ensuring the
+// bookkeeping table exists first.
+func appliedMigrations(db *gorm.DB) (map[string]bool, error) {
+	if err := db.AutoMigrate(&schemaMigration{}); err != nil {
+		return nil, err
+	}
+	var rows []schemaMigration
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		applied[row.ID] = true
+	}
+	return applied, nil
+}
+
+// runMigrations applies every pending migration in order, each inside
+// its own transaction.
+func runMigrations(db *gorm.DB) (int, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return 0, err
+	}
+
+	ran := 0
+	for _, m := range migrations {
+		if applied[m.ID] {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Migrate(tx); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{ID: m.ID, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return ran, fmt.Errorf("migration %s: %w", m.ID, err)
+		}
+		ran++
+	}
+	return ran, nil
+}
+
+// rollbackLastMigration undoes the most recently applied migration.
+func rollbackLastMigration(db *gorm.DB) (string, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return "", err
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if !applied[m.ID] {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Rollback(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{ID: m.ID}).Error
+		})
+		if err != nil {
+			return m.ID, fmt.Errorf("rollback %s: %w", m.ID, err)
+		}
+		return m.ID, nil
+	}
+	return "", fmt.Errorf("no applied migrations to roll back")
+}
+
+// runDBMigrate backs `go-idle db migrate`.
+func runDBMigrate() {
+	db, err := openDatabase()
+	if err != nil {
+		log.Fatalf("db migrate: %v", err)
+	}
+	ran, err := runMigrations(db)
+	if err != nil {
+		log.Fatalf("db migrate: %v", err)
+	}
+	fmt.Printf("applied %d migration(s); %d total in history\n", ran, len(migrations))
+}
+
+// runDBRollback backs `go-idle db rollback`.
+func runDBRollback() {
+	db, err := openDatabase()
+	if err != nil {
+		log.Fatalf("db rollback: %v", err)
+	}
+	id, err := rollbackLastMigration(db)
+	if err != nil {
+		log.Fatalf("db rollback: %v", err)
+	}
+	fmt.Printf("rolled back %s\n", id)
+}
+
+// devAutoMigrate reports whether the schema may be synced with
+// AutoMigrate: always in non-production, or explicitly via
+// AUTO_MIGRATE=true.
+func devAutoMigrate() bool {
+	if os.Getenv("AUTO_MIGRATE") == "true" {
+		return true
+	}
+	return config.Get().Env != "production"
+}
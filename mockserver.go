@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/bernardmuller/go-idle/config"
+	"github.com/gin-gonic/gin"
+)
+
+// Mock server mode. `go-idle --mock` serves the API surface with
+// deterministic fake data and no database, so client teams can build
+// against it offline. Latency and failures are injected globally via
+// MOCK_LATENCY_MS and MOCK_ERROR_EVERY, or per request with the
+// X-Mock-Delay-Ms and X-Mock-Status headers.
+
+var mockRequestCount int64
+
+// mockScenario applies the configured latency and error injection to
+// every mock response. Errors are injected deterministically (every
+// Nth request) so client retry logic can be tested reproducibly.
+func mockScenario(c *gin.Context) {
+	n := atomic.AddInt64(&mockRequestCount, 1)
+
+	delay := 0
+	if v, err := strconv.Atoi(os.Getenv("MOCK_LATENCY_MS")); err == nil && v > 0 {
+		delay = v
+	}
+	if v, err := strconv.Atoi(c.GetHeader("X-Mock-Delay-Ms")); err == nil && v > 0 {
+		delay = v
+	}
+	if delay > 0 {
+		time.Sleep(time.Duration(delay) * time.Millisecond)
+	}
+
+	if v, err := strconv.Atoi(c.GetHeader("X-Mock-Status")); err == nil && v >= 400 {
+		c.AbortWithStatusJSON(v, gin.H{"error": "injected by X-Mock-Status"})
+		return
+	}
+	if every, err := strconv.Atoi(os.Getenv("MOCK_ERROR_EVERY")); err == nil && every > 0 && n%int64(every) == 0 {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "injected mock failure"})
+		return
+	}
+	c.Next()
+}
+
+// mockUser produces the same user for the same id on every run.
+func mockUser(id uint) gin.H {
+	return gin.H{
+		"id":         id,
+		"name":       fmt.Sprintf("Mock User %d", id),
+		"email":      fmt.Sprintf("user%d@example.com", id),
+		"created_at": time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(id) * time.Hour),
+	}
+}
+
+func mockPlayer(userID uint) gin.H {
+	return gin.H{
+		"id":           userID,
+		"user_id":      userID,
+		"gold":         int64(userID) * 1000,
+		"gold_rate":    int64(1 + userID%5),
+		"last_tick_at": time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// runMockServer serves fake data on the configured port until killed.
+func runMockServer(cfg *config.Config) {
+	r := gin.Default()
+	r.Use(mockScenario)
+
+	r.POST("/register", func(c *gin.Context) {
+		c.JSON(http.StatusCreated, mockUser(1))
+	})
+	r.POST("/login", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"token": "mock-token"})
+	})
+
+	r.GET("/users", func(c *gin.Context) {
+		users := make([]gin.H, 0, 10)
+		for id := uint(1); id <= 10; id++ {
+			users = append(users, mockUser(id))
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"data": users,
+			"meta": gin.H{"page": 1, "per_page": 50, "total": 10, "total_pages": 1},
+		})
+	})
+	r.GET("/users/:id", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil || id < 1 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		c.JSON(http.StatusOK, mockUser(uint(id)))
+	})
+
+	r.GET("/game/state", func(c *gin.Context) {
+		c.JSON(http.StatusOK, mockPlayer(1))
+	})
+	r.POST("/game/tick", func(c *gin.Context) {
+		player := mockPlayer(1)
+		player["gold"] = player["gold"].(int64) + atomic.LoadInt64(&mockRequestCount)
+		c.JSON(http.StatusOK, player)
+	})
+
+	r.GET("/leaderboard", func(c *gin.Context) {
+		entries := make([]gin.H, 0, 10)
+		for rank := 1; rank <= 10; rank++ {
+			entries = append(entries, gin.H{
+				"rank":    rank,
+				"user_id": rank,
+				"name":    fmt.Sprintf("Mock User %d", rank),
+				"gold":    int64(1000000 / rank),
+			})
+		}
+		c.JSON(http.StatusOK, entries)
+	})
+
+	r.GET("/users/me/messages", func(c *gin.Context) {
+		c.JSON(http.StatusOK, []gin.H{
+			{"id": 1, "title": "Welcome to the mock server", "body": "This data is fake.", "created_at": time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		})
+	})
+
+	// Everything not mocked explicitly still answers, so clients can
+	// exercise the full surface without 404 noise.
+	r.NoRoute(func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"mock": true, "path": c.Request.URL.Path})
+	})
+
+	r.Run(fmt.Sprintf(":%d", cfg.Port))
+}
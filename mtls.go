@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Mutual-TLS admin listener. With ADMIN_MTLS_PORT set, the same router
+// is served a second time over TLS with client-certificate
+// verification against ADMIN_MTLS_CA_FILE, so the admin surface can be
+// reached from the internal mesh with workload certificates. Setting
+// ADMIN_MTLS_ONLY then closes /admin on the plain listener entirely:
+// a leaked admin credential is useless without a mesh certificate.
+// Requests are marked as mesh-verified through the request context,
+// which a public client has no way to forge.
+
+type mtlsContextKey struct{}
+
+func adminMTLSPort() string { return os.Getenv("ADMIN_MTLS_PORT") }
+
+func adminMTLSOnly() bool { return os.Getenv("ADMIN_MTLS_ONLY") == "true" }
+
+// fromAdminMTLS reports whether the request arrived over the verified
+// mTLS listener.
+func fromAdminMTLS(c *gin.Context) bool {
+	return c.Request.Context().Value(mtlsContextKey{}) != nil
+}
+
+// AdminMTLSGuard closes admin routes on the plain listener when
+// ADMIN_MTLS_ONLY is set.
+func AdminMTLSGuard(c *gin.Context) {
+	if adminMTLSOnly() && !fromAdminMTLS(c) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin endpoints require the mTLS listener"})
+		return
+	}
+	c.Next()
+}
+
+// mtlsMarker wraps the router for the admin listener, stamping every
+// request's context before it enters the middleware chain.
+type mtlsMarker struct{ next http.Handler }
+
+func (h mtlsMarker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r = r.WithContext(context.WithValue(r.Context(), mtlsContextKey{}, true))
+	h.next.ServeHTTP(w, r)
+}
+
+// StartAdminMTLSListener serves the router on the mTLS port; a no-op
+// when ADMIN_MTLS_PORT is unset. Misconfiguration is fatal — silently
+// starting without client verification would defeat the point.
+func StartAdminMTLSListener(r *gin.Engine) {
+	port := adminMTLSPort()
+	if port == "" {
+		return
+	}
+
+	certFile := os.Getenv("ADMIN_MTLS_CERT_FILE")
+	keyFile := os.Getenv("ADMIN_MTLS_KEY_FILE")
+	caFile := os.Getenv("ADMIN_MTLS_CA_FILE")
+	if certFile == "" || keyFile == "" || caFile == "" {
+		log.Fatalf("mtls: ADMIN_MTLS_PORT requires ADMIN_MTLS_CERT_FILE, ADMIN_MTLS_KEY_FILE, and ADMIN_MTLS_CA_FILE")
+	}
+
+	caPEM, err := os.ReadFile(caFile)
+	if err != nil {
+		log.Fatalf("mtls: reading client CA: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		log.Fatalf("mtls: %s contains no usable certificates", caFile)
+	}
+
+	srv := &http.Server{
+		Addr:    ":" + port,
+		Handler: mtlsMarker{next: r},
+		TLSConfig: &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			ClientAuth: tls.RequireAndVerifyClientCert,
+			ClientCAs:  pool,
+		},
+	}
+	go func() {
+		log.Printf("mtls: admin listener on :%s", port)
+		if err := srv.ListenAndServeTLS(certFile, keyFile); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("mtls: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bernardmuller/go-idle/api"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Notification router. Callers hand it one logical notification; the
+// router fans out to whichever channels are available for the user
+// (in-app storage, email, push gateway, WebSocket poke) and records
+// every attempt per channel in notification_deliveries. Channels are
+// injected at wiring time, so tests or deployments can swap them out
+// without touching the router.
+
+// Notification is the channel-agnostic payload.
+type Notification struct {
+	Type  string
+	Title string
+	Body  string
+}
+
+// NotificationChannel is one delivery mechanism.
+type NotificationChannel interface {
+	Name() string
+	// Available reports whether this channel can reach this user at
+	// all; unavailable channels are recorded as skipped.
+	Available(user *models.User) bool
+	Deliver(user *models.User, n Notification) error
+}
+
+type NotificationRouter struct {
+	channels []NotificationChannel
+}
+
+func NewNotificationRouter(channels ...NotificationChannel) *NotificationRouter {
+	return &NotificationRouter{channels: channels}
+}
+
+// notificationRouter is wired in main with the real channels.
+var notificationRouter *NotificationRouter
+
+// Send fans one notification out to every eligible channel and
+// reports how many deliveries succeeded.
+func (r *NotificationRouter) Send(userID uint, n Notification) (int, error) {
+	user, err := loadUserCached(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	// Marketing notifications respect the marketing consent flag no
+	// matter the channel.
+	if strings.HasPrefix(n.Type, "marketing.") && !user.ConsentMarketing {
+		return 0, nil
+	}
+
+	stored := models.Notification{UserID: userID, Type: n.Type, Title: n.Title, Body: n.Body, CreatedAt: time.Now()}
+	if err := DB.Create(&stored).Error; err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, channel := range r.channels {
+		record := models.NotificationDelivery{
+			NotificationID: stored.ID,
+			UserID:         userID,
+			Channel:        channel.Name(),
+			CreatedAt:      time.Now(),
+		}
+		if !channel.Available(&user) {
+			record.Status = "skipped"
+		} else if err := channel.Deliver(&user, n); err != nil {
+			record.Status = "failed"
+			record.Error = err.Error()
+		} else {
+			record.Status = "sent"
+			delivered++
+		}
+		DB.Create(&record)
+	}
+	return delivered, nil
+}
+
+// inAppChannel relies on the notification row itself; by the time the
+// channel runs the row is already stored, so delivery is a no-op.
+type inAppChannel struct{}
+
+func (inAppChannel) Name() string                             { return "in_app" }
+func (inAppChannel) Available(*models.User) bool              { return true }
+func (inAppChannel) Deliver(*models.User, Notification) error { return nil }
+
+// emailChannel sends through the mailer.
+type emailChannel struct{}
+
+func (emailChannel) Name() string { return "email" }
+func (emailChannel) Available(user *models.User) bool {
+	return user.Email != "" && user.AnonymizedAt == nil
+}
+func (emailChannel) Deliver(user *models.User, n Notification) error {
+	return sendEmail(user.Email, n.Title, n.Body)
+}
+
+// wsChannel pokes connected clients through the state notifier; they
+// re-fetch their notifications on the next poll.
+type wsChannel struct{}
+
+func (wsChannel) Name() string                { return "websocket" }
+func (wsChannel) Available(*models.User) bool { return true }
+func (wsChannel) Deliver(user *models.User, n Notification) error {
+	notifier.Bump(user.ID)
+	return nil
+}
+
+// pushChannel forwards to an external push gateway when one is
+// configured.
+type pushChannel struct{}
+
+func (pushChannel) Name() string { return "push" }
+func (pushChannel) Available(*models.User) bool {
+	return os.Getenv("PUSH_GATEWAY_URL") != ""
+}
+func (pushChannel) Deliver(user *models.User, n Notification) error {
+	payload, err := json.Marshal(gin.H{
+		"user_id": user.ID,
+		"type":    n.Type,
+		"title":   n.Title,
+		"body":    n.Body,
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := http.Post(os.Getenv("PUSH_GATEWAY_URL"), "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("push gateway returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// GetMyNotifications lists the caller's in-app notifications, unread
+// first.
+func GetMyNotifications(c *gin.Context) {
+	var notifications []models.Notification
+	err := RequestDB(c).Where("user_id = ?", CurrentUserID(c)).
+		Order("read_at IS NOT NULL, created_at DESC").Limit(100).Find(&notifications).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, notifications)
+}
+
+// ReadMyNotification marks one as read.
+func ReadMyNotification(c *gin.Context) {
+	result := RequestDB(c).Model(&models.Notification{}).
+		Where("id = ? AND user_id = ? AND read_at IS NULL", c.Param("id"), CurrentUserID(c)).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "notification not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+type AdminNotificationInput struct {
+	UserID uint   `json:"user_id" binding:"required"`
+	Type   string `json:"type" binding:"required"`
+	Title  string `json:"title" binding:"required"`
+	Body   string `json:"body"`
+}
+
+// AdminSendNotification routes one notification through the router.
+func AdminSendNotification(c *gin.Context) {
+	var input AdminNotificationInput
+	if !api.BindJSON(c, &input) {
+		return
+	}
+	delivered, err := notificationRouter.Send(input.UserID, Notification{Type: input.Type, Title: input.Title, Body: input.Body})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, gin.H{"delivered_channels": delivered})
+}
+
+// AdminNotificationDeliveries shows recent per-channel outcomes.
+func AdminNotificationDeliveries(c *gin.Context) {
+	var deliveries []models.NotificationDelivery
+	if err := RequestDB(c).Order("created_at DESC").Limit(200).Find(&deliveries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, deliveries)
+}
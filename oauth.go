@@ -0,0 +1,282 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OAuth2 social login for Google and GitHub. GET
+// /auth/:provider/login redirects the browser to the provider's
+// consent screen; the callback exchanges the code, resolves the
+// external identity to a local user through the same linking rules as
+// Sign in with Apple, and issues our usual JWT. Identities live in the
+// social_accounts table keyed by (provider, provider_id).
+
+type oauthProvider struct {
+	Name            string
+	AuthURL         string
+	TokenURL        string
+	Scopes          string
+	ClientIDEnv     string
+	ClientSecretEnv string
+
+	// fetchIdentity turns an access token into (providerID, email,
+	// name). Only verified email addresses come back; an unverified one
+	// is returned empty so it never links to a password account.
+	fetchIdentity func(accessToken string) (string, string, string, error)
+}
+
+var oauthProviders = map[string]oauthProvider{
+	"google": {
+		Name:            "google",
+		AuthURL:         "https://accounts.google.com/o/oauth2/v2/auth",
+		TokenURL:        "https://oauth2.googleapis.com/token",
+		Scopes:          "openid email profile",
+		ClientIDEnv:     "GOOGLE_CLIENT_ID",
+		ClientSecretEnv: "GOOGLE_CLIENT_SECRET",
+		fetchIdentity:   fetchGoogleIdentity,
+	},
+	"github": {
+		Name:            "github",
+		AuthURL:         "https://github.com/login/oauth/authorize",
+		TokenURL:        "https://github.com/login/oauth/access_token",
+		Scopes:          "read:user user:email",
+		ClientIDEnv:     "GITHUB_CLIENT_ID",
+		ClientSecretEnv: "GITHUB_CLIENT_SECRET",
+		fetchIdentity:   fetchGitHubIdentity,
+	},
+}
+
+const oauthStateTTL = 10 * time.Minute
+
+// oauthStates holds outstanding login attempts; a state is one-shot
+// and expires quickly, which is all the CSRF protection the callback
+// needs.
+var oauthStates = struct {
+	mu     sync.Mutex
+	states map[string]oauthStateEntry
+}{states: make(map[string]oauthStateEntry)}
+
+type oauthStateEntry struct {
+	provider string
+	expires  time.Time
+}
+
+func newOAuthState(provider string) string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	state := hex.EncodeToString(b)
+
+	oauthStates.mu.Lock()
+	for key, entry := range oauthStates.states {
+		if time.Now().After(entry.expires) {
+			delete(oauthStates.states, key)
+		}
+	}
+	oauthStates.states[state] = oauthStateEntry{provider: provider, expires: time.Now().Add(oauthStateTTL)}
+	oauthStates.mu.Unlock()
+	return state
+}
+
+func redeemOAuthState(state, provider string) bool {
+	oauthStates.mu.Lock()
+	defer oauthStates.mu.Unlock()
+
+	entry, ok := oauthStates.states[state]
+	if !ok {
+		return false
+	}
+	delete(oauthStates.states, state)
+	return entry.provider == provider && time.Now().Before(entry.expires)
+}
+
+// oauthRedirectURI is the callback URL registered with the provider.
+func oauthRedirectURI(provider string) string {
+	base := strings.TrimRight(os.Getenv("OAUTH_REDIRECT_BASE_URL"), "/")
+	if base == "" {
+		base = "http://localhost:" + os.Getenv("PORT")
+	}
+	return base + "/auth/" + provider + "/callback"
+}
+
+// OAuthLogin redirects to the provider's consent screen.
+func OAuthLogin(c *gin.Context) {
+	provider, ok := oauthProviders[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown provider"})
+		return
+	}
+	clientID := os.Getenv(provider.ClientIDEnv)
+	if clientID == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": provider.Name + " login is not configured"})
+		return
+	}
+
+	params := url.Values{
+		"client_id":     {clientID},
+		"redirect_uri":  {oauthRedirectURI(provider.Name)},
+		"response_type": {"code"},
+		"scope":         {provider.Scopes},
+		"state":         {newOAuthState(provider.Name)},
+	}
+	c.Redirect(http.StatusFound, provider.AuthURL+"?"+params.Encode())
+}
+
+// OAuthCallback exchanges the authorization code and logs the user in.
+func OAuthCallback(c *gin.Context) {
+	provider, ok := oauthProviders[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown provider"})
+		return
+	}
+	if !redeemOAuthState(c.Query("state"), provider.Name) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired state"})
+		return
+	}
+	code := c.Query("code")
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing authorization code"})
+		return
+	}
+
+	accessToken, err := exchangeOAuthCode(provider, code)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "code exchange failed"})
+		return
+	}
+	providerID, email, name, err := provider.fetchIdentity(accessToken)
+	if err != nil || providerID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "failed to fetch identity"})
+		return
+	}
+
+	user, err := findOrCreateSocialUser(provider.Name, providerID, email, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	jwtToken, tokenID, err := CreateTokenWithID(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create token"})
+		return
+	}
+	if err := createSession(c, user.ID, tokenID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create session"})
+		return
+	}
+	RenderJSON(c, http.StatusOK, gin.H{"token": jwtToken})
+}
+
+func exchangeOAuthCode(provider oauthProvider, code string) (string, error) {
+	form := url.Values{
+		"client_id":     {os.Getenv(provider.ClientIDEnv)},
+		"client_secret": {os.Getenv(provider.ClientSecretEnv)},
+		"code":          {code},
+		"grant_type":    {"authorization_code"},
+		"redirect_uri":  {oauthRedirectURI(provider.Name)},
+	}
+	req, err := http.NewRequest(http.MethodPost, provider.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		Error       string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if body.AccessToken == "" {
+		return "", fmt.Errorf("token exchange: %s", body.Error)
+	}
+	return body.AccessToken, nil
+}
+
+func oauthGetJSON(rawURL, accessToken string, out interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("userinfo: unexpected status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func fetchGoogleIdentity(accessToken string) (string, string, string, error) {
+	var info struct {
+		Sub           string `json:"sub"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		Name          string `json:"name"`
+	}
+	if err := oauthGetJSON("https://openidconnect.googleapis.com/v1/userinfo", accessToken, &info); err != nil {
+		return "", "", "", err
+	}
+	email := info.Email
+	if !info.EmailVerified {
+		email = ""
+	}
+	return info.Sub, email, info.Name, nil
+}
+
+func fetchGitHubIdentity(accessToken string) (string, string, string, error) {
+	var profile struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+		Name  string `json:"name"`
+	}
+	if err := oauthGetJSON("https://api.github.com/user", accessToken, &profile); err != nil {
+		return "", "", "", err
+	}
+	name := profile.Name
+	if name == "" {
+		name = profile.Login
+	}
+
+	// The profile email can be private or unverified; the emails
+	// endpoint says which address is both primary and verified.
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	email := ""
+	if err := oauthGetJSON("https://api.github.com/user/emails", accessToken, &emails); err == nil {
+		for _, e := range emails {
+			if e.Primary && e.Verified {
+				email = e.Email
+				break
+			}
+		}
+	}
+	return fmt.Sprintf("%d", profile.ID), email, name, nil
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/bernardmuller/go-idle/config"
+	"github.com/gin-gonic/gin"
+)
+
+// OpenAPI 3 document. The path list is generated from the live route
+// table so it can't drift from what the server actually mounts; the
+// schemas for the hot DTOs are hand-maintained below. The document is
+// served at /openapi.json (and thus /api/v1/openapi.json through the
+// version prefix), with a Swagger UI on /docs outside production.
+
+// openAPIPublicPrefixes are served without a bearer token; everything
+// else is marked as requiring one.
+var openAPIPublicPrefixes = []string{
+	"/register", "/login", "/status", "/healthz", "/readyz", "/metrics",
+	"/auth/", "/oauth/", "/password-reset/", "/shared/", "/.well-known/",
+	"/openapi.json", "/docs",
+}
+
+// openAPIShapes maps "METHOD /path" to hand-maintained request/response
+// schema refs; unlisted operations get the generic envelopes.
+var openAPIShapes = map[string]struct {
+	Request  string
+	Response string
+}{
+	"POST /register":    {Request: "RegisterRequest", Response: "UserResponse"},
+	"POST /login":       {Request: "LoginRequest", Response: "LoginResponse"},
+	"GET /me":           {Response: "UserResponse"},
+	"GET /users":        {Response: "UserListResponse"},
+	"GET /users/{id}":   {Response: "UserResponse"},
+	"GET /game/state":   {Response: "PlayerState"},
+	"POST /game/tick":   {Response: "PlayerState"},
+	"GET /openapi.json": {Response: "OpenAPIDocument"},
+}
+
+var openAPISchemas = gin.H{
+	"ErrorResponse": gin.H{
+		"type":       "object",
+		"properties": gin.H{"error": gin.H{"type": "string"}},
+		"required":   []string{"error"},
+	},
+	"RegisterRequest": gin.H{
+		"type": "object",
+		"properties": gin.H{
+			"name":     gin.H{"type": "string", "minLength": 2, "maxLength": 64},
+			"email":    gin.H{"type": "string", "format": "email"},
+			"password": gin.H{"type": "string", "minLength": 8, "maxLength": 128},
+		},
+		"required": []string{"name", "email", "password"},
+	},
+	"LoginRequest": gin.H{
+		"type": "object",
+		"properties": gin.H{
+			"email":    gin.H{"type": "string", "format": "email"},
+			"password": gin.H{"type": "string"},
+			"otp":      gin.H{"type": "string"},
+		},
+		"required": []string{"email", "password"},
+	},
+	"LoginResponse": gin.H{
+		"type": "object",
+		"properties": gin.H{
+			"token":              gin.H{"type": "string"},
+			"deletion_scheduled": gin.H{"type": "boolean"},
+			"recoverable_until":  gin.H{"type": "string", "format": "date-time"},
+		},
+		"required": []string{"token"},
+	},
+	"UserResponse": gin.H{
+		"type": "object",
+		"properties": gin.H{
+			"id":             gin.H{"type": "integer"},
+			"name":           gin.H{"type": "string"},
+			"email":          gin.H{"type": "string", "format": "email"},
+			"role":           gin.H{"type": "string"},
+			"created_at":     gin.H{"type": "string", "format": "date-time"},
+			"last_active_at": gin.H{"type": "string", "format": "date-time"},
+			"deleted_at":     gin.H{"type": "string", "format": "date-time"},
+		},
+		"required": []string{"id", "name", "email", "created_at"},
+	},
+	"UserListResponse": gin.H{
+		"type": "object",
+		"properties": gin.H{
+			"data": gin.H{"type": "array", "items": gin.H{"$ref": "#/components/schemas/UserResponse"}},
+			"meta": gin.H{
+				"type": "object",
+				"properties": gin.H{
+					"page":        gin.H{"type": "integer"},
+					"per_page":    gin.H{"type": "integer"},
+					"total":       gin.H{"type": "integer"},
+					"total_pages": gin.H{"type": "integer"},
+				},
+			},
+		},
+	},
+	"PlayerState": gin.H{
+		"type": "object",
+		"properties": gin.H{
+			"id":           gin.H{"type": "integer"},
+			"user_id":      gin.H{"type": "integer"},
+			"gold":         gin.H{"type": "integer", "format": "int64"},
+			"gold_rate":    gin.H{"type": "integer", "format": "int64"},
+			"last_tick_at": gin.H{"type": "string", "format": "date-time"},
+		},
+	},
+	"OpenAPIDocument": gin.H{"type": "object"},
+}
+
+func openAPIPublic(path string) bool {
+	for _, prefix := range openAPIPublicPrefixes {
+		if path == strings.TrimSuffix(prefix, "/") || strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ginPathToOpenAPI rewrites :param segments as {param}.
+func ginPathToOpenAPI(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "{" + strings.TrimPrefix(segment, ":") + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+var (
+	openAPIOnce sync.Once
+	openAPIDoc  gin.H
+)
+
+func buildOpenAPIDoc() gin.H {
+	paths := gin.H{}
+	for _, route := range appRouter.Routes() {
+		path := ginPathToOpenAPI(route.Path)
+		// Tags come from the route table; routes registered outside it
+		// (catalog scaffolding) fall back to the first path segment.
+		tags := routeMeta[route.Method+" "+route.Path].Tags
+		if len(tags) == 0 {
+			tags = []string{strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)[0]}
+		}
+		operation := gin.H{
+			"operationId": route.Method + " " + path,
+			"tags":        tags,
+			"responses": gin.H{
+				"200": gin.H{"description": "success"},
+				"default": gin.H{
+					"description": "error",
+					"content": gin.H{"application/json": gin.H{
+						"schema": gin.H{"$ref": "#/components/schemas/ErrorResponse"},
+					}},
+				},
+			},
+		}
+
+		shape := openAPIShapes[route.Method+" "+path]
+		if shape.Response != "" {
+			operation["responses"].(gin.H)["200"] = gin.H{
+				"description": "success",
+				"content": gin.H{"application/json": gin.H{
+					"schema": gin.H{"$ref": "#/components/schemas/" + shape.Response},
+				}},
+			}
+		}
+		if shape.Request != "" {
+			operation["requestBody"] = gin.H{
+				"required": true,
+				"content": gin.H{"application/json": gin.H{
+					"schema": gin.H{"$ref": "#/components/schemas/" + shape.Request},
+				}},
+			}
+		}
+		if !openAPIPublic(route.Path) {
+			operation["security"] = []gin.H{{"bearerAuth": []string{}}}
+		}
+		if meta, ok := routeMeta[route.Method+" "+route.Path]; ok && meta.Deprecated {
+			operation["deprecated"] = true
+		}
+
+		var params []gin.H
+		for _, segment := range strings.Split(path, "/") {
+			if strings.HasPrefix(segment, "{") {
+				params = append(params, gin.H{
+					"name":     strings.Trim(segment, "{}"),
+					"in":       "path",
+					"required": true,
+					"schema":   gin.H{"type": "string"},
+				})
+			}
+		}
+
+		entry, ok := paths[path].(gin.H)
+		if !ok {
+			entry = gin.H{}
+			if len(params) > 0 {
+				entry["parameters"] = params
+			}
+			paths[path] = entry
+		}
+		entry[strings.ToLower(route.Method)] = operation
+	}
+
+	return gin.H{
+		"openapi": "3.0.3",
+		"info": gin.H{
+			"title":   "go-idle API",
+			"version": "v1",
+		},
+		"servers": []gin.H{{"url": apiV1Prefix}, {"url": "/"}},
+		"paths":   paths,
+		"components": gin.H{
+			"schemas": openAPISchemas,
+			"securitySchemes": gin.H{
+				"bearerAuth": gin.H{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+	}
+}
+
+// GetOpenAPISpec serves the generated document.
+func GetOpenAPISpec(c *gin.Context) {
+	openAPIOnce.Do(func() { openAPIDoc = buildOpenAPIDoc() })
+	c.JSON(http.StatusOK, openAPIDoc)
+}
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html>
+<head>
+  <title>go-idle API docs</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    SwaggerUIBundle({ url: "/api/v1/openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>`
+
+// GetSwaggerUI serves the interactive docs; outside development it
+// stays off unless explicitly enabled.
+func GetSwaggerUI(c *gin.Context) {
+	if config.Get().Env == "production" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUIPage))
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Tenant export and offboarding. Org admins can pull a complete
+// bundle of their tenant's data (for migration or compliance) and
+// dismantle the org afterwards; offboarding honors dry-run.
+
+type orgMemberExport struct {
+	UserID            uint   `json:"user_id"`
+	Name              string `json:"name"`
+	Email             string `json:"email"`
+	Admin             bool   `json:"admin"`
+	RoleID            *uint  `json:"role_id,omitempty"`
+	ConsentThirdParty bool   `json:"-"`
+}
+
+// ExportOrg returns everything the tenant owns in one JSON document,
+// streamed progressively: the header and organization go out first,
+// then the member roster in cursor-walked batches with a flush after
+// each, so a big tenant's export starts arriving immediately instead
+// of after the whole roster is in memory.
+func ExportOrg(c *gin.Context) {
+	org, ok := requireOrgAdmin(c)
+	if !ok {
+		return
+	}
+
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="org-%d-export.json"`, org.ID))
+	c.Status(http.StatusOK)
+
+	stream := newExportStream(c)
+	stream.WriteString(`{"exported_at":`)
+	stream.EncodeJSON(time.Now())
+	stream.WriteString(`,"organization":`)
+	stream.EncodeJSON(org)
+	stream.WriteString(`,"members":[`)
+	stream.Flush()
+
+	wrote := 0
+	cursor := uint(0)
+	for {
+		var members []orgMemberExport
+		err := RequestDB(c).Model(&models.Membership{}).
+			Select("memberships.user_id, users.name, users.email, users.consent_third_party, memberships.admin, memberships.role_id").
+			Joins("JOIN users ON users.id = memberships.user_id").
+			Where("memberships.organization_id = ? AND memberships.user_id > ?", org.ID, cursor).
+			Order("memberships.user_id").Limit(exportBatchSize).
+			Scan(&members).Error
+		if err != nil || len(members) == 0 {
+			break
+		}
+		for i := range members {
+			// Exports leave the tenant, so members who haven't
+			// consented to third-party sharing appear without contact
+			// details.
+			if !members[i].ConsentThirdParty {
+				members[i].Email = "withheld (no third-party consent)"
+			}
+			if wrote > 0 {
+				stream.WriteString(",")
+			}
+			stream.EncodeJSON(members[i])
+			wrote++
+		}
+		cursor = members[len(members)-1].UserID
+		stream.Flush()
+		if len(members) < exportBatchSize {
+			break
+		}
+	}
+	stream.WriteString(`],"custom_roles":`)
+
+	var roles []models.Role
+	if err := RequestDB(c).Preload("Permissions").Where("organization_id = ?", org.ID).Find(&roles).Error; err != nil {
+		roles = nil
+	}
+	stream.EncodeJSON(roles)
+	stream.WriteString("}")
+	stream.Flush()
+}
+
+// OffboardOrg removes the tenant: memberships and custom roles go,
+// user accounts stay (they may belong to other orgs).
+func OffboardOrg(c *gin.Context) {
+	org, ok := requireOrgAdmin(c)
+	if !ok {
+		return
+	}
+
+	var memberCount, roleCount int64
+	RequestDB(c).Model(&models.Membership{}).Where("organization_id = ?", org.ID).Count(&memberCount)
+	RequestDB(c).Model(&models.Role{}).Where("organization_id = ?", org.ID).Count(&roleCount)
+
+	if isDryRun(c) {
+		RenderJSON(c, http.StatusOK, gin.H{
+			"dry_run":              true,
+			"would_remove_members": memberCount,
+			"would_remove_roles":   roleCount,
+			"would_delete_org":     org.Name,
+		})
+		return
+	}
+
+	err := RequestDB(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("organization_id = ?", org.ID).Delete(&models.Membership{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("organization_id = ?", org.ID).Delete(&models.Role{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(org).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	RenderJSON(c, http.StatusOK, gin.H{
+		"offboarded":      true,
+		"removed_members": memberCount,
+		"removed_roles":   roleCount,
+	})
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Per-organization custom roles. Org admins can compose roles from the
+// global permission catalog; how many custom roles an org may define
+// depends on its billing plan.
+
+var customRoleLimits = map[string]int{
+	"free":       2,
+	"pro":        10,
+	"enterprise": 50,
+}
+
+type CustomRoleInput struct {
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions"`
+}
+
+// requireOrgAdmin loads the organization from the :id param and checks
+// that the caller is an admin member of it.
+func requireOrgAdmin(c *gin.Context) (*models.Organization, bool) {
+	orgID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid organization id"})
+		return nil, false
+	}
+
+	var org models.Organization
+	if err := DB.First(&org, orgID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "organization not found"})
+		return nil, false
+	}
+
+	var membership models.Membership
+	err = DB.Where("organization_id = ? AND user_id = ?", org.ID, CurrentUserID(c)).First(&membership).Error
+	if err != nil || !membership.Admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "organization admin required"})
+		return nil, false
+	}
+	return &org, true
+}
+
+func ListOrgRoles(c *gin.Context) {
+	org, ok := requireOrgAdmin(c)
+	if !ok {
+		return
+	}
+
+	var roles []models.Role
+	if err := RequestDB(c).Preload("Permissions").Where("organization_id = ?", org.ID).Find(&roles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, roles)
+}
+
+func CreateOrgRole(c *gin.Context) {
+	org, ok := requireOrgAdmin(c)
+	if !ok {
+		return
+	}
+
+	var input CustomRoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+
+	limit, ok := customRoleLimits[org.Plan]
+	if !ok {
+		limit = customRoleLimits["free"]
+	}
+	var count int64
+	RequestDB(c).Model(&models.Role{}).Where("organization_id = ?", org.ID).Count(&count)
+	if int(count) >= limit {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "custom role limit reached for plan",
+			"plan":  org.Plan,
+			"limit": limit,
+		})
+		return
+	}
+
+	// Roles may only grant permissions that exist in the global catalog.
+	var permissions []models.Permission
+	if len(input.Permissions) > 0 {
+		if err := RequestDB(c).Where("name IN ?", input.Permissions).Find(&permissions).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if len(permissions) != len(input.Permissions) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown permission in list"})
+			return
+		}
+	}
+
+	role := models.Role{Name: input.Name, OrganizationID: &org.ID, Permissions: permissions}
+	if err := RequestDB(c).Create(&role).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusCreated, role)
+}
+
+func DeleteOrgRole(c *gin.Context) {
+	org, ok := requireOrgAdmin(c)
+	if !ok {
+		return
+	}
+
+	var role models.Role
+	err := RequestDB(c).Where("organization_id = ?", org.ID).First(&role, c.Param("roleId")).Error
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "role not found"})
+		return
+	}
+
+	var inUse int64
+	RequestDB(c).Model(&models.Membership{}).Where("role_id = ?", role.ID).Count(&inUse)
+	if inUse > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "role is still assigned to members"})
+		return
+	}
+
+	if isDryRun(c) {
+		RenderJSON(c, http.StatusOK, gin.H{"dry_run": true, "would_delete": role.Name})
+		return
+	}
+
+	if err := RequestDB(c).Delete(&role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
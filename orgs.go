@@ -0,0 +1,237 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/bernardmuller/go-idle/api"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Organization lifecycle and membership. Any account can create an
+// organization (becoming its first admin); org admins invite and
+// remove members and pick their per-org role. Tenancy rides in the
+// token's orgs claim, so membership changes take effect on the next
+// login; OrgScope turns that claim into the query scoping that keeps
+// one tenant's users invisible to another.
+
+// OrgScope makes the caller's tenancy visible to everything
+// downstream. The org ids normally arrive in the token's orgs claim
+// (set by the default claims hooks); tokens minted before the claim
+// existed fall back to the memberships table.
+func OrgScope(c *gin.Context) {
+	if _, ok := c.Get(api.ContextOrgIDs); !ok {
+		var orgIDs []uint
+		DB.Model(&models.Membership{}).Where("user_id = ?", CurrentUserID(c)).Pluck("organization_id", &orgIDs)
+		if len(orgIDs) > 0 {
+			c.Set(api.ContextOrgIDs, orgIDs)
+		}
+	}
+	c.Next()
+}
+
+// userSharesOrg reports whether the target user belongs to any of the
+// given organizations; it backs the tenancy check on single-user reads.
+func userSharesOrg(orgIDs []uint, targetID uint) bool {
+	if len(orgIDs) == 0 {
+		return false
+	}
+	var count int64
+	DB.Model(&models.Membership{}).
+		Where("user_id = ? AND organization_id IN ?", targetID, orgIDs).
+		Count(&count)
+	return count > 0
+}
+
+type CreateOrgInput struct {
+	Name string `json:"name" binding:"required,min=2,max=64"`
+}
+
+// CreateOrg creates a tenant with the caller as its first admin. The
+// caller's current token predates the membership, so the new org shows
+// up in the orgs claim after the next login.
+func CreateOrg(c *gin.Context) {
+	var input CreateOrgInput
+	if !api.BindJSON(c, &input) {
+		return
+	}
+
+	org := models.Organization{Name: input.Name}
+	err := RequestDB(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&org).Error; err != nil {
+			return err
+		}
+		membership := models.Membership{OrganizationID: org.ID, UserID: CurrentUserID(c), Admin: true}
+		return tx.Create(&membership).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	PublishEvent("org.created", map[string]interface{}{"org_id": org.ID, "name": org.Name})
+	RenderJSON(c, http.StatusCreated, org)
+}
+
+// GetMyOrgs lists the caller's organizations with their role in each.
+func GetMyOrgs(c *gin.Context) {
+	var memberships []models.Membership
+	err := RequestDB(c).Preload("Role").Where("user_id = ?", CurrentUserID(c)).Find(&memberships).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	out := make([]gin.H, 0, len(memberships))
+	for _, m := range memberships {
+		var org models.Organization
+		if err := RequestDB(c).First(&org, m.OrganizationID).Error; err != nil {
+			continue
+		}
+		entry := gin.H{"id": org.ID, "name": org.Name, "plan": org.Plan, "admin": m.Admin}
+		if m.Role != nil {
+			entry["role"] = m.Role.Name
+		}
+		out = append(out, entry)
+	}
+	RenderJSON(c, http.StatusOK, out)
+}
+
+// requireOrgMember loads the organization from the :id param and
+// checks that the caller belongs to it; 404 either way so outsiders
+// can't distinguish "no such org" from "not yours".
+func requireOrgMember(c *gin.Context) (*models.Organization, bool) {
+	var org models.Organization
+	if err := DB.First(&org, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "organization not found"})
+		return nil, false
+	}
+	var membership models.Membership
+	err := DB.Where("organization_id = ? AND user_id = ?", org.ID, CurrentUserID(c)).First(&membership).Error
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "organization not found"})
+		return nil, false
+	}
+	return &org, true
+}
+
+// ListOrgMembers shows an organization's roster to its members.
+func ListOrgMembers(c *gin.Context) {
+	org, ok := requireOrgMember(c)
+	if !ok {
+		return
+	}
+
+	var memberships []models.Membership
+	err := RequestDB(c).Preload("Role").Where("organization_id = ?", org.ID).Find(&memberships).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	out := make([]gin.H, 0, len(memberships))
+	for _, m := range memberships {
+		user, err := loadUserCached(m.UserID)
+		if err != nil {
+			continue
+		}
+		entry := gin.H{"user_id": user.ID, "name": user.Name, "email": user.Email, "admin": m.Admin}
+		if m.Role != nil {
+			entry["role"] = m.Role.Name
+		}
+		out = append(out, entry)
+	}
+	RenderJSON(c, http.StatusOK, out)
+}
+
+type InviteMemberInput struct {
+	Email string `json:"email" binding:"required,email"`
+	Admin bool   `json:"admin"`
+	// RoleID picks one of the org's custom roles for the new member.
+	RoleID *uint `json:"role_id"`
+}
+
+// InviteOrgMember adds an existing account to the organization. The
+// invitee's next login picks up the new org claim.
+func InviteOrgMember(c *gin.Context) {
+	org, ok := requireOrgAdmin(c)
+	if !ok {
+		return
+	}
+	var input InviteMemberInput
+	if !api.BindJSON(c, &input) {
+		return
+	}
+
+	var user models.User
+	if err := RequestDB(c).Where("email = ?", input.Email).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no account with that email"})
+		return
+	}
+
+	if input.RoleID != nil {
+		var role models.Role
+		err := RequestDB(c).Where("id = ? AND organization_id = ?", *input.RoleID, org.ID).First(&role).Error
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "role does not belong to this organization"})
+			return
+		}
+	}
+
+	var existing int64
+	RequestDB(c).Model(&models.Membership{}).
+		Where("organization_id = ? AND user_id = ?", org.ID, user.ID).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "already a member"})
+		return
+	}
+
+	membership := models.Membership{
+		OrganizationID: org.ID,
+		UserID:         user.ID,
+		Admin:          input.Admin,
+		RoleID:         input.RoleID,
+	}
+	if err := RequestDB(c).Create(&membership).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	PublishEvent("org.member_added", map[string]interface{}{"org_id": org.ID, "user_id": user.ID})
+	RenderJSON(c, http.StatusCreated, gin.H{"user_id": user.ID, "admin": membership.Admin})
+}
+
+// RemoveOrgMember drops a member. The last admin can't be removed, or
+// the org would be unmanageable.
+func RemoveOrgMember(c *gin.Context) {
+	org, ok := requireOrgAdmin(c)
+	if !ok {
+		return
+	}
+
+	var membership models.Membership
+	err := RequestDB(c).Where("organization_id = ? AND user_id = ?", org.ID, c.Param("userId")).
+		First(&membership).Error
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "member not found"})
+		return
+	}
+
+	if membership.Admin {
+		var admins int64
+		RequestDB(c).Model(&models.Membership{}).
+			Where("organization_id = ? AND admin = true", org.ID).Count(&admins)
+		if admins <= 1 {
+			c.JSON(http.StatusConflict, gin.H{"error": "cannot remove the last admin"})
+			return
+		}
+	}
+
+	if err := RequestDB(c).Delete(&membership).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	PublishEvent("org.member_removed", map[string]interface{}{"org_id": org.ID, "user_id": membership.UserID})
+	c.Status(http.StatusNoContent)
+}
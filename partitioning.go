@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Time-based partition maintenance for the high-volume event tables.
+// When a table has been converted to a native Postgres partitioned
+// table (a one-time DBA operation, since it requires rewriting the
+// table), the scheduler keeps monthly partitions rolling: the current
+// and next month always exist, and partitions past the retention
+// horizon are detached and renamed with an _archived suffix so they
+// can be dumped and dropped out of band. Non-partitioned tables and
+// non-Postgres backends are left alone.
+
+var partitionedTables = []string{"telemetry_events", "game_events"}
+
+const partitionMaintenanceInterval = 24 * time.Hour
+
+func partitionRetentionMonths() int {
+	if v, err := strconv.Atoi(os.Getenv("EVENT_PARTITION_RETENTION_MONTHS")); err == nil && v > 0 {
+		return v
+	}
+	return 6
+}
+
+// tableIsPartitioned checks the catalog for a declarative partitioned
+// parent.
+func tableIsPartitioned(table string) bool {
+	var count int64
+	DB.Raw(
+		"SELECT count(*) FROM pg_partitioned_table pt JOIN pg_class c ON c.oid = pt.partrelid WHERE c.relname = ?",
+		table,
+	).Scan(&count)
+	return count > 0
+}
+
+func partitionName(table string, month time.Time) string {
+	return fmt.Sprintf("%s_y%dm%02d", table, month.Year(), int(month.Month()))
+}
+
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// ensureMonthPartition creates one monthly partition if it does not
+// exist.
+func ensureMonthPartition(table string, month time.Time) error {
+	from := monthStart(month)
+	to := from.AddDate(0, 1, 0)
+	return DB.Exec(fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')",
+		partitionName(table, from), table,
+		from.Format("2006-01-02"), to.Format("2006-01-02"),
+	)).Error
+}
+
+// archiveOldPartitions detaches partitions entirely before the
+// retention horizon.
+func archiveOldPartitions(table string) {
+	horizon := monthStart(time.Now().UTC()).AddDate(0, -partitionRetentionMonths(), 0)
+
+	var names []string
+	DB.Raw(
+		"SELECT c.relname FROM pg_inherits i JOIN pg_class c ON c.oid = i.inhrelid JOIN pg_class p ON p.oid = i.inhparent WHERE p.relname = ?",
+		table,
+	).Scan(&names)
+
+	for _, name := range names {
+		var year, month int
+		if _, err := fmt.Sscanf(name, table+"_y%dm%d", &year, &month); err != nil {
+			continue
+		}
+		start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+		if !start.AddDate(0, 1, 0).After(horizon) {
+			if err := DB.Exec(fmt.Sprintf("ALTER TABLE %s DETACH PARTITION %s", table, name)).Error; err != nil {
+				log.Printf("partitions: detach %s failed: %v", name, err)
+				continue
+			}
+			if err := DB.Exec(fmt.Sprintf("ALTER TABLE %s RENAME TO %s_archived", name, name)).Error; err != nil {
+				log.Printf("partitions: rename %s failed: %v", name, err)
+			}
+			log.Printf("partitions: archived %s", name)
+		}
+	}
+}
+
+// maintainPartitions runs one maintenance pass over every partitioned
+// event table.
+func maintainPartitions() {
+	now := time.Now().UTC()
+	for _, table := range partitionedTables {
+		if !tableIsPartitioned(table) {
+			continue
+		}
+		if err := ensureMonthPartition(table, now); err != nil {
+			log.Printf("partitions: create current for %s failed: %v", table, err)
+		}
+		if err := ensureMonthPartition(table, now.AddDate(0, 1, 0)); err != nil {
+			log.Printf("partitions: create next for %s failed: %v", table, err)
+		}
+		archiveOldPartitions(table)
+	}
+}
+
+// StartPartitionMaintenance keeps monthly partitions rolling; a no-op
+// off Postgres.
+func StartPartitionMaintenance() {
+	if !usingPostgres() {
+		return
+	}
+	go func() {
+		for {
+			maintainPartitions()
+			time.Sleep(partitionMaintenanceInterval)
+		}
+	}()
+}
+
+// AdminListPartitions reports each event table's partitioning state so
+// operators can see rollover and archival working.
+func AdminListPartitions(c *gin.Context) {
+	if !usingPostgres() {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "partitioning requires postgres"})
+		return
+	}
+
+	out := make([]gin.H, 0, len(partitionedTables))
+	for _, table := range partitionedTables {
+		entry := gin.H{"table": table, "partitioned": tableIsPartitioned(table)}
+		var names []string
+		DB.Raw(
+			"SELECT c.relname FROM pg_inherits i JOIN pg_class c ON c.oid = i.inhrelid JOIN pg_class p ON p.oid = i.inhparent WHERE p.relname = ? ORDER BY c.relname",
+			table,
+		).Scan(&names)
+		entry["partitions"] = names
+		out = append(out, entry)
+	}
+	RenderJSON(c, http.StatusOK, out)
+}
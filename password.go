@@ -0,0 +1,94 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/bernardmuller/go-idle/config"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password hash lifecycle. Hashes are written at a configurable cost
+// and quietly upgraded on the next successful login when the stored
+// cost falls behind, so raising BCRYPT_COST migrates the user base
+// over time without a flag day.
+
+func bcryptTargetCost() int {
+	return config.Get().BcryptCost
+}
+
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcryptTargetCost())
+	return string(hash), err
+}
+
+// maybeUpgradeHash rehashes the user's password at the current target
+// cost. Call only after the plaintext has been verified.
+func maybeUpgradeHash(user *models.User, password string) {
+	cost, err := bcrypt.Cost([]byte(user.Password))
+	if err != nil || cost >= bcryptTargetCost() {
+		return
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		log.Printf("password: hash upgrade failed for user %d: %v", user.ID, err)
+		return
+	}
+	user.Password = hash
+	if err := DB.Model(user).Update("password", hash).Error; err != nil {
+		log.Printf("password: hash upgrade save failed for user %d: %v", user.ID, err)
+	}
+}
+
+// AdminHashBenchmark times hashing and verification across a range of
+// bcrypt costs so operators can pick a cost that fits their latency
+// budget before changing BCRYPT_COST.
+func AdminHashBenchmark(c *gin.Context) {
+	q := ValidateQuery(c)
+	min := q.Int("min", 10, bcrypt.MinCost, bcrypt.MaxCost)
+	max := q.Int("max", 13, bcrypt.MinCost, bcrypt.MaxCost)
+	if !q.Valid() {
+		return
+	}
+	if min > max {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min must not exceed max"})
+		return
+	}
+
+	type costResult struct {
+		Cost     int     `json:"cost"`
+		HashMs   float64 `json:"hash_ms"`
+		VerifyMs float64 `json:"verify_ms"`
+	}
+
+	const sample = "benchmark-password-sample"
+	results := make([]costResult, 0, max-min+1)
+	for cost := min; cost <= max; cost++ {
+		start := time.Now()
+		hash, err := bcrypt.GenerateFromPassword([]byte(sample), cost)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		hashTime := time.Since(start)
+
+		start = time.Now()
+		_ = bcrypt.CompareHashAndPassword(hash, []byte(sample))
+		verifyTime := time.Since(start)
+
+		results = append(results, costResult{
+			Cost:     cost,
+			HashMs:   float64(hashTime.Microseconds()) / 1000,
+			VerifyMs: float64(verifyTime.Microseconds()) / 1000,
+		})
+	}
+
+	RenderJSON(c, http.StatusOK, gin.H{
+		"current_target_cost": bcryptTargetCost(),
+		"results":             results,
+	})
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Password reset. Requesting a reset always answers 202 so the
+// endpoint can't be used to probe for accounts; when the email exists,
+// a single-use token (stored only as a hash) is mailed out and must be
+// redeemed within the TTL. Tokens are consumed atomically — two
+// concurrent confirms can't both succeed — and requesting a new reset
+// invalidates any older ones still outstanding. RESET_TOKEN_BINDING
+// additionally ties redemption to the requesting user agent and IP
+// family.
+
+const passwordResetTTL = time.Hour
+
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func resetTokenBindingEnabled() bool {
+	return os.Getenv("RESET_TOKEN_BINDING") == "true"
+}
+
+// ipFamily collapses an address to its network — /24 for IPv4, /48 for
+// IPv6 — wide enough to survive NAT churn on the same connection while
+// still rejecting redemption from somewhere else entirely.
+func ipFamily(addr string) string {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return addr
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(48, 128)).String()
+}
+
+type ResetRequestInput struct {
+	Email string `json:"email"`
+}
+
+func RequestPasswordReset(c *gin.Context) {
+	var input ResetRequestInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The response is identical whether or not the account exists.
+	defer c.JSON(http.StatusAccepted, gin.H{"message": "if the account exists, a reset email has been sent"})
+
+	var user models.User
+	if err := RequestDB(c).Where("email = ? AND anonymized_at IS NULL", input.Email).First(&user).Error; err != nil {
+		return
+	}
+
+	// A fresh request supersedes every outstanding token for the
+	// account, so only the newest emailed link works.
+	now := time.Now()
+	RequestDB(c).Model(&models.PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", user.ID).
+		Update("used_at", now)
+
+	raw := make([]byte, 32)
+	rand.Read(raw)
+	token := hex.EncodeToString(raw)
+
+	record := models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashResetToken(token),
+		ExpiresAt: now.Add(passwordResetTTL),
+		IssuedUA:  c.GetHeader("User-Agent"),
+		IssuedIP:  c.ClientIP(),
+	}
+	if err := RequestDB(c).Create(&record).Error; err != nil {
+		return
+	}
+
+	base := os.Getenv("APP_BASE_URL")
+	if base == "" {
+		base = "http://localhost:8080"
+	}
+	subject, body, err := renderEmail("password_reset", map[string]interface{}{
+		"Name":      user.Name,
+		"Link":      fmt.Sprintf("%s/password-reset?token=%s", base, token),
+		"ExpiresIn": passwordResetTTL.String(),
+	})
+	if err != nil {
+		return
+	}
+	sendEmail(user.Email, subject, body)
+}
+
+type ResetConfirmInput struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func ConfirmPasswordReset(c *gin.Context) {
+	var input ResetConfirmInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(input.Password) < 8 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "password must be at least 8 characters"})
+		return
+	}
+	if rejectBreachedPassword(c, input.Password) {
+		return
+	}
+
+	// Consume first, atomically: the guarded UPDATE flips used_at only
+	// if the token is still live, so a replayed or concurrent confirm
+	// finds it already burned.
+	now := time.Now()
+	res := RequestDB(c).Model(&models.PasswordResetToken{}).
+		Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hashResetToken(input.Token), now).
+		Update("used_at", now)
+	if res.Error != nil || res.RowsAffected == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired reset token"})
+		return
+	}
+
+	var record models.PasswordResetToken
+	if err := RequestDB(c).Where("token_hash = ?", hashResetToken(input.Token)).First(&record).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired reset token"})
+		return
+	}
+	if resetTokenBindingEnabled() &&
+		(record.IssuedUA != c.GetHeader("User-Agent") || ipFamily(record.IssuedIP) != ipFamily(c.ClientIP())) {
+		// The token is already consumed above, so a stolen link is
+		// burned by the failed attempt rather than left redeemable.
+		recordAudit(AuditPasswordChanged, record.UserID, 0, c.ClientIP(), "reset token rejected: binding mismatch")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired reset token"})
+		return
+	}
+
+	hash, err := hashPassword(input.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to hash password"})
+		return
+	}
+
+	if err := RequestDB(c).Model(&models.User{}).Where("id = ?", record.UserID).Update("password", hash).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	userCache.Invalidate(record.UserID)
+	recordAudit(AuditPasswordChanged, record.UserID, 0, c.ClientIP(), "reset token")
+
+	// A reset means the old password may be in hostile hands: every
+	// session dies and the token version moves on, so whoever holds an
+	// old token is out once any grace period lapses.
+	revokeUserSessions(record.UserID)
+	if err := bumpTokenVersion(RequestDB(c), record.UserID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to invalidate old tokens"})
+		return
+	}
+
+	RenderJSON(c, http.StatusOK, gin.H{"reset": true})
+}
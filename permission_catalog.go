@@ -0,0 +1,81 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+)
+
+// Canonical permission catalog. The permissions the code enforces are
+// defined here, with descriptions, and synced into the permissions
+// table at startup: new entries are created, descriptions are kept
+// current, and stored permissions the code no longer knows are flagged
+// rather than deleted (a role may still reference them, and
+// admin-created custom permissions live outside the canonical set by
+// design). The route table is checked against the catalog at mount
+// time, so a route can't require a permission the catalog doesn't
+// define — the two are in the same binary and drift between them is a
+// bug, caught at boot.
+
+type permissionDefinition struct {
+	Name        string
+	Description string
+}
+
+var permissionCatalog = []permissionDefinition{
+	{Name: "admin", Description: "full access to the /admin surface and user management"},
+	{Name: "support", Description: "read access to user accounts and audit trails for support work"},
+	{Name: "billing", Description: "manage plans, usage line items, and Stripe links"},
+	{Name: "moderation", Description: "act on player-generated content and messaging"},
+}
+
+// SyncPermissionCatalog reconciles the stored catalog with the code.
+func SyncPermissionCatalog() {
+	known := map[string]bool{}
+	for _, def := range permissionCatalog {
+		known[def.Name] = true
+		var perm models.Permission
+		err := DB.Where("name = ?", def.Name).First(&perm).Error
+		if err != nil {
+			if err := DB.Create(&models.Permission{Name: def.Name, Description: def.Description}).Error; err != nil {
+				log.Printf("permissions: seeding %q failed: %v", def.Name, err)
+			}
+			continue
+		}
+		if perm.Description != def.Description || perm.DeprecatedAt != nil {
+			DB.Model(&perm).Updates(map[string]interface{}{"description": def.Description, "deprecated_at": nil})
+		}
+	}
+
+	// Everything else in the table is either removed from the code or
+	// admin-created; both get flagged so listings show they're outside
+	// the canonical set.
+	var stored []models.Permission
+	if err := DB.Find(&stored).Error; err != nil {
+		log.Printf("permissions: catalog scan failed: %v", err)
+		return
+	}
+	now := time.Now()
+	for _, perm := range stored {
+		if !known[perm.Name] && perm.DeprecatedAt == nil {
+			log.Printf("permissions: %q is not in the code catalog, flagging", perm.Name)
+			DB.Model(&perm).Update("deprecated_at", now)
+		}
+	}
+}
+
+// verifyRoutePermissions refuses to boot a route table that names a
+// permission the catalog doesn't define.
+func verifyRoutePermissions(table []apiRoute) {
+	known := map[string]bool{}
+	for _, def := range permissionCatalog {
+		known[def.Name] = true
+	}
+	for _, rt := range table {
+		if rt.Permission != "" && !known[rt.Permission] {
+			log.Fatalf("permissions: route %s %s requires %q, which the catalog doesn't define",
+				rt.Method, rt.Path, rt.Permission)
+		}
+	}
+}
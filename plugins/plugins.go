@@ -0,0 +1,15 @@
+// Package plugins holds deployment-specific integrations, compiled in.
+// Upstream this package is intentionally empty; a deployment adds its
+// own files here and registers behavior with the hooks package from
+// init, for example:
+//
+//	func init() {
+//		hooks.OnUserRegistered(func(user *models.User) {
+//			go crm.SyncContact(user.Email, user.Name)
+//		})
+//	}
+//
+// The server blank-imports this package, so anything registered here
+// is live without touching the rest of the tree — keep company logic
+// in this directory and the upstream merge stays clean.
+package plugins
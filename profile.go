@@ -0,0 +1,289 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/bernardmuller/go-idle/api"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// Own-profile endpoints. GET /me shows the authenticated account and
+// PATCH /me updates name and email; an email change only takes effect
+// after the token mailed to the new address is redeemed, and password
+// hashes never appear in any response.
+
+// profileView is what an account looks like to its owner.
+type profileView struct {
+	ID           uint       `json:"id"`
+	Name         string     `json:"name"`
+	Email        string     `json:"email"`
+	PendingEmail *string    `json:"pending_email,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	LastActiveAt *time.Time `json:"last_active_at,omitempty"`
+}
+
+func profileOf(user *models.User) profileView {
+	return profileView{
+		ID:           user.ID,
+		Name:         user.Name,
+		Email:        user.Email,
+		PendingEmail: user.PendingEmail,
+		CreatedAt:    user.CreatedAt,
+		LastActiveAt: user.LastActiveAt,
+	}
+}
+
+func GetMe(c *gin.Context) {
+	user, err := loadUserCachedCtx(c.Request.Context(), CurrentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	RenderJSON(c, http.StatusOK, profileOf(&user))
+}
+
+type PatchMeInput struct {
+	Name  *string `json:"name" binding:"omitempty,min=2,max=64"`
+	Email *string `json:"email" binding:"omitempty,email"`
+}
+
+// PatchMe applies partial updates. A new email address goes through
+// verification: it is stored as pending, a token goes to the new
+// address, and the switch happens in ConfirmEmailChange.
+func PatchMe(c *gin.Context) {
+	var input PatchMeInput
+	if !api.BindJSON(c, &input) {
+		return
+	}
+
+	userID := CurrentUserID(c)
+	var user models.User
+	if err := RequestDB(c).First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if input.Name != nil && *input.Name != user.Name {
+		updates["name"] = *input.Name
+	}
+
+	var verifyToken string
+	if input.Email != nil && *input.Email != user.Email {
+		if ok, reason := registrationEmailAllowed(*input.Email); !ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": reason})
+			return
+		}
+		var taken int64
+		RequestDB(c).Model(&models.User{}).Where("email = ? AND id <> ?", *input.Email, user.ID).Count(&taken)
+		if taken > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "email already in use"})
+			return
+		}
+
+		raw := make([]byte, 32)
+		rand.Read(raw)
+		verifyToken = hex.EncodeToString(raw)
+		updates["pending_email"] = *input.Email
+		updates["email_verify_token_hash"] = hashResetToken(verifyToken)
+	}
+
+	if len(updates) > 0 {
+		if err := RequestDB(c).Model(&user).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		userCache.Invalidate(user.ID)
+	}
+
+	if verifyToken != "" {
+		if subject, body, err := renderEmail("email_verification", gin.H{"Name": user.Name, "Token": verifyToken}); err == nil {
+			sendEmail(*input.Email, subject, body)
+		}
+	}
+
+	RequestDB(c).First(&user, userID)
+	RenderJSON(c, http.StatusOK, profileOf(&user))
+}
+
+type ConfirmEmailInput struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ConfirmEmailChange redeems the verification token and switches the
+// account to the pending address.
+func ConfirmEmailChange(c *gin.Context) {
+	var input ConfirmEmailInput
+	if !api.BindJSON(c, &input) {
+		return
+	}
+
+	userID := CurrentUserID(c)
+
+	// The guarded UPDATE matches only while the token is still stored,
+	// switching the address and burning the token in one statement so a
+	// replayed link finds nothing to redeem.
+	res := RequestDB(c).Model(&models.User{}).
+		Where("id = ? AND pending_email IS NOT NULL AND email_verify_token_hash = ?",
+			userID, hashResetToken(input.Token)).
+		Updates(map[string]interface{}{
+			"email":                   gorm.Expr("pending_email"),
+			"pending_email":           nil,
+			"email_verify_token_hash": "",
+		})
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": res.Error.Error()})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid verification token"})
+		return
+	}
+	userCache.Invalidate(userID)
+
+	var user models.User
+	if err := RequestDB(c).First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	indexUser(&user)
+	PublishEvent("user.email_changed", map[string]interface{}{"user_id": user.ID, "email": user.Email})
+
+	RenderJSON(c, http.StatusOK, profileOf(&user))
+}
+
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=8,max=128"`
+}
+
+// ChangeMyPassword rotates the password after confirming the current
+// one. Every other session is revoked: a stolen session must not
+// survive the owner changing their credentials.
+func ChangeMyPassword(c *gin.Context) {
+	var input ChangePasswordInput
+	if !api.BindJSON(c, &input) {
+		return
+	}
+
+	var user models.User
+	if err := RequestDB(c).First(&user, CurrentUserID(c)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.CurrentPassword)) != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "current password is incorrect"})
+		return
+	}
+	if input.NewPassword == input.CurrentPassword {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "new password must differ from the current one"})
+		return
+	}
+	if rejectBreachedPassword(c, input.NewPassword) {
+		return
+	}
+
+	hash, err := hashPassword(input.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to hash password"})
+		return
+	}
+	if err := RequestDB(c).Model(&user).Update("password", hash).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	userCache.Invalidate(user.ID)
+	recordAudit(AuditPasswordChanged, user.ID, 0, c.ClientIP(), "self-service")
+
+	// Bumping the token version kills every token minted under the old
+	// password, the caller's included, so the response carries a fresh
+	// one for the session making the change.
+	revoked := revokeOtherSessions(c, user.ID)
+	if err := bumpTokenVersion(RequestDB(c), user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to invalidate old tokens"})
+		return
+	}
+	token, tokenID, err := CreateTokenWithID(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create token"})
+		return
+	}
+	if err := createSession(c, user.ID, tokenID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create session"})
+		return
+	}
+	if cookieAuthEnabled() {
+		setAuthCookies(c, token)
+	}
+	RenderJSON(c, http.StatusOK, gin.H{"changed": true, "sessions_revoked": revoked, "token": token})
+}
+
+type DeleteMeInput struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// DeleteMe schedules the caller's account for deletion after a
+// password confirmation, so a hijacked session alone can't destroy an
+// account. The recovery window is the same as DELETE /users/me; only
+// admins can remove a user for good.
+func DeleteMe(c *gin.Context) {
+	var input DeleteMeInput
+	if !api.BindJSON(c, &input) {
+		return
+	}
+
+	var user models.User
+	if err := RequestDB(c).First(&user, CurrentUserID(c)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)) != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "password is incorrect"})
+		return
+	}
+
+	now := time.Now()
+	user.DeletionRequestedAt = &now
+	if err := RequestDB(c).Save(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	userCache.Invalidate(user.ID)
+
+	recordAudit(AuditDeletionRequested, user.ID, 0, c.ClientIP(), "password confirmed")
+	PublishEvent("user.deletion_requested", map[string]interface{}{"user_id": user.ID})
+	if cookieAuthEnabled() {
+		clearAuthCookies(c)
+	}
+
+	RenderJSON(c, http.StatusOK, gin.H{
+		"deletion_requested_at": now,
+		"recoverable_until":     now.Add(deletionRecoveryWindow),
+	})
+}
+
+// revokeOtherSessions kills every live session except the one making
+// the request and returns how many it revoked.
+func revokeOtherSessions(c *gin.Context, userID uint) int {
+	current := c.GetString(api.ContextTokenID)
+	var sessions []models.Session
+	if err := RequestDB(c).Where("user_id = ? AND revoked_at IS NULL", userID).Find(&sessions).Error; err != nil {
+		return 0
+	}
+	revoked := 0
+	for i := range sessions {
+		if sessions[i].TokenID == current {
+			continue
+		}
+		if revokeSession(&sessions[i]) == nil {
+			revoked++
+		}
+	}
+	return revoked
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/bernardmuller/go-idle/api"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Profile share links. POST /users/me/profile/share mints a random
+// token whose hash is stored alongside an expiry; anyone holding the
+// link can read a snapshot of the profile and game stats without
+// authenticating until it expires or the owner revokes it. Each view
+// bumps a counter the owner can see on their share list.
+
+const (
+	defaultShareTTL = 7 * 24 * time.Hour
+	maxShareTTL     = 30 * 24 * time.Hour
+)
+
+type ShareProfileInput struct {
+	ExpiresIn string `json:"expires_in" binding:"omitempty"`
+}
+
+// CreateProfileShare mints a share link. The plaintext token appears in
+// this response only; the database keeps its hash.
+func CreateProfileShare(c *gin.Context) {
+	var input ShareProfileInput
+	if !api.BindJSON(c, &input) {
+		return
+	}
+
+	ttl := defaultShareTTL
+	if input.ExpiresIn != "" {
+		d, err := time.ParseDuration(input.ExpiresIn)
+		if err != nil || d <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in must be a positive duration"})
+			return
+		}
+		if d > maxShareTTL {
+			d = maxShareTTL
+		}
+		ttl = d
+	}
+
+	raw := make([]byte, 24)
+	rand.Read(raw)
+	token := "shr_" + hex.EncodeToString(raw)
+
+	share := models.ProfileShare{
+		UserID:    CurrentUserID(c),
+		TokenHash: hashResetToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := RequestDB(c).Create(&share).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	RenderJSON(c, http.StatusCreated, gin.H{
+		"id":         share.ID,
+		"token":      token,
+		"url":        "/shared/profiles/" + token,
+		"expires_at": share.ExpiresAt,
+	})
+}
+
+// GetMyProfileShares lists the caller's share links with view counts.
+func GetMyProfileShares(c *gin.Context) {
+	var shares []models.ProfileShare
+	err := RequestDB(c).Where("user_id = ?", CurrentUserID(c)).
+		Order("created_at desc").Find(&shares).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, shares)
+}
+
+// RevokeProfileShare kills one of the caller's share links; the link
+// stops resolving immediately.
+func RevokeProfileShare(c *gin.Context) {
+	var share models.ProfileShare
+	err := RequestDB(c).Where("id = ? AND user_id = ? AND revoked_at IS NULL", c.Param("id"), CurrentUserID(c)).
+		First(&share).Error
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "share link not found"})
+		return
+	}
+	now := time.Now()
+	if err := RequestDB(c).Model(&share).Update("revoked_at", now).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetSharedProfile is the unauthenticated read side. Expired, revoked,
+// and unknown tokens are indistinguishable to the caller.
+func GetSharedProfile(c *gin.Context) {
+	var share models.ProfileShare
+	err := RequestDB(c).Where("token_hash = ? AND revoked_at IS NULL AND expires_at > ?",
+		hashResetToken(c.Param("token")), time.Now()).First(&share).Error
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "share link not found"})
+		return
+	}
+
+	user, err := loadUserCached(share.UserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "share link not found"})
+		return
+	}
+
+	RequestDB(c).Model(&share).UpdateColumn("views", gorm.Expr("views + 1"))
+
+	snapshot := gin.H{
+		"name":         user.Name,
+		"member_since": user.CreatedAt,
+		"expires_at":   share.ExpiresAt,
+	}
+	var player models.Player
+	if RequestDB(c).Where("user_id = ?", share.UserID).First(&player).Error == nil {
+		snapshot["gold"] = player.Gold
+		snapshot["gold_rate"] = player.GoldRate
+	}
+	RenderJSON(c, http.StatusOK, snapshot)
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/bernardmuller/go-idle/api"
+)
+
+// Aliases so handlers in this package keep their short call sites; the
+// validator itself lives in the api package.
+
+type FieldError = api.FieldError
+
+type QueryValidator = api.QueryValidator
+
+func ValidateQuery(c *gin.Context) *QueryValidator {
+	return api.ValidateQuery(c)
+}
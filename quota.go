@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Plan-tiered quotas. Request and action limits scale with the billing
+// plan of the user's organization; accounts without an org fall back
+// to the free tier. Counters are in-memory sliding windows, which is
+// fine for soft limits on a single instance.
+
+type PlanQuota struct {
+	RequestsPerMinute int `json:"requests_per_minute"`
+	ActionsPerDay     int `json:"actions_per_day"`
+}
+
+var planQuotas = map[string]PlanQuota{
+	"free":       {RequestsPerMinute: 60, ActionsPerDay: 1000},
+	"pro":        {RequestsPerMinute: 600, ActionsPerDay: 20000},
+	"enterprise": {RequestsPerMinute: 6000, ActionsPerDay: 500000},
+}
+
+type quotaWindow struct {
+	count   int
+	resetAt time.Time
+	// warned is the highest warning rung already notified this window,
+	// so the 80% and 95% notifications fire once each.
+	warned int
+}
+
+type quotaTracker struct {
+	mu      sync.Mutex
+	windows map[uint]*quotaWindow
+	period  time.Duration
+}
+
+func newQuotaTracker(period time.Duration) *quotaTracker {
+	return &quotaTracker{windows: make(map[uint]*quotaWindow), period: period}
+}
+
+// hit records one event for the user and reports whether the limit is
+// now exceeded, along with the window's reset time.
+func (t *quotaTracker) hit(userID uint, limit int) (bool, int, time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	w, ok := t.windows[userID]
+	if !ok || now.After(w.resetAt) {
+		w = &quotaWindow{resetAt: now.Add(t.period)}
+		t.windows[userID] = w
+	}
+	w.count++
+	return w.count > limit, w.count, w.resetAt
+}
+
+func (t *quotaTracker) usage(userID uint) (int, time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	w, ok := t.windows[userID]
+	if !ok || time.Now().After(w.resetAt) {
+		return 0, time.Now().Add(t.period)
+	}
+	return w.count, w.resetAt
+}
+
+// markWarned records that the rung has been notified this window and
+// reports whether this call was the first to reach it.
+func (t *quotaTracker) markWarned(userID uint, rung int) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	w, ok := t.windows[userID]
+	if !ok || w.warned >= rung {
+		return false
+	}
+	w.warned = rung
+	return true
+}
+
+var (
+	requestQuota = newQuotaTracker(time.Minute)
+	actionQuota  = newQuotaTracker(24 * time.Hour)
+)
+
+// quotaWarnRungs are the soft-limit thresholds, in percent, announced
+// before the hard block at 100%.
+var quotaWarnRungs = []int{80, 95}
+
+// quotaUsagePct is integer percent; a zero limit never warns.
+func quotaUsagePct(count, limit int) int {
+	if limit <= 0 {
+		return 0
+	}
+	return count * 100 / limit
+}
+
+// applyQuotaWarnings sets the soft-limit headers on every response at
+// or past a rung and sends the one-time notification when a rung is
+// first crossed. The hard block at 100% stays with the caller.
+func applyQuotaWarnings(c *gin.Context, t *quotaTracker, name string, userID uint, count, limit int, resetAt time.Time) {
+	rung := 0
+	for _, r := range quotaWarnRungs {
+		if quotaUsagePct(count, limit) >= r {
+			rung = r
+		}
+	}
+	if rung == 0 {
+		return
+	}
+
+	c.Header("X-Quota-Warning", strconv.Itoa(rung))
+	c.Header("X-Quota-Limit", strconv.Itoa(limit))
+	c.Header("X-Quota-Remaining", strconv.Itoa(limit-count))
+	c.Header("X-Quota-Reset", strconv.FormatInt(resetAt.Unix(), 10))
+
+	if t.markWarned(userID, rung) && notificationRouter != nil {
+		notificationRouter.Send(userID, Notification{
+			Type:  "quota_warning",
+			Title: fmt.Sprintf("You've used %d%% of your %s quota", rung, name),
+			Body: fmt.Sprintf("You have used %d of %d %ss in the current window. The window resets at %s.",
+				count, limit, name, resetAt.Format(time.RFC3339)),
+		})
+	}
+}
+
+// userPlan resolves the billing plan that applies to a user.
+func userPlan(userID uint) string {
+	var membership models.Membership
+	if err := DB.Where("user_id = ?", userID).First(&membership).Error; err == nil {
+		var org models.Organization
+		if err := DB.First(&org, membership.OrganizationID).Error; err == nil {
+			if _, ok := planQuotas[org.Plan]; ok {
+				return org.Plan
+			}
+		}
+	}
+	return "free"
+}
+
+// RateLimitByPlan enforces the per-minute request quota for the
+// authenticated user's plan.
+func RateLimitByPlan(c *gin.Context) {
+	userID := CurrentUserID(c)
+	if userID == 0 {
+		c.Next()
+		return
+	}
+
+	meterAPICall(userID)
+
+	quota := planQuotas[userPlan(userID)]
+	exceeded, count, resetAt := requestQuota.hit(userID, quota.RequestsPerMinute)
+	if exceeded {
+		c.Header("Retry-After", strconv.Itoa(int(time.Until(resetAt).Seconds())+1))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+			"error": "request quota exceeded for plan",
+			"limit": quota.RequestsPerMinute,
+		})
+		return
+	}
+	applyQuotaWarnings(c, requestQuota, "request", userID, count, quota.RequestsPerMinute, resetAt)
+	c.Next()
+}
+
+// ActionQuotaLimit guards game-action endpoints with the daily action
+// quota.
+func ActionQuotaLimit(c *gin.Context) {
+	userID := CurrentUserID(c)
+	quota := planQuotas[userPlan(userID)]
+	exceeded, count, resetAt := actionQuota.hit(userID, quota.ActionsPerDay)
+	if exceeded {
+		c.Header("Retry-After", strconv.Itoa(int(time.Until(resetAt).Seconds())+1))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+			"error": "daily action quota exceeded for plan",
+			"limit": quota.ActionsPerDay,
+		})
+		return
+	}
+	applyQuotaWarnings(c, actionQuota, "action", userID, count, quota.ActionsPerDay, resetAt)
+	c.Next()
+}
+
+// GetQuota reports the caller's plan, limits, and current usage.
+func GetQuota(c *gin.Context) {
+	userID := CurrentUserID(c)
+	plan := userPlan(userID)
+	quota := planQuotas[plan]
+
+	requestsUsed, requestsReset := requestQuota.usage(userID)
+	actionsUsed, actionsReset := actionQuota.usage(userID)
+
+	RenderJSON(c, http.StatusOK, gin.H{
+		"plan":   plan,
+		"limits": quota,
+		"usage": gin.H{
+			"requests_this_minute": requestsUsed,
+			"requests_reset_at":    requestsReset,
+			"requests_pct":         quotaUsagePct(requestsUsed, quota.RequestsPerMinute),
+			"actions_today":        actionsUsed,
+			"actions_reset_at":     actionsReset,
+			"actions_pct":          quotaUsagePct(actionsUsed, quota.ActionsPerDay),
+		},
+		"warning_thresholds_pct": quotaWarnRungs,
+	})
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Read-only replica mode. Instances started with READ_ONLY=true serve
+// only safe methods; anything mutating gets a 503 pointing clients at
+// the primary (PRIMARY_URL) so replicas can sit behind the same load
+// balancer rule-free. Unpromoted failover standbys behave the same way.
+
+func readOnlyMode() bool {
+	return os.Getenv("READ_ONLY") == "true" || standbyMode()
+}
+
+// ReadOnlyGuard rejects mutating requests on read-only replicas.
+func ReadOnlyGuard(c *gin.Context) {
+	if !readOnlyMode() {
+		c.Next()
+		return
+	}
+
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		c.Next()
+		return
+	}
+
+	// Promotion is the one write a standby must accept, or it could
+	// never become the primary.
+	if c.FullPath() == "/admin/promote" {
+		c.Next()
+		return
+	}
+
+	if primary := os.Getenv("PRIMARY_URL"); primary != "" {
+		c.Header("Location", primary+c.Request.URL.Path)
+	}
+	c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+		"error": "this instance is a read-only replica; send writes to the primary",
+	})
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Request/response recording. When switched on (admin API, dev/staging
+// only), full exchanges are captured into a bounded ring buffer for
+// debugging client issues. Credentials are redacted before anything is
+// stored.
+
+const (
+	recordingBufferSize = 200
+	recordingBodyLimit  = 16 << 10 // 16 KiB per direction
+)
+
+type RecordedExchange struct {
+	At           time.Time `json:"at"`
+	Method       string    `json:"method"`
+	Path         string    `json:"path"`
+	Status       int       `json:"status"`
+	DurationMs   float64   `json:"duration_ms"`
+	RequestBody  string    `json:"request_body,omitempty"`
+	ResponseBody string    `json:"response_body,omitempty"`
+	UserID       uint      `json:"user_id,omitempty"`
+}
+
+type exchangeRecorder struct {
+	mu      sync.Mutex
+	enabled bool
+	buf     []RecordedExchange
+	next    int
+}
+
+var recorder exchangeRecorder
+
+var sensitiveJSONFields = regexp.MustCompile(`"(password|token|identity_token|secret)"\s*:\s*"[^"]*"`)
+
+func redactBody(body []byte) string {
+	if len(body) > recordingBodyLimit {
+		body = body[:recordingBodyLimit]
+	}
+	return sensitiveJSONFields.ReplaceAllString(string(body), `"$1":"[REDACTED]"`)
+}
+
+func (r *exchangeRecorder) add(e RecordedExchange) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.buf) < recordingBufferSize {
+		r.buf = append(r.buf, e)
+		return
+	}
+	r.buf[r.next] = e
+	r.next = (r.next + 1) % recordingBufferSize
+}
+
+func (r *exchangeRecorder) snapshot() []RecordedExchange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]RecordedExchange, 0, len(r.buf))
+	out = append(out, r.buf[r.next:]...)
+	out = append(out, r.buf[:r.next]...)
+	return out
+}
+
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	if w.body.Len() < recordingBodyLimit {
+		w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// RecordExchanges captures request/response pairs while recording is
+// enabled.
+func RecordExchanges(c *gin.Context) {
+	recorder.mu.Lock()
+	enabled := recorder.enabled
+	recorder.mu.Unlock()
+	if !enabled || !chaosAllowed() {
+		c.Next()
+		return
+	}
+
+	var requestBody []byte
+	if c.Request.Body != nil {
+		requestBody, _ = io.ReadAll(io.LimitReader(c.Request.Body, recordingBodyLimit+1))
+		c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(requestBody), c.Request.Body))
+	}
+
+	writer := &recordingWriter{ResponseWriter: c.Writer}
+	c.Writer = writer
+	start := time.Now()
+
+	c.Next()
+
+	recorder.add(RecordedExchange{
+		At:           start,
+		Method:       c.Request.Method,
+		Path:         c.Request.URL.RequestURI(),
+		Status:       writer.Status(),
+		DurationMs:   float64(time.Since(start).Microseconds()) / 1000,
+		RequestBody:  redactBody(requestBody),
+		ResponseBody: redactBody(writer.body.Bytes()),
+		UserID:       CurrentUserID(c),
+	})
+}
+
+func AdminGetRecordings(c *gin.Context) {
+	recorder.mu.Lock()
+	enabled := recorder.enabled
+	recorder.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":   enabled,
+		"exchanges": recorder.snapshot(),
+	})
+}
+
+func AdminSetRecording(c *gin.Context) {
+	if !chaosAllowed() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "recording is disabled in this environment"})
+		return
+	}
+
+	var input struct {
+		Enabled bool `json:"enabled"`
+		Clear   bool `json:"clear"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	recorder.mu.Lock()
+	recorder.enabled = input.Enabled
+	if input.Clear {
+		recorder.buf = nil
+		recorder.next = 0
+	}
+	recorder.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"enabled": input.Enabled})
+}
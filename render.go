@@ -0,0 +1,20 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/bernardmuller/go-idle/api"
+)
+
+// RenderJSON forwards to the api package so handlers living in this
+// package keep their short call sites. It also marks the start of the
+// render segment for Server-Timing (see server_timing.go).
+func RenderJSON(c *gin.Context, status int, v interface{}) {
+	if t := timingFromContext(c.Request.Context()); t != nil {
+		atomic.StoreInt64(&t.renderStart, time.Now().UnixNano())
+	}
+	api.RenderJSON(c, status, v)
+}
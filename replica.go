@@ -0,0 +1,63 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Read-replica routing. With READ_REPLICA_URL set, a second handle is
+// opened against the replica and the read-heavy paths (user listings,
+// leaderboard) query it instead of the primary, keeping list traffic
+// off the write pool. Routing is explicit — a handler opts in through
+// ReadDB/ReadRequestDB — because replica lag makes blanket
+// read-routing wrong for read-after-write flows like login following
+// registration. The replica is optional at startup: if it never comes
+// up, reads fall back to the primary with a logged warning.
+
+var readReplicaDB *gorm.DB
+
+// ConnectReadReplica opens the replica handle; a no-op when
+// READ_REPLICA_URL is unset.
+func ConnectReadReplica() {
+	dsn := os.Getenv("READ_REPLICA_URL")
+	if dsn == "" {
+		return
+	}
+
+	db, err := openDatabaseDSN("read replica", dsn)
+	if err != nil {
+		log.Printf("replica: unavailable, reads stay on the primary: %v", err)
+		return
+	}
+	if err := db.Use(statementCounter{}); err != nil {
+		log.Printf("replica: installing statement counter failed: %v", err)
+	}
+	if err := db.Use(tracingPlugin{}); err != nil {
+		log.Printf("replica: installing tracing failed: %v", err)
+	}
+	if err := db.Use(serverTimingPlugin{}); err != nil {
+		log.Printf("replica: installing server timing failed: %v", err)
+	}
+	readReplicaDB = db
+}
+
+// ReadDB returns the handle read paths should use: the replica when
+// configured, the primary otherwise.
+func ReadDB() *gorm.DB {
+	if readReplicaDB != nil {
+		return readReplicaDB
+	}
+	return DB
+}
+
+// ReadRequestDB is RequestDB for read paths. Sandbox-routed requests
+// stay on their sandbox dataset regardless of the replica.
+func ReadRequestDB(c *gin.Context) *gorm.DB {
+	if db, ok := c.Get(sandboxDBKey); ok {
+		return db.(*gorm.DB).WithContext(c.Request.Context())
+	}
+	return ReadDB().WithContext(c.Request.Context())
+}
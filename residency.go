@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/bernardmuller/go-idle/api"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// Data residency. Organizations and users carry a residency tag
+// ("global" or "eu"); with DATABASE_URL_EU set, a second connection
+// serves EU tenants and residencyDB routes each query to the right
+// region. Asking for a region that has no database configured is an
+// error, never a silent fallback — cross-region reads are blocked by
+// construction. Admin tooling shows the tag and the per-region row
+// counts.
+
+const (
+	ResidencyGlobal = "global"
+	ResidencyEU     = "eu"
+)
+
+// euDB is nil unless DATABASE_URL_EU is configured.
+var euDB *gorm.DB
+
+// ConnectResidencyDatabases opens the EU connection when configured.
+// The EU schema is managed the same way as the primary one.
+func ConnectResidencyDatabases() {
+	dsn := os.Getenv("DATABASE_URL_EU")
+	if dsn == "" {
+		return
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		requestLogger.Error("eu database unavailable", "error", err)
+		return
+	}
+	if _, err := runMigrations(db); err != nil {
+		requestLogger.Error("eu database migrations failed", "error", err)
+		return
+	}
+	if devAutoMigrate() {
+		if err := db.AutoMigrate(migratedModels...); err != nil {
+			requestLogger.Error("eu database automigrate failed", "error", err)
+			return
+		}
+	}
+	euDB = db
+}
+
+// residencyDB returns the connection a region's rows live on.
+func residencyDB(region string) (*gorm.DB, error) {
+	switch region {
+	case "", ResidencyGlobal:
+		return DB, nil
+	case ResidencyEU:
+		if euDB == nil {
+			return nil, fmt.Errorf("cross-region access blocked: no EU database configured")
+		}
+		return euDB, nil
+	default:
+		return nil, fmt.Errorf("unknown residency region %q", region)
+	}
+}
+
+// userResidencyDB routes by a user's tag.
+func userResidencyDB(userID uint) (*gorm.DB, error) {
+	user, err := loadUserCached(userID)
+	if err != nil {
+		return nil, err
+	}
+	return residencyDB(user.Residency)
+}
+
+type ResidencyInput struct {
+	Residency string `json:"residency" binding:"required,oneof=global eu"`
+}
+
+// AdminSetOrgResidency tags an organization and all of its members.
+// The tag controls where rows are written from now on; moving existing
+// rows between regions is an operational task, not something this
+// endpoint does behind your back.
+func AdminSetOrgResidency(c *gin.Context) {
+	var org models.Organization
+	if err := DB.First(&org, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "organization not found"})
+		return
+	}
+
+	var input ResidencyInput
+	if !api.BindJSON(c, &input) {
+		return
+	}
+	if _, err := residencyDB(input.Residency); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := DB.Model(&org).Update("residency", input.Residency).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Mirror the tag onto member users so row routing needs no join.
+	var memberIDs []uint
+	DB.Model(&models.Membership{}).Where("organization_id = ?", org.ID).Pluck("user_id", &memberIDs)
+	if len(memberIDs) > 0 {
+		DB.Model(&models.User{}).Where("id IN ?", memberIDs).Update("residency", input.Residency)
+		for _, id := range memberIDs {
+			userCache.Invalidate(id)
+		}
+	}
+	RenderJSON(c, http.StatusOK, gin.H{"organization_id": org.ID, "residency": input.Residency, "users_tagged": len(memberIDs)})
+}
+
+// AdminResidencyOverview summarizes tags and region availability.
+func AdminResidencyOverview(c *gin.Context) {
+	type regionCount struct {
+		Residency string `json:"residency"`
+		Count     int64  `json:"count"`
+	}
+	var userCounts, orgCounts []regionCount
+	DB.Model(&models.User{}).Select("residency, count(*) as count").Group("residency").Scan(&userCounts)
+	DB.Model(&models.Organization{}).Select("residency, count(*) as count").Group("residency").Scan(&orgCounts)
+
+	RenderJSON(c, http.StatusOK, gin.H{
+		"eu_database_configured": euDB != nil,
+		"users":                  userCounts,
+		"organizations":          orgCounts,
+	})
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Response size guardrails. A list handler that forgets pagination can
+// cheerfully serialize the whole table into one 200MB payload; every
+// response is therefore capped, by default at RESPONSE_MAX_BYTES
+// (10MB). Oversized responses are rejected whole — truncated JSON is
+// worse than an error — with a message pointing the client at the
+// page/per_page parameters. Routes that are legitimately large
+// (exports) or that hijack the connection (WebSocket) raise or drop
+// the cap through the MaxResponseBytes column of the route table. The
+// body buffers until it's known to fit, which the cap itself keeps
+// bounded.
+
+const responseUnlimited = -1
+
+func responseMaxBytes() int64 {
+	if v, err := strconv.ParseInt(os.Getenv("RESPONSE_MAX_BYTES"), 10, 64); err == nil && v > 0 {
+		return v
+	}
+	return 10 << 20
+}
+
+// routeResponseLimit holds per-route overrides from the route table,
+// keyed "METHOD path"; responseUnlimited disables the cap.
+var routeResponseLimit = map[string]int64{}
+
+type sizeLimitWriter struct {
+	gin.ResponseWriter
+	limit    int64
+	written  int64
+	body     []byte
+	overflow bool
+}
+
+func (w *sizeLimitWriter) Write(b []byte) (int, error) {
+	if w.overflow {
+		return len(b), nil
+	}
+	w.written += int64(len(b))
+	if w.written > w.limit {
+		w.overflow = true
+		w.body = nil
+		return len(b), nil
+	}
+	w.body = append(w.body, b...)
+	return len(b), nil
+}
+
+func (w *sizeLimitWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// ResponseSizeLimit enforces the cap; see the file comment.
+func ResponseSizeLimit(c *gin.Context) {
+	limit := responseMaxBytes()
+	if override, ok := routeResponseLimit[c.Request.Method+" "+c.FullPath()]; ok {
+		limit = override
+	}
+	if limit <= 0 {
+		c.Next()
+		return
+	}
+
+	writer := &sizeLimitWriter{ResponseWriter: c.Writer, limit: limit}
+	c.Writer = writer
+	c.Next()
+	c.Writer = writer.ResponseWriter
+
+	if writer.overflow {
+		route := c.Request.Method + " " + c.FullPath()
+		log.Printf("response: %s produced %d bytes against a %d byte cap", route, writer.written, limit)
+		// Gin hasn't flushed the buffered status yet, so the response
+		// can still be replaced wholesale.
+		c.Writer.Header().Del("Content-Disposition")
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		c.Writer.Write([]byte(`{"error":"response too large; narrow the request with page and per_page"}`))
+		return
+	}
+	if len(writer.body) > 0 {
+		c.Writer.Write(writer.body)
+	}
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/bernardmuller/go-idle/internal/handlers"
+)
+
+// Declarative route table. Every hand-written endpoint is one row here
+// — method, path, handler, auth tier, permission, rate class, tags —
+// and mountRoutes turns the rows into gin registrations, route
+// metadata (/admin/routes), OpenAPI tags, and metrics labels, so those
+// views can't drift from what the server actually mounts. The catalog
+// CRUD scaffolding (mountCatalog) registers its own routes and is the
+// one deliberate exception.
+
+type apiRoute struct {
+	Method  string
+	Path    string
+	Handler gin.HandlerFunc
+
+	// Public routes mount outside the Authenticate/RateLimitByPlan/
+	// OrgScope stack.
+	Public bool
+	// Permission is enforced with RequirePermission when set.
+	Permission string
+	// RateClass: "auth" adds AuthRateLimit (public routes only),
+	// "plan+action" adds ActionQuotaLimit on top of the plan limiter;
+	// authorized routes default to "plan".
+	RateClass string
+	// Signed routes additionally require a signed request body.
+	Signed bool
+	// EconomyAction names the capped game action a sink spends; the
+	// row gets EconomyQuotaGuard for it (see economy_quota.go).
+	EconomyAction string
+	// MaxResponseBytes overrides the response size cap for the route;
+	// responseUnlimited lifts it (exports, hijacked connections).
+	MaxResponseBytes int64
+	// WaitingRoom queues the route's excess traffic behind a FIFO
+	// ticket when its concurrency cap is hit (see waiting_room.go).
+	WaitingRoom bool
+
+	Tags       []string
+	Deprecated bool
+	Sunset     string
+}
+
+// routeTags maps "METHOD path" to the route's primary tag for metrics.
+var routeTags = map[string]string{}
+
+// routeTable returns every hand-written route. Handlers that live on
+// wired handler structs are taken as parameters; everything else is a
+// package-level function.
+func routeTable(userHandlers *handlers.UserHandlers, gameHandlers *handlers.GameHandlers) []apiRoute {
+	return []apiRoute{
+		// Public surface.
+		{Method: "GET", Path: "/status", Handler: GetStatus, Public: true, Tags: []string{"ops"}},
+		{Method: "GET", Path: "/healthz", Handler: Healthz, Public: true, Tags: []string{"ops"}},
+		{Method: "GET", Path: "/readyz", Handler: Readyz, Public: true, Tags: []string{"ops"}},
+		{Method: "GET", Path: "/metrics", Handler: GetMetrics, Public: true, Tags: []string{"ops"}},
+		{Method: "POST", Path: "/register", Handler: Register, Public: true, RateClass: "auth", Tags: []string{"auth"}},
+		{Method: "POST", Path: "/login", Handler: Login, Public: true, RateClass: "auth", WaitingRoom: true, Tags: []string{"auth"}},
+		{Method: "POST", Path: "/login/2fa", Handler: ConfirmTwoFactorLogin, Public: true, RateClass: "auth", Tags: []string{"auth"}},
+		{Method: "POST", Path: "/login/passkey/options", Handler: BeginPasskeyLogin, Public: true, RateClass: "auth", Tags: []string{"auth"}},
+		{Method: "POST", Path: "/login/passkey", Handler: FinishPasskeyLogin, Public: true, RateClass: "auth", Tags: []string{"auth"}},
+		{Method: "POST", Path: "/auth/apple", Handler: AppleLogin, Public: true, RateClass: "auth", Tags: []string{"auth"}},
+		{Method: "GET", Path: "/auth/:provider/login", Handler: OAuthLogin, Public: true, Tags: []string{"auth"}},
+		{Method: "GET", Path: "/auth/:provider/callback", Handler: OAuthCallback, Public: true, Tags: []string{"auth"}},
+		{Method: "GET", Path: "/.well-known/jwks.json", Handler: GetJWKS, Public: true, Tags: []string{"auth"}},
+		{Method: "GET", Path: "/openapi.json", Handler: GetOpenAPISpec, Public: true, Tags: []string{"docs"}},
+		{Method: "GET", Path: "/docs", Handler: GetSwaggerUI, Public: true, Tags: []string{"docs"}},
+		{Method: "POST", Path: "/oauth/introspect", Handler: IntrospectToken, Public: true, Tags: []string{"auth"}},
+		{Method: "POST", Path: "/oauth/token", Handler: ExchangeToken, Public: true, Tags: []string{"auth"}},
+		{Method: "POST", Path: "/password-reset/request", Handler: RequestPasswordReset, Public: true, RateClass: "auth", Tags: []string{"auth"}},
+		{Method: "POST", Path: "/password-reset/confirm", Handler: ConfirmPasswordReset, Public: true, RateClass: "auth", Tags: []string{"auth"}},
+		{Method: "GET", Path: "/shared/profiles/:token", Handler: GetSharedProfile, Public: true, Tags: []string{"profiles"}},
+		{Method: "GET", Path: "/failover/config", Handler: GetFailoverConfig, Public: true, Tags: []string{"ops"}},
+
+		// Own account.
+		{Method: "GET", Path: "/me", Handler: GetMe, Tags: []string{"me"}},
+		{Method: "PATCH", Path: "/me", Handler: PatchMe, Tags: []string{"me"}},
+		{Method: "POST", Path: "/me/email/confirm", Handler: ConfirmEmailChange, Tags: []string{"me"}},
+		{Method: "POST", Path: "/me/password", Handler: ChangeMyPassword, Tags: []string{"me"}},
+		{Method: "DELETE", Path: "/me", Handler: DeleteMe, Tags: []string{"me"}},
+		{Method: "GET", Path: "/me/sessions", Handler: GetMySessions, Tags: []string{"me"}},
+		{Method: "DELETE", Path: "/me/sessions/:id", Handler: DeleteMySession, Tags: []string{"me"}},
+		{Method: "POST", Path: "/me/sessions/logout-all", Handler: LogoutAllSessions, Tags: []string{"me"}},
+		{Method: "POST", Path: "/me/2fa/setup", Handler: SetupTwoFactor, Tags: []string{"me"}},
+		{Method: "POST", Path: "/me/2fa/verify", Handler: VerifyTwoFactor, Tags: []string{"me"}},
+		{Method: "POST", Path: "/me/passkeys/options", Handler: BeginPasskeyRegistration, Tags: []string{"me"}},
+		{Method: "POST", Path: "/me/passkeys", Handler: FinishPasskeyRegistration, Tags: []string{"me"}},
+		{Method: "GET", Path: "/me/passkeys", Handler: GetMyPasskeys, Tags: []string{"me"}},
+		{Method: "DELETE", Path: "/me/passkeys/:id", Handler: DeleteMyPasskey, Tags: []string{"me"}},
+		{Method: "GET", Path: "/me/mailbox", Handler: GetMyMailbox, Tags: []string{"me"}},
+		{Method: "POST", Path: "/me/mailbox/:id/read", Handler: ReadMailboxMessage, Tags: []string{"me"}},
+		{Method: "POST", Path: "/me/mailbox/:id/claim", Handler: ClaimMailboxMessage, EconomyAction: "mailbox_claim", Tags: []string{"me"}},
+		{Method: "POST", Path: "/me/api-keys", Handler: CreateMyAPIKey, Tags: []string{"me"}},
+		{Method: "GET", Path: "/me/api-keys", Handler: GetMyAPIKeys, Tags: []string{"me"}},
+		{Method: "DELETE", Path: "/me/api-keys/:id", Handler: DeleteMyAPIKey, Tags: []string{"me"}},
+		{Method: "POST", Path: "/me/api-keys/:id/rotate", Handler: RotateMyAPIKey, Tags: []string{"me"}},
+		{Method: "GET", Path: "/users/me/quota", Handler: GetQuota, Tags: []string{"me"}},
+		{Method: "DELETE", Path: "/users/me", Handler: DeleteAccount, Tags: []string{"me"}},
+		{Method: "POST", Path: "/users/me/reactivate", Handler: ReactivateAccount, Tags: []string{"me"}},
+		{Method: "GET", Path: "/users/me/messages", Handler: GetMyMessages, Tags: []string{"me"}},
+		{Method: "POST", Path: "/users/me/heartbeat", Handler: Heartbeat, Tags: []string{"me"}},
+		{Method: "GET", Path: "/users/me/consent", Handler: GetMyConsent, Tags: []string{"me"}},
+		{Method: "PUT", Path: "/users/me/consent", Handler: UpdateMyConsent, Tags: []string{"me"}},
+		{Method: "GET", Path: "/users/me/notifications", Handler: GetMyNotifications, Tags: []string{"me"}},
+		{Method: "POST", Path: "/users/me/notifications/:id/read", Handler: ReadMyNotification, Tags: []string{"me"}},
+		{Method: "POST", Path: "/users/me/profile/share", Handler: CreateProfileShare, Tags: []string{"me"}},
+		{Method: "GET", Path: "/users/me/profile/shares", Handler: GetMyProfileShares, Tags: []string{"me"}},
+		{Method: "DELETE", Path: "/users/me/profile/shares/:id", Handler: RevokeProfileShare, Tags: []string{"me"}},
+
+		// Users and search.
+		{Method: "GET", Path: "/users", Handler: userHandlers.GetUsers, Tags: []string{"users"}},
+		{Method: "GET", Path: "/users/:id", Handler: userHandlers.GetUser, Tags: []string{"users"}},
+		{Method: "GET", Path: "/search/users", Handler: SearchUsers, Tags: []string{"users"}},
+
+		// Game.
+		{Method: "GET", Path: "/game/state", Handler: gameHandlers.GetGameState, Tags: []string{"game"}},
+		{Method: "GET", Path: "/game/allowances", Handler: GetGameAllowances, Tags: []string{"game"}},
+		// EconomyAction on the tick row is uncapped unless the config
+		// says otherwise, but it puts the core earn loop behind the
+		// emergency freeze switch (see economy_freeze.go).
+		{Method: "POST", Path: "/game/tick", Handler: gameHandlers.Tick, RateClass: "plan+action", EconomyAction: "tick", Tags: []string{"game"}},
+		{Method: "POST", Path: "/telemetry", Handler: IngestTelemetry, Tags: []string{"telemetry"}},
+		{Method: "GET", Path: "/leaderboard", Handler: GetLeaderboard, Tags: []string{"game"}},
+		// Long polling is superseded by the websocket; its remaining
+		// callers show up in /admin/deprecations.
+		{Method: "GET", Path: "/game/poll", Handler: PollGameState, Tags: []string{"game"}, Deprecated: true, Sunset: "2027-03-01"},
+		{Method: "GET", Path: "/sync", Handler: GetSync, Tags: []string{"game"}},
+		{Method: "GET", Path: "/ws", Handler: GameSocket, Tags: []string{"game"}, MaxResponseBytes: responseUnlimited},
+		{Method: "POST", Path: "/saves/checkpoint", Handler: CreateSaveCheckpoint, Tags: []string{"game"}},
+		{Method: "POST", Path: "/saves/validate", Handler: ValidateSave, Tags: []string{"game"}},
+
+		// Sandbox.
+		{Method: "POST", Path: "/sandbox/keys", Handler: CreateSandboxKey, Tags: []string{"sandbox"}},
+		{Method: "POST", Path: "/sandbox/reset", Handler: ResetSandbox, Tags: []string{"sandbox"}},
+
+		// Org lifecycle and org-scoped role management check org
+		// membership themselves.
+		{Method: "POST", Path: "/orgs", Handler: CreateOrg, Tags: []string{"orgs"}},
+		{Method: "GET", Path: "/orgs", Handler: GetMyOrgs, Tags: []string{"orgs"}},
+		{Method: "GET", Path: "/orgs/:id/members", Handler: ListOrgMembers, Tags: []string{"orgs"}},
+		{Method: "POST", Path: "/orgs/:id/members", Handler: InviteOrgMember, Tags: []string{"orgs"}},
+		{Method: "DELETE", Path: "/orgs/:id/members/:userId", Handler: RemoveOrgMember, Tags: []string{"orgs"}},
+		{Method: "GET", Path: "/orgs/:id/roles", Handler: ListOrgRoles, Tags: []string{"orgs"}},
+		{Method: "POST", Path: "/orgs/:id/roles", Handler: CreateOrgRole, Tags: []string{"orgs"}},
+		{Method: "DELETE", Path: "/orgs/:id/roles/:roleId", Handler: DeleteOrgRole, Tags: []string{"orgs"}},
+		{Method: "GET", Path: "/orgs/:id/export", Handler: ExportOrg, Tags: []string{"orgs"}, MaxResponseBytes: responseUnlimited},
+		{Method: "PUT", Path: "/orgs/:id/api-key-policy", Handler: SetOrgAPIKeyPolicy, Tags: []string{"orgs"}},
+		{Method: "DELETE", Path: "/orgs/:id", Handler: OffboardOrg, Tags: []string{"orgs"}},
+
+		// Admin surface.
+		{Method: "GET", Path: "/admin/deletions", Handler: AdminListDeletions, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/inactivity", Handler: AdminInactivityReport, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "POST", Path: "/admin/deletions/purge", Handler: AdminTriggerPurge, Permission: "admin", Signed: true, Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/shredding/report", Handler: AdminShreddingReport, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/chaos", Handler: AdminGetChaos, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "PUT", Path: "/admin/chaos", Handler: AdminSetChaos, Permission: "admin", Signed: true, Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/routes", Handler: AdminListRoutes, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/deprecations", Handler: AdminDeprecationReport, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/audit-logs", Handler: AdminListAuditLogs, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/failover", Handler: AdminFailoverStatus, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "POST", Path: "/admin/promote", Handler: AdminPromote, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "POST", Path: "/admin/drain", Handler: AdminStartDrain, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "DELETE", Path: "/admin/drain", Handler: AdminStopDrain, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "POST", Path: "/admin/messages", Handler: AdminSendBulkMessage, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "POST", Path: "/admin/mailbox", Handler: AdminSendMailboxCampaign, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/password-hash/benchmark", Handler: AdminHashBenchmark, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/game-events", Handler: AdminListGameEvents, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/recordings", Handler: AdminGetRecordings, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "PUT", Path: "/admin/recordings", Handler: AdminSetRecording, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/email-templates", Handler: AdminListEmailTemplates, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "PUT", Path: "/admin/email-templates/:name", Handler: AdminUpdateEmailTemplate, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "POST", Path: "/admin/email-templates/:name/preview", Handler: AdminPreviewEmailTemplate, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/config", Handler: AdminExportConfig, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/billing/usage", Handler: AdminBillingUsage, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/sagas", Handler: AdminListSagas, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "POST", Path: "/admin/reports", Handler: AdminRunReport, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/reports/sources", Handler: AdminListReportSources, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "POST", Path: "/admin/orgs/:id/billing", Handler: AdminProvisionOrgBilling, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/approvals", Handler: AdminListApprovals, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "POST", Path: "/admin/approvals/:id", Handler: AdminDecideApproval, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/funnel", Handler: AdminGetFunnel, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "PUT", Path: "/admin/funnel", Handler: AdminPutFunnel, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/funnel/report", Handler: AdminFunnelReport, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/jobs", Handler: AdminListJobs, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "POST", Path: "/admin/jobs/:id/retry", Handler: AdminRetryJob, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "POST", Path: "/admin/search/reindex", Handler: AdminReindexUsers, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/game-config", Handler: AdminGetGameConfig, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "PATCH", Path: "/admin/game-config", Handler: AdminPatchGameConfig, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/consistency/balances", Handler: AdminCheckBalances, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/telemetry/saturation", Handler: AdminTelemetrySaturation, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/telemetry/history", Handler: AdminTelemetryHistory, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/players/:id/replay", Handler: AdminReplayPlayer, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/users/:id/consent-history", Handler: AdminConsentHistory, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "POST", Path: "/admin/players/:id/rollback", Handler: AdminRollbackPlayer, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/webhooks", Handler: AdminListWebhooks, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "POST", Path: "/admin/webhooks", Handler: AdminCreateWebhook, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "DELETE", Path: "/admin/webhooks/:id", Handler: AdminDeleteWebhook, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "POST", Path: "/admin/webhooks/:id/test", Handler: AdminTestWebhook, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/partitions", Handler: AdminListPartitions, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/maintenance", Handler: AdminListMaintenance, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "POST", Path: "/admin/maintenance", Handler: AdminScheduleMaintenance, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "DELETE", Path: "/admin/maintenance/:id", Handler: AdminCancelMaintenance, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/economy/freeze", Handler: AdminListEconomyFreezes, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "POST", Path: "/admin/economy/freeze", Handler: AdminFreezeEconomyAction, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "DELETE", Path: "/admin/economy/freeze/:action", Handler: AdminUnfreezeEconomyAction, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/incidents", Handler: AdminListIncidents, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "POST", Path: "/admin/incidents", Handler: AdminCreateIncident, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "PUT", Path: "/admin/incidents/:id", Handler: AdminUpdateIncident, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/backups", Handler: AdminListBackups, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "POST", Path: "/admin/backups/run", Handler: AdminRunBackup, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/catalog/history", Handler: AdminCatalogHistory, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/roles", Handler: AdminListRoles, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "POST", Path: "/admin/roles", Handler: AdminCreateRole, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "PUT", Path: "/admin/roles/:id", Handler: AdminUpdateRole, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "DELETE", Path: "/admin/roles/:id", Handler: AdminDeleteRole, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/permissions", Handler: AdminListPermissions, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "POST", Path: "/admin/permissions", Handler: AdminCreatePermission, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "DELETE", Path: "/admin/permissions/:id", Handler: AdminDeletePermission, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "POST", Path: "/admin/users/import", Handler: AdminImportUsers, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/users/export", Handler: AdminExportUsers, Permission: "admin", Tags: []string{"admin"}, MaxResponseBytes: responseUnlimited},
+		{Method: "POST", Path: "/admin/users/:id/roles", Handler: AdminAssignRole, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "POST", Path: "/admin/users/:id/restore", Handler: AdminRestoreUser, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "DELETE", Path: "/admin/users/:id", Handler: AdminPurgeUser, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "POST", Path: "/admin/notifications", Handler: AdminSendNotification, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/notifications/deliveries", Handler: AdminNotificationDeliveries, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "GET", Path: "/admin/residency", Handler: AdminResidencyOverview, Permission: "admin", Tags: []string{"admin"}},
+		{Method: "PUT", Path: "/admin/orgs/:id/residency", Handler: AdminSetOrgResidency, Permission: "admin", Tags: []string{"admin"}},
+	}
+}
+
+// mountRoutes registers the table on the engine, assembling each row's
+// middleware chain and recording its metadata as it goes.
+func mountRoutes(public, authorized gin.IRoutes, table []apiRoute) {
+	for _, rt := range table {
+		target := public
+		rateClass := rt.RateClass
+		var middleware []string
+		if !rt.Public {
+			target = authorized
+			middleware = append(middleware, "Authenticate", "RateLimitByPlan", "OrgScope")
+			if rateClass == "" {
+				rateClass = "plan"
+			}
+		}
+
+		chain := make([]gin.HandlerFunc, 0, 4)
+		if rt.WaitingRoom {
+			chain = append(chain, LoginWaitingRoomGuard)
+			middleware = append(middleware, "LoginWaitingRoomGuard")
+		}
+		if rt.RateClass == "auth" {
+			chain = append(chain, AuthRateLimit)
+			middleware = append(middleware, "AuthRateLimit")
+		}
+		if rt.Permission != "" {
+			chain = append(chain, RequirePermission(rt.Permission))
+			middleware = append(middleware, "RequirePermission("+rt.Permission+")")
+		}
+		if rt.Permission == "admin" {
+			chain = append(chain, AdminMTLSGuard)
+			middleware = append(middleware, "AdminMTLSGuard")
+		}
+		if rt.Signed {
+			chain = append(chain, RequireSignedRequest)
+			middleware = append(middleware, "RequireSignedRequest")
+		}
+		if rateClass == "plan+action" {
+			chain = append(chain, ActionQuotaLimit)
+			middleware = append(middleware, "ActionQuotaLimit")
+		}
+		if rt.EconomyAction != "" {
+			chain = append(chain, EconomyQuotaGuard(rt.EconomyAction))
+			middleware = append(middleware, "EconomyQuotaGuard("+rt.EconomyAction+")")
+		}
+		chain = append(chain, rt.Handler)
+
+		target.Handle(rt.Method, rt.Path, chain...)
+		annotateRoute(rt.Method, rt.Path, RouteMeta{
+			Permission:     rt.Permission,
+			RateLimitClass: rateClass,
+			Middleware:     middleware,
+			Tags:           rt.Tags,
+			Deprecated:     rt.Deprecated,
+			Sunset:         rt.Sunset,
+		})
+		if len(rt.Tags) > 0 {
+			routeTags[rt.Method+" "+rt.Path] = rt.Tags[0]
+		}
+		if rt.MaxResponseBytes != 0 {
+			routeResponseLimit[rt.Method+" "+rt.Path] = rt.MaxResponseBytes
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Route table introspection. Route registrations can be annotated with
+// the permission they require and their rate-limit class; the admin
+// endpoint merges those annotations with gin's own route table so a
+// security review doesn't require reading source.
+
+type RouteMeta struct {
+	Permission     string   `json:"permission,omitempty"`
+	RateLimitClass string   `json:"rate_limit_class,omitempty"`
+	Middleware     []string `json:"middleware,omitempty"`
+	Tags           []string `json:"tags,omitempty"`
+
+	// Deprecated routes advertise themselves via Deprecation/Sunset
+	// headers and have their remaining callers tracked; see
+	// deprecations.go. Sunset is the planned removal date (YYYY-MM-DD).
+	Deprecated       bool     `json:"deprecated,omitempty"`
+	Sunset           string   `json:"sunset,omitempty"`
+	DeprecatedFields []string `json:"deprecated_fields,omitempty"`
+}
+
+var (
+	appRouter *gin.Engine
+	routeMeta = map[string]RouteMeta{}
+)
+
+// annotateRoute records metadata for a registered route, keyed by
+// "METHOD /path".
+func annotateRoute(method, path string, meta RouteMeta) {
+	routeMeta[method+" "+path] = meta
+}
+
+func AdminListRoutes(c *gin.Context) {
+	if appRouter == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "router not initialized"})
+		return
+	}
+
+	type routeEntry struct {
+		Method         string   `json:"method"`
+		Path           string   `json:"path"`
+		Handler        string   `json:"handler"`
+		Permission     string   `json:"permission,omitempty"`
+		RateLimitClass string   `json:"rate_limit_class,omitempty"`
+		Middleware     []string `json:"middleware,omitempty"`
+		Tags           []string `json:"tags,omitempty"`
+		Deprecated     bool     `json:"deprecated,omitempty"`
+		Sunset         string   `json:"sunset,omitempty"`
+	}
+
+	routes := appRouter.Routes()
+	out := make([]routeEntry, 0, len(routes))
+	for _, r := range routes {
+		meta := routeMeta[r.Method+" "+r.Path]
+		handler := r.Handler
+		if i := strings.LastIndex(handler, "."); i >= 0 {
+			handler = handler[i+1:]
+		}
+		out = append(out, routeEntry{
+			Method:         r.Method,
+			Path:           r.Path,
+			Handler:        handler,
+			Permission:     meta.Permission,
+			RateLimitClass: meta.RateLimitClass,
+			Middleware:     meta.Middleware,
+			Tags:           meta.Tags,
+			Deprecated:     meta.Deprecated,
+			Sunset:         meta.Sunset,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Path != out[j].Path {
+			return out[i].Path < out[j].Path
+		}
+		return out[i].Method < out[j].Method
+	})
+
+	RenderJSON(c, http.StatusOK, out)
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bernardmuller/go-idle/api"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Sagas. A flow that spans systems — Stripe, the database, email —
+// can't be wrapped in one transaction, so each one runs as a saga:
+// named steps executed in order with the cursor persisted after every
+// step, and a compensating action per step that undoes its work when
+// a later step fails. A crash mid-saga leaves a persisted row that
+// boot-time recovery compensates, so partial failures never strand
+// orphaned records in an external system. Compensations must be
+// idempotent: recovery can't know whether the step at the cursor
+// finished, so it compensates it either way.
+
+// sagaStep is one unit of work and its undo. Both receive the saga's
+// payload, which steps mutate to pass ids forward (and to their
+// compensation).
+type sagaStep struct {
+	Name       string
+	Run        func(payload map[string]interface{}) error
+	Compensate func(payload map[string]interface{}) error
+}
+
+// sagaRegistry maps saga names to their step lists; registration
+// happens at startup, before any saga can run or recover.
+var sagaRegistry = map[string][]sagaStep{}
+
+func registerSaga(name string, steps ...sagaStep) {
+	sagaRegistry[name] = steps
+}
+
+// runSaga executes a registered saga to completion, compensating
+// completed steps in reverse on failure. It returns the persisted row
+// and the step error, if any.
+func runSaga(name string, payload map[string]interface{}) (*models.Saga, error) {
+	steps, ok := sagaRegistry[name]
+	if !ok {
+		return nil, fmt.Errorf("saga: unknown saga %q", name)
+	}
+
+	saga := &models.Saga{Name: name, State: models.SagaRunning}
+	savePayload(saga, payload)
+	if err := DB.Create(saga).Error; err != nil {
+		return nil, err
+	}
+
+	for i, step := range steps {
+		if err := step.Run(payload); err != nil {
+			saga.Error = fmt.Sprintf("%s: %v", step.Name, err)
+			compensateSaga(saga, payload, i-1)
+			return saga, err
+		}
+		saga.Step = i + 1
+		savePayload(saga, payload)
+		DB.Model(saga).Updates(map[string]interface{}{"step": saga.Step, "payload": saga.Payload})
+	}
+
+	saga.State = models.SagaCompleted
+	DB.Model(saga).Update("state", saga.State)
+	return saga, nil
+}
+
+// compensateSaga undoes steps from upTo down to the first. A
+// compensation that itself fails leaves the saga in the failed state
+// for an operator; the rest are still attempted.
+func compensateSaga(saga *models.Saga, payload map[string]interface{}, upTo int) {
+	steps := sagaRegistry[saga.Name]
+	if upTo > len(steps)-1 {
+		upTo = len(steps) - 1
+	}
+	saga.State = models.SagaCompensating
+	DB.Model(saga).Updates(map[string]interface{}{"state": saga.State, "error": saga.Error})
+
+	clean := true
+	for i := upTo; i >= 0; i-- {
+		if steps[i].Compensate == nil {
+			continue
+		}
+		if err := steps[i].Compensate(payload); err != nil {
+			log.Printf("saga: %s #%d compensating %q failed: %v", saga.Name, saga.ID, steps[i].Name, err)
+			clean = false
+		}
+	}
+
+	saga.State = models.SagaCompensated
+	if !clean {
+		saga.State = models.SagaFailed
+	}
+	savePayload(saga, payload)
+	DB.Model(saga).Updates(map[string]interface{}{"state": saga.State, "payload": saga.Payload})
+}
+
+func savePayload(saga *models.Saga, payload map[string]interface{}) {
+	raw, err := json.Marshal(payload)
+	if err == nil {
+		saga.Payload = string(raw)
+	}
+}
+
+// RecoverSagas compensates sagas left running by a crash; called once
+// at primary startup, after registration.
+func RecoverSagas() {
+	var stuck []models.Saga
+	if err := DB.Where("state = ?", models.SagaRunning).Find(&stuck).Error; err != nil {
+		log.Printf("saga: recovery scan failed: %v", err)
+		return
+	}
+	for i := range stuck {
+		saga := &stuck[i]
+		payload := map[string]interface{}{}
+		json.Unmarshal([]byte(saga.Payload), &payload)
+		saga.Error = "interrupted by restart"
+		log.Printf("saga: compensating interrupted %s #%d at step %d", saga.Name, saga.ID, saga.Step)
+		// The step at the cursor may or may not have finished; its
+		// compensation runs regardless, which is why they're idempotent.
+		compensateSaga(saga, payload, saga.Step)
+	}
+}
+
+// AdminListSagas shows recent sagas, optionally filtered by state.
+func AdminListSagas(c *gin.Context) {
+	q := api.ValidateQuery(c)
+	limit := q.Int("limit", 50, 1, 500)
+	state := q.Enum("state", "", string(models.SagaRunning), string(models.SagaCompleted),
+		string(models.SagaCompensating), string(models.SagaCompensated), string(models.SagaFailed))
+	if !q.Valid() {
+		return
+	}
+
+	query := ReadRequestDB(c).Order("id DESC").Limit(limit)
+	if state != "" {
+		query = query.Where("state = ?", state)
+	}
+	var sagas []models.Saga
+	if err := query.Find(&sagas).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, sagas)
+}
+
+// --- org billing provisioning ----------------------------------------
+
+// registerBillingSaga wires the saga that links an organization to
+// Stripe: create the customer there, persist the link here, then tell
+// the org's admins. If persisting or notifying fails, the Stripe
+// customer is deleted again rather than left orphaned.
+func registerBillingSaga() {
+	registerSaga("org.billing_provision",
+		sagaStep{
+			Name: "create stripe customer",
+			Run: func(p map[string]interface{}) error {
+				id, err := stripeCreateCustomer(stringField(p, "org_name"))
+				if err != nil {
+					return err
+				}
+				p["stripe_customer_id"] = id
+				return nil
+			},
+			Compensate: func(p map[string]interface{}) error {
+				if id := stringField(p, "stripe_customer_id"); id != "" {
+					return stripeDeleteCustomer(id)
+				}
+				return nil
+			},
+		},
+		sagaStep{
+			Name: "link organization",
+			Run: func(p map[string]interface{}) error {
+				res := DB.Model(&models.Organization{}).Where("id = ?", uintField(p, "org_id")).
+					Update("stripe_customer_id", stringField(p, "stripe_customer_id"))
+				if res.Error != nil {
+					return res.Error
+				}
+				if res.RowsAffected == 0 {
+					return fmt.Errorf("organization %d not found", uintField(p, "org_id"))
+				}
+				return nil
+			},
+			Compensate: func(p map[string]interface{}) error {
+				return DB.Model(&models.Organization{}).Where("id = ?", uintField(p, "org_id")).
+					Update("stripe_customer_id", "").Error
+			},
+		},
+		sagaStep{
+			Name: "notify org admins",
+			Run: func(p map[string]interface{}) error {
+				if notificationRouter == nil {
+					return nil
+				}
+				var admins []models.Membership
+				if err := DB.Where("organization_id = ? AND admin", uintField(p, "org_id")).Find(&admins).Error; err != nil {
+					return err
+				}
+				for _, m := range admins {
+					notificationRouter.Send(m.UserID, Notification{
+						Type:  "billing_enabled",
+						Title: "Usage billing enabled",
+						Body:  "Your organization is now linked to Stripe for usage-based billing.",
+					})
+				}
+				return nil
+			},
+			// Notifications can't be unsent; nothing to compensate.
+		},
+	)
+}
+
+// payload values round-trip through JSON, so numbers come back as
+// float64.
+func stringField(p map[string]interface{}, key string) string {
+	s, _ := p[key].(string)
+	return s
+}
+
+func uintField(p map[string]interface{}, key string) uint {
+	switch v := p[key].(type) {
+	case float64:
+		return uint(v)
+	case uint:
+		return v
+	}
+	return 0
+}
+
+// stripeCreateCustomer creates a customer and returns its id.
+func stripeCreateCustomer(name string) (string, error) {
+	key := stripeAPIKey()
+	if key == "" {
+		return "", fmt.Errorf("STRIPE_API_KEY is not configured")
+	}
+	form := url.Values{"name": {name}}
+	req, err := http.NewRequest(http.MethodPost, "https://api.stripe.com/v1/customers", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(key, "")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("stripe: %s", resp.Status)
+	}
+	var out struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil || out.ID == "" {
+		return "", fmt.Errorf("stripe: malformed customer response")
+	}
+	return out.ID, nil
+}
+
+// stripeDeleteCustomer removes a customer; deleting one that's already
+// gone succeeds, which keeps the compensation idempotent.
+func stripeDeleteCustomer(id string) error {
+	req, err := http.NewRequest(http.MethodDelete, "https://api.stripe.com/v1/customers/"+url.PathEscape(id), nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(stripeAPIKey(), "")
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("stripe: %s", resp.Status)
+	}
+	return nil
+}
+
+// AdminProvisionOrgBilling runs the billing saga for one organization.
+func AdminProvisionOrgBilling(c *gin.Context) {
+	var org models.Organization
+	if err := RequestDB(c).First(&org, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "organization not found"})
+		return
+	}
+	if org.StripeCustomerID != "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "organization is already linked to Stripe"})
+		return
+	}
+
+	payload := map[string]interface{}{"org_id": org.ID, "org_name": org.Name}
+	saga, err := runSaga("org.billing_provision", payload)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "provisioning failed and was rolled back", "saga": saga})
+		return
+	}
+	RenderJSON(c, http.StatusOK, gin.H{"provisioned": true, "saga": saga})
+}
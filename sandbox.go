@@ -0,0 +1,157 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/bernardmuller/go-idle/api"
+	"github.com/bernardmuller/go-idle/config"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// Developer sandboxes. With SANDBOX_ENABLED=true a second connection
+// points at the `sandbox` schema of the same database; requests that
+// carry a valid X-Sandbox-Key header have RequestDB routed there, so
+// integrators can exercise destructive flows against throwaway data.
+// POST /sandbox/reset wipes the schema and rebuilds it. Handlers going
+// through RequestDB are sandbox-aware automatically; background
+// writers always run against live data.
+
+const sandboxSchema = "sandbox"
+
+// sandboxDB is nil unless sandboxes are enabled and the schema opened.
+var sandboxDB *gorm.DB
+
+func sandboxEnabled() bool {
+	return os.Getenv("SANDBOX_ENABLED") == "true" && usingPostgres()
+}
+
+// sandboxDSN pins the connection's search_path to the sandbox schema.
+func sandboxDSN() string {
+	dsn := config.Get().DatabaseURL
+	if u, err := url.Parse(dsn); err == nil && u.Host != "" {
+		q := u.Query()
+		q.Set("search_path", sandboxSchema)
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	return dsn + " search_path=" + sandboxSchema
+}
+
+// ConnectSandboxDatabase creates the schema and opens the routed
+// connection.
+func ConnectSandboxDatabase() {
+	if !sandboxEnabled() {
+		return
+	}
+	if err := DB.Exec("CREATE SCHEMA IF NOT EXISTS " + sandboxSchema).Error; err != nil {
+		requestLogger.Error("sandbox schema creation failed", "error", err)
+		return
+	}
+
+	db, err := gorm.Open(postgres.Open(sandboxDSN()), &gorm.Config{})
+	if err != nil {
+		requestLogger.Error("sandbox database unavailable", "error", err)
+		return
+	}
+	if err := db.AutoMigrate(migratedModels...); err != nil {
+		requestLogger.Error("sandbox automigrate failed", "error", err)
+		return
+	}
+	sandboxDB = db
+}
+
+const sandboxDBKey = "sandboxDB"
+
+// SandboxRouter swaps RequestDB to the sandbox connection for requests
+// carrying a valid sandbox key.
+func SandboxRouter(c *gin.Context) {
+	key := c.GetHeader("X-Sandbox-Key")
+	if key == "" {
+		c.Next()
+		return
+	}
+	if sandboxDB == nil {
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "sandboxes are not enabled"})
+		return
+	}
+
+	var record models.SandboxKey
+	if err := DB.Where("key_hash = ?", hashResetToken(key)).First(&record).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid sandbox key"})
+		return
+	}
+	if time.Since(deref(record.LastUsedAt)) > time.Minute {
+		now := time.Now()
+		DB.Model(&record).Update("last_used_at", now)
+	}
+	c.Set(sandboxDBKey, sandboxDB)
+	c.Header("X-Sandbox", "true")
+	c.Next()
+}
+
+func deref(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
+
+type SandboxKeyInput struct {
+	Label string `json:"label" binding:"max=100"`
+}
+
+// CreateSandboxKey mints a key for the caller; the secret is shown
+// exactly once.
+func CreateSandboxKey(c *gin.Context) {
+	if !sandboxEnabled() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "sandboxes are not enabled"})
+		return
+	}
+	var input SandboxKeyInput
+	if !api.BindJSON(c, &input) {
+		return
+	}
+
+	raw := make([]byte, 24)
+	rand.Read(raw)
+	secret := "sbx_" + hex.EncodeToString(raw)
+
+	record := models.SandboxKey{UserID: CurrentUserID(c), Label: input.Label, KeyHash: hashResetToken(secret)}
+	if err := DB.Create(&record).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusCreated, gin.H{"id": record.ID, "label": record.Label, "key": secret})
+}
+
+// ResetSandbox drops and rebuilds the sandbox schema. It requires a
+// valid sandbox key on the request, which SandboxRouter has already
+// verified if the context carries the sandbox handle.
+func ResetSandbox(c *gin.Context) {
+	if _, ok := c.Get(sandboxDBKey); !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "a sandbox key is required"})
+		return
+	}
+
+	if err := DB.Exec("DROP SCHEMA IF EXISTS " + sandboxSchema + " CASCADE").Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := DB.Exec("CREATE SCHEMA " + sandboxSchema).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := sandboxDB.AutoMigrate(migratedModels...); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, gin.H{"reset": true})
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bernardmuller/go-idle/api"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+// Client save validation. Clients that keep a local save ask the
+// server for a checkpoint: a hash of the authoritative player state,
+// HMAC-signed so the client cannot mint its own. On reconnect the
+// client submits its save plus the last signature; a save whose fields
+// don't hash to the signed value was tampered with, and a valid
+// signature older than the newest checkpoint is a fork (the save
+// branched off a state that has since moved on).
+
+// saveFields is the canonical portion of the player state covered by a
+// checkpoint signature.
+type saveFields struct {
+	Gold       int64     `json:"gold" binding:"required"`
+	GoldRate   int64     `json:"gold_rate"`
+	LastTickAt time.Time `json:"last_tick_at" binding:"required"`
+}
+
+// saveStateHash canonicalizes and hashes the signed fields. The fixed
+// field order and RFC 3339 nano timestamps make the hash independent
+// of client-side JSON formatting.
+func saveStateHash(userID uint, save saveFields) string {
+	canonical := fmt.Sprintf("%d|%d|%d|%s",
+		userID, save.Gold, save.GoldRate, save.LastTickAt.UTC().Format(time.RFC3339Nano))
+	sum := sha256.Sum256([]byte(canonical))
+	return hex.EncodeToString(sum[:])
+}
+
+// signSaveHash binds the state hash to a user and issue time with the
+// server secret.
+func signSaveHash(userID uint, stateHash string, issuedAt time.Time) string {
+	mac := hmac.New(sha256.New, jwtSecret)
+	fmt.Fprintf(mac, "save|%d|%s|%d", userID, stateHash, issuedAt.Unix())
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// CreateSaveCheckpoint signs the authoritative state and records it as
+// the newest checkpoint for the caller.
+func CreateSaveCheckpoint(c *gin.Context) {
+	userID := CurrentUserID(c)
+	var player models.Player
+	if err := RequestDB(c).Where("user_id = ?", userID).First(&player).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "player not found"})
+		return
+	}
+
+	save := saveFields{Gold: player.Gold, GoldRate: player.GoldRate, LastTickAt: player.LastTickAt}
+	issuedAt := time.Now().Truncate(time.Second)
+	stateHash := saveStateHash(userID, save)
+
+	checkpoint := models.SaveCheckpoint{UserID: userID, StateHash: stateHash, IssuedAt: issuedAt}
+	err := RequestDB(c).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"state_hash", "issued_at"}),
+	}).Create(&checkpoint).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	RenderJSON(c, http.StatusOK, gin.H{
+		"save":       save,
+		"state_hash": stateHash,
+		"issued_at":  issuedAt.Unix(),
+		"signature":  signSaveHash(userID, stateHash, issuedAt),
+	})
+}
+
+type ValidateSaveInput struct {
+	Save      saveFields `json:"save" binding:"required"`
+	IssuedAt  int64      `json:"issued_at" binding:"required"`
+	Signature string     `json:"signature" binding:"required"`
+}
+
+// ValidateSave checks a submitted local save against its signature and
+// the newest checkpoint.
+func ValidateSave(c *gin.Context) {
+	var input ValidateSaveInput
+	if !api.BindJSON(c, &input) {
+		return
+	}
+	userID := CurrentUserID(c)
+
+	stateHash := saveStateHash(userID, input.Save)
+	expected := signSaveHash(userID, stateHash, time.Unix(input.IssuedAt, 0))
+	if !hmac.Equal([]byte(expected), []byte(input.Signature)) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"valid":  false,
+			"reason": "tampered",
+		})
+		return
+	}
+
+	var checkpoint models.SaveCheckpoint
+	if err := RequestDB(c).Where("user_id = ?", userID).First(&checkpoint).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no checkpoint on record; request one first"})
+		return
+	}
+	if checkpoint.StateHash != stateHash {
+		// The signature is genuine but covers an older state: the save
+		// branched off before the latest checkpoint.
+		c.JSON(http.StatusConflict, gin.H{
+			"valid":                false,
+			"reason":               "forked",
+			"checkpoint_issued_at": checkpoint.IssuedAt.Unix(),
+		})
+		return
+	}
+
+	RenderJSON(c, http.StatusOK, gin.H{"valid": true, "state_hash": stateHash})
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/bernardmuller/go-idle/config"
+	"github.com/gin-gonic/gin"
+)
+
+// Response schema checking. Outside production, successful responses
+// on routes with a documented shape (openAPIShapes) are validated
+// against their OpenAPI schema, so a handler that drifts from the
+// contract is caught in dev or staging rather than by a client.
+// RESPONSE_SCHEMA_CHECK picks the reaction: "log" (the default outside
+// production) records mismatches, "fail" additionally turns the
+// response into a 500, and "off" disables the check. Only routes
+// listed in openAPIShapes pay the buffering cost.
+
+func schemaCheckMode() string {
+	if config.Get().Env == "production" {
+		return "off"
+	}
+	switch v := os.Getenv("RESPONSE_SCHEMA_CHECK"); v {
+	case "off", "fail":
+		return v
+	default:
+		return "log"
+	}
+}
+
+// schemaForRoute resolves the documented 200-response schema for a
+// mounted route, nil when the route has none.
+func schemaForRoute(method, path string) gin.H {
+	shape, ok := openAPIShapes[method+" "+ginPathToOpenAPI(path)]
+	if !ok || shape.Response == "" {
+		return nil
+	}
+	schema, _ := openAPISchemas[shape.Response].(gin.H)
+	return schema
+}
+
+// resolveSchemaRef follows a $ref into the schema components.
+func resolveSchemaRef(schema gin.H) gin.H {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		if resolved, ok := openAPISchemas[name].(gin.H); ok {
+			return resolved
+		}
+	}
+	return schema
+}
+
+// checkSchema validates a decoded JSON value against the subset of
+// JSON Schema the documented shapes use: type, properties, required,
+// items, and $ref. Unknown properties pass — responses may carry more
+// than they promise — and formats aren't checked.
+func checkSchema(schema gin.H, value interface{}, path string) []string {
+	schema = resolveSchemaRef(schema)
+	typ, _ := schema["type"].(string)
+	if value == nil {
+		// null is fine for optional fields; required-ness is enforced
+		// on the parent object.
+		return nil
+	}
+
+	switch typ {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected object, got %T", path, value)}
+		}
+		var problems []string
+		if required, ok := schema["required"].([]string); ok {
+			for _, name := range required {
+				if _, present := obj[name]; !present {
+					problems = append(problems, fmt.Sprintf("%s: missing required property %q", path, name))
+				}
+			}
+		}
+		if props, ok := schema["properties"].(gin.H); ok {
+			for name, raw := range props {
+				propSchema, ok := raw.(gin.H)
+				if !ok {
+					continue
+				}
+				if v, present := obj[name]; present {
+					problems = append(problems, checkSchema(propSchema, v, path+"."+name)...)
+				}
+			}
+		}
+		return problems
+	case "array":
+		arr, ok := value.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected array, got %T", path, value)}
+		}
+		items, ok := schema["items"].(gin.H)
+		if !ok {
+			return nil
+		}
+		var problems []string
+		for i, item := range arr {
+			problems = append(problems, checkSchema(items, item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return problems
+	case "string":
+		if _, ok := value.(string); !ok {
+			return []string{fmt.Sprintf("%s: expected string, got %T", path, value)}
+		}
+	case "integer", "number":
+		if _, ok := value.(float64); !ok {
+			return []string{fmt.Sprintf("%s: expected %s, got %T", path, typ, value)}
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return []string{fmt.Sprintf("%s: expected boolean, got %T", path, value)}
+		}
+	}
+	return nil
+}
+
+// schemaCheckWriter buffers the response instead of writing through,
+// so fail mode can still replace it.
+type schemaCheckWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *schemaCheckWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *schemaCheckWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+// SchemaCheckMiddleware validates documented responses in dev and
+// staging; see the file comment for modes.
+func SchemaCheckMiddleware(c *gin.Context) {
+	mode := schemaCheckMode()
+	if mode == "off" {
+		c.Next()
+		return
+	}
+	schema := schemaForRoute(c.Request.Method, c.FullPath())
+	if schema == nil {
+		c.Next()
+		return
+	}
+
+	writer := &schemaCheckWriter{ResponseWriter: c.Writer}
+	c.Writer = writer
+	c.Next()
+	c.Writer = writer.ResponseWriter
+
+	body := writer.body.Bytes()
+	if writer.Status() == 200 {
+		var decoded interface{}
+		problems := []string{"response is not valid JSON"}
+		if err := json.Unmarshal(body, &decoded); err == nil {
+			problems = checkSchema(schema, decoded, "$")
+		}
+		if len(problems) > 0 {
+			route := c.Request.Method + " " + c.FullPath()
+			log.Printf("schema: %s drifted from its documented shape: %s", route, strings.Join(problems, "; "))
+			if mode == "fail" {
+				c.Writer.WriteHeader(500)
+				c.Writer.Write([]byte(`{"error":"response failed schema validation"}`))
+				return
+			}
+		}
+	}
+	c.Writer.Write(body)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Schema drift detection. The model set in migratedModels is the
+// source of truth; `go-idle db diff` compares the live schema against
+// it and reports tables or columns someone added by hand, expected
+// columns that are missing, and dropped indexes. With
+// SCHEMA_DRIFT_STRICT=true the same check runs at startup and a
+// drifted schema keeps the server from coming up.
+
+// DetectSchemaDrift returns one human-readable line per divergence
+// between the live schema and the model set.
+func DetectSchemaDrift(db *gorm.DB) ([]string, error) {
+	migrator := db.Migrator()
+	var drift []string
+
+	for _, model := range migratedModels {
+		parsed, err := schema.Parse(model, &sync.Map{}, db.NamingStrategy)
+		if err != nil {
+			return nil, err
+		}
+
+		if !migrator.HasTable(model) {
+			drift = append(drift, fmt.Sprintf("table %s: missing", parsed.Table))
+			continue
+		}
+
+		expected := map[string]bool{}
+		for _, field := range parsed.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			expected[field.DBName] = true
+			if !migrator.HasColumn(model, field.DBName) {
+				drift = append(drift, fmt.Sprintf("table %s: column %s missing", parsed.Table, field.DBName))
+			}
+		}
+
+		// Columns in the live table that no model field accounts for
+		// were added by hand.
+		columns, err := migrator.ColumnTypes(model)
+		if err == nil {
+			for _, col := range columns {
+				if !expected[col.Name()] {
+					drift = append(drift, fmt.Sprintf("table %s: unexpected column %s", parsed.Table, col.Name()))
+				}
+			}
+		}
+
+		for name := range parsed.ParseIndexes() {
+			if !migrator.HasIndex(model, name) {
+				drift = append(drift, fmt.Sprintf("table %s: index %s missing", parsed.Table, name))
+			}
+		}
+	}
+	return drift, nil
+}
+
+// runDBDiff implements the `db diff` command: print the drift report
+// and exit non-zero when the schema has diverged.
+func runDBDiff() {
+	db, err := openDatabase()
+	if err != nil {
+		log.Fatalf("db diff: %v", err)
+	}
+
+	drift, err := DetectSchemaDrift(db)
+	if err != nil {
+		log.Fatalf("db diff: %v", err)
+	}
+	if len(drift) == 0 {
+		fmt.Println("schema matches the model set")
+		return
+	}
+	for _, line := range drift {
+		fmt.Println(line)
+	}
+	os.Exit(1)
+}
+
+// enforceSchemaDrift fails startup on a drifted schema when strict
+// mode is on.
+func enforceSchemaDrift() {
+	if !strings.EqualFold(os.Getenv("SCHEMA_DRIFT_STRICT"), "true") {
+		return
+	}
+	drift, err := DetectSchemaDrift(DB)
+	if err != nil {
+		log.Fatalf("schema drift check failed: %v", err)
+	}
+	if len(drift) > 0 {
+		log.Fatalf("schema drift detected in strict mode:\n  %s", strings.Join(drift, "\n  "))
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Search indexing. User lookup by fuzzy name/email goes through a
+// SearchIndex instead of LIKE scans. With ELASTICSEARCH_URL set the
+// index lives in Elasticsearch (plain REST, no client dependency);
+// otherwise an in-memory index keeps dev and small deployments
+// working with the same API.
+
+type SearchDoc struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type SearchIndex interface {
+	Index(doc SearchDoc) error
+	Delete(id uint) error
+	Search(query string, limit int) ([]SearchDoc, error)
+}
+
+var userIndex SearchIndex = newSearchIndex()
+
+func newSearchIndex() SearchIndex {
+	if url := os.Getenv("ELASTICSEARCH_URL"); url != "" {
+		return &elasticIndex{baseURL: strings.TrimRight(url, "/") + "/go-idle-users"}
+	}
+	return &memoryIndex{docs: make(map[uint]SearchDoc)}
+}
+
+// memoryIndex is a linear-scan fallback; fine for dev-sized data.
+type memoryIndex struct {
+	mu   sync.RWMutex
+	docs map[uint]SearchDoc
+}
+
+func (m *memoryIndex) Index(doc SearchDoc) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.docs[doc.ID] = doc
+	return nil
+}
+
+func (m *memoryIndex) Delete(id uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.docs, id)
+	return nil
+}
+
+func (m *memoryIndex) Search(query string, limit int) ([]SearchDoc, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	needle := strings.ToLower(query)
+	var out []SearchDoc
+	for _, doc := range m.docs {
+		if strings.Contains(strings.ToLower(doc.Name), needle) ||
+			strings.Contains(strings.ToLower(doc.Email), needle) {
+			out = append(out, doc)
+			if len(out) >= limit {
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+// elasticIndex talks to Elasticsearch over its REST API.
+type elasticIndex struct {
+	baseURL string
+}
+
+func (e *elasticIndex) do(method, path string, body interface{}, out interface{}) error {
+	var reader *bytes.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(raw)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequest(method, e.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("elasticsearch: %s %s returned %d", method, path, resp.StatusCode)
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+func (e *elasticIndex) Index(doc SearchDoc) error {
+	return e.do(http.MethodPut, fmt.Sprintf("/_doc/%d", doc.ID), doc, nil)
+}
+
+func (e *elasticIndex) Delete(id uint) error {
+	return e.do(http.MethodDelete, fmt.Sprintf("/_doc/%d", id), nil, nil)
+}
+
+func (e *elasticIndex) Search(query string, limit int) ([]SearchDoc, error) {
+	body := map[string]interface{}{
+		"size": limit,
+		"query": map[string]interface{}{
+			"multi_match": map[string]interface{}{
+				"query":     query,
+				"fields":    []string{"name", "email"},
+				"fuzziness": "AUTO",
+			},
+		},
+	}
+	var result struct {
+		Hits struct {
+			Hits []struct {
+				Source SearchDoc `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := e.do(http.MethodPost, "/_search", body, &result); err != nil {
+		return nil, err
+	}
+	out := make([]SearchDoc, 0, len(result.Hits.Hits))
+	for _, hit := range result.Hits.Hits {
+		out = append(out, hit.Source)
+	}
+	return out, nil
+}
+
+// indexUser pushes the user's current state into the search index.
+func indexUser(user *models.User) {
+	if user.AnonymizedAt != nil {
+		userIndex.Delete(user.ID)
+		return
+	}
+	userIndex.Index(SearchDoc{ID: user.ID, Name: user.Name, Email: user.Email})
+}
+
+// ReindexAllUsers rebuilds the index from the database, e.g. after
+// pointing at a fresh Elasticsearch cluster.
+func ReindexAllUsers() (int, error) {
+	var users []models.User
+	if err := DB.Where("anonymized_at IS NULL").Find(&users).Error; err != nil {
+		return 0, err
+	}
+	for i := range users {
+		indexUser(&users[i])
+	}
+	return len(users), nil
+}
+
+func SearchUsers(c *gin.Context) {
+	q := ValidateQuery(c)
+	limit := q.Int("limit", 20, 1, 100)
+	if !q.Valid() {
+		return
+	}
+	query := c.Query("q")
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q parameter is required"})
+		return
+	}
+
+	docs, err := userIndex.Search(query, limit)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, docs)
+}
+
+func AdminReindexUsers(c *gin.Context) {
+	count, err := ReindexAllUsers()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, gin.H{"indexed": count})
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/bernardmuller/go-idle/config"
+	"github.com/gin-gonic/gin"
+)
+
+// HTTP server lifecycle. The server runs with explicit read/write/idle
+// timeouts, and a SIGINT or SIGTERM drains in-flight requests within
+// the shutdown deadline before the database pool is closed, so rolling
+// deploys don't drop requests.
+
+func runServer(cfg *config.Config, r *gin.Engine) {
+	srv := &http.Server{
+		Addr:         fmt.Sprintf(":%d", cfg.Port),
+		Handler:      apiVersionHandler{next: r},
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+	}
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("server: %v", err)
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-quit
+	log.Printf("server: received %s, draining for up to %s", sig, cfg.ShutdownTimeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("server: shutdown incomplete: %v", err)
+	}
+
+	// Flush what the background writers still hold, then release the
+	// pool.
+	bulk.Flush()
+	if sqlDB, err := DB.DB(); err == nil {
+		sqlDB.Close()
+	}
+	log.Printf("server: stopped")
+}
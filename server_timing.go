@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Server-Timing. With SERVER_TIMING=true (a dev/staging debug flag),
+// or on request (an X-Server-Timing header from a caller holding the
+// admin permission), responses carry a Server-Timing header breaking
+// the latency into db (statements run with the request's context),
+// cache (user-cache reads on paths that thread their context through),
+// render (JSON marshalling up to the first byte), and total — enough
+// for a client developer to see where a slow call spends its time
+// without access to server traces. The header is composed on the first
+// body write, which gin defers until after the handler has done its
+// work, so the segments are complete by then.
+
+type serverTimingCtxKey struct{}
+
+type serverTiming struct {
+	start time.Time
+	// accumulated nanoseconds, atomics because db callbacks and the
+	// handler share the struct.
+	db          int64
+	cache       int64
+	renderStart int64
+}
+
+func serverTimingDebug() bool { return os.Getenv("SERVER_TIMING") == "true" }
+
+// timingFromContext returns the request's timing sink, nil outside an
+// instrumented request.
+func timingFromContext(ctx context.Context) *serverTiming {
+	t, _ := ctx.Value(serverTimingCtxKey{}).(*serverTiming)
+	return t
+}
+
+// observeCacheTiming adds one cache operation's duration to the
+// request sink, if any.
+func observeCacheTiming(ctx context.Context, d time.Duration) {
+	if t := timingFromContext(ctx); t != nil {
+		atomic.AddInt64(&t.cache, int64(d))
+	}
+}
+
+// serverTimingWriter emits the header just before the first byte.
+type serverTimingWriter struct {
+	gin.ResponseWriter
+	c      *gin.Context
+	timing *serverTiming
+	done   bool
+}
+
+func (w *serverTimingWriter) emit() {
+	if w.done {
+		return
+	}
+	w.done = true
+
+	// The on-request path is only honoured for admins, checked here
+	// because authentication hadn't run when the writer was installed.
+	if !serverTimingDebug() && !userHasPermission(CurrentUserID(w.c), "admin") {
+		return
+	}
+
+	t := w.timing
+	now := time.Now()
+	ms := func(n int64) string { return fmt.Sprintf("%.1f", float64(n)/float64(time.Millisecond)) }
+
+	parts := []string{
+		"db;dur=" + ms(atomic.LoadInt64(&t.db)),
+		"cache;dur=" + ms(atomic.LoadInt64(&t.cache)),
+	}
+	if rs := atomic.LoadInt64(&t.renderStart); rs > 0 {
+		parts = append(parts, "render;dur="+ms(now.UnixNano()-rs))
+	}
+	parts = append(parts, "total;dur="+ms(int64(now.Sub(t.start))))
+	w.Header().Set("Server-Timing", strings.Join(parts, ", "))
+}
+
+func (w *serverTimingWriter) Write(b []byte) (int, error) {
+	w.emit()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *serverTimingWriter) WriteString(s string) (int, error) {
+	w.emit()
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *serverTimingWriter) WriteHeaderNow() {
+	w.emit()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// ServerTimingMiddleware installs the sink and writer when timing was
+// asked for; the common case (neither flag nor header) costs a header
+// lookup.
+func ServerTimingMiddleware(c *gin.Context) {
+	if !serverTimingDebug() && c.GetHeader("X-Server-Timing") == "" {
+		c.Next()
+		return
+	}
+
+	t := &serverTiming{start: time.Now()}
+	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), serverTimingCtxKey{}, t))
+	c.Writer = &serverTimingWriter{ResponseWriter: c.Writer, c: c, timing: t}
+	c.Next()
+}
+
+// --- DB segment ------------------------------------------------------
+
+type dbTimingStartKey struct{}
+
+type serverTimingPlugin struct{}
+
+func (serverTimingPlugin) Name() string { return "go-idle:server-timing" }
+
+// Initialize accumulates statement durations into the request sink,
+// registering around every operation the way the statement counter
+// does.
+func (serverTimingPlugin) Initialize(db *gorm.DB) error {
+	before := func(db *gorm.DB) {
+		if timingFromContext(db.Statement.Context) == nil {
+			return
+		}
+		db.Statement.Context = context.WithValue(db.Statement.Context, dbTimingStartKey{}, time.Now())
+	}
+	after := func(db *gorm.DB) {
+		start, ok := db.Statement.Context.Value(dbTimingStartKey{}).(time.Time)
+		if !ok {
+			return
+		}
+		if t := timingFromContext(db.Statement.Context); t != nil {
+			atomic.AddInt64(&t.db, int64(time.Since(start)))
+		}
+	}
+
+	if err := db.Callback().Query().Before("gorm:query").Register("go-idle:timing_query_begin", before); err != nil {
+		return err
+	}
+	if err := db.Callback().Query().After("gorm:query").Register("go-idle:timing_query", after); err != nil {
+		return err
+	}
+	if err := db.Callback().Row().Before("gorm:row").Register("go-idle:timing_row_begin", before); err != nil {
+		return err
+	}
+	if err := db.Callback().Row().After("gorm:row").Register("go-idle:timing_row", after); err != nil {
+		return err
+	}
+	if err := db.Callback().Raw().Before("gorm:raw").Register("go-idle:timing_raw_begin", before); err != nil {
+		return err
+	}
+	if err := db.Callback().Raw().After("gorm:raw").Register("go-idle:timing_raw", after); err != nil {
+		return err
+	}
+	if err := db.Callback().Create().Before("gorm:create").Register("go-idle:timing_create_begin", before); err != nil {
+		return err
+	}
+	if err := db.Callback().Create().After("gorm:create").Register("go-idle:timing_create", after); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register("go-idle:timing_update_begin", before); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().After("gorm:update").Register("go-idle:timing_update", after); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().Before("gorm:delete").Register("go-idle:timing_delete_begin", before); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().After("gorm:delete").Register("go-idle:timing_delete", after); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+// Session revocation on credential change. A password change or reset
+// revokes the user's sessions and bumps their token version, so access
+// tokens minted under the old password carry a stale "ver" claim and
+// die at the next check even before their expiry. Deployments that
+// prefer a softer cutover set SESSION_REVOKE_GRACE to a duration
+// during which stale-version tokens still pass, and
+// SESSION_REVOKE_ON_PASSWORD_CHANGE=false turns the version check off
+// entirely.
+
+func revokeOnPasswordChange() bool {
+	return os.Getenv("SESSION_REVOKE_ON_PASSWORD_CHANGE") != "false"
+}
+
+func sessionRevokeGrace() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("SESSION_REVOKE_GRACE")); err == nil && d > 0 {
+		return d
+	}
+	return 0
+}
+
+// tokenVersionCurrent checks a verified token's "ver" claim against
+// the user's current token version. Tokens minted before versioning
+// carry no claim and read as version zero, which matches every account
+// that has never changed its password.
+func tokenVersionCurrent(userID uint, claims jwt.MapClaims) bool {
+	if !revokeOnPasswordChange() {
+		return true
+	}
+	user, err := loadUserCached(userID)
+	if err != nil {
+		// No user record means nothing the token can act on.
+		return false
+	}
+
+	ver, _ := claims["ver"].(float64)
+	if uint(ver) >= user.TokenVersion {
+		return true
+	}
+	if grace := sessionRevokeGrace(); grace > 0 && user.PasswordChangedAt != nil &&
+		time.Since(*user.PasswordChangedAt) < grace {
+		return true
+	}
+	return false
+}
+
+// bumpTokenVersion invalidates every token minted under the previous
+// password. The atomic column increment means concurrent changes can't
+// lose a bump.
+func bumpTokenVersion(db *gorm.DB, userID uint) error {
+	err := db.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"token_version":       gorm.Expr("token_version + 1"),
+		"password_changed_at": time.Now(),
+	}).Error
+	if err != nil {
+		return err
+	}
+	userCache.Invalidate(userID)
+	return nil
+}
+
+// revokeUserSessions kills every live session of one user, writing
+// each revocation through the cache; used by the reset flow, where
+// there is no "current session" to spare.
+func revokeUserSessions(userID uint) int {
+	var sessions []models.Session
+	if err := DB.Where("user_id = ? AND revoked_at IS NULL", userID).Find(&sessions).Error; err != nil {
+		return 0
+	}
+	revoked := 0
+	for i := range sessions {
+		if err := revokeSession(&sessions[i]); err == nil {
+			revoked++
+		}
+	}
+	return revoked
+}
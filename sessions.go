@@ -0,0 +1,211 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bernardmuller/go-idle/api"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Session concurrency limits. Each login mints a session row keyed by
+// the token's jti; a user may only hold a bounded number of live
+// sessions, and logging in past the limit evicts the least recently
+// seen one, which invalidates its token.
+
+func maxSessionsPerUser() int {
+	if v, err := strconv.Atoi(os.Getenv("MAX_SESSIONS_PER_USER")); err == nil && v > 0 {
+		return v
+	}
+	return 5
+}
+
+func newTokenID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// createSession records a new session and enforces the concurrency
+// limit by revoking the stalest sessions over it.
+func createSession(c *gin.Context, userID uint, tokenID string) error {
+	now := time.Now()
+	session := models.Session{
+		UserID:     userID,
+		TokenID:    tokenID,
+		UserAgent:  c.GetHeader("User-Agent"),
+		IP:         c.ClientIP(),
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}
+	if err := DB.Create(&session).Error; err != nil {
+		return err
+	}
+
+	var live []models.Session
+	err := DB.Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("last_seen_at DESC").Find(&live).Error
+	if err != nil {
+		return err
+	}
+	if len(live) > maxSessionsPerUser() {
+		for _, stale := range live[maxSessionsPerUser():] {
+			DB.Model(&models.Session{}).Where("id = ?", stale.ID).Update("revoked_at", now)
+		}
+	}
+	return nil
+}
+
+const sessionCacheTTL = 30 * time.Second
+
+// sessionStateCache keeps the per-request jti check off the database.
+// Revocations on this node are written through immediately; a session
+// revoked elsewhere takes at most the TTL to be rejected here.
+var sessionStateCache = struct {
+	mu      sync.Mutex
+	entries map[string]sessionCacheEntry
+}{entries: make(map[string]sessionCacheEntry)}
+
+type sessionCacheEntry struct {
+	active  bool
+	checked time.Time
+}
+
+// sessionCacheRetention is how long an entry may sit unrefreshed
+// before the insert-time sweep drops it. Active entries go stale after
+// sessionCacheTTL anyway; negative ones are kept longer so dead tokens
+// stay cheap to reject, and re-checking one after eviction just costs
+// a database read.
+const sessionCacheRetention = 10 * time.Minute
+
+func cacheSessionState(tokenID string, active bool) {
+	sessionStateCache.mu.Lock()
+	for key, entry := range sessionStateCache.entries {
+		if time.Since(entry.checked) > sessionCacheRetention {
+			delete(sessionStateCache.entries, key)
+		}
+	}
+	sessionStateCache.entries[tokenID] = sessionCacheEntry{active: active, checked: time.Now()}
+	sessionStateCache.mu.Unlock()
+}
+
+// sessionActive reports whether the token's session is still live and
+// touches its last-seen timestamp.
+func sessionActive(tokenID string) bool {
+	if tokenID == "" {
+		return false
+	}
+
+	sessionStateCache.mu.Lock()
+	entry, ok := sessionStateCache.entries[tokenID]
+	sessionStateCache.mu.Unlock()
+	if ok {
+		// A cached revocation is permanent; a cached "active" expires.
+		if !entry.active {
+			return false
+		}
+		if time.Since(entry.checked) < sessionCacheTTL {
+			return true
+		}
+	}
+
+	var session models.Session
+	if err := DB.Where("token_id = ? AND revoked_at IS NULL", tokenID).First(&session).Error; err != nil {
+		cacheSessionState(tokenID, false)
+		return false
+	}
+	// Throttle the touch: once a minute per session is plenty.
+	if time.Since(session.LastSeenAt) > time.Minute {
+		DB.Model(&session).Update("last_seen_at", time.Now())
+	}
+	cacheSessionState(tokenID, true)
+	return true
+}
+
+// sessionView is what a user sees of their own sessions; the jti stays
+// internal.
+func sessionView(s models.Session, currentTokenID string) gin.H {
+	return gin.H{
+		"id":           s.ID,
+		"user_agent":   s.UserAgent,
+		"ip":           s.IP,
+		"created_at":   s.CreatedAt,
+		"last_seen_at": s.LastSeenAt,
+		"current":      s.TokenID == currentTokenID,
+	}
+}
+
+// GetMySessions lists the caller's live sessions, current one first.
+func GetMySessions(c *gin.Context) {
+	var sessions []models.Session
+	err := RequestDB(c).Where("user_id = ? AND revoked_at IS NULL", CurrentUserID(c)).
+		Order("last_seen_at DESC").Find(&sessions).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	current := c.GetString(api.ContextTokenID)
+	out := make([]gin.H, 0, len(sessions))
+	for _, s := range sessions {
+		out = append(out, sessionView(s, current))
+	}
+	RenderJSON(c, http.StatusOK, out)
+}
+
+// revokeSession marks one session dead and writes the revocation
+// through the cache so the token stops working on this node at once.
+func revokeSession(session *models.Session) error {
+	now := time.Now()
+	if err := DB.Model(session).Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	cacheSessionState(session.TokenID, false)
+	return nil
+}
+
+// DeleteMySession revokes one of the caller's sessions by id.
+func DeleteMySession(c *gin.Context) {
+	var session models.Session
+	err := RequestDB(c).Where("id = ? AND user_id = ? AND revoked_at IS NULL", c.Param("id"), CurrentUserID(c)).
+		First(&session).Error
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return
+	}
+	if err := revokeSession(&session); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// LogoutAllSessions revokes every live session the caller holds,
+// including the one making the request.
+func LogoutAllSessions(c *gin.Context) {
+	var sessions []models.Session
+	if err := RequestDB(c).Where("user_id = ? AND revoked_at IS NULL", CurrentUserID(c)).Find(&sessions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for i := range sessions {
+		if err := revokeSession(&sessions[i]); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if cookieAuthEnabled() {
+		clearAuthCookies(c)
+	}
+	RenderJSON(c, http.StatusOK, gin.H{"revoked": len(sessions)})
+}
+
+func sessionUnauthorized(c *gin.Context) {
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "session revoked"})
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+)
+
+// SIEM export. Every audit row is also forwarded to the security
+// team's collector, configured with SIEM_URL: https endpoints get
+// batched NDJSON (or CEF with SIEM_FORMAT=cef), syslog:// endpoints
+// get one line per event over TCP. Events buffer in a bounded channel;
+// delivery retries with backoff, and what was delivered, dropped, or
+// failed shows up in /metrics. The exporter must never block or fail a
+// request, so a full buffer drops the oldest events and counts them.
+
+const (
+	siemBufferCap     = 1024
+	siemBatchMax      = 64
+	siemFlushInterval = 5 * time.Second
+	siemRetries       = 3
+)
+
+var (
+	siemQueue = make(chan models.AuditLog, siemBufferCap)
+
+	siemDelivered int64
+	siemFailed    int64
+	siemDropped   int64
+)
+
+func siemURL() string { return os.Getenv("SIEM_URL") }
+
+// siemEnqueue hands an event to the exporter without ever blocking the
+// caller.
+func siemEnqueue(event models.AuditLog) {
+	if siemURL() == "" {
+		return
+	}
+	select {
+	case siemQueue <- event:
+	default:
+		// Buffer full: drop the oldest so fresh events still get out.
+		select {
+		case <-siemQueue:
+			atomic.AddInt64(&siemDropped, 1)
+		default:
+		}
+		select {
+		case siemQueue <- event:
+		default:
+			atomic.AddInt64(&siemDropped, 1)
+		}
+	}
+}
+
+// cefLine renders one event in ArcSight CEF.
+func cefLine(event models.AuditLog) string {
+	escape := strings.NewReplacer("\\", "\\\\", "|", "\\|", "=", "\\=", "\n", " ")
+	return fmt.Sprintf("CEF:0|go-idle|api|1|%s|%s|5|rt=%d suid=%d duid=%d src=%s msg=%s",
+		escape.Replace(event.Action), escape.Replace(event.Action),
+		event.CreatedAt.UnixMilli(), event.ActorID, event.TargetID,
+		escape.Replace(event.IP), escape.Replace(event.Detail))
+}
+
+// encodeSIEMBatch renders a batch in the configured format, one event
+// per line.
+func encodeSIEMBatch(events []models.AuditLog) []byte {
+	var b bytes.Buffer
+	for _, event := range events {
+		if os.Getenv("SIEM_FORMAT") == "cef" {
+			b.WriteString(cefLine(event))
+		} else {
+			line, _ := json.Marshal(event)
+			b.Write(line)
+		}
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+// deliverSIEMBatch sends one batch, retrying with backoff before
+// giving up on it.
+func deliverSIEMBatch(events []models.AuditLog) {
+	payload := encodeSIEMBatch(events)
+
+	var lastErr error
+	for attempt := 0; attempt <= siemRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(1<<(attempt-1)) * time.Second)
+		}
+		if lastErr = sendSIEMPayload(payload); lastErr == nil {
+			atomic.AddInt64(&siemDelivered, int64(len(events)))
+			return
+		}
+	}
+	atomic.AddInt64(&siemFailed, int64(len(events)))
+	log.Printf("siem: batch of %d dropped after retries: %v", len(events), lastErr)
+}
+
+func sendSIEMPayload(payload []byte) error {
+	target := siemURL()
+	u, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+
+	switch u.Scheme {
+	case "syslog":
+		conn, err := net.DialTimeout("tcp", u.Host, 5*time.Second)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		_, err = conn.Write(payload)
+		return err
+	case "http", "https":
+		contentType := "application/x-ndjson"
+		if os.Getenv("SIEM_FORMAT") == "cef" {
+			contentType = "text/plain"
+		}
+		client := &http.Client{Timeout: 10 * time.Second}
+		resp, err := client.Post(target, contentType, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("siem endpoint returned %d", resp.StatusCode)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported SIEM_URL scheme %q", u.Scheme)
+	}
+}
+
+// StartSIEMExporter drains the buffer in batches, flushing on size or
+// the interval, whichever comes first.
+func StartSIEMExporter() {
+	if siemURL() == "" {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(siemFlushInterval)
+		batch := make([]models.AuditLog, 0, siemBatchMax)
+		for {
+			select {
+			case event := <-siemQueue:
+				batch = append(batch, event)
+				if len(batch) < siemBatchMax {
+					continue
+				}
+			case <-ticker.C:
+				if len(batch) == 0 {
+					continue
+				}
+			}
+			deliverSIEMBatch(batch)
+			batch = make([]models.AuditLog, 0, siemBatchMax)
+		}
+	}()
+}
+
+// writeSIEMMetrics appends the exporter's counters to the /metrics
+// output.
+func writeSIEMMetrics(b *strings.Builder) {
+	if siemURL() == "" {
+		return
+	}
+	b.WriteString("# HELP siem_events_total Audit events by export outcome.\n")
+	b.WriteString("# TYPE siem_events_total counter\n")
+	fmt.Fprintf(b, "siem_events_total{outcome=\"delivered\"} %d\n", atomic.LoadInt64(&siemDelivered))
+	fmt.Fprintf(b, "siem_events_total{outcome=\"failed\"} %d\n", atomic.LoadInt64(&siemFailed))
+	fmt.Fprintf(b, "siem_events_total{outcome=\"dropped\"} %d\n", atomic.LoadInt64(&siemDropped))
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Automatic created_by/updated_by stamping. Authenticate places the
+// acting user on the request context, and a pair of GORM callbacks
+// stamps any model embedding models.Stamps on its way to the database —
+// handlers never have to remember. Background jobs have no actor, so
+// their writes simply leave the columns alone.
+
+// actorKey carries the authenticated user id on a context.Context (as
+// opposed to the gin context), which is what reaches the GORM
+// callbacks through RequestDB.
+type actorKey struct{}
+
+// withActor returns a request context carrying the acting user.
+func withActor(c *gin.Context, userID uint) {
+	ctx := context.WithValue(c.Request.Context(), actorKey{}, userID)
+	c.Request = c.Request.WithContext(ctx)
+}
+
+// contextActor extracts the acting user, zero when there is none.
+func contextActor(ctx context.Context) uint {
+	actor, _ := ctx.Value(actorKey{}).(uint)
+	return actor
+}
+
+// stampColumn sets one audit column when the model declares it and an
+// actor is known.
+func stampColumn(column string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Statement.Schema == nil {
+			return
+		}
+		actor := contextActor(db.Statement.Context)
+		if actor == 0 {
+			return
+		}
+		if field := db.Statement.Schema.LookUpField(column); field != nil {
+			db.Statement.SetColumn(column, actor)
+		}
+	}
+}
+
+// registerStampingCallbacks installs the audit stamping on a
+// connection; creates get both columns, updates only updated_by.
+func registerStampingCallbacks(db *gorm.DB) error {
+	create := db.Callback().Create().Before("gorm:create")
+	if err := create.Register("go-idle:stamp_created_by", stampColumn("created_by")); err != nil {
+		return err
+	}
+	if err := create.Register("go-idle:stamp_updated_by_create", stampColumn("updated_by")); err != nil {
+		return err
+	}
+	update := db.Callback().Update().Before("gorm:update")
+	return update.Register("go-idle:stamp_updated_by", stampColumn("updated_by"))
+}
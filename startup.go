@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Startup dependency waiting. In container orchestration the database
+// routinely comes up after the API; instead of crash-looping we retry
+// with exponential backoff up to a deadline, logging each attempt so
+// the wait is visible in startup logs.
+
+const (
+	startupInitialBackoff = 500 * time.Millisecond
+	startupMaxBackoff     = 10 * time.Second
+)
+
+func startupDeadline() time.Duration {
+	if v, err := strconv.Atoi(os.Getenv("STARTUP_WAIT_SECONDS")); err == nil && v > 0 {
+		return time.Duration(v) * time.Second
+	}
+	return 60 * time.Second
+}
+
+// waitForDependency retries probe with exponential backoff until it
+// succeeds or the startup deadline passes.
+func waitForDependency(name string, probe func() error) error {
+	deadline := time.Now().Add(startupDeadline())
+	backoff := startupInitialBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := probe()
+		if err == nil {
+			if attempt > 1 {
+				log.Printf("startup: %s ready after %d attempts", name, attempt)
+			}
+			return nil
+		}
+		if time.Now().Add(backoff).After(deadline) {
+			return fmt.Errorf("startup: %s not ready before deadline: %w", name, err)
+		}
+
+		log.Printf("startup: waiting for %s (attempt %d): %v", name, attempt, err)
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > startupMaxBackoff {
+			backoff = startupMaxBackoff
+		}
+	}
+}
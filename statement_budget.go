@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Per-request statement budget. A gorm plugin counts every statement
+// executed with the request's context; in dev/staging, requests that
+// blow past the budget are logged (or failed outright) so N+1
+// patterns get caught before production traffic finds them.
+//
+// Only DB calls made through RequestDB (i.e. carrying the request
+// context) are attributed to the request.
+
+type statementCounterKey struct{}
+
+type statementCounter struct{}
+
+func (statementCounter) Name() string { return "go-idle:statement-counter" }
+
+func (statementCounter) Initialize(db *gorm.DB) error {
+	count := func(db *gorm.DB) {
+		if c, ok := db.Statement.Context.Value(statementCounterKey{}).(*int64); ok {
+			atomic.AddInt64(c, 1)
+		}
+	}
+	if err := db.Callback().Query().After("gorm:query").Register("go-idle:count_query", count); err != nil {
+		return err
+	}
+	if err := db.Callback().Row().After("gorm:row").Register("go-idle:count_row", count); err != nil {
+		return err
+	}
+	if err := db.Callback().Raw().After("gorm:raw").Register("go-idle:count_raw", count); err != nil {
+		return err
+	}
+	if err := db.Callback().Create().After("gorm:create").Register("go-idle:count_create", count); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().After("gorm:update").Register("go-idle:count_update", count); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().After("gorm:delete").Register("go-idle:count_delete", count); err != nil {
+		return err
+	}
+	return nil
+}
+
+// RequestDB returns a DB handle bound to the request context so its
+// statements count against the request's budget. Requests routed to a
+// sandbox by SandboxRouter get the sandbox connection instead.
+func RequestDB(c *gin.Context) *gorm.DB {
+	if db, ok := c.Get(sandboxDBKey); ok {
+		return db.(*gorm.DB).WithContext(c.Request.Context())
+	}
+	return DB.WithContext(c.Request.Context())
+}
+
+func statementBudget() int {
+	if v, err := strconv.Atoi(os.Getenv("DB_STATEMENT_BUDGET")); err == nil && v > 0 {
+		return v
+	}
+	return 25
+}
+
+// StatementBudgetGuard attaches a statement counter to the request and
+// complains when the handler exceeded the budget.
+func StatementBudgetGuard(c *gin.Context) {
+	if !chaosAllowed() { // same dev/staging gate as fault injection
+		c.Next()
+		return
+	}
+
+	var count int64
+	ctx := context.WithValue(c.Request.Context(), statementCounterKey{}, &count)
+	c.Request = c.Request.WithContext(ctx)
+
+	c.Next()
+
+	budget := int64(statementBudget())
+	if n := atomic.LoadInt64(&count); n > budget {
+		log.Printf("statement budget: %s %s ran %d statements (budget %d)", c.Request.Method, c.FullPath(), n, budget)
+		if os.Getenv("DB_STATEMENT_BUDGET_ENFORCE") == "true" && !c.Writer.Written() {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":  "statement budget exceeded",
+				"count":  n,
+				"budget": budget,
+			})
+		}
+	}
+}
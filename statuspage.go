@@ -0,0 +1,188 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bernardmuller/go-idle/api"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Public status page. A background sampler probes each dependency once
+// a minute and keeps a 24h in-memory history; GET /status folds the
+// per-component uptime, open incidents, and scheduled maintenance into
+// one payload a status page can embed directly. Incidents are written
+// by admins through /admin/incidents.
+
+const (
+	healthSampleInterval = time.Minute
+	healthHistoryWindow  = 24 * time.Hour
+)
+
+type healthSample struct {
+	At time.Time
+	OK bool
+}
+
+// componentHistory is a per-dependency ring of probe results; samples
+// older than the 24h window are dropped as new ones arrive.
+var componentHistory = struct {
+	mu      sync.Mutex
+	samples map[string][]healthSample
+}{samples: make(map[string][]healthSample)}
+
+func recordHealthSample(component string, ok bool) {
+	componentHistory.mu.Lock()
+	defer componentHistory.mu.Unlock()
+
+	samples := append(componentHistory.samples[component], healthSample{At: time.Now(), OK: ok})
+	cutoff := time.Now().Add(-healthHistoryWindow)
+	for len(samples) > 0 && samples[0].At.Before(cutoff) {
+		samples = samples[1:]
+	}
+	componentHistory.samples[component] = samples
+}
+
+// componentStatus summarizes one dependency: its latest probe and the
+// fraction of the last day it was healthy.
+func componentStatus() []gin.H {
+	componentHistory.mu.Lock()
+	defer componentHistory.mu.Unlock()
+
+	names := make([]string, 0, len(componentHistory.samples))
+	for name := range componentHistory.samples {
+		names = append(names, name)
+	}
+	// Stable order for the public payload.
+	for i := 0; i < len(names); i++ {
+		for j := i + 1; j < len(names); j++ {
+			if names[j] < names[i] {
+				names[i], names[j] = names[j], names[i]
+			}
+		}
+	}
+
+	out := make([]gin.H, 0, len(names))
+	for _, name := range names {
+		samples := componentHistory.samples[name]
+		healthy := 0
+		for _, s := range samples {
+			if s.OK {
+				healthy++
+			}
+		}
+		entry := gin.H{
+			"name":       name,
+			"healthy":    len(samples) > 0 && samples[len(samples)-1].OK,
+			"uptime_24h": 0.0,
+		}
+		if len(samples) > 0 {
+			entry["uptime_24h"] = float64(healthy) / float64(len(samples))
+		}
+		out = append(out, entry)
+	}
+	return out
+}
+
+// searchHealthy probes the search backend. The in-memory index cannot
+// fail; Elasticsearch gets a real round trip.
+func searchHealthy() bool {
+	if os.Getenv("ELASTICSEARCH_URL") == "" {
+		return true
+	}
+	_, err := userIndex.Search("healthcheck", 1)
+	return err == nil
+}
+
+// probeComponents runs one round of dependency checks.
+func probeComponents() {
+	dbOK := false
+	if sqlDB, err := DB.DB(); err == nil {
+		dbOK = sqlDB.Ping() == nil
+	}
+	recordHealthSample("database", dbOK)
+	recordHealthSample("search", searchHealthy())
+}
+
+// StartHealthSampler probes dependencies once a minute. It only reads,
+// so it runs on read-only replicas too.
+func StartHealthSampler() {
+	probeComponents()
+	go func() {
+		for range time.Tick(healthSampleInterval) {
+			probeComponents()
+		}
+	}()
+}
+
+// openIncidents returns incidents that have not been resolved yet,
+// newest first.
+func openIncidents() []models.Incident {
+	var incidents []models.Incident
+	DB.Where("status <> ?", "resolved").Order("created_at desc").Find(&incidents)
+	return incidents
+}
+
+type IncidentInput struct {
+	Title    string `json:"title" binding:"required,min=3,max=200"`
+	Body     string `json:"body"`
+	Severity string `json:"severity" binding:"required,oneof=minor major critical"`
+	Status   string `json:"status" binding:"omitempty,oneof=investigating identified monitoring resolved"`
+}
+
+func AdminListIncidents(c *gin.Context) {
+	var incidents []models.Incident
+	if err := RequestDB(c).Order("created_at desc").Find(&incidents).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, incidents)
+}
+
+func AdminCreateIncident(c *gin.Context) {
+	var input IncidentInput
+	if !api.BindJSON(c, &input) {
+		return
+	}
+	if input.Status == "" {
+		input.Status = "investigating"
+	}
+
+	incident := models.Incident{Title: input.Title, Body: input.Body, Severity: input.Severity, Status: input.Status}
+	if err := RequestDB(c).Create(&incident).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusCreated, incident)
+}
+
+func AdminUpdateIncident(c *gin.Context) {
+	var incident models.Incident
+	if err := RequestDB(c).First(&incident, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "incident not found"})
+		return
+	}
+
+	var input IncidentInput
+	if !api.BindJSON(c, &input) {
+		return
+	}
+	incident.Title = input.Title
+	incident.Body = input.Body
+	incident.Severity = input.Severity
+	if input.Status != "" {
+		incident.Status = input.Status
+	}
+	if incident.Status == "resolved" && incident.ResolvedAt == nil {
+		now := time.Now()
+		incident.ResolvedAt = &now
+	}
+	if err := RequestDB(c).Save(&incident).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, incident)
+}
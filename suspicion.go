@@ -0,0 +1,115 @@
+package main
+
+import (
+	"crypto/rand"
+	"math/big"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+)
+
+// Suspicious-login scoring. Every login attempt gets a risk score from
+// cheap signals — recent failures against the account and whether the
+// IP has been seen on one of the user's sessions before. Medium-risk
+// attempts must answer an email OTP before a token is issued;
+// high-risk attempts are refused outright and the account owner is
+// notified. Thresholds are deployment-configurable.
+
+const (
+	riskMediumDefault = 3
+	riskHighDefault   = 6
+
+	loginOTPTTL    = 10 * time.Minute
+	loginOTPDigits = 6
+)
+
+func riskMediumThreshold() int {
+	if v, err := strconv.Atoi(os.Getenv("LOGIN_RISK_MEDIUM")); err == nil && v > 0 {
+		return v
+	}
+	return riskMediumDefault
+}
+
+func riskHighThreshold() int {
+	if v, err := strconv.Atoi(os.Getenv("LOGIN_RISK_HIGH")); err == nil && v > 0 {
+		return v
+	}
+	return riskHighDefault
+}
+
+// loginRiskScore adds up the suspicion signals for one attempt.
+func loginRiskScore(user *models.User, ip string) int {
+	score := authLimiter.Failures("email:" + user.Email)
+
+	// An IP the account has never logged in from is worth two
+	// failures.
+	var known int64
+	DB.Model(&models.Session{}).Where("user_id = ? AND ip = ?", user.ID, ip).Count(&known)
+	if known == 0 {
+		var any int64
+		DB.Model(&models.Session{}).Where("user_id = ?", user.ID).Count(&any)
+		if any > 0 {
+			score += 2
+		}
+	}
+	return score
+}
+
+type otpEntry struct {
+	codeHash string
+	expires  time.Time
+}
+
+var loginOTPs = struct {
+	mu      sync.Mutex
+	entries map[string]otpEntry
+}{entries: make(map[string]otpEntry)}
+
+// issueLoginOTP mails a short-lived one-time code to the account and
+// remembers its hash.
+func issueLoginOTP(user *models.User) error {
+	max := big.NewInt(1)
+	for i := 0; i < loginOTPDigits; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return err
+	}
+	code := n.Add(n, max).String()[1:] // zero-padded to loginOTPDigits
+
+	loginOTPs.mu.Lock()
+	loginOTPs.entries[user.Email] = otpEntry{codeHash: hashResetToken(code), expires: time.Now().Add(loginOTPTTL)}
+	loginOTPs.mu.Unlock()
+
+	subject, body, err := renderEmail("login_otp", map[string]interface{}{"Name": user.Name, "Code": code})
+	if err != nil {
+		return err
+	}
+	return sendEmail(user.Email, subject, body)
+}
+
+// redeemLoginOTP checks and consumes a code.
+func redeemLoginOTP(email, code string) bool {
+	loginOTPs.mu.Lock()
+	defer loginOTPs.mu.Unlock()
+
+	entry, ok := loginOTPs.entries[email]
+	if !ok || time.Now().After(entry.expires) || entry.codeHash != hashResetToken(code) {
+		return false
+	}
+	delete(loginOTPs.entries, email)
+	return true
+}
+
+// notifyBlockedLogin tells the account owner someone was refused.
+func notifyBlockedLogin(user *models.User, ip string) {
+	subject, body, err := renderEmail("login_blocked", map[string]interface{}{"Name": user.Name, "IP": ip})
+	if err != nil {
+		return
+	}
+	sendEmail(user.Email, subject, body)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/bernardmuller/go-idle/gameproto"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Delta sync for mobile clients. Instead of re-fetching state,
+// messages, and config separately, GET /sync?since=<timestamp>
+// returns only the sections that changed since the client last
+// synced; unchanged sections are omitted entirely to keep payloads
+// small on cellular connections.
+
+func GetSync(c *gin.Context) {
+	q := ValidateQuery(c)
+	since := q.Date("since")
+	if !q.Valid() {
+		return
+	}
+
+	userID := CurrentUserID(c)
+	response := syncResponse{ServerTime: time.Now()}
+
+	var player models.Player
+	if err := RequestDB(c).Where("user_id = ?", userID).First(&player).Error; err == nil {
+		if since == nil || player.UpdatedAt.After(*since) {
+			response.Player = &player
+		}
+	}
+
+	// Messages delivered since the last sync.
+	if since != nil {
+		var messages []syncMessage
+		RequestDB(c).Model(&models.UserMessage{}).
+			Select("user_messages.id, admin_messages.title, admin_messages.body, user_messages.created_at").
+			Joins("JOIN admin_messages ON admin_messages.id = user_messages.admin_message_id").
+			Where("user_messages.user_id = ? AND user_messages.created_at > ?", userID, since).
+			Order("user_messages.created_at").
+			Limit(100).
+			Scan(&messages)
+		response.Messages = messages
+	}
+
+	// Config version lets the client decide whether to re-pull the
+	// full document.
+	if cfg, err := loadGameConfig(); err == nil {
+		if since == nil || cfg.UpdatedAt.After(*since) {
+			version := cfg.Version
+			response.ConfigVersion = &version
+		}
+	}
+
+	if gameproto.Accepts(c.GetHeader("Accept")) {
+		pb := gameproto.Sync{
+			ServerTime:    response.ServerTime,
+			Player:        response.Player,
+			ConfigVersion: response.ConfigVersion,
+		}
+		for _, m := range response.Messages {
+			pb.Messages = append(pb.Messages, gameproto.SyncMessage{
+				ID: m.ID, Title: m.Title, Body: m.Body, CreatedAt: m.CreatedAt,
+			})
+		}
+		c.Data(http.StatusOK, gameproto.ContentType, gameproto.MarshalSync(&pb))
+		return
+	}
+	renderHot(c, http.StatusOK, &response)
+}
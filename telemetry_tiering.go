@@ -0,0 +1,242 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/bernardmuller/go-idle/api"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Tiered telemetry storage. Postgres keeps only the hot window
+// (TELEMETRY_HOT_DAYS, default 7); a daily pass moves each complete day
+// past the window into a gzipped NDJSON file under
+// TELEMETRY_ARCHIVE_DIR (typically an object storage mount, like
+// BACKUP_DIR) and deletes the rows. The file is written and renamed
+// into place before the delete, so a crash between the two leaves a
+// duplicate day on disk rather than a lost one. AdminTelemetryHistory
+// is the query shim: it reads the hot table and the cold files behind
+// one endpoint for occasional historical digs.
+
+const (
+	telemetryTierInterval  = time.Hour
+	telemetryArchiveBatch  = 5000
+	telemetryHistoryLimit  = 1000
+	defaultTelemetryHotDay = 7
+)
+
+func telemetryArchiveDir() string {
+	return os.Getenv("TELEMETRY_ARCHIVE_DIR")
+}
+
+func telemetryHotDays() int {
+	if v, err := strconv.Atoi(os.Getenv("TELEMETRY_HOT_DAYS")); err == nil && v > 0 {
+		return v
+	}
+	return defaultTelemetryHotDay
+}
+
+// telemetryHotCutoff is midnight UTC at the start of the hot window;
+// days strictly before it are archive candidates.
+func telemetryHotCutoff() time.Time {
+	now := time.Now().UTC()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return midnight.AddDate(0, 0, -(telemetryHotDays() - 1))
+}
+
+func telemetryArchivePath(day time.Time) string {
+	return filepath.Join(telemetryArchiveDir(), fmt.Sprintf("telemetry-%s.ndjson.gz", day.Format("2006-01-02")))
+}
+
+// archiveTelemetryDay writes one day's rows to the archive file and
+// deletes them from the hot table.
+func archiveTelemetryDay(day time.Time) error {
+	from, to := day, day.AddDate(0, 0, 1)
+
+	tmp, err := os.CreateTemp(telemetryArchiveDir(), "telemetry-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	zw := gzip.NewWriter(tmp)
+	enc := json.NewEncoder(zw)
+	var (
+		lastID uint
+		total  int64
+	)
+	for {
+		var batch []models.TelemetryEvent
+		err := DB.Where("created_at >= ? AND created_at < ? AND id > ?", from, to, lastID).
+			Order("id").Limit(telemetryArchiveBatch).Find(&batch).Error
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		for i := range batch {
+			if err := enc.Encode(&batch[i]); err != nil {
+				tmp.Close()
+				return err
+			}
+		}
+		total += int64(len(batch))
+		if len(batch) < telemetryArchiveBatch {
+			break
+		}
+		lastID = batch[len(batch)-1].ID
+	}
+	if err := zw.Close(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), telemetryArchivePath(day)); err != nil {
+		return err
+	}
+
+	if err := DB.Where("created_at >= ? AND created_at < ?", from, to).
+		Delete(&models.TelemetryEvent{}).Error; err != nil {
+		return err
+	}
+	log.Printf("telemetry: archived %s (%d events)", day.Format("2006-01-02"), total)
+	return nil
+}
+
+// tierTelemetry runs one pass: every complete day before the hot
+// window moves to the archive, oldest first.
+func tierTelemetry() {
+	cutoff := telemetryHotCutoff()
+
+	var oldest time.Time
+	row := DB.Model(&models.TelemetryEvent{}).Select("min(created_at)").Row()
+	if err := row.Scan(&oldest); err != nil || oldest.IsZero() {
+		return
+	}
+
+	day := time.Date(oldest.Year(), oldest.Month(), oldest.Day(), 0, 0, 0, 0, time.UTC)
+	for day.Before(cutoff) {
+		if err := archiveTelemetryDay(day); err != nil {
+			log.Printf("telemetry: archiving %s failed: %v", day.Format("2006-01-02"), err)
+			return
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+}
+
+// StartTelemetryTiering runs the archival pass in the background; off
+// unless TELEMETRY_ARCHIVE_DIR is set.
+func StartTelemetryTiering() {
+	if telemetryArchiveDir() == "" {
+		return
+	}
+	go func() {
+		for {
+			tierTelemetry()
+			time.Sleep(telemetryTierInterval)
+		}
+	}()
+}
+
+// readTelemetryArchiveDay loads one archived day, applying the filters
+// and appending to out until the limit is reached.
+func readTelemetryArchiveDay(day time.Time, name string, userID uint, limit int, out []models.TelemetryEvent) ([]models.TelemetryEvent, error) {
+	f, err := os.Open(telemetryArchivePath(day))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return out, nil
+		}
+		return out, err
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return out, err
+	}
+	defer zr.Close()
+
+	dec := json.NewDecoder(zr)
+	for dec.More() && len(out) < limit {
+		var event models.TelemetryEvent
+		if err := dec.Decode(&event); err != nil {
+			return out, err
+		}
+		if name != "" && event.Name != name {
+			continue
+		}
+		if userID != 0 && event.UserID != userID {
+			continue
+		}
+		out = append(out, event)
+	}
+	return out, nil
+}
+
+// AdminTelemetryHistory answers a date-ranged telemetry query across
+// both tiers: archived days come from the cold files, the rest from
+// the hot table.
+func AdminTelemetryHistory(c *gin.Context) {
+	q := api.ValidateQuery(c)
+	fromPtr := q.Date("from")
+	toPtr := q.Date("to")
+	userID := q.Int("user_id", 0, 0, 1<<31)
+	limit := q.Int("limit", 200, 1, telemetryHistoryLimit)
+	if !q.Valid() {
+		return
+	}
+	name := c.Query("name")
+	if fromPtr == nil || toPtr == nil || toPtr.Before(*fromPtr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to are required, with from <= to"})
+		return
+	}
+	from := time.Date(fromPtr.Year(), fromPtr.Month(), fromPtr.Day(), 0, 0, 0, 0, time.UTC)
+	to := time.Date(toPtr.Year(), toPtr.Month(), toPtr.Day(), 0, 0, 0, 0, time.UTC)
+
+	cutoff := telemetryHotCutoff()
+	events := make([]models.TelemetryEvent, 0, limit)
+
+	// Cold portion, day by day, oldest first.
+	if telemetryArchiveDir() != "" {
+		for day := from; day.Before(cutoff) && !day.After(to) && len(events) < limit; day = day.AddDate(0, 0, 1) {
+			var err error
+			events, err = readTelemetryArchiveDay(day, name, uint(userID), limit, events)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "archive read failed: " + err.Error()})
+				return
+			}
+		}
+	}
+
+	// Hot portion.
+	if remaining := limit - len(events); remaining > 0 {
+		query := RequestDB(c).Where("created_at >= ? AND created_at < ?", from, to.AddDate(0, 0, 1))
+		if name != "" {
+			query = query.Where("name = ?", name)
+		}
+		if userID != 0 {
+			query = query.Where("user_id = ?", userID)
+		}
+		var hot []models.TelemetryEvent
+		if err := query.Order("created_at").Limit(remaining).Find(&hot).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		events = append(events, hot...)
+	}
+
+	RenderJSON(c, http.StatusOK, gin.H{
+		"data":       events,
+		"hot_cutoff": cutoff,
+		"truncated":  len(events) >= limit,
+	})
+}
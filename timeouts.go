@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Per-endpoint timeouts. Every route belongs to a priority class that
+// caps how long its request context lives; interactive game calls get
+// a short leash while bulk/background endpoints may run longer. DB
+// work done through RequestDB is cancelled when the deadline passes,
+// and requests that produced nothing by then turn into a 504.
+
+type PriorityClass string
+
+const (
+	PriorityCritical   PriorityClass = "critical"
+	PriorityNormal     PriorityClass = "normal"
+	PriorityBackground PriorityClass = "background"
+)
+
+var priorityTimeouts = map[PriorityClass]time.Duration{
+	PriorityCritical:   3 * time.Second,
+	PriorityNormal:     10 * time.Second,
+	PriorityBackground: 30 * time.Second,
+}
+
+// classTimeout returns the deadline for a class, overridable per
+// environment (REQUEST_TIMEOUT_CRITICAL and friends).
+func classTimeout(class PriorityClass) time.Duration {
+	env := "REQUEST_TIMEOUT_" + strings.ToUpper(string(class))
+	if d, err := time.ParseDuration(os.Getenv(env)); err == nil && d > 0 {
+		return d
+	}
+	return priorityTimeouts[class]
+}
+
+// routePriorities assigns classes to routes that shouldn't use the
+// normal default.
+var routePriorities = map[string]PriorityClass{
+	"POST /login":                        PriorityCritical,
+	"POST /register":                     PriorityCritical,
+	"GET /game/state":                    PriorityCritical,
+	"POST /game/tick":                    PriorityCritical,
+	"POST /telemetry":                    PriorityBackground,
+	"GET /game/poll":                     PriorityBackground,
+	"GET /admin/password-hash/benchmark": PriorityBackground,
+}
+
+func routePriority(method, path string) PriorityClass {
+	if class, ok := routePriorities[method+" "+path]; ok {
+		return class
+	}
+	return PriorityNormal
+}
+
+// RequestTimeouts applies the route's priority-class deadline to the
+// request context.
+func RequestTimeouts(c *gin.Context) {
+	class := routePriority(c.Request.Method, c.FullPath())
+	timeout := classTimeout(class)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+	c.Request = c.Request.WithContext(ctx)
+	c.Set("priorityClass", string(class))
+
+	c.Next()
+
+	if ctx.Err() == context.DeadlineExceeded && !c.Writer.Written() {
+		c.JSON(http.StatusGatewayTimeout, gin.H{
+			"error":          "request deadline exceeded",
+			"priority_class": string(class),
+		})
+	}
+}
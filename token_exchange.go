@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// RFC 8693 token exchange. A backend service holding a user's access
+// token can trade it for a narrower delegated token: shorter-lived,
+// pinned to one audience, and optionally restricted to a subset of the
+// subject's permissions. Callers authenticate with the same client
+// credentials as introspection; the delegated token records the
+// exchanging client in an act (actor) claim per the RFC.
+
+const (
+	tokenExchangeGrant = "urn:ietf:params:oauth:grant-type:token-exchange"
+	accessTokenType    = "urn:ietf:params:oauth:token-type:access_token"
+
+	// delegatedTokenTTL deliberately undercuts the normal token
+	// lifetime: exchanged tokens are for one hop, not for keeping.
+	delegatedTokenTTL = 5 * time.Minute
+)
+
+func exchangeError(c *gin.Context, status int, code, description string) {
+	c.JSON(status, gin.H{"error": code, "error_description": description})
+}
+
+// ExchangeToken implements POST /oauth/token for the token-exchange
+// grant.
+func ExchangeToken(c *gin.Context) {
+	id, secret, ok := c.Request.BasicAuth()
+	if !ok || !introspectionClientOK(id, secret) {
+		c.Header("WWW-Authenticate", `Basic realm="token-exchange"`)
+		exchangeError(c, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		return
+	}
+
+	if c.PostForm("grant_type") != tokenExchangeGrant {
+		exchangeError(c, http.StatusBadRequest, "unsupported_grant_type", "only token-exchange is supported")
+		return
+	}
+	if t := c.PostForm("subject_token_type"); t != "" && t != accessTokenType {
+		exchangeError(c, http.StatusBadRequest, "invalid_request", "unsupported subject_token_type")
+		return
+	}
+	subjectToken := c.PostForm("subject_token")
+	if subjectToken == "" {
+		exchangeError(c, http.StatusBadRequest, "invalid_request", "subject_token is required")
+		return
+	}
+	audience := c.PostForm("audience")
+	if audience == "" {
+		exchangeError(c, http.StatusBadRequest, "invalid_request", "audience is required")
+		return
+	}
+
+	token, err := jwt.Parse(subjectToken, jwtKeyfunc,
+		jwt.WithValidMethods(jwtValidMethods()),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil || !token.Valid {
+		exchangeError(c, http.StatusBadRequest, "invalid_grant", "subject token is invalid or expired")
+		return
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		exchangeError(c, http.StatusBadRequest, "invalid_grant", "subject token is invalid or expired")
+		return
+	}
+	sub, hasSub := claims["sub"].(float64)
+	if !hasSub {
+		exchangeError(c, http.StatusBadRequest, "invalid_grant", "subject token carries no subject")
+		return
+	}
+	// A revoked session must not be laundered into a fresh token.
+	if jti, ok := claims["jti"].(string); ok && !sessionActive(jti) {
+		exchangeError(c, http.StatusBadRequest, "invalid_grant", "subject token's session is revoked")
+		return
+	}
+
+	// The delegated scope can only narrow what the subject already
+	// holds; asking for more is an error, not a silent upgrade.
+	var scopes []string
+	if requested := c.PostForm("scope"); requested != "" {
+		for _, scope := range strings.Fields(requested) {
+			if !userHasPermission(uint(sub), scope) {
+				exchangeError(c, http.StatusBadRequest, "invalid_scope", "subject does not hold scope "+scope)
+				return
+			}
+			scopes = append(scopes, scope)
+		}
+	}
+
+	now := time.Now()
+	delegated := jwt.MapClaims{
+		"sub": uint(sub),
+		"aud": audience,
+		"exp": now.Add(delegatedTokenTTL).Unix(),
+		"iat": now.Unix(),
+		// The jti is fresh and has no session row, so a delegated
+		// token cannot be replayed against this API; it is only good
+		// for the audience it names.
+		"jti": newTokenID(),
+		// act identifies the service the token was delegated to.
+		"act": gin.H{"sub": id},
+	}
+	if len(scopes) > 0 {
+		delegated["scope"] = strings.Join(scopes, " ")
+	}
+
+	signed, err := signToken(delegated)
+	if err != nil {
+		exchangeError(c, http.StatusInternalServerError, "server_error", "failed to sign token")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token":      signed,
+		"issued_token_type": accessTokenType,
+		"token_type":        "Bearer",
+		"expires_in":        int(delegatedTokenTTL.Seconds()),
+	})
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Distributed tracing. TracingMiddleware opens one server span per
+// request, honouring an incoming W3C traceparent header so our spans
+// join the caller's trace, and a gorm plugin hangs a child span off it
+// for every SQL statement carrying the request context. Finished spans
+// are batched to an OTLP/HTTP collector (OTEL_EXPORTER_OTLP_ENDPOINT,
+// protobuf-JSON encoding on /v1/traces); with no endpoint configured
+// the whole subsystem is a no-op and spans are never even allocated.
+// As with metrics and the SIEM export, this speaks the wire format
+// directly instead of pulling in the SDK.
+
+const (
+	traceExportInterval = 5 * time.Second
+	traceExportBatch    = 512
+	traceBufferSize     = 4096
+)
+
+func tracingEndpoint() string {
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+}
+
+func tracingEnabled() bool {
+	return tracingEndpoint() != ""
+}
+
+// traceSpan is one finished span, shaped for the OTLP JSON encoding.
+type traceSpan struct {
+	TraceID  string
+	SpanID   string
+	ParentID string
+	Name     string
+	// Kind uses the OTLP numbering: 2 server, 3 client.
+	Kind  int
+	Start time.Time
+	End   time.Time
+	Attrs map[string]string
+	Error bool
+}
+
+type traceContextKey struct{}
+
+// activeSpan is the live parent carried in the request context.
+type activeSpan struct {
+	traceID string
+	spanID  string
+}
+
+var traceBuffer = make(chan traceSpan, traceBufferSize)
+
+func newTraceID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+func newSpanID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// parseTraceparent extracts the trace and parent span ids from a W3C
+// traceparent header (version-traceid-spanid-flags).
+func parseTraceparent(header string) (traceID, parentID string, ok bool) {
+	if len(header) != 55 || header[2] != '-' || header[35] != '-' || header[52] != '-' {
+		return "", "", false
+	}
+	traceID, parentID = header[3:35], header[36:52]
+	if _, err := hex.DecodeString(traceID); err != nil {
+		return "", "", false
+	}
+	if _, err := hex.DecodeString(parentID); err != nil {
+		return "", "", false
+	}
+	return traceID, parentID, true
+}
+
+// recordSpan hands one finished span to the exporter, dropping it if
+// the buffer is full — tracing must never block a request.
+func recordSpan(span traceSpan) {
+	select {
+	case traceBuffer <- span:
+	default:
+	}
+}
+
+// TracingMiddleware opens the server span and propagates the trace
+// context to the handler chain via the request context and to the
+// client via a traceparent response header.
+func TracingMiddleware(c *gin.Context) {
+	if !tracingEnabled() {
+		c.Next()
+		return
+	}
+
+	traceID, parentID, ok := parseTraceparent(c.GetHeader("traceparent"))
+	if !ok {
+		traceID, parentID = newTraceID(), ""
+	}
+	spanID := newSpanID()
+
+	ctx := context.WithValue(c.Request.Context(), traceContextKey{}, activeSpan{traceID: traceID, spanID: spanID})
+	c.Request = c.Request.WithContext(ctx)
+	c.Header("traceparent", fmt.Sprintf("00-%s-%s-01", traceID, spanID))
+
+	start := time.Now()
+	c.Next()
+
+	path := c.FullPath()
+	if path == "" {
+		path = c.Request.URL.Path
+	}
+	recordSpan(traceSpan{
+		TraceID:  traceID,
+		SpanID:   spanID,
+		ParentID: parentID,
+		Name:     c.Request.Method + " " + path,
+		Kind:     2,
+		Start:    start,
+		End:      time.Now(),
+		Attrs: map[string]string{
+			"http.request.method":       c.Request.Method,
+			"http.route":                path,
+			"http.response.status_code": strconv.Itoa(c.Writer.Status()),
+			"client.address":            c.ClientIP(),
+		},
+		Error: c.Writer.Status() >= 500,
+	})
+}
+
+// --- gorm instrumentation --------------------------------------------
+
+type sqlSpanKey struct{}
+
+type tracingPlugin struct{}
+
+func (tracingPlugin) Name() string { return "go-idle:tracing" }
+
+// Initialize hangs a child span off the request's server span around
+// every statement, mirroring how the statement counter registers.
+func (tracingPlugin) Initialize(db *gorm.DB) error {
+	before := func(db *gorm.DB) {
+		if !tracingEnabled() {
+			return
+		}
+		if _, ok := db.Statement.Context.Value(traceContextKey{}).(activeSpan); !ok {
+			return
+		}
+		db.Statement.Context = context.WithValue(db.Statement.Context, sqlSpanKey{}, time.Now())
+	}
+	after := func(op string) func(db *gorm.DB) {
+		return func(db *gorm.DB) {
+			start, ok := db.Statement.Context.Value(sqlSpanKey{}).(time.Time)
+			if !ok {
+				return
+			}
+			parent := db.Statement.Context.Value(traceContextKey{}).(activeSpan)
+			recordSpan(traceSpan{
+				TraceID:  parent.traceID,
+				SpanID:   newSpanID(),
+				ParentID: parent.spanID,
+				Name:     "db." + op,
+				Kind:     3,
+				Start:    start,
+				End:      time.Now(),
+				Attrs: map[string]string{
+					"db.system":    "postgresql",
+					"db.operation": op,
+					"db.table":     db.Statement.Table,
+				},
+				Error: db.Error != nil,
+			})
+		}
+	}
+
+	if err := db.Callback().Query().Before("gorm:query").Register("go-idle:trace_query_begin", before); err != nil {
+		return err
+	}
+	if err := db.Callback().Query().After("gorm:query").Register("go-idle:trace_query", after("query")); err != nil {
+		return err
+	}
+	if err := db.Callback().Row().Before("gorm:row").Register("go-idle:trace_row_begin", before); err != nil {
+		return err
+	}
+	if err := db.Callback().Row().After("gorm:row").Register("go-idle:trace_row", after("row")); err != nil {
+		return err
+	}
+	if err := db.Callback().Raw().Before("gorm:raw").Register("go-idle:trace_raw_begin", before); err != nil {
+		return err
+	}
+	if err := db.Callback().Raw().After("gorm:raw").Register("go-idle:trace_raw", after("raw")); err != nil {
+		return err
+	}
+	if err := db.Callback().Create().Before("gorm:create").Register("go-idle:trace_create_begin", before); err != nil {
+		return err
+	}
+	if err := db.Callback().Create().After("gorm:create").Register("go-idle:trace_create", after("create")); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register("go-idle:trace_update_begin", before); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().After("gorm:update").Register("go-idle:trace_update", after("update")); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().Before("gorm:delete").Register("go-idle:trace_delete_begin", before); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().After("gorm:delete").Register("go-idle:trace_delete", after("delete")); err != nil {
+		return err
+	}
+	return nil
+}
+
+// --- OTLP export -----------------------------------------------------
+
+// otlpPayload renders a batch in the OTLP/HTTP JSON encoding.
+func otlpPayload(spans []traceSpan) []byte {
+	encoded := make([]map[string]interface{}, 0, len(spans))
+	for _, s := range spans {
+		attrs := make([]map[string]interface{}, 0, len(s.Attrs))
+		for k, v := range s.Attrs {
+			attrs = append(attrs, map[string]interface{}{
+				"key":   k,
+				"value": map[string]string{"stringValue": v},
+			})
+		}
+		span := map[string]interface{}{
+			"traceId":           s.TraceID,
+			"spanId":            s.SpanID,
+			"name":              s.Name,
+			"kind":              s.Kind,
+			"startTimeUnixNano": strconv.FormatInt(s.Start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(s.End.UnixNano(), 10),
+			"attributes":        attrs,
+		}
+		if s.ParentID != "" {
+			span["parentSpanId"] = s.ParentID
+		}
+		if s.Error {
+			span["status"] = map[string]interface{}{"code": 2}
+		}
+		encoded = append(encoded, span)
+	}
+
+	payload := map[string]interface{}{
+		"resourceSpans": []map[string]interface{}{{
+			"resource": map[string]interface{}{
+				"attributes": []map[string]interface{}{{
+					"key":   "service.name",
+					"value": map[string]string{"stringValue": "go-idle"},
+				}},
+			},
+			"scopeSpans": []map[string]interface{}{{
+				"scope": map[string]string{"name": "go-idle"},
+				"spans": encoded,
+			}},
+		}},
+	}
+	raw, _ := json.Marshal(payload)
+	return raw
+}
+
+func exportSpans(spans []traceSpan) {
+	req, err := http.NewRequest(http.MethodPost, tracingEndpoint()+"/v1/traces", bytes.NewReader(otlpPayload(spans)))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Printf("tracing: export failed: %v", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		log.Printf("tracing: collector answered %d", resp.StatusCode)
+	}
+}
+
+var traceExporterOnce sync.Once
+
+// StartTraceExporter drains the span buffer to the collector in
+// batches; a no-op without an endpoint.
+func StartTraceExporter() {
+	if !tracingEnabled() {
+		return
+	}
+	traceExporterOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(traceExportInterval)
+			batch := make([]traceSpan, 0, traceExportBatch)
+			for {
+				select {
+				case span := <-traceBuffer:
+					batch = append(batch, span)
+					if len(batch) >= traceExportBatch {
+						exportSpans(batch)
+						batch = batch[:0]
+					}
+				case <-ticker.C:
+					if len(batch) > 0 {
+						exportSpans(batch)
+						batch = batch[:0]
+					}
+				}
+			}
+		}()
+	})
+}
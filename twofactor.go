@@ -0,0 +1,261 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bernardmuller/go-idle/api"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Optional TOTP two-factor auth. Setup stages a secret and returns the
+// otpauth provisioning URI; verify proves the authenticator works
+// before the account is actually protected, and hands out one-time
+// backup codes (stored hashed, shown once). Once enabled, Login stops
+// at a short-lived challenge token and POST /login/2fa trades it plus a
+// valid code for the real session. The TOTP itself is plain RFC 6238:
+// HMAC-SHA1 over a 30-second counter, six digits, with one step of
+// clock drift tolerated either side.
+
+const (
+	totpStep       = 30 * time.Second
+	totpDigits     = 1000000 // modulus for a six-digit code
+	totpSecretLen  = 20
+	twoFactorTTL   = 5 * time.Minute
+	backupCodeQty  = 10
+	twoFactorIss   = "go-idle"
+	backupCodeByte = 5 // 10 hex chars per code
+)
+
+// base32NoPad is what authenticator apps expect in otpauth URIs.
+var base32NoPad = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// totpCodeAt computes the six-digit code for one time step.
+func totpCodeAt(secret []byte, t time.Time) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(t.Unix()/int64(totpStep.Seconds())))
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0xf
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", value%totpDigits)
+}
+
+// totpValid checks a submitted code against the current step and its
+// immediate neighbours, so slightly skewed clocks still work.
+func totpValid(secretB32, code string) bool {
+	secret, err := base32NoPad.DecodeString(strings.ToUpper(strings.TrimSpace(secretB32)))
+	if err != nil {
+		return false
+	}
+	now := time.Now()
+	for _, drift := range []time.Duration{0, -totpStep, totpStep} {
+		if hmac.Equal([]byte(totpCodeAt(secret, now.Add(drift))), []byte(code)) {
+			return true
+		}
+	}
+	return false
+}
+
+// newTwoFactorChallenge mints the opaque token Login hands back when a
+// 2FA-enabled account passes the password check. It is deliberately not
+// a JWT: nothing but POST /login/2fa accepts it.
+func newTwoFactorChallenge(userID uint) string {
+	nonce := make([]byte, 8)
+	rand.Read(nonce)
+	exp := time.Now().Add(twoFactorTTL).Unix()
+	body := fmt.Sprintf("%d.%d.%s", userID, exp, hex.EncodeToString(nonce))
+	return body + "." + twoFactorChallengeSig(body)
+}
+
+func twoFactorChallengeSig(body string) string {
+	mac := hmac.New(sha256.New, jwtSecret)
+	mac.Write([]byte("2fa-challenge|" + body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// parseTwoFactorChallenge verifies the signature and expiry and returns
+// the user id the challenge was issued for.
+func parseTwoFactorChallenge(token string) (uint, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 4 {
+		return 0, false
+	}
+	body := strings.Join(parts[:3], ".")
+	if !hmac.Equal([]byte(twoFactorChallengeSig(body)), []byte(parts[3])) {
+		return 0, false
+	}
+	userID, err1 := strconv.ParseUint(parts[0], 10, 64)
+	exp, err2 := strconv.ParseInt(parts[1], 10, 64)
+	if err1 != nil || err2 != nil || time.Now().Unix() > exp {
+		return 0, false
+	}
+	return uint(userID), true
+}
+
+// SetupTwoFactor stages a fresh secret on the account and returns it
+// with the provisioning URI; nothing is enforced until verify succeeds.
+func SetupTwoFactor(c *gin.Context) {
+	var user models.User
+	if err := RequestDB(c).First(&user, CurrentUserID(c)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	if user.TOTPEnabledAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "two-factor auth is already enabled"})
+		return
+	}
+
+	raw := make([]byte, totpSecretLen)
+	if _, err := rand.Read(raw); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate secret"})
+		return
+	}
+	secret := base32NoPad.EncodeToString(raw)
+	// At rest the secret lives under the user's data key so a purge can
+	// shred it out of backups; see crypto_shred.go.
+	stored, err := encryptPII(RequestDB(c), user.ID, secret)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to protect secret"})
+		return
+	}
+	if err := RequestDB(c).Model(&user).Update("totp_secret", stored).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save secret"})
+		return
+	}
+	userCache.Invalidate(user.ID)
+
+	uri := fmt.Sprintf("otpauth://totp/%s:%s?secret=%s&issuer=%s",
+		twoFactorIss, url.PathEscape(user.Email), secret, twoFactorIss)
+	RenderJSON(c, http.StatusOK, gin.H{"secret": secret, "otpauth_uri": uri})
+}
+
+type twoFactorVerifyInput struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+// VerifyTwoFactor confirms the authenticator was provisioned correctly,
+// flips enforcement on, and returns the backup codes — the only time
+// they are ever shown in plaintext.
+func VerifyTwoFactor(c *gin.Context) {
+	var input twoFactorVerifyInput
+	if !api.BindJSON(c, &input) {
+		return
+	}
+
+	var user models.User
+	if err := RequestDB(c).First(&user, CurrentUserID(c)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	if user.TOTPEnabledAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "two-factor auth is already enabled"})
+		return
+	}
+	if user.TOTPSecret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "run setup before verifying"})
+		return
+	}
+	secret, err := decryptPII(RequestDB(c), user.ID, user.TOTPSecret)
+	if err != nil || !totpValid(secret, input.Code) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid code"})
+		return
+	}
+
+	codes := make([]string, 0, backupCodeQty)
+	rows := make([]models.TwoFactorBackupCode, 0, backupCodeQty)
+	for i := 0; i < backupCodeQty; i++ {
+		raw := make([]byte, backupCodeByte)
+		if _, err := rand.Read(raw); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate backup codes"})
+			return
+		}
+		code := hex.EncodeToString(raw)
+		codes = append(codes, code)
+		rows = append(rows, models.TwoFactorBackupCode{UserID: user.ID, CodeHash: hashResetToken(code)})
+	}
+
+	now := time.Now()
+	err = RequestDB(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("totp_enabled_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(&rows).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to enable two-factor auth"})
+		return
+	}
+	userCache.Invalidate(user.ID)
+	recordAudit(Audit2FAEnabled, user.ID, 0, c.ClientIP(), "")
+
+	RenderJSON(c, http.StatusOK, gin.H{"enabled": true, "backup_codes": codes})
+}
+
+type twoFactorLoginInput struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required,min=6,max=16"`
+}
+
+// ConfirmTwoFactorLogin trades a challenge token plus a TOTP or backup
+// code for the real session.
+func ConfirmTwoFactorLogin(c *gin.Context) {
+	var input twoFactorLoginInput
+	if !api.BindJSON(c, &input) {
+		return
+	}
+
+	userID, ok := parseTwoFactorChallenge(input.ChallengeToken)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired challenge"})
+		return
+	}
+
+	var user models.User
+	if err := RequestDB(c).First(&user, userID).Error; err != nil || user.TOTPEnabledAt == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired challenge"})
+		return
+	}
+
+	// A decrypt failure (missing master key, shredded data key) must
+	// disqualify the TOTP path entirely — checking an empty secret
+	// instead would accept a code anyone can compute offline. Backup
+	// codes are hashed, not encrypted, so they still work.
+	secret, err := decryptPII(RequestDB(c), user.ID, user.TOTPSecret)
+	totpOK := err == nil && totpValid(secret, input.Code)
+	if !totpOK && !redeemBackupCode(c, user.ID, input.Code) {
+		recordLoginOutcome(false)
+		recordAudit(AuditLoginFailure, 0, user.ID, c.ClientIP(), "2fa code rejected")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid code"})
+		return
+	}
+
+	resetLoginFailures(user.Email)
+	recordLoginOutcome(true)
+	recordAudit(AuditLoginSuccess, user.ID, 0, c.ClientIP(), "2fa")
+
+	issueLoginSession(c, &user)
+}
+
+// redeemBackupCode consumes a backup code; the guarded update makes the
+// burn atomic so a code can never be spent twice.
+func redeemBackupCode(c *gin.Context, userID uint, code string) bool {
+	res := RequestDB(c).Model(&models.TwoFactorBackupCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hashResetToken(code)).
+		Update("used_at", time.Now())
+	return res.Error == nil && res.RowsAffected == 1
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"crypto/rand"
+
+	"github.com/bernardmuller/go-idle/api"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Bulk user migration. POST /admin/users/import streams CSV or NDJSON
+// off the request body row by row — the file never sits in memory —
+// validates each row, and inserts the good ones in batched
+// transactions, answering with a per-line error report for the rest.
+// GET /admin/users/export streams the user table back out the same
+// way, without password hashes and without loading it whole.
+
+const (
+	userImportBatch     = 500
+	userImportMaxErrors = 100
+)
+
+// importRow is one incoming user. PasswordHash is an existing bcrypt
+// hash carried over from the old system; rows without one get an
+// unusable placeholder, so the account can't log in until a password
+// reset.
+type importRow struct {
+	Name         string `json:"name"`
+	Email        string `json:"email"`
+	PasswordHash string `json:"password_hash"`
+}
+
+type importRowError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// userImport accumulates validated rows and flushes them in batches.
+type userImport struct {
+	db       *gorm.DB
+	batch    []models.User
+	lines    []int
+	seen     map[string]bool
+	imported int
+	errors   []importRowError
+}
+
+func (imp *userImport) fail(line int, reason string) {
+	if len(imp.errors) < userImportMaxErrors {
+		imp.errors = append(imp.errors, importRowError{Line: line, Error: reason})
+	}
+}
+
+// unusablePassword is random hex, deliberately not a valid bcrypt
+// hash: comparison always fails, and no hashing cost is paid per row.
+func unusablePassword() string {
+	raw := make([]byte, 16)
+	rand.Read(raw)
+	return "!imported:" + hex.EncodeToString(raw)
+}
+
+func (imp *userImport) add(line int, row importRow) {
+	row.Email = strings.TrimSpace(strings.ToLower(row.Email))
+	row.Name = strings.TrimSpace(row.Name)
+
+	switch {
+	case len(row.Name) < 2:
+		imp.fail(line, "name must be at least 2 characters")
+		return
+	case row.Email == "":
+		imp.fail(line, "email is required")
+		return
+	}
+	if _, err := mail.ParseAddress(row.Email); err != nil {
+		imp.fail(line, "invalid email address")
+		return
+	}
+	if imp.seen[row.Email] {
+		imp.fail(line, "duplicate email within file")
+		return
+	}
+	if row.PasswordHash != "" && !strings.HasPrefix(row.PasswordHash, "$2") {
+		imp.fail(line, "password_hash is not a bcrypt hash")
+		return
+	}
+	imp.seen[row.Email] = true
+
+	password := row.PasswordHash
+	if password == "" {
+		password = unusablePassword()
+	}
+	imp.batch = append(imp.batch, models.User{Name: row.Name, Email: row.Email, Password: password})
+	imp.lines = append(imp.lines, line)
+	if len(imp.batch) >= userImportBatch {
+		imp.flush()
+	}
+}
+
+// flush inserts the pending batch in one transaction. Emails already
+// taken become per-row errors instead of sinking the batch.
+func (imp *userImport) flush() {
+	if len(imp.batch) == 0 {
+		return
+	}
+	batch, lines := imp.batch, imp.lines
+	imp.batch, imp.lines = nil, nil
+
+	emails := make([]string, len(batch))
+	for i, u := range batch {
+		emails[i] = u.Email
+	}
+	var taken []string
+	imp.db.Model(&models.User{}).Where("email IN ?", emails).Pluck("email", &taken)
+	takenSet := make(map[string]bool, len(taken))
+	for _, e := range taken {
+		takenSet[e] = true
+	}
+
+	insert := batch[:0]
+	for i, u := range batch {
+		if takenSet[u.Email] {
+			imp.fail(lines[i], "email already registered")
+			continue
+		}
+		insert = append(insert, u)
+	}
+	if len(insert) == 0 {
+		return
+	}
+
+	err := imp.db.Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(insert, bulkInsertBatch).Error
+	})
+	if err != nil {
+		imp.fail(lines[0], fmt.Sprintf("batch insert failed: %v", err))
+		return
+	}
+	imp.imported += len(insert)
+	for i := range insert {
+		indexUser(&insert[i])
+	}
+}
+
+// AdminImportUsers loads users from a CSV (name,email[,password_hash])
+// or NDJSON body, chosen by Content-Type.
+func AdminImportUsers(c *gin.Context) {
+	imp := &userImport{db: RequestDB(c), seen: make(map[string]bool)}
+
+	var parseErr error
+	switch {
+	case strings.Contains(c.ContentType(), "csv"):
+		parseErr = importCSV(c.Request.Body, imp)
+	case strings.Contains(c.ContentType(), "ndjson") || strings.Contains(c.ContentType(), "json"):
+		parseErr = importNDJSON(c.Request.Body, imp)
+	default:
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "send text/csv or application/x-ndjson"})
+		return
+	}
+	if parseErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": parseErr.Error()})
+		return
+	}
+	imp.flush()
+
+	if imp.errors == nil {
+		imp.errors = []importRowError{}
+	}
+	RenderJSON(c, http.StatusOK, gin.H{
+		"imported": imp.imported,
+		"failed":   len(imp.errors),
+		"errors":   imp.errors,
+	})
+}
+
+// importCSV reads name,email[,password_hash] rows; a header line
+// naming those columns is skipped.
+func importCSV(r io.Reader, imp *userImport) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	line := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: %v", line+1, err)
+		}
+		line++
+		if line == 1 && len(record) > 0 && strings.EqualFold(strings.TrimSpace(record[0]), "name") {
+			continue
+		}
+		if len(record) < 2 {
+			imp.fail(line, "want name,email[,password_hash]")
+			continue
+		}
+		row := importRow{Name: record[0], Email: record[1]}
+		if len(record) > 2 {
+			row.PasswordHash = strings.TrimSpace(record[2])
+		}
+		imp.add(line, row)
+	}
+}
+
+// importNDJSON reads one JSON object per line.
+func importNDJSON(r io.Reader, imp *userImport) error {
+	decoder := json.NewDecoder(r)
+	line := 0
+	for decoder.More() {
+		line++
+		var row importRow
+		if err := decoder.Decode(&row); err != nil {
+			return fmt.Errorf("object %d: %v", line, err)
+		}
+		imp.add(line, row)
+	}
+	return nil
+}
+
+// AdminExportUsers streams the user table as CSV (default) or NDJSON.
+// Password hashes and verification tokens never leave the database.
+// The export is resumable: rows stream in id order, every record
+// carries its id, and ?cursor=<id> restarts strictly after it.
+func AdminExportUsers(c *gin.Context) {
+	q := api.ValidateQuery(c)
+	format := q.Enum("format", "csv", "csv", "ndjson")
+	cursor := q.Int("cursor", 0, 0, 1<<30)
+	if !q.Valid() {
+		return
+	}
+
+	rows, err := RequestDB(c).Model(&models.User{}).
+		Select("id, name, email, created_at, residency").
+		Where("id > ?", cursor).
+		Order("id").Rows()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	filename := fmt.Sprintf("users-%s.%s", time.Now().Format("2006-01-02"), format)
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+
+	stream := newExportStream(c)
+	count := 0
+	switch format {
+	case "csv":
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		w := csv.NewWriter(stream)
+		w.Write([]string{"id", "name", "email", "created_at", "residency"})
+		for rows.Next() {
+			var (
+				id               uint
+				name, email, res string
+				createdAt        time.Time
+			)
+			if err := rows.Scan(&id, &name, &email, &createdAt, &res); err != nil {
+				break
+			}
+			w.Write([]string{fmt.Sprint(id), name, email, createdAt.Format(time.RFC3339), res})
+			if count++; count%exportBatchSize == 0 {
+				w.Flush()
+				stream.Flush()
+			}
+		}
+		w.Flush()
+	case "ndjson":
+		c.Header("Content-Type", "application/x-ndjson")
+		encoder := json.NewEncoder(stream)
+		for rows.Next() {
+			var (
+				id               uint
+				name, email, res string
+				createdAt        time.Time
+			)
+			if err := rows.Scan(&id, &name, &email, &createdAt, &res); err != nil {
+				break
+			}
+			encoder.Encode(gin.H{
+				"id": id, "name": name, "email": email,
+				"created_at": createdAt, "residency": res,
+			})
+			if count++; count%exportBatchSize == 0 {
+				stream.Flush()
+			}
+		}
+	}
+	stream.Flush()
+	c.Status(http.StatusOK)
+}
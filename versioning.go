@@ -0,0 +1,28 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// API versioning. The stable contract lives under /api/v1; the prefix
+// is stripped before the request reaches the router, so every handler
+// serves both the versioned path and the legacy unprefixed one while
+// clients migrate. Responses name the contract they answered with in
+// X-API-Version.
+
+const apiV1Prefix = "/api/v1"
+
+type apiVersionHandler struct {
+	next http.Handler
+}
+
+func (h apiVersionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == apiV1Prefix {
+		r.URL.Path = "/"
+	} else if strings.HasPrefix(r.URL.Path, apiV1Prefix+"/") {
+		r.URL.Path = strings.TrimPrefix(r.URL.Path, apiV1Prefix)
+	}
+	w.Header().Set("X-API-Version", "v1")
+	h.next.ServeHTTP(w, r)
+}
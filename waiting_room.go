@@ -0,0 +1,181 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Login waiting room. On a launch day the login endpoint is CPU-bound
+// on bcrypt; past a concurrency cap more traffic only makes every
+// attempt time out. With LOGIN_MAX_CONCURRENT set, excess attempts get
+// a signed FIFO ticket with their queue position and an estimated wait
+// instead of a hash slot, and retry with it until the watermark of
+// completed logins reaches their number. Tickets are numbered once and
+// admitted strictly in order, so retrying early doesn't jump the
+// queue; abandoned tickets fall out naturally because the watermark
+// never waits for anyone. The room is per-instance — the cap protects
+// this process's CPU — and off without the env knob.
+
+const (
+	queueTicketHeader = "X-Queue-Token"
+	queueTicketTTL    = 10 * time.Minute
+)
+
+// loginMaxConcurrent caps in-flight password checks; 0 disables the
+// waiting room.
+func loginMaxConcurrent() int {
+	if v, err := strconv.Atoi(os.Getenv("LOGIN_MAX_CONCURRENT")); err == nil && v > 0 {
+		return v
+	}
+	return 0
+}
+
+var loginQueue struct {
+	mu         sync.Mutex
+	inFlight   int
+	nextTicket uint64
+	admitted   uint64
+	// avgLoginMS is an EWMA of recent login durations, feeding the wait
+	// estimate.
+	avgLoginMS float64
+}
+
+func queueTicketSig(body string) string {
+	mac := hmac.New(sha256.New, jwtSecret)
+	mac.Write([]byte("login-queue|" + body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// newQueueTicket mints the opaque ticket for one queue number.
+func newQueueTicket(number uint64) string {
+	body := fmt.Sprintf("%d.%d", number, time.Now().Add(queueTicketTTL).Unix())
+	return body + "." + queueTicketSig(body)
+}
+
+// parseQueueTicket verifies a ticket and returns its queue number.
+func parseQueueTicket(token string) (uint64, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, false
+	}
+	body := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(queueTicketSig(body)), []byte(parts[2])) {
+		return 0, false
+	}
+	number, err1 := strconv.ParseUint(parts[0], 10, 64)
+	exp, err2 := strconv.ParseInt(parts[1], 10, 64)
+	if err1 != nil || err2 != nil || time.Now().Unix() > exp {
+		return 0, false
+	}
+	return number, true
+}
+
+// estimatedWait guesses how long a position will take to clear given
+// recent login durations and the concurrency cap.
+func estimatedWait(position uint64, capacity int) time.Duration {
+	avg := loginQueue.avgLoginMS
+	if avg <= 0 {
+		avg = 200
+	}
+	wait := time.Duration(float64(position)*avg/float64(capacity)) * time.Millisecond
+	if wait < time.Second {
+		wait = time.Second
+	}
+	return wait
+}
+
+// queuedResponse parks the client with its ticket, position, and wait
+// estimate. Caller holds the queue mutex.
+func queuedResponse(c *gin.Context, ticket string, number uint64, capacity int) {
+	position := uint64(1)
+	if number > loginQueue.admitted {
+		position = number - loginQueue.admitted
+	}
+	wait := estimatedWait(position, capacity)
+	c.Header(queueTicketHeader, ticket)
+	c.Header("Retry-After", strconv.FormatInt(int64(wait.Seconds())+1, 10))
+	c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+		"error":                  "login is queued due to high load",
+		"queued":                 true,
+		"queue_token":            ticket,
+		"position":               position,
+		"estimated_wait_seconds": int64(wait.Seconds()),
+	})
+}
+
+// releaseLoginSlot frees a slot and folds the login's duration into
+// the wait estimate; the admission watermark advances on arrival, when
+// someone is actually asking to get in.
+func releaseLoginSlot(started time.Time) {
+	loginQueue.mu.Lock()
+	loginQueue.inFlight--
+	elapsed := float64(time.Since(started).Milliseconds())
+	if loginQueue.avgLoginMS == 0 {
+		loginQueue.avgLoginMS = elapsed
+	} else {
+		loginQueue.avgLoginMS = 0.9*loginQueue.avgLoginMS + 0.1*elapsed
+	}
+	loginQueue.mu.Unlock()
+}
+
+// LoginWaitingRoomGuard is attached to the login route through the
+// WaitingRoom column of the route table.
+func LoginWaitingRoomGuard(c *gin.Context) {
+	capacity := loginMaxConcurrent()
+	if capacity == 0 {
+		c.Next()
+		return
+	}
+
+	loginQueue.mu.Lock()
+	// A free slot lets the watermark cover one more waiting ticket per
+	// arrival: the head of the queue gets in on its next retry, and an
+	// abandoned ticket at the head is stepped past by later polls
+	// instead of stalling everyone behind it.
+	if loginQueue.inFlight < capacity && loginQueue.admitted < loginQueue.nextTicket {
+		loginQueue.admitted++
+	}
+	// With slots free and nobody waiting there's no queue to be fair
+	// to; serve straight away.
+	idle := loginQueue.admitted == loginQueue.nextTicket
+	if idle && loginQueue.inFlight < capacity {
+		loginQueue.inFlight++
+		loginQueue.mu.Unlock()
+		started := time.Now()
+		defer releaseLoginSlot(started)
+		c.Next()
+		return
+	}
+
+	if token := c.GetHeader(queueTicketHeader); token != "" {
+		if number, ok := parseQueueTicket(token); ok {
+			if number <= loginQueue.admitted && loginQueue.inFlight < capacity {
+				loginQueue.inFlight++
+				loginQueue.mu.Unlock()
+				started := time.Now()
+				defer releaseLoginSlot(started)
+				c.Next()
+				return
+			}
+			queuedResponse(c, token, number, capacity)
+			loginQueue.mu.Unlock()
+			return
+		}
+		// Invalid or expired ticket: back of the line.
+	}
+
+	loginQueue.nextTicket++
+	number := loginQueue.nextTicket
+	queuedResponse(c, newQueueTicket(number), number, capacity)
+	loginQueue.mu.Unlock()
+}
@@ -0,0 +1,611 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bernardmuller/go-idle/api"
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// WebAuthn passkeys. Users enrol an authenticator against their
+// account and can then sign in without a password (or use the passkey
+// as a second factor by enrolling it alongside TOTP). The protocol
+// pieces — CBOR attestation objects, COSE keys, assertion signatures —
+// are decoded by hand, the same way the Apple and OAuth integrations
+// parse their tokens: registration accepts the "none" attestation
+// format (we trust the browser, not the authenticator vendor), and
+// assertions are verified as ES256 or RS256 signatures over
+// authenticatorData || SHA-256(clientDataJSON). Challenges are
+// single-use, short-lived, and held in memory like the login OTPs.
+
+const (
+	webauthnChallengeTTL = 5 * time.Minute
+	webauthnMaxPasskeys  = 10
+
+	coseAlgES256 = -7
+	coseAlgRS256 = -257
+)
+
+func webauthnRPID() string {
+	if v := os.Getenv("WEBAUTHN_RP_ID"); v != "" {
+		return v
+	}
+	return "localhost"
+}
+
+func webauthnOrigin() string {
+	if v := os.Getenv("WEBAUTHN_ORIGIN"); v != "" {
+		return v
+	}
+	return "http://localhost:8080"
+}
+
+// --- challenge management --------------------------------------------
+
+type webauthnChallenge struct {
+	userID  uint
+	purpose string // "register" or "login"
+	expires time.Time
+}
+
+var webauthnChallenges = struct {
+	sync.Mutex
+	m map[string]webauthnChallenge
+}{m: map[string]webauthnChallenge{}}
+
+// newWebauthnChallenge mints and stores a single-use challenge; userID
+// is zero for discoverable-credential logins.
+func newWebauthnChallenge(userID uint, purpose string) string {
+	raw := make([]byte, 32)
+	rand.Read(raw)
+	challenge := base64.RawURLEncoding.EncodeToString(raw)
+
+	webauthnChallenges.Lock()
+	now := time.Now()
+	for k, v := range webauthnChallenges.m {
+		if now.After(v.expires) {
+			delete(webauthnChallenges.m, k)
+		}
+	}
+	webauthnChallenges.m[challenge] = webauthnChallenge{userID: userID, purpose: purpose, expires: now.Add(webauthnChallengeTTL)}
+	webauthnChallenges.Unlock()
+	return challenge
+}
+
+// takeWebauthnChallenge burns a challenge, returning who it was issued
+// to.
+func takeWebauthnChallenge(challenge, purpose string) (uint, bool) {
+	webauthnChallenges.Lock()
+	defer webauthnChallenges.Unlock()
+	entry, ok := webauthnChallenges.m[challenge]
+	if !ok {
+		return 0, false
+	}
+	delete(webauthnChallenges.m, challenge)
+	if entry.purpose != purpose || time.Now().After(entry.expires) {
+		return 0, false
+	}
+	return entry.userID, true
+}
+
+// --- CBOR ------------------------------------------------------------
+
+// cborReader decodes the definite-length subset CTAP2 authenticators
+// produce.
+type cborReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *cborReader) head() (major byte, value uint64, err error) {
+	if r.pos >= len(r.data) {
+		return 0, 0, fmt.Errorf("cbor: truncated")
+	}
+	b := r.data[r.pos]
+	r.pos++
+	major, info := b>>5, b&0x1f
+	switch {
+	case info < 24:
+		return major, uint64(info), nil
+	case info == 24, info == 25, info == 26, info == 27:
+		n := 1 << (info - 24)
+		if r.pos+n > len(r.data) {
+			return 0, 0, fmt.Errorf("cbor: truncated length")
+		}
+		value = 0
+		for i := 0; i < n; i++ {
+			value = value<<8 | uint64(r.data[r.pos+i])
+		}
+		r.pos += n
+		return major, value, nil
+	default:
+		return 0, 0, fmt.Errorf("cbor: indefinite lengths unsupported")
+	}
+}
+
+// clamp bounds a claimed element count by the input actually left, so
+// preallocations can't be driven past memory by a forged header.
+func (r *cborReader) clamp(n uint64) int {
+	if remaining := uint64(len(r.data) - r.pos); n > remaining {
+		return int(remaining)
+	}
+	return int(n)
+}
+
+func (r *cborReader) bytes(n uint64) ([]byte, error) {
+	if uint64(len(r.data)-r.pos) < n {
+		return nil, fmt.Errorf("cbor: truncated payload")
+	}
+	out := r.data[r.pos : r.pos+int(n)]
+	r.pos += int(n)
+	return out, nil
+}
+
+// value decodes one CBOR item: integers as int64, byte/text strings,
+// arrays, and maps keyed by whatever the item used.
+func (r *cborReader) value() (interface{}, error) {
+	major, val, err := r.head()
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case 0:
+		return int64(val), nil
+	case 1:
+		return -1 - int64(val), nil
+	case 2:
+		return r.bytes(val)
+	case 3:
+		raw, err := r.bytes(val)
+		return string(raw), err
+	case 4:
+		// The count is attacker-controlled; preallocating it verbatim
+		// panics on a crafted header claiming 2^60 elements. Every
+		// element costs at least one byte, so the remaining input bounds
+		// any honest count; dishonest ones fail as truncated below.
+		items := make([]interface{}, 0, r.clamp(val))
+		for i := uint64(0); i < val; i++ {
+			item, err := r.value()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case 5:
+		m := make(map[interface{}]interface{}, r.clamp(val))
+		for i := uint64(0); i < val; i++ {
+			key, err := r.value()
+			if err != nil {
+				return nil, err
+			}
+			item, err := r.value()
+			if err != nil {
+				return nil, err
+			}
+			m[key] = item
+		}
+		return m, nil
+	case 7:
+		// Simple values (true/false/null); nothing we need survives.
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("cbor: unsupported major type %d", major)
+	}
+}
+
+// --- COSE keys and signatures ----------------------------------------
+
+// parseCOSEKey decodes a COSE key and checks it's an algorithm we can
+// verify assertions with.
+func parseCOSEKey(coseKey []byte) (map[interface{}]interface{}, int64, error) {
+	r := &cborReader{data: coseKey}
+	raw, err := r.value()
+	if err != nil {
+		return nil, 0, err
+	}
+	key, ok := raw.(map[interface{}]interface{})
+	if !ok {
+		return nil, 0, fmt.Errorf("cose: key is not a map")
+	}
+	alg, _ := key[int64(3)].(int64)
+	if alg != coseAlgES256 && alg != coseAlgRS256 {
+		return nil, 0, fmt.Errorf("cose: unsupported algorithm %d", alg)
+	}
+	return key, alg, nil
+}
+
+// verifyCOSESignature checks sig over message with a stored COSE key.
+func verifyCOSESignature(coseKey, message, sig []byte) error {
+	key, alg, err := parseCOSEKey(coseKey)
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256(message)
+
+	switch alg {
+	case coseAlgES256:
+		x, _ := key[int64(-2)].([]byte)
+		y, _ := key[int64(-3)].([]byte)
+		if len(x) == 0 || len(y) == 0 {
+			return fmt.Errorf("cose: malformed EC key")
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !ecdsa.VerifyASN1(pub, digest[:], sig) {
+			return fmt.Errorf("cose: signature mismatch")
+		}
+		return nil
+	default: // coseAlgRS256; parseCOSEKey admits nothing else
+		n, _ := key[int64(-1)].([]byte)
+		e, _ := key[int64(-2)].([]byte)
+		if len(n) == 0 || len(e) == 0 {
+			return fmt.Errorf("cose: malformed RSA key")
+		}
+		pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig)
+	}
+}
+
+// --- clientData and authenticator data -------------------------------
+
+type webauthnClientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+// checkClientData validates the browser-signed context and burns the
+// challenge, returning who it was issued to.
+func checkClientData(raw []byte, wantType, purpose string) (uint, error) {
+	var cd webauthnClientData
+	if err := json.Unmarshal(raw, &cd); err != nil {
+		return 0, fmt.Errorf("malformed clientDataJSON")
+	}
+	if cd.Type != wantType {
+		return 0, fmt.Errorf("unexpected ceremony type %q", cd.Type)
+	}
+	if cd.Origin != webauthnOrigin() {
+		return 0, fmt.Errorf("origin mismatch")
+	}
+	userID, ok := takeWebauthnChallenge(cd.Challenge, purpose)
+	if !ok {
+		return 0, fmt.Errorf("unknown or expired challenge")
+	}
+	return userID, nil
+}
+
+// parsedAuthData is the slice of authenticator data we act on.
+type parsedAuthData struct {
+	rpIDHash  []byte
+	flags     byte
+	signCount uint32
+	credID    []byte
+	coseKey   []byte
+}
+
+func parseAuthData(data []byte) (*parsedAuthData, error) {
+	if len(data) < 37 {
+		return nil, fmt.Errorf("authenticator data too short")
+	}
+	out := &parsedAuthData{
+		rpIDHash:  data[:32],
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	if out.flags&0x40 != 0 { // attested credential data present
+		if len(data) < 55 {
+			return nil, fmt.Errorf("attested credential data truncated")
+		}
+		idLen := int(binary.BigEndian.Uint16(data[53:55]))
+		if len(data) < 55+idLen {
+			return nil, fmt.Errorf("credential id truncated")
+		}
+		out.credID = data[55 : 55+idLen]
+		// The COSE key is the next CBOR item; decode it once to find
+		// where it ends so the raw bytes can be stored as-is.
+		r := &cborReader{data: data[55+idLen:]}
+		if _, err := r.value(); err != nil {
+			return nil, fmt.Errorf("malformed credential key: %v", err)
+		}
+		out.coseKey = data[55+idLen : 55+idLen+r.pos]
+	}
+	return out, nil
+}
+
+func checkRPIDHash(hash []byte) error {
+	want := sha256.Sum256([]byte(webauthnRPID()))
+	if !bytes.Equal(hash, want[:]) {
+		return fmt.Errorf("rpId hash mismatch")
+	}
+	return nil
+}
+
+// --- registration ----------------------------------------------------
+
+// BeginPasskeyRegistration returns creation options for the browser's
+// navigator.credentials.create call.
+func BeginPasskeyRegistration(c *gin.Context) {
+	user, err := loadUserCachedCtx(c.Request.Context(), CurrentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	var count int64
+	RequestDB(c).Model(&models.WebauthnCredential{}).Where("user_id = ?", user.ID).Count(&count)
+	if count >= webauthnMaxPasskeys {
+		c.JSON(http.StatusConflict, gin.H{"error": "passkey limit reached"})
+		return
+	}
+
+	RenderJSON(c, http.StatusOK, gin.H{"publicKey": gin.H{
+		"challenge": newWebauthnChallenge(user.ID, "register"),
+		"rp":        gin.H{"id": webauthnRPID(), "name": "go-idle"},
+		"user": gin.H{
+			"id":          base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(uint64(user.ID), 10))),
+			"name":        user.Email,
+			"displayName": user.Name,
+		},
+		"pubKeyCredParams": []gin.H{
+			{"type": "public-key", "alg": coseAlgES256},
+			{"type": "public-key", "alg": coseAlgRS256},
+		},
+		"timeout":     webauthnChallengeTTL.Milliseconds(),
+		"attestation": "none",
+	}})
+}
+
+type passkeyRegisterInput struct {
+	Label    string `json:"label" binding:"max=64"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON" binding:"required"`
+		AttestationObject string `json:"attestationObject" binding:"required"`
+	} `json:"response" binding:"required"`
+}
+
+// FinishPasskeyRegistration verifies the attestation response and
+// stores the credential.
+func FinishPasskeyRegistration(c *gin.Context) {
+	var input passkeyRegisterInput
+	if !api.BindJSON(c, &input) {
+		return
+	}
+
+	clientData, err1 := base64.RawURLEncoding.DecodeString(input.Response.ClientDataJSON)
+	attObj, err2 := base64.RawURLEncoding.DecodeString(input.Response.AttestationObject)
+	if err1 != nil || err2 != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "response fields must be base64url"})
+		return
+	}
+
+	challengeUser, err := checkClientData(clientData, "webauthn.create", "register")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if challengeUser != CurrentUserID(c) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "challenge was issued to a different user"})
+		return
+	}
+
+	r := &cborReader{data: attObj}
+	raw, err := r.value()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "malformed attestation object"})
+		return
+	}
+	att, ok := raw.(map[interface{}]interface{})
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "malformed attestation object"})
+		return
+	}
+	authDataRaw, _ := att["authData"].([]byte)
+	authData, err := parseAuthData(authDataRaw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := checkRPIDHash(authData.rpIDHash); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if authData.flags&0x01 == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user presence not asserted"})
+		return
+	}
+	if len(authData.credID) == 0 || len(authData.coseKey) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no credential in attestation"})
+		return
+	}
+	// Reject keys we couldn't verify assertions with later.
+	if _, _, err := parseCOSEKey(authData.coseKey); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported credential algorithm"})
+		return
+	}
+
+	label := input.Label
+	if label == "" {
+		label = "passkey"
+	}
+	cred := models.WebauthnCredential{
+		UserID:       challengeUser,
+		CredentialID: base64.RawURLEncoding.EncodeToString(authData.credID),
+		PublicKey:    authData.coseKey,
+		SignCount:    authData.signCount,
+		Label:        label,
+	}
+	if err := RequestDB(c).Create(&cred).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "credential already registered"})
+		return
+	}
+	recordAudit(AuditPasskeyAdded, challengeUser, 0, c.ClientIP(), label)
+
+	RenderJSON(c, http.StatusCreated, cred)
+}
+
+// GetMyPasskeys lists the caller's registered passkeys.
+func GetMyPasskeys(c *gin.Context) {
+	var creds []models.WebauthnCredential
+	if err := RequestDB(c).Where("user_id = ?", CurrentUserID(c)).Find(&creds).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, creds)
+}
+
+// DeleteMyPasskey removes one passkey.
+func DeleteMyPasskey(c *gin.Context) {
+	var cred models.WebauthnCredential
+	if err := RequestDB(c).Where("user_id = ?", CurrentUserID(c)).First(&cred, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "passkey not found"})
+		return
+	}
+	if err := RequestDB(c).Delete(&cred).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// --- login -----------------------------------------------------------
+
+type passkeyLoginOptionsInput struct {
+	Email string `json:"email" binding:"omitempty,email"`
+}
+
+// BeginPasskeyLogin returns assertion options. With an email the
+// response names that account's credentials; without one the browser
+// offers discoverable credentials.
+func BeginPasskeyLogin(c *gin.Context) {
+	var input passkeyLoginOptionsInput
+	if !api.BindJSON(c, &input) {
+		return
+	}
+
+	var userID uint
+	allowed := []gin.H{}
+	if input.Email != "" {
+		user, err := authService.Users.ByEmail(c.Request.Context(), input.Email)
+		if err == nil {
+			userID = user.ID
+			var ids []string
+			RequestDB(c).Model(&models.WebauthnCredential{}).Where("user_id = ?", user.ID).Pluck("credential_id", &ids)
+			for _, id := range ids {
+				allowed = append(allowed, gin.H{"type": "public-key", "id": id})
+			}
+		}
+		// An unknown address still gets well-formed options, so the
+		// endpoint doesn't confirm which emails exist.
+	}
+
+	RenderJSON(c, http.StatusOK, gin.H{"publicKey": gin.H{
+		"challenge":        newWebauthnChallenge(userID, "login"),
+		"rpId":             webauthnRPID(),
+		"allowCredentials": allowed,
+		"timeout":          webauthnChallengeTTL.Milliseconds(),
+		"userVerification": "preferred",
+	}})
+}
+
+type passkeyLoginInput struct {
+	ID       string `json:"id" binding:"required"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON" binding:"required"`
+		AuthenticatorData string `json:"authenticatorData" binding:"required"`
+		Signature         string `json:"signature" binding:"required"`
+	} `json:"response" binding:"required"`
+}
+
+// FinishPasskeyLogin verifies the assertion and signs the user in; a
+// valid passkey bypasses the password and TOTP steps.
+func FinishPasskeyLogin(c *gin.Context) {
+	var input passkeyLoginInput
+	if !api.BindJSON(c, &input) {
+		return
+	}
+
+	clientData, err1 := base64.RawURLEncoding.DecodeString(input.Response.ClientDataJSON)
+	authDataRaw, err2 := base64.RawURLEncoding.DecodeString(input.Response.AuthenticatorData)
+	sig, err3 := base64.RawURLEncoding.DecodeString(input.Response.Signature)
+	if err1 != nil || err2 != nil || err3 != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "response fields must be base64url"})
+		return
+	}
+
+	var cred models.WebauthnCredential
+	if err := RequestDB(c).Where("credential_id = ?", input.ID).First(&cred).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credential"})
+		return
+	}
+
+	challengeUser, err := checkClientData(clientData, "webauthn.get", "login")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if challengeUser != 0 && challengeUser != cred.UserID {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "credential does not match challenge"})
+		return
+	}
+
+	authData, err := parseAuthData(authDataRaw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := checkRPIDHash(authData.rpIDHash); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if authData.flags&0x01 == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user presence not asserted"})
+		return
+	}
+
+	// The authenticator signs authenticatorData || SHA-256(clientData).
+	cdHash := sha256.Sum256(clientData)
+	message := append(append([]byte{}, authDataRaw...), cdHash[:]...)
+	if err := verifyCOSESignature(cred.PublicKey, message, sig); err != nil {
+		recordLoginOutcome(false)
+		recordAudit(AuditLoginFailure, 0, cred.UserID, c.ClientIP(), "passkey signature rejected")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credential"})
+		return
+	}
+
+	// A shrinking sign count means a cloned credential somewhere.
+	if authData.signCount != 0 && authData.signCount <= cred.SignCount {
+		recordAudit(AuditLoginFailure, 0, cred.UserID, c.ClientIP(), "passkey sign count regression")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credential"})
+		return
+	}
+	now := time.Now()
+	RequestDB(c).Model(&cred).Updates(map[string]interface{}{"sign_count": authData.signCount, "last_used_at": now})
+
+	var user models.User
+	if err := RequestDB(c).First(&user, cred.UserID).Error; err != nil || user.AnonymizedAt != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credential"})
+		return
+	}
+
+	recordLoginOutcome(true)
+	recordAudit(AuditLoginSuccess, user.ID, 0, c.ClientIP(), "passkey")
+	PublishEvent("login.succeeded", map[string]interface{}{"user_id": user.ID})
+	issueLoginSession(c, &user)
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/bernardmuller/go-idle/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Admin-defined webhooks. Subscriptions name the events they want and
+// optionally a payload template, extra headers, and a filter
+// expression, so receivers that expect a specific format can be fed
+// directly. Deliveries go through the job queue and inherit its
+// retry/backoff and dead-letter behaviour.
+
+const (
+	webhookJobType        = "webhook_delivery"
+	webhookDeliverTimeout = 10 * time.Second
+)
+
+// WebhookEvent is what templates and filters are evaluated against.
+type WebhookEvent struct {
+	Type   string                 `json:"type"`
+	Data   map[string]interface{} `json:"data"`
+	SentAt time.Time              `json:"sent_at"`
+}
+
+type webhookDelivery struct {
+	SubscriptionID uint   `json:"subscription_id"`
+	EventType      string `json:"event_type"`
+	Body           string `json:"body"`
+	ContentType    string `json:"content_type"`
+}
+
+// subscriptionWantsEvent checks the comma-separated event list; "*"
+// subscribes to everything.
+func subscriptionWantsEvent(sub *models.WebhookSubscription, eventType string) bool {
+	for _, e := range strings.Split(sub.Events, ",") {
+		e = strings.TrimSpace(e)
+		if e == "*" || e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// matchWebhookFilter evaluates a field:op:value expression against the
+// event data. An empty filter matches; a malformed one never does.
+func matchWebhookFilter(filter string, data map[string]interface{}) bool {
+	if filter == "" {
+		return true
+	}
+	field, op, want, err := parseWebhookFilter(filter)
+	if err != nil {
+		return false
+	}
+	got, ok := data[field]
+	if !ok {
+		return false
+	}
+
+	gotStr := fmt.Sprintf("%v", got)
+	switch op {
+	case "eq":
+		return gotStr == want
+	case "ne":
+		return gotStr != want
+	}
+
+	// Ordered comparisons are numeric.
+	gotNum, err1 := strconv.ParseFloat(gotStr, 64)
+	wantNum, err2 := strconv.ParseFloat(want, 64)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	switch op {
+	case "lt":
+		return gotNum < wantNum
+	case "lte":
+		return gotNum <= wantNum
+	case "gt":
+		return gotNum > wantNum
+	case "gte":
+		return gotNum >= wantNum
+	}
+	return false
+}
+
+func parseWebhookFilter(filter string) (field, op, value string, err error) {
+	parts := strings.SplitN(filter, ":", 3)
+	if len(parts) != 3 {
+		return "", "", "", fmt.Errorf("filter %q must be field:op:value", filter)
+	}
+	switch parts[1] {
+	case "eq", "ne", "lt", "lte", "gt", "gte":
+	default:
+		return "", "", "", fmt.Errorf("unsupported filter operator %q", parts[1])
+	}
+	return parts[0], parts[1], parts[2], nil
+}
+
+// renderWebhookBody produces the request body for one delivery: the
+// subscription's template if it has one, otherwise the default JSON
+// envelope.
+func renderWebhookBody(sub *models.WebhookSubscription, event WebhookEvent) (body, contentType string, err error) {
+	if sub.Template == "" {
+		raw, err := json.Marshal(event)
+		if err != nil {
+			return "", "", err
+		}
+		return string(raw), "application/json", nil
+	}
+
+	tmpl, err := template.New("webhook").Parse(sub.Template)
+	if err != nil {
+		return "", "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, event); err != nil {
+		return "", "", err
+	}
+	return buf.String(), "application/json", nil
+}
+
+// EmitWebhookEvent fans an event out to every matching subscription by
+// enqueuing one delivery job per receiver. It is subscribed to the
+// event bus (events.go); handlers publish there rather than calling it
+// directly.
+func EmitWebhookEvent(eventType string, data map[string]interface{}) {
+	var subs []models.WebhookSubscription
+	if err := DB.Where("active = ?", true).Find(&subs).Error; err != nil {
+		return
+	}
+	event := WebhookEvent{Type: eventType, Data: data, SentAt: time.Now()}
+
+	for i := range subs {
+		sub := &subs[i]
+		if !subscriptionWantsEvent(sub, eventType) || !matchWebhookFilter(sub.Filter, data) {
+			continue
+		}
+		body, contentType, err := renderWebhookBody(sub, event)
+		if err != nil {
+			continue
+		}
+		payload, _ := json.Marshal(webhookDelivery{
+			SubscriptionID: sub.ID,
+			EventType:      eventType,
+			Body:           body,
+			ContentType:    contentType,
+		})
+		EnqueueJob(webhookJobType, string(payload), time.Now())
+	}
+}
+
+func registerWebhookJob() {
+	RegisterJobHandler(webhookJobType, deliverWebhook)
+}
+
+// deliverWebhook posts one rendered delivery. A non-2xx response is an
+// error so the job queue retries it.
+func deliverWebhook(payload string) error {
+	var delivery webhookDelivery
+	if err := json.Unmarshal([]byte(payload), &delivery); err != nil {
+		return err
+	}
+
+	var sub models.WebhookSubscription
+	if err := DB.First(&sub, delivery.SubscriptionID).Error; err != nil || !sub.Active {
+		// Subscription removed or disabled after enqueue; drop quietly.
+		return nil
+	}
+
+	req, err := http.NewRequest(http.MethodPost, sub.URL, strings.NewReader(delivery.Body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", delivery.ContentType)
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	if sub.Headers != "" {
+		var extra map[string]string
+		if err := json.Unmarshal([]byte(sub.Headers), &extra); err == nil {
+			for k, v := range extra {
+				req.Header.Set(k, v)
+			}
+		}
+	}
+	if sub.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(sub.Secret))
+		mac.Write([]byte(delivery.Body))
+		req.Header.Set("X-Webhook-Signature", hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	client := &http.Client{Timeout: webhookDeliverTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %d: receiver answered %d", sub.ID, resp.StatusCode)
+	}
+	return nil
+}
+
+type WebhookSubscriptionInput struct {
+	URL      string            `json:"url"`
+	Events   []string          `json:"events"`
+	Template string            `json:"template"`
+	Headers  map[string]string `json:"headers"`
+	Filter   string            `json:"filter"`
+}
+
+func AdminListWebhooks(c *gin.Context) {
+	var subs []models.WebhookSubscription
+	if err := RequestDB(c).Find(&subs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, subs)
+}
+
+func AdminCreateWebhook(c *gin.Context) {
+	var input WebhookSubscriptionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	parsed, err := url.Parse(input.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "url must be an absolute http(s) URL"})
+		return
+	}
+	if len(input.Events) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at least one event is required"})
+		return
+	}
+	// Bad templates and filters fail at creation, not at delivery time.
+	if input.Template != "" {
+		if _, err := template.New("webhook").Parse(input.Template); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid template: " + err.Error()})
+			return
+		}
+	}
+	if input.Filter != "" {
+		if _, _, _, err := parseWebhookFilter(input.Filter); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	headers := ""
+	if len(input.Headers) > 0 {
+		raw, _ := json.Marshal(input.Headers)
+		headers = string(raw)
+	}
+
+	sub := models.WebhookSubscription{
+		URL:      input.URL,
+		Secret:   newTokenID(),
+		Events:   strings.Join(input.Events, ","),
+		Template: input.Template,
+		Headers:  headers,
+		Filter:   input.Filter,
+		Active:   true,
+	}
+	if err := RequestDB(c).Create(&sub).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The signing secret is shown exactly once, at creation.
+	RenderJSON(c, http.StatusCreated, gin.H{"subscription": sub, "secret": sub.Secret})
+}
+
+func AdminDeleteWebhook(c *gin.Context) {
+	var sub models.WebhookSubscription
+	if err := RequestDB(c).First(&sub, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+		return
+	}
+	if err := RequestDB(c).Delete(&sub).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// AdminTestWebhook delivers a synthetic event to one subscription
+// synchronously and reports the outcome.
+func AdminTestWebhook(c *gin.Context) {
+	var sub models.WebhookSubscription
+	if err := RequestDB(c).First(&sub, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+		return
+	}
+
+	event := WebhookEvent{Type: "webhook.test", Data: map[string]interface{}{"subscription_id": sub.ID}, SentAt: time.Now()}
+	body, contentType, err := renderWebhookBody(&sub, event)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "template failed: " + err.Error()})
+		return
+	}
+	payload, _ := json.Marshal(webhookDelivery{SubscriptionID: sub.ID, EventType: event.Type, Body: body, ContentType: contentType})
+	if err := deliverWebhook(string(payload)); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	RenderJSON(c, http.StatusOK, gin.H{"delivered": true})
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// WebSocket push with configurable compression and batching. State
+// change notifications are coalesced inside a short window and sent as
+// one frame; permessage-deflate is negotiated when WS_COMPRESSION is
+// on. Both knobs exist because small mobile clients prefer fewer,
+// larger frames while desktop dashboards want latency.
+
+func wsBatchWindow() time.Duration {
+	if v, err := strconv.Atoi(os.Getenv("WS_BATCH_WINDOW_MS")); err == nil && v >= 0 {
+		return time.Duration(v) * time.Millisecond
+	}
+	return 100 * time.Millisecond
+}
+
+func wsBatchMax() int {
+	if v, err := strconv.Atoi(os.Getenv("WS_BATCH_MAX")); err == nil && v > 0 {
+		return v
+	}
+	return 20
+}
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:    1024,
+	WriteBufferSize:   4096,
+	EnableCompression: os.Getenv("WS_COMPRESSION") != "false",
+	CheckOrigin:       func(r *http.Request) bool { return true },
+}
+
+type wsMessage struct {
+	Type    string `json:"type"`
+	Version uint64 `json:"version,omitempty"`
+
+	// Set on maintenance countdown pushes.
+	Message      string `json:"message,omitempty"`
+	SecondsUntil int64  `json:"seconds_until,omitempty"`
+}
+
+// maintenanceCountdownMessage builds the periodic countdown push, or
+// nil when no window is scheduled.
+func maintenanceCountdownMessage() *wsMessage {
+	w := nextMaintenanceWindow()
+	if w == nil {
+		return nil
+	}
+	msg := wsMessage{Type: "maintenance", Message: w.Message}
+	if maintenanceActive(w) {
+		msg.SecondsUntil = 0
+	} else {
+		msg.SecondsUntil = int64(time.Until(w.StartsAt).Seconds())
+	}
+	return &msg
+}
+
+// GameSocket streams state-change notifications over a WebSocket.
+func GameSocket(c *gin.Context) {
+	userID := CurrentUserID(c)
+
+	conn, err := wsUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	conn.EnableWriteCompression(os.Getenv("WS_COMPRESSION") != "false")
+
+	updates, cancel := notifier.Subscribe(userID)
+	defer cancel()
+
+	// Discard client frames but notice disconnects.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	window := wsBatchWindow()
+	maxBatch := wsBatchMax()
+	var batch []wsMessage
+	var flushAt <-chan time.Time
+
+	// Maintenance countdowns piggyback on the same connection.
+	maintTicker := time.NewTicker(30 * time.Second)
+	defer maintTicker.Stop()
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := conn.WriteJSON(batch)
+		batch = nil
+		flushAt = nil
+		return err
+	}
+
+	for {
+		select {
+		case v := <-updates:
+			batch = append(batch, wsMessage{Type: "state", Version: v})
+			if len(batch) >= maxBatch {
+				if err := flush(); err != nil {
+					return
+				}
+			} else if flushAt == nil {
+				flushAt = time.After(window)
+			}
+		case <-flushAt:
+			if err := flush(); err != nil {
+				return
+			}
+		case <-maintTicker.C:
+			if msg := maintenanceCountdownMessage(); msg != nil {
+				batch = append(batch, *msg)
+				if err := flush(); err != nil {
+					return
+				}
+			}
+		case <-done:
+			return
+		case <-c.Request.Context().Done():
+			flush()
+			return
+		}
+	}
+}
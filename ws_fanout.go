@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Clustered WebSocket fan-out. A state bump on one instance only wakes
+// sockets held by that instance, so with several replicas behind the
+// load balancer a tick processed on A never reaches a client connected
+// to B. With REDIS_URL set, bumps are additionally published over
+// Redis pub/sub and every instance replays what the others publish
+// into its local notifier — WebSocket, SSE-style long polls, and the
+// batching logic all keep working unchanged. Channels are sharded by
+// user id so a busy fleet can point tooling at one shard's traffic,
+// and each message carries the publishing instance's id so replicas
+// skip their own echoes. Like the cache, Redis is spoken to in raw
+// RESP; the subscribe connection is separate because it lives in
+// push mode.
+
+const fanoutRedialDelay = 5 * time.Second
+
+// instanceID distinguishes this process's messages from the fleet's.
+var instanceID = func() string {
+	raw := make([]byte, 8)
+	rand.Read(raw)
+	return hex.EncodeToString(raw)
+}()
+
+func wsFanoutShards() int {
+	if v, err := strconv.Atoi(os.Getenv("WS_FANOUT_SHARDS")); err == nil && v > 0 {
+		return v
+	}
+	return 8
+}
+
+func fanoutChannel(userID uint) string {
+	return "goidle:bump:" + strconv.Itoa(int(userID)%wsFanoutShards())
+}
+
+// Delivery metrics, surfaced through /metrics.
+var (
+	fanoutPublished   int64
+	fanoutDropped     int64
+	fanoutReceived    int64
+	fanoutSelfSkipped int64
+)
+
+// fanoutQueue decouples Bump from Redis I/O; nil when fan-out is off.
+var fanoutQueue chan uint
+
+// publishBump hands a bump to the fan-out publisher without blocking
+// the notifier; overflow is dropped and counted.
+func publishBump(userID uint) {
+	if fanoutQueue == nil {
+		return
+	}
+	select {
+	case fanoutQueue <- userID:
+	default:
+		atomic.AddInt64(&fanoutDropped, 1)
+	}
+}
+
+// StartWSFanout wires the publisher and subscriber; a no-op without
+// REDIS_URL or with WS_FANOUT=false.
+func StartWSFanout() {
+	raw := os.Getenv("REDIS_URL")
+	if raw == "" || os.Getenv("WS_FANOUT") == "false" {
+		return
+	}
+	pub, err := newRedisCache(raw)
+	if err != nil {
+		log.Printf("fanout: %v", err)
+		return
+	}
+
+	fanoutQueue = make(chan uint, 1024)
+	go func() {
+		for userID := range fanoutQueue {
+			payload := instanceID + ":" + strconv.Itoa(int(userID))
+			if _, err := pub.command("PUBLISH", fanoutChannel(userID), payload); err != nil {
+				atomic.AddInt64(&fanoutDropped, 1)
+				continue
+			}
+			atomic.AddInt64(&fanoutPublished, 1)
+		}
+	}()
+
+	go subscribeLoop(raw)
+	log.Printf("fanout: clustered bumps over redis, %d shards, instance %s", wsFanoutShards(), instanceID)
+}
+
+// subscribeLoop holds the SUBSCRIBE connection, redialing on any
+// error.
+func subscribeLoop(raw string) {
+	for {
+		if err := subscribeOnce(raw); err != nil {
+			log.Printf("fanout: subscriber: %v", err)
+		}
+		time.Sleep(fanoutRedialDelay)
+	}
+}
+
+func subscribeOnce(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	addr := u.Host
+	if !strings.Contains(addr, ":") {
+		addr += ":6379"
+	}
+	conn, err := net.DialTimeout("tcp", addr, redisTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	writeCmd := func(args ...string) error {
+		var b strings.Builder
+		fmt.Fprintf(&b, "*%d\r\n", len(args))
+		for _, arg := range args {
+			fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+		}
+		_, err := conn.Write([]byte(b.String()))
+		return err
+	}
+
+	if u.User != nil {
+		if password, ok := u.User.Password(); ok && password != "" {
+			if err := writeCmd("AUTH", password); err != nil {
+				return err
+			}
+			if _, err := readFanoutReply(reader); err != nil {
+				return err
+			}
+		}
+	}
+
+	args := []string{"SUBSCRIBE"}
+	for shard := 0; shard < wsFanoutShards(); shard++ {
+		args = append(args, "goidle:bump:"+strconv.Itoa(shard))
+	}
+	// Economy freezes share the subscriber so a freeze on one instance
+	// invalidates the others' caches immediately; see economy_freeze.go.
+	args = append(args, economyFreezeChannel)
+	if err := writeCmd(args...); err != nil {
+		return err
+	}
+
+	// Push mode: every reply from here on is an array; "message"
+	// entries carry channel and payload, the rest are subscribe acks.
+	for {
+		reply, err := readFanoutReply(reader)
+		if err != nil {
+			return err
+		}
+		items, ok := reply.([]interface{})
+		if !ok || len(items) < 3 {
+			continue
+		}
+		if kind, _ := items[0].(string); kind != "message" {
+			continue
+		}
+		if channel, _ := items[1].(string); channel == economyFreezeChannel {
+			invalidateFreezeCache()
+			continue
+		}
+		payload, _ := items[2].(string)
+		sender, idStr, found := strings.Cut(payload, ":")
+		if !found {
+			continue
+		}
+		if sender == instanceID {
+			atomic.AddInt64(&fanoutSelfSkipped, 1)
+			continue
+		}
+		userID, err := strconv.Atoi(idStr)
+		if err != nil || userID <= 0 {
+			continue
+		}
+		atomic.AddInt64(&fanoutReceived, 1)
+		notifier.bump(uint(userID))
+	}
+}
+
+// readFanoutReply parses one RESP reply including arrays, which the
+// cache client never needs but pub/sub push messages are made of.
+func readFanoutReply(reader *bufio.Reader) (interface{}, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("empty reply")
+	}
+	switch line[0] {
+	case '+', ':':
+		return line[1:], nil
+	case '-':
+		return nil, fmt.Errorf("redis: %s", line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		items := make([]interface{}, 0, n)
+		for i := 0; i < n; i++ {
+			item, err := readFanoutReply(reader)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unexpected reply type %q", line[0])
+	}
+}